@@ -42,6 +42,7 @@ func TestRoundtrip(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+
 		out := new(bytes.Buffer)
 		err = Encode(out, img, rt.opts)
 		if err != nil {
@@ -74,7 +75,17 @@ func TestRoundtrip2(t *testing.T) {
 	compare(t, m0, m1)
 }
 
+func TestUnsupported(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	out := new(bytes.Buffer)
+	err := Encode(out, img, &Options{Compression: LZW})
+	if err == nil {
+		t.Error("tiff.Encode(LZW): no error returned, expected an error")
+	}
+}
+
 func benchmarkEncode(b *testing.B, name string, pixelSize int) {
+	b.Helper()
 	img, err := openImage(name)
 	if err != nil {
 		b.Fatal(err)
@@ -17,6 +17,15 @@ const (
 	delete  = `D`
 )
 
+// Actions maps the indication of each auditable action, as recorded, to its
+// name
+var Actions = map[string]string{
+	create:  "create",
+	replace: "replace",
+	update:  "update",
+	delete:  "delete",
+}
+
 // Create records an insert/create/POST action
 func Create(
 	siteID int64,
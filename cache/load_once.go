@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"golang.org/x/sync/singleflight"
+)
+
+var loads singleflight.Group
+
+// loadResult is the value and status returned by a loader
+type loadResult struct {
+	val    interface{}
+	status int
+}
+
+// CacheLoadOnce calls fn to load the item for a cache key that has missed, but
+// if fn is already being called for the same key then it waits for that call
+// and returns the same result. This prevents a burst of requests for a popular
+// item from all querying the database when the item expires from the cache.
+//
+// Callers share the value returned, so it must not be modified in place.
+func CacheLoadOnce(
+	key string,
+	fn func() (interface{}, int, error),
+) (
+	interface{},
+	int,
+	error,
+) {
+	v, err, _ := loads.Do(key, func() (interface{}, error) {
+		val, status, err := fn()
+		return loadResult{val: val, status: status}, err
+	})
+
+	r := v.(loadResult)
+
+	return r.val, r.status, err
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheLoadOnceSharesResult(t *testing.T) {
+	var (
+		calls   int
+		started = make(chan bool)
+		release = make(chan bool)
+	)
+
+	fn := func() (interface{}, int, error) {
+		calls++
+		started <- true
+		<-release
+		return "loaded", http.StatusOK, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan interface{}, 5)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		val, _, _ := CacheLoadOnce("k", fn)
+		results <- val
+	}()
+	<-started
+
+	var ready sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		ready.Add(1)
+		go func() {
+			defer wg.Done()
+			ready.Done()
+			val, _, _ := CacheLoadOnce("k", fn)
+			results <- val
+		}()
+	}
+
+	// Give the other callers time to join the load in flight
+	ready.Wait()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if calls != 1 {
+		t.Errorf("Expected the loader to be called once, got %d", calls)
+	}
+	for val := range results {
+		if val != "loaded" {
+			t.Errorf("Expected every caller to get the loaded value, got %v", val)
+		}
+	}
+}
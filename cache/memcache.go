@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/gob"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/golang/glog"
@@ -16,15 +18,16 @@ import (
 // This allows us to nuke item 1 from cache and to purge the detail and summary
 // for the item at the same time
 const (
-	CacheDetail     int = 1
-	CacheSummary    int = 2
-	CacheTitle      int = 3
-	CacheItem       int = 4
-	CacheDomain     int = 5
-	CacheSubdomain  int = 6
-	CacheUser       int = 7
-	CacheProfileIds int = 8
-	CacheCounts     int = 9
+	CacheDetail       int = 1
+	CacheSummary      int = 2
+	CacheTitle        int = 3
+	CacheItem         int = 4
+	CacheDomain       int = 5
+	CacheSubdomain    int = 6
+	CacheUser         int = 7
+	CacheProfileIds   int = 8
+	CacheCounts       int = 9
+	CacheFollowCounts int = 10
 )
 
 var (
@@ -69,6 +72,42 @@ func CacheSet(key string, data interface{}, timeToLive int32) {
 	}
 }
 
+// CacheAdd puts the given interface into the cache only if the key is not
+// already there, and returns whether it was added. When the cache is disabled
+// nothing can be there already, so it is always added.
+func CacheAdd(key string, data interface{}, timeToLive int32) bool {
+	if !enabled {
+		return true
+	}
+
+	// Encode the data for serialisation in memcache
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(&data)
+	if err != nil {
+		glog.Errorf("enc.Encode(&data) %+v", err)
+		return false
+	}
+
+	err = mc.Add(
+		&memcache.Item{
+			Key:        key,
+			Value:      buf.Bytes(),
+			Expiration: timeToLive, // time in seconds
+		},
+	)
+	if err == memcache.ErrNotStored {
+		return false
+	}
+	if err != nil {
+		// Failing to reach the cache should not block the caller
+		glog.Errorf("mc.Add() %+v", err)
+		return true
+	}
+
+	return true
+}
+
 // CacheGet gets the data for the given key, if the data is in the cache
 func CacheGet(key string, dst interface{}) (interface{}, bool) {
 	if !enabled {
@@ -108,3 +147,64 @@ func CacheDelete(key string) {
 		glog.Warningf("mc.Delete(key) %+v", err)
 	}
 }
+
+// CacheIncrement adds delta to the counter held in key and returns the new
+// value, creating the counter if it does not exist. The expiry is set when the
+// counter is created and is not extended by later increments.
+//
+// Counters are stored as decimal strings as memcache can only increment those,
+// so they must be read with CacheGetCounter and not CacheGet.
+func CacheIncrement(key string, delta uint64, timeToLive int32) (uint64, bool) {
+	if !enabled {
+		return 0, false
+	}
+
+	n, err := mc.Increment(key, delta)
+	if err == memcache.ErrCacheMiss {
+		err = mc.Add(
+			&memcache.Item{
+				Key:        key,
+				Value:      []byte(strconv.FormatUint(delta, 10)),
+				Expiration: timeToLive, // time in seconds
+			},
+		)
+		if err == nil {
+			return delta, true
+		}
+
+		// Another request created the counter first
+		if err == memcache.ErrNotStored {
+			n, err = mc.Increment(key, delta)
+		}
+	}
+	if err != nil {
+		glog.Warningf("mc.Increment(key) %+v", err)
+		return 0, false
+	}
+
+	return n, true
+}
+
+// CacheGetCounter gets the value of a counter created by CacheIncrement. A
+// counter that does not exist is zero.
+func CacheGetCounter(key string) (uint64, bool) {
+	if !enabled {
+		return 0, false
+	}
+
+	item, err := mc.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return 0, true
+	} else if err != nil {
+		glog.Warningf("mc.Get(key) %+v", err)
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(string(item.Value)), 10, 64)
+	if err != nil {
+		glog.Errorf("strconv.ParseUint() %+v", err)
+		return 0, false
+	}
+
+	return n, true
+}
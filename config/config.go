@@ -10,6 +10,17 @@ const CONFIG_FILE string = "/etc/microcosm/api.conf"
 
 const SECTION_API string = "api"
 
+// SECTION_CRON overrides the schedules of cron jobs, keyed by job name
+const SECTION_CRON string = "cron"
+
+// SECTION_CACHE overrides the number of seconds that items are cached for,
+// keyed by item type name, i.e. "profile", or "default" for all other items
+const SECTION_CACHE string = "cache"
+
+// CACHE_TTL_DEFAULT is the SECTION_CACHE key that applies to every item type
+// that does not have a TTL of its own
+const CACHE_TTL_DEFAULT string = "default"
+
 var (
 	KEY_ENVIRONMENT string = "environment"
 
@@ -29,6 +40,10 @@ var (
 	KEY_AWS_ACCESS_KEY_ID     string = "aws_access_key_id"
 	KEY_AWS_SECRET_ACCESS_KEY string = "aws_secret_access_key"
 	KEY_S3_BUCKET             string = "s3_bucket"
+	KEY_S3_REGION             string = "s3_region"
+
+	KEY_STORAGE_BACKEND string = "storage_backend"
+	KEY_STORAGE_PATH    string = "storage_path"
 
 	KEY_MAILGUN_API_URL string = "mailgun_api_url"
 	KEY_MAILGUN_API_KEY string = "mailgun_api_key"
@@ -41,6 +56,35 @@ var (
 	KEY_ELASTICSEARCH_PORT string = "elasticsearch_port"
 
 	KEY_PERSONA_VERIFIER_URL string = "persona_verifier_url"
+
+	KEY_GEOCODER_URL string = "geocoder_url"
+	KEY_GEOCODER_KEY string = "geocoder_key"
+
+	KEY_ONLINE_WINDOW_MINUTES string = "online_window_minutes"
+
+	KEY_MAX_FILE_SIZE_BYTES string = "max_file_size_bytes"
+
+	KEY_AFFWIN_CONFIG_FILE string = "affwin_config_file"
+	KEY_SKIMLINKS_ID       string = "skimlinks_id"
+	KEY_SKIMLINKS_DOMAINS  string = "skimlinks_domains"
+
+	KEY_ACCESS_TOKEN_TTL_DAYS string = "access_token_ttl_days"
+
+	KEY_AUTH_RATE_LIMIT_WINDOW_SECONDS string = "auth_rate_limit_window_seconds"
+	KEY_AUTH_RATE_LIMIT_IP             string = "auth_rate_limit_ip"
+	KEY_AUTH_RATE_LIMIT_EMAIL          string = "auth_rate_limit_email"
+	KEY_AUTH_RATE_LIMIT_FAILURES       string = "auth_rate_limit_failures"
+
+	KEY_EVENT_RATE_LIMIT                string = "event_rate_limit"
+	KEY_EVENT_RATE_LIMIT_WINDOW_SECONDS string = "event_rate_limit_window_seconds"
+
+	// KEY_SEARCH_SNIPPET_MAX_WORDS is the most words in the excerpt of each
+	// search result
+	KEY_SEARCH_SNIPPET_MAX_WORDS string = "search_snippet_max_words"
+
+	// KEY_PROFILE_GENDERS is a comma separated list of the genders that a
+	// profile may choose from, it should include "unspecified"
+	KEY_PROFILE_GENDERS string = "profile_genders"
 )
 
 var configRequiredStrings = []string{
@@ -72,12 +116,45 @@ var configRequiredInt64s = []string{
 	KEY_MEMCACHED_PORT,
 }
 
+// configOptionalInt64s are keys that may be omitted from the config file, the
+// value here is used when the key is absent
+var configOptionalInt64s = map[string]int64{
+	KEY_ACCESS_TOKEN_TTL_DAYS:           90,
+	KEY_AUTH_RATE_LIMIT_EMAIL:           10,
+	KEY_AUTH_RATE_LIMIT_FAILURES:        5,
+	KEY_AUTH_RATE_LIMIT_IP:              30,
+	KEY_AUTH_RATE_LIMIT_WINDOW_SECONDS:  900,
+	KEY_EVENT_RATE_LIMIT:                20,
+	KEY_EVENT_RATE_LIMIT_WINDOW_SECONDS: 86400,
+	KEY_MAX_FILE_SIZE_BYTES:             10485760,
+	KEY_ONLINE_WINDOW_MINUTES:           90,
+	KEY_SEARCH_SNIPPET_MAX_WORDS:        35,
+}
+
+// configOptionalStrings are keys that may be omitted from the config file, the
+// value here is used when the key is absent
+var configOptionalStrings = map[string]string{
+	KEY_AFFWIN_CONFIG_FILE: "",
+	KEY_GEOCODER_KEY:       "",
+	KEY_GEOCODER_URL:       "",
+	KEY_PROFILE_GENDERS:    "female,male,nonbinary,unspecified",
+	KEY_S3_REGION:          "eu-west-1",
+	KEY_SKIMLINKS_DOMAINS:  "",
+	KEY_SKIMLINKS_ID:       "",
+	KEY_STORAGE_BACKEND:    "s3",
+	KEY_STORAGE_PATH:       "",
+}
+
 var CONFIG_STRING = map[string]string{}
 
 var CONFIG_INT64 = map[string]int64{}
 
 var CONFIG_BOOL = map[string]bool{}
 
+var CONFIG_CRON = map[string]string{}
+
+var CONFIG_CACHE_TTL = map[string]int64{}
+
 func init() {
 
 	c, err := goconfig.ReadConfigFile(CONFIG_FILE)
@@ -100,4 +177,50 @@ func init() {
 		}
 		CONFIG_INT64[key] = ii
 	}
+
+	for key, defaultValue := range configOptionalStrings {
+		s, err := c.GetString(SECTION_API, key)
+		if err != nil {
+			s = defaultValue
+		}
+		CONFIG_STRING[key] = s
+	}
+
+	for key, defaultValue := range configOptionalInt64s {
+		ii, err := c.GetInt64(SECTION_API, key)
+		if err != nil {
+			ii = defaultValue
+		}
+		CONFIG_INT64[key] = ii
+	}
+
+	if c.HasSection(SECTION_CRON) {
+		keys, err := c.GetOptions(SECTION_CRON)
+		if err != nil {
+			glog.Fatal(err)
+		}
+
+		for _, key := range keys {
+			s, err := c.GetString(SECTION_CRON, key)
+			if err != nil {
+				glog.Fatal(err)
+			}
+			CONFIG_CRON[key] = s
+		}
+	}
+
+	if c.HasSection(SECTION_CACHE) {
+		keys, err := c.GetOptions(SECTION_CACHE)
+		if err != nil {
+			glog.Fatal(err)
+		}
+
+		for _, key := range keys {
+			ii, err := c.GetInt64(SECTION_CACHE, key)
+			if err != nil {
+				glog.Fatal(err)
+			}
+			CONFIG_CACHE_TTL[key] = ii
+		}
+	}
 }
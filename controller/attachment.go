@@ -55,6 +55,29 @@ func (ctl *AttachmentController) Delete(c *models.Context) {
 		return
 	}
 
+	// Only the owner of an event and moderators may remove its files
+	if itemTypeId == h.ItemTypes[h.ItemTypeEvent] {
+		if !(perms.IsOwner || perms.IsModerator || perms.IsSiteOwner) {
+			c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+			return
+		}
+
+		event, status, err := models.GetEvent(c.Site.Id, itemId, c.Auth.ProfileId)
+		if err != nil {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+
+		status, err = event.DetachFromEvent(fileHash)
+		if err != nil {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+
+		c.RespondWithOK()
+		return
+	}
+
 	metadata, status, err := models.GetMetadata(fileHash)
 	if err != nil {
 		if status == http.StatusNotFound {
@@ -147,9 +170,34 @@ func ParseItemInfo(c *models.Context) (int64, int64, models.PermissionType, int,
 		itemId = commentId
 		itemTypeId = h.ItemTypes[h.ItemTypeComment]
 
+	} else if c.RouteVars["event_id"] != "" {
+
+		eventId, err := strconv.ParseInt(c.RouteVars["event_id"], 10, 64)
+		if err != nil {
+			return 0, 0, models.PermissionType{}, http.StatusBadRequest,
+				errors.New(fmt.Sprintf(
+					"The supplied event ID ('%s') is not a number.",
+					c.RouteVars["event_id"],
+				))
+		}
+		_, status, err := models.GetEventSummary(c.Site.Id, eventId, c.Auth.ProfileId)
+		if err != nil {
+			if status == http.StatusNotFound {
+				return 0, 0, models.PermissionType{}, http.StatusBadRequest,
+					errors.New(fmt.Sprintf(
+						"Event with ID ('%d') does not exist.", eventId,
+					))
+			} else {
+				return 0, 0, models.PermissionType{}, http.StatusBadRequest, err
+			}
+		}
+
+		itemId = eventId
+		itemTypeId = h.ItemTypes[h.ItemTypeEvent]
+
 	} else {
 		return 0, 0, models.PermissionType{}, http.StatusBadRequest,
-			errors.New("You must supply a profile_id or comment_id as a RouteVar")
+			errors.New("You must supply a profile_id, comment_id or event_id as a RouteVar")
 	}
 
 	perms := models.GetPermission(
@@ -169,9 +169,51 @@ func (ctl *AttachmentsController) Create(c *models.Context) {
 		attachment.ItemTypeId = h.ItemTypes[h.ItemTypeComment]
 		path_prefix = h.ApiTypeComment
 
+	} else if c.RouteVars["event_id"] != "" {
+
+		eventId, err := strconv.ParseInt(c.RouteVars["event_id"], 10, 64)
+		if err != nil {
+			c.RespondWithErrorMessage(
+				fmt.Sprintf("The supplied event ID ('%s') is not a number.", c.RouteVars["event_id"]),
+				http.StatusBadRequest,
+			)
+			return
+		}
+
+		// Only the owner of an event and moderators may attach files to it
+		perms := models.GetPermission(
+			models.MakeAuthorisationContext(
+				c, 0, h.ItemTypes[h.ItemTypeEvent], eventId),
+		)
+		if !(perms.IsOwner || perms.IsModerator || perms.IsSiteOwner) {
+			c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+			return
+		}
+
+		event, status, err := models.GetEvent(c.Site.Id, eventId, c.Auth.ProfileId)
+		if err != nil {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+
+		_, status, err = event.AttachToEvent(
+			c.Auth.ProfileId,
+			attachment.FileHash,
+			attachment.FileName,
+		)
+		if err != nil {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+
+		c.RespondWithSeeOther(
+			fmt.Sprintf("%s/%d/%s", h.ApiTypeEvent, eventId, h.ApiTypeAttachment),
+		)
+		return
+
 	} else {
 		c.RespondWithErrorMessage(
-			"You must supply a profile_id or comment_id as a RouteVar",
+			"You must supply a profile_id, comment_id or event_id as a RouteVar",
 			http.StatusBadRequest,
 		)
 		return
@@ -10,6 +10,7 @@ import (
 	"github.com/lib/pq"
 
 	"github.com/microcosm-cc/microcosm/audit"
+	e "github.com/microcosm-cc/microcosm/errors"
 	h "github.com/microcosm-cc/microcosm/helpers"
 	"github.com/microcosm-cc/microcosm/models"
 )
@@ -27,7 +28,7 @@ func AttendeeHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch c.GetHttpMethod() {
 	case "OPTIONS":
-		c.RespondWithOptions([]string{"OPTIONS", "HEAD", "GET", "PUT", "DELETE"})
+		c.RespondWithOptions([]string{"OPTIONS", "HEAD", "GET", "PUT", "PATCH", "DELETE"})
 		return
 	case "HEAD":
 		ctl.Read(c)
@@ -35,6 +36,8 @@ func AttendeeHandler(w http.ResponseWriter, r *http.Request) {
 		ctl.Read(c)
 	case "PUT":
 		ctl.Update(c)
+	case "PATCH":
+		ctl.Patch(c)
 	case "DELETE":
 		ctl.Delete(c)
 	default:
@@ -76,7 +79,7 @@ func (ctl *AttendeeController) Read(c *models.Context) {
 			c, 0, h.ItemTypes[h.ItemTypeAttendee], attendeeId),
 	)
 	if !perms.CanRead {
-		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		c.RespondWithErrorCode(e.NoRead, h.NoAuthMessage, http.StatusForbidden)
 		return
 	}
 	// End Authorisation
@@ -118,28 +121,35 @@ func (ctl *AttendeeController) Update(c *models.Context) {
 		return
 	}
 
+	// Normalised before authorisation as that depends on the RSVP
+	status, err := m.NormaliseRSVP()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
 	// Start Authorisation
 	perms := models.GetPermission(
 		models.MakeAuthorisationContext(
 			c, 0, h.ItemTypes[h.ItemTypeEvent], eventId),
 	)
 	if !perms.CanUpdate {
-		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		c.RespondWithErrorCode(e.NoUpdate, h.NoAuthMessage, http.StatusForbidden)
 		return
 	}
 
 	if perms.IsOwner || perms.IsModerator || perms.IsSiteOwner {
-		if m.ProfileId != c.Auth.ProfileId && m.RSVP == "yes" {
-			c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		if m.ProfileId != c.Auth.ProfileId && m.RSVP == models.RsvpYes {
+			c.RespondWithErrorCode(e.NoUpdate, h.NoAuthMessage, http.StatusForbidden)
 			return
 		}
 	} else {
 		if m.ProfileId != c.Auth.ProfileId {
-			c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+			c.RespondWithErrorCode(e.NoUpdate, h.NoAuthMessage, http.StatusForbidden)
 			return
 		}
 	}
-	_, status, err := models.GetProfileSummary(c.Site.Id, m.ProfileId)
+	_, status, err = models.GetProfileSummary(c.Site.Id, m.ProfileId)
 	if err != nil {
 		c.RespondWithErrorMessage(h.NoAuthMessage, status)
 		return
@@ -160,7 +170,7 @@ func (ctl *AttendeeController) Update(c *models.Context) {
 		return
 	}
 
-	if m.RSVP == "yes" {
+	if m.RSVP == models.RsvpYes {
 		go models.SendUpdatesForNewAttendeeInAnEvent(c.Site.Id, m)
 	}
 
@@ -178,6 +188,93 @@ func (ctl *AttendeeController) Update(c *models.Context) {
 	)
 }
 
+// Patch checks an attendee in to, or out of, the event. Only the event owner,
+// moderators and site owners may do this.
+func (ctl *AttendeeController) Patch(c *models.Context) {
+
+	// Validate inputs
+	eventId, err := strconv.ParseInt(c.RouteVars["event_id"], 10, 64)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The supplied event_id ('%s') is not a number.", c.RouteVars["event_id"]),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	profileId, err := strconv.ParseInt(c.RouteVars["profile_id"], 10, 64)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The supplied profile_id ('%s') is not a number.", c.RouteVars["profile_id"]),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	patches := []h.PatchType{}
+	err = c.Fill(&patches)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The post data is invalid: %v", err.Error()),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	status, err := h.TestPatch(patches)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(
+			c, 0, h.ItemTypes[h.ItemTypeEvent], eventId),
+	)
+	if !(perms.IsOwner || perms.IsModerator || perms.IsSiteOwner) {
+		c.RespondWithErrorCode(e.NotAdmin, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	// All patches are 'replace'
+	checkedIn, status, err := models.ParseCheckedInPatch(patches)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	attendeeId, status, err := models.GetAttendeeId(eventId, profileId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	m, status, err := models.GetAttendee(c.Site.Id, attendeeId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	status, err = m.SetCheckedIn(checkedIn)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	audit.Update(
+		c.Site.Id,
+		h.ItemTypes[h.ItemTypeAttendee],
+		m.Id,
+		c.Auth.ProfileId,
+		time.Now(),
+		c.IP,
+	)
+
+	c.RespondWithOK()
+}
+
 func (ctl *AttendeeController) Delete(c *models.Context) {
 
 	// Validate inputs
@@ -211,7 +308,7 @@ func (ctl *AttendeeController) Delete(c *models.Context) {
 			c, 0, h.ItemTypes[h.ItemTypeAttendee], attendeeId),
 	)
 	if !perms.CanDelete {
-		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		c.RespondWithErrorCode(e.NoDelete, h.NoAuthMessage, http.StatusForbidden)
 		return
 	}
 	// End Authorisation
@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/csv"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
@@ -16,6 +19,9 @@ import (
 	"github.com/microcosm-cc/microcosm/models"
 )
 
+// The CSV export is not paginated, this bounds it to something sane
+const maxAttendeesCsv int64 = 10000
+
 type AttendeesController struct{}
 
 func AttendeesHandler(w http.ResponseWriter, r *http.Request) {
@@ -68,6 +74,15 @@ func (ctl *AttendeesController) UpdateMany(c *models.Context) {
 		return
 	}
 
+	// Normalised before authorisation as that depends on the RSVP
+	for i := range ems {
+		status, err := ems[i].NormaliseRSVP()
+		if err != nil {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+	}
+
 	// Start : Authorisation
 	perms := models.GetPermission(
 		models.MakeAuthorisationContext(
@@ -85,8 +100,8 @@ func (ctl *AttendeesController) UpdateMany(c *models.Context) {
 	// Also check that profile exists on site.
 	if perms.IsOwner || perms.IsModerator || perms.IsSiteOwner {
 		for _, m := range ems {
-			if m.ProfileId != c.Auth.ProfileId && m.RSVP == "yes" {
-				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+			if m.ProfileId != c.Auth.ProfileId && m.RSVP == models.RsvpYes {
+				c.RespondWithErrorCode(e.NoCreate, h.NoAuthMessage, http.StatusForbidden)
 				return
 			}
 			_, status, err := models.GetProfileSummary(c.Site.Id, m.ProfileId)
@@ -98,7 +113,7 @@ func (ctl *AttendeesController) UpdateMany(c *models.Context) {
 	} else {
 		for _, m := range ems {
 			if m.ProfileId != c.Auth.ProfileId {
-				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+				c.RespondWithErrorCode(e.NoCreate, h.NoAuthMessage, http.StatusForbidden)
 				return
 			}
 			_, status, err := models.GetProfileSummary(c.Site.Id, m.ProfileId)
@@ -127,17 +142,28 @@ func (ctl *AttendeesController) UpdateMany(c *models.Context) {
 		return
 	}
 	for _, m := range ems {
-		if m.RSVP == "yes" {
+		if m.RSVP == models.RsvpYes {
 			go models.SendUpdatesForNewAttendeeInAnEvent(c.Site.Id, m)
 
-			// The new attendee should be following the event now
-			go models.RegisterWatcher(
-				m.ProfileId,
-				h.UpdateTypes[h.UpdateTypeEventReminder],
-				m.EventId,
-				h.ItemTypes[h.ItemTypeEvent],
-				c.Site.Id,
-			)
+			// The new attendee should be following the event now, unless
+			// they have chosen not to. A profile without options has the
+			// default, which is to watch.
+			watch := true
+			options, status, err := models.GetProfileOptions(m.ProfileId)
+			if err == nil {
+				watch = options.WatchOnRSVP
+			} else if status != http.StatusNotFound {
+				glog.Errorf("models.GetProfileOptions(%d) %+v", m.ProfileId, err)
+			}
+			if watch {
+				go models.RegisterWatcher(
+					m.ProfileId,
+					h.UpdateTypes[h.UpdateTypeEventReminder],
+					m.EventId,
+					h.ItemTypes[h.ItemTypeEvent],
+					c.Site.Id,
+				)
+			}
 		}
 
 		audit.Replace(
@@ -170,7 +196,14 @@ func (ctl *AttendeesController) ReadMany(c *models.Context) {
 			c, 0, h.ItemTypes[h.ItemTypeEvent], eventId),
 	)
 	if !perms.CanRead {
-		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		c.RespondWithErrorCode(e.NoRead, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+
+	// The attendees of a private event are as private as the event
+	_, status, err := models.GetEventSummary(c.Site.Id, eventId, c.Auth.ProfileId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
 		return
 	}
 	// End Authorisation
@@ -178,6 +211,13 @@ func (ctl *AttendeesController) ReadMany(c *models.Context) {
 	// Fetch query string args if any exist
 	query := c.Request.URL.Query()
 
+	if query.Get("format") == "csv" ||
+		strings.Contains(c.Request.Header.Get("Accept"), "text/csv") {
+
+		ctl.readManyCsv(c, eventId, perms)
+		return
+	}
+
 	limit, offset, status, err := h.GetLimitAndOffset(query)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
@@ -219,3 +259,76 @@ func (ctl *AttendeesController) ReadMany(c *models.Context) {
 
 	c.RespondWithData(m)
 }
+
+// readManyCsv responds with every attendee of an event as a CSV sign-in
+// sheet. The roster is more sensitive than the attendee count so only the
+// event owner, moderators and site owners may fetch it. At most
+// maxAttendeesCsv attendees are listed, a final row says if there were more.
+func (ctl *AttendeesController) readManyCsv(
+	c *models.Context,
+	eventId int64,
+	perms models.PermissionType,
+) {
+	if !(perms.IsOwner || perms.IsModerator || c.Auth.IsSiteOwner) {
+		c.RespondWithErrorCode(e.NotAdmin, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+
+	ems, total, _, status, err := models.GetAttendees(
+		c.Site.Id,
+		eventId,
+		maxAttendeesCsv,
+		0,
+		false,
+	)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"profileName", "rsvp", "rsvpdOn", "guests", "checkedInAt"})
+	for _, m := range ems {
+		var profileName string
+		if profile, ok := m.Profile.(models.ProfileSummaryType); ok {
+			profileName = profile.ProfileName
+		}
+		w.Write([]string{
+			h.CsvCell(profileName),
+			h.CsvCell(m.RSVP),
+			h.CsvCell(m.RSVPdOn),
+			strconv.FormatInt(int64(m.Guests), 10),
+			h.CsvCell(m.CheckedInAt),
+		})
+	}
+	if total > int64(len(ems)) {
+		w.Write([]string{
+			fmt.Sprintf(
+				"Truncated: only the first %d of %d attendees are listed",
+				len(ems),
+				total,
+			),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("Could not write CSV: %v", err.Error()),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	output := buf.Bytes()
+
+	c.ResponseWriter.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	c.ResponseWriter.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="event-%d-attendees.csv"`, eventId),
+	)
+	c.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(output)))
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+
+	c.WriteResponse(output, http.StatusOK)
+}
@@ -155,6 +155,10 @@ func (ctl *AttributeController) Delete(c *models.Context) {
 		return
 	}
 
+	if itemTypeId == h.ItemTypes[h.ItemTypeSite] {
+		models.PurgeSiteHTMLPolicy(itemId)
+	}
+
 	audit.Delete(
 		c.Site.Id,
 		h.ItemTypes[h.ItemTypeAttribute],
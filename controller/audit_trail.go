@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	e "github.com/microcosm-cc/microcosm/errors"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func AuditTrailHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := AuditTrailController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD"})
+		return
+	case "GET":
+		ctl.ReadMany(c)
+	case "HEAD":
+		ctl.ReadMany(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type AuditTrailController struct{}
+
+// ReadMany returns the actions recorded against the item in the URL, only
+// moderators and site owners may see them. ?profileId= restricts them to the
+// actions of a profile, and ?from= and ?to= to a time range.
+func (ctl *AuditTrailController) ReadMany(c *models.Context) {
+	itemType, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(c, 0, itemTypeId, itemId),
+	)
+	if !(perms.IsModerator || perms.IsSiteOwner) {
+		c.RespondWithErrorCode(e.NotAdmin, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	query := c.Request.URL.Query()
+
+	limit, offset, status, err := h.GetLimitAndOffset(query)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	filter, status, err := models.ParseAuditTrailFilter(query)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ems, total, pages, status, err := models.GetAuditTrail(
+		c.Site.Id,
+		itemTypeId,
+		itemId,
+		filter,
+		limit,
+		offset,
+	)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	m := models.AuditTrailType{}
+	m.Actions = h.ConstructArray(
+		ems,
+		fmt.Sprintf("%s/%d/audit", h.ItemTypesToApiItem[itemType], itemId),
+		total,
+		limit,
+		offset,
+		pages,
+		c.Request.URL,
+	)
+
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+
+	c.RespondWithData(m)
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/microcosm-cc/microcosm/audit"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func AuthEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := AuthEmailsController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD", "POST"})
+		return
+	case "GET":
+		ctl.ReadMany(c)
+	case "HEAD":
+		ctl.ReadMany(c)
+	case "POST":
+		ctl.Create(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type AuthEmailsController struct{}
+
+// ReadMany returns the email addresses of the authenticated user
+func (ctl *AuthEmailsController) ReadMany(c *models.Context) {
+
+	if c.Auth.UserId <= 0 {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+
+	ems, status, err := models.GetUserEmails(c.Auth.UserId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.RespondWithData(ems)
+}
+
+// Create verifies a Persona assertion of an email address and adds the address
+// to the authenticated user, so that they may sign in with it
+func (ctl *AuthEmailsController) Create(c *models.Context) {
+
+	if c.Auth.UserId <= 0 {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+
+	if !models.AuthAttemptAllowedForIP(c.IP.String()) {
+		glog.Warningf("Too many authentication attempts from %s", c.IP)
+		respondWithTooManyAuthAttempts(c)
+		return
+	}
+
+	m := models.UserEmailRequestType{}
+	err := c.Fill(&m)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The post data is invalid: %v", err.Error()),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	email, status, err := verifyPersonaAssertion(c, m.Assertion)
+	if err != nil {
+		c.RespondWithErrorMessage(err.Error(), status)
+		return
+	}
+
+	status, err = models.AddVerifiedUserEmail(c.Auth.UserId, email)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	audit.Create(
+		c.Site.Id,
+		h.ItemTypes[h.ItemTypeUser],
+		c.Auth.UserId,
+		c.Auth.ProfileId,
+		time.Now(),
+		c.IP,
+	)
+
+	ems, status, err := models.GetUserEmails(c.Auth.UserId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.RespondWithData(ems)
+}
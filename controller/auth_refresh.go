@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/microcosm-cc/microcosm/audit"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func AuthRefreshHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := AuthRefreshController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "POST"})
+		return
+	case "POST":
+		ctl.Create(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type AuthRefreshController struct{}
+
+// Create exchanges the unexpired access token the request was made with for a
+// new token, which is returned. The old token can no longer be used.
+func (ctl *AuthRefreshController) Create(c *models.Context) {
+
+	if c.Auth.UserId <= 0 {
+		c.RespondWithErrorMessage(
+			"You must supply an unexpired access token to refresh it",
+			http.StatusUnauthorized,
+		)
+		return
+	}
+
+	tokenValue, err := h.RandString(128)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("Could not generate a random string: %v", err.Error()),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	m, status, err := c.Auth.AccessToken.Refresh(tokenValue)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("Could not refresh the access token: %v", err.Error()),
+			status,
+		)
+		return
+	}
+
+	audit.Update(
+		c.Site.Id,
+		h.ItemTypes[h.ItemTypeAuth],
+		c.Auth.ProfileId,
+		c.Auth.ProfileId,
+		time.Now(),
+		c.IP,
+	)
+
+	c.RespondWithData(m.TokenValue)
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,12 +16,23 @@ import (
 
 	"github.com/microcosm-cc/microcosm/audit"
 	conf "github.com/microcosm-cc/microcosm/config"
+	e "github.com/microcosm-cc/microcosm/errors"
 	h "github.com/microcosm-cc/microcosm/helpers"
 	"github.com/microcosm-cc/microcosm/models"
 )
 
 type AuthController struct{}
 
+// authProfileResponse is the response to a successful authentication when
+// ?profile=true is given, telling the client which profile it is using and
+// whether that profile was created by this authentication
+type authProfileResponse struct {
+	AccessToken    string `json:"accessToken"`
+	ProfileId      int64  `json:"profileId"`
+	ProfileCreated bool   `json:"profileCreated"`
+	Location       string `json:"location"`
+}
+
 func AuthHandler(w http.ResponseWriter, r *http.Request) {
 	c, status, err := models.MakeContext(r, w)
 	if err != nil {
@@ -49,114 +62,48 @@ func AuthHandler(w http.ResponseWriter, r *http.Request) {
 
 func (ctl *AuthController) Create(c *models.Context) {
 
-	accessTokenRequest := models.AccessTokenRequestType{}
-	err := c.Fill(&accessTokenRequest)
-	if err != nil {
-		c.RespondWithErrorMessage(
-			fmt.Sprintf("The post data is invalid: %v", err.Error()),
-			http.StatusBadRequest,
-		)
+	if !models.AuthAttemptAllowedForIP(c.IP.String()) {
+		glog.Warningf("Too many authentication attempts from %s", c.IP)
+		respondWithTooManyAuthAttempts(c)
 		return
 	}
 
-	// Audience is the host that Persona authenticates the user for
-	var audience string
-	if c.Site.Domain != "" {
-		audience = c.Site.Domain
-	} else if c.Site.SubdomainKey == "root" {
-		audience = conf.CONFIG_STRING[conf.KEY_MICROCOSM_DOMAIN]
-	} else {
-		audience = fmt.Sprintf("%s.%s", c.Site.SubdomainKey, conf.CONFIG_STRING[conf.KEY_MICROCOSM_DOMAIN])
-	}
-
-	// Verify persona assertion
-	personaRequest := models.PersonaRequestType{
-		Assertion: accessTokenRequest.Assertion,
-		Audience:  audience,
-	}
-
-	jsonData, err := json.Marshal(personaRequest)
+	accessTokenRequest := models.AccessTokenRequestType{}
+	err := c.Fill(&accessTokenRequest)
 	if err != nil {
-		glog.Errorf("Could not marshal Persona req: %s", err.Error())
 		c.RespondWithErrorMessage(
-			fmt.Sprintf("Bad persona request format: %v", err.Error()),
+			fmt.Sprintf("The post data is invalid: %v", err.Error()),
 			http.StatusBadRequest,
 		)
 		return
 	}
 
-	resp, err := http.Post(
-		conf.CONFIG_STRING[conf.KEY_PERSONA_VERIFIER_URL],
-		"application/json",
-		bytes.NewReader(jsonData),
-	)
+	email, status, err := verifyPersonaAssertion(c, accessTokenRequest.Assertion)
 	if err != nil {
-		glog.Errorln(err.Error())
-		c.RespondWithErrorMessage(
-			fmt.Sprintf("Persona verification error: %v", err.Error()),
-			http.StatusInternalServerError,
-		)
+		respondWithTokenError(c, err.Error(), status)
 		return
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		glog.Errorf("Couldn't read Persona response: %s", err.Error())
-		c.RespondWithErrorMessage(
-			fmt.Sprintf("Error unmarshalling persona response: %v", err.Error()),
-			http.StatusInternalServerError,
-		)
-		return
-	}
-	resp.Body.Close()
-	var personaResponse = models.PersonaResponseType{}
-	json.Unmarshal(body, &personaResponse)
-
-	if personaResponse.Status != "okay" {
-		// Split and decode the assertion to log the user's email address.
-		var decoded bool
-		if personaRequest.Assertion != "" {
-			parts := strings.Split(personaRequest.Assertion, "~")
-			moreParts := strings.Split(parts[0], ".")
-			if len(moreParts) > 1 {
-				data, err := base64.StdEncoding.DecodeString(moreParts[1] + "====")
-				if err == nil {
-					decoded = true
-					glog.Errorf("Bad Persona response: %+v with decoded assertion: %+v", personaResponse, data)
-				}
-			}
-		}
-		if !decoded {
-			glog.Errorf("Bad Persona response: %+v with assertion: %+v", personaResponse, personaRequest)
-		}
-		c.RespondWithErrorMessage(
-			fmt.Sprintf("Persona login error: %v", personaResponse.Status),
-			http.StatusInternalServerError,
-		)
-		return
-	}
-
-	if personaResponse.Email == "" {
-		glog.Errorf("No persona email address")
-		c.RespondWithErrorMessage(
-			"Persona error: no email address received",
-			http.StatusInternalServerError,
-		)
+	if !models.AuthAttemptAllowedForEmail(email) {
+		glog.Warningf("Too many authentication attempts for %s", email)
+		respondWithTooManyAuthAttempts(c)
 		return
 	}
 
-	// Retrieve user details by email address
-	user, status, err := models.GetUserByEmailAddress(personaResponse.Email)
+	// Retrieve user details by email address, which may be the primary
+	// address of the user or any other address they have verified
+	user, status, err := models.GetUserByEmailAddress(email)
 	if status == http.StatusNotFound {
 		// Check whether this email is a spammer before we attempt to create
 		// an account
-		if models.IsSpammer(personaResponse.Email) {
-			glog.Errorf("Spammer: %s", personaResponse.Email)
+		if models.IsSpammer(email) {
+			glog.Errorf("Spammer: %s", email)
+			models.RecordFailedAuthAttempt(c.IP.String())
 			c.RespondWithErrorMessage("Spammer", http.StatusInternalServerError)
 			return
 		}
 
-		user, status, err = models.CreateUserByEmailAddress(personaResponse.Email)
+		user, status, err = models.CreateUserByEmailAddress(email)
 		if err != nil {
 			c.RespondWithErrorMessage(
 				fmt.Sprintf("Couldn't create user: %v", err.Error()),
@@ -181,6 +128,7 @@ func (ctl *AuthController) Create(c *models.Context) {
 		)
 		return
 	}
+	profileCreated := status == http.StatusCreated
 
 	// Fetch API client details by secret
 	client, err := models.RetrieveClientBySecret(accessTokenRequest.ClientSecret)
@@ -225,15 +173,141 @@ func (ctl *AuthController) Create(c *models.Context) {
 		c.IP,
 	)
 
+	// The token alone is returned unless the client asks for the profile, as
+	// that is what existing clients expect
+	withProfile, _ := strconv.ParseBool(c.Request.URL.Query().Get("profile"))
+	if withProfile {
+		c.RespondWithData(authProfileResponse{
+			AccessToken:    tokenValue,
+			ProfileId:      profile.Id,
+			ProfileCreated: profileCreated,
+			Location:       fmt.Sprintf("%s/%d", h.ApiTypeProfile, profile.Id),
+		})
+		return
+	}
+
 	c.RespondWithData(tokenValue)
 }
 
+// verifyPersonaAssertion asks the Persona verifier whether the assertion is
+// valid for this site, and returns the email address it asserts. Failed
+// assertions count towards the rate limit of the IP address.
+func verifyPersonaAssertion(
+	c *models.Context,
+	assertion string,
+) (
+	string,
+	int,
+	error,
+) {
+
+	// Audience is the host that Persona authenticates the user for
+	var audience string
+	if c.Site.Domain != "" {
+		audience = c.Site.Domain
+	} else if c.Site.SubdomainKey == "root" {
+		audience = conf.CONFIG_STRING[conf.KEY_MICROCOSM_DOMAIN]
+	} else {
+		audience = fmt.Sprintf("%s.%s", c.Site.SubdomainKey, conf.CONFIG_STRING[conf.KEY_MICROCOSM_DOMAIN])
+	}
+
+	// Verify persona assertion
+	personaRequest := models.PersonaRequestType{
+		Assertion: assertion,
+		Audience:  audience,
+	}
+
+	jsonData, err := json.Marshal(personaRequest)
+	if err != nil {
+		glog.Errorf("Could not marshal Persona req: %s", err.Error())
+		return "", http.StatusBadRequest,
+			errors.New(fmt.Sprintf("Bad persona request format: %v", err.Error()))
+	}
+
+	resp, err := http.Post(
+		conf.CONFIG_STRING[conf.KEY_PERSONA_VERIFIER_URL],
+		"application/json",
+		bytes.NewReader(jsonData),
+	)
+	if err != nil {
+		glog.Errorln(err.Error())
+		return "", http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Persona verification error: %v", err.Error()))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		glog.Errorf("Couldn't read Persona response: %s", err.Error())
+		return "", http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Error unmarshalling persona response: %v", err.Error()))
+	}
+	resp.Body.Close()
+	var personaResponse = models.PersonaResponseType{}
+	json.Unmarshal(body, &personaResponse)
+
+	if personaResponse.Status != "okay" {
+		// Split and decode the assertion to log the user's email address.
+		var decoded bool
+		if personaRequest.Assertion != "" {
+			parts := strings.Split(personaRequest.Assertion, "~")
+			moreParts := strings.Split(parts[0], ".")
+			if len(moreParts) > 1 {
+				data, err := base64.StdEncoding.DecodeString(moreParts[1] + "====")
+				if err == nil {
+					decoded = true
+					glog.Errorf("Bad Persona response: %+v with decoded assertion: %+v", personaResponse, data)
+				}
+			}
+		}
+		if !decoded {
+			glog.Errorf("Bad Persona response: %+v with assertion: %+v", personaResponse, personaRequest)
+		}
+		models.RecordFailedAuthAttempt(c.IP.String())
+		return "", http.StatusUnauthorized,
+			errors.New(fmt.Sprintf("Persona login error: %v", personaResponse.Status))
+	}
+
+	if personaResponse.Email == "" {
+		glog.Errorf("No persona email address")
+		return "", http.StatusInternalServerError,
+			errors.New("Persona error: no email address received")
+	}
+
+	return personaResponse.Email, http.StatusOK, nil
+}
+
+func respondWithTooManyAuthAttempts(c *models.Context) {
+	c.RespondWithErrorDetail(
+		e.New(
+			c.Site.Id,
+			0,
+			"authentication.go::Create",
+			e.ExceededQuota,
+			"Too many sign in attempts, please try again later",
+		),
+		http.StatusTooManyRequests,
+	)
+}
+
+// respondWithTokenError responds with e.InvalidToken if the access token or
+// assertion was refused. Other errors, such as the database or verifier being
+// unavailable, are passed through with their status.
+func respondWithTokenError(c *models.Context, message string, status int) {
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		c.RespondWithErrorCode(e.InvalidToken, message, status)
+		return
+	}
+
+	c.RespondWithErrorMessage(message, status)
+}
+
 func (ctl *AuthController) Read(c *models.Context) {
 
 	// Extract access token from request and retrieve its metadata
 	m, status, err := models.GetAccessToken(c.RouteVars["id"])
 	if err != nil {
-		c.RespondWithErrorMessage(
+		respondWithTokenError(
+			c,
 			fmt.Sprintf("Error retrieving access token: %v", err.Error()),
 			status,
 		)
@@ -247,7 +321,8 @@ func (ctl *AuthController) Delete(c *models.Context) {
 	// Extract access token from request and delete its record
 	m, status, err := models.GetAccessToken(c.RouteVars["id"])
 	if err != nil {
-		c.RespondWithErrorMessage(
+		respondWithTokenError(
+			c,
 			fmt.Sprintf("Error retrieving access token: %v", err.Error()),
 			status,
 		)
@@ -69,7 +69,13 @@ func (ctl *CommentController) Read(c *models.Context) {
 		return
 	}
 
-	m, status, err := models.GetComment(c.Site.Id, itemId, c.Auth.ProfileId, limit)
+	m, status, err := models.GetComment(
+		c.Site.Id,
+		itemId,
+		c.Auth.ProfileId,
+		limit,
+		perms.IsModerator || perms.IsSiteOwner,
+	)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
@@ -188,7 +194,7 @@ func (ctl *CommentController) Patch(c *models.Context) {
 	// All patches are 'replace'
 	for _, patch := range patches {
 		status, err := patch.ScanRawValue()
-		if !patch.Bool.Valid {
+		if err != nil {
 			c.RespondWithErrorDetail(err, status)
 			return
 		}
@@ -209,6 +215,10 @@ func (ctl *CommentController) Patch(c *models.Context) {
 				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
 				return
 			}
+			if !patch.Bool.Valid {
+				c.RespondWithErrorMessage("/meta/flags/moderated requires a bool value", http.StatusBadRequest)
+				return
+			}
 		default:
 			c.RespondWithErrorMessage("Invalid patch operation path", http.StatusBadRequest)
 			return
@@ -59,7 +59,22 @@ func (ctl *CommentContextController) Read(c *models.Context) {
 		return
 	}
 
-	m, status, err := models.GetCommentSummary(c.Site.Id, itemId)
+	m, status, err := models.GetVisibleCommentSummary(
+		c.Site.Id,
+		itemId,
+		perms.IsModerator || perms.IsSiteOwner,
+	)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	status, err = models.CanSeeComments(
+		c.Site.Id,
+		m.ItemTypeId,
+		m.ItemId,
+		c.Auth.ProfileId,
+	)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type CommentReactionsController struct{}
+
+func CommentReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := CommentReactionsController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "POST"})
+		return
+	case "POST":
+		ctl.Create(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type CommentReactionController struct{}
+
+func CommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := CommentReactionController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "DELETE"})
+		return
+	case "DELETE":
+		ctl.Delete(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// canReact returns the comment in the URL if the profile may react to it,
+// which requires being signed in and able to read the comment. A response
+// has been sent if the returned bool is false.
+func canReact(c *models.Context) (int64, bool) {
+	_, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return 0, false
+	}
+
+	if c.Auth.ProfileId <= 0 {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return 0, false
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(
+			c, 0, itemTypeId, itemId),
+	)
+	if !perms.CanRead {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return 0, false
+	}
+	// End Authorisation
+
+	return itemId, true
+}
+
+// Create adds a reaction to the comment
+func (ctl *CommentReactionsController) Create(c *models.Context) {
+	commentId, ok := canReact(c)
+	if !ok {
+		return
+	}
+
+	m := models.CommentReactionRequestType{}
+	err := c.Fill(&m)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The post data is invalid: %v", err.Error()),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	status, err := models.AddReaction(
+		c.Site.Id,
+		c.Auth.ProfileId,
+		commentId,
+		m.Reaction,
+	)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.RespondWithSeeOther(
+		fmt.Sprintf("%s/%d", h.ApiTypeComment, commentId),
+	)
+}
+
+// Delete removes a reaction from the comment
+func (ctl *CommentReactionController) Delete(c *models.Context) {
+	commentId, ok := canReact(c)
+	if !ok {
+		return
+	}
+
+	status, err := models.RemoveReaction(
+		c.Auth.ProfileId,
+		commentId,
+		c.RouteVars["reaction"],
+	)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.RespondWithOK()
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type CommentRevisionsController struct{}
+
+func CommentRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := CommentRevisionsController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD"})
+		return
+	case "GET":
+		ctl.ReadMany(c)
+	case "HEAD":
+		ctl.ReadMany(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// Returns the edit history of a comment to anyone who may read the comment
+func (ctl *CommentRevisionsController) ReadMany(c *models.Context) {
+	_, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(
+			c, 0, itemTypeId, itemId),
+	)
+	if !perms.CanRead {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	ems, status, err := models.GetCommentRevisions(c.Site.Id, itemId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+	c.RespondWithData(ems)
+}
@@ -37,6 +37,10 @@ func CommentsHandler(w http.ResponseWriter, r *http.Request) {
 // Creates a single comment
 func (ctl *CommentsController) Create(c *models.Context) {
 
+	if c.RespondWithIdempotentReplay() {
+		return
+	}
+
 	// Initialise (non-zero defaults must be set)
 	m := models.CommentSummaryType{}
 
@@ -120,11 +124,12 @@ func (ctl *CommentsController) Create(c *models.Context) {
 	}
 
 	// Respond
-	c.RespondWithSeeOther(
-		fmt.Sprintf(
-			"%s/%d",
-			h.ApiTypeComment,
-			m.Id,
-		),
+	location := fmt.Sprintf(
+		"%s/%d",
+		h.ApiTypeComment,
+		m.Id,
 	)
+	c.RecordIdempotentCreate(location)
+
+	c.RespondWithSeeOther(location)
 }
@@ -72,7 +72,7 @@ func (ctl *ConversationController) Read(c *models.Context) {
 	}
 
 	// Get Comments
-	m.Comments, status, err = models.GetComments(c.Site.Id, h.ItemTypeConversation, m.Id, c.Request.URL, c.Auth.ProfileId, m.Meta.Created)
+	m.Comments, status, err = models.GetComments(c.Site.Id, h.ItemTypeConversation, m.Id, c.Request.URL, c.Auth.ProfileId, m.Meta.Created, perms.IsModerator || perms.IsSiteOwner)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
@@ -216,20 +216,80 @@ func (ctl *ConversationController) Patch(c *models.Context) {
 	// Start Authorisation
 	ac := models.MakeAuthorisationContext(c, 0, itemTypeId, itemId)
 	perms := models.GetPermission(ac)
-	if !perms.CanUpdate {
+	if !perms.CanRead {
 		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
 		return
 	}
 
 	// All patches are 'replace'
+	var (
+		moveTo    int64
+		watched   sql.NullBool
+		sendEmail sql.NullBool
+		sendSms   sql.NullBool
+	)
+	flagPatches := []h.PatchType{}
 	for _, patch := range patches {
 		status, err := patch.ScanRawValue()
-		if !patch.Bool.Valid {
+		if err != nil {
 			c.RespondWithErrorDetail(err, status)
 			return
 		}
 
 		switch patch.Path {
+		case "/meta/flags/watched", "/meta/flags/sendEmail", "/meta/flags/sendSms":
+			// Anyone signed in who can read the conversation may watch it
+			if c.Auth.ProfileId <= 0 {
+				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+				return
+			}
+			if !patch.Bool.Valid {
+				c.RespondWithErrorMessage(
+					fmt.Sprintf("%s requires a bool value", patch.Path),
+					http.StatusBadRequest,
+				)
+				return
+			}
+
+			switch patch.Path {
+			case "/meta/flags/watched":
+				watched = patch.Bool
+			case "/meta/flags/sendEmail":
+				sendEmail = patch.Bool
+			case "/meta/flags/sendSms":
+				sendSms = patch.Bool
+			}
+			continue
+		}
+
+		// Everything else changes the conversation
+		if !perms.CanUpdate {
+			c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+			return
+		}
+
+		switch patch.Path {
+		case "/meta/microcosmId":
+			// Only super users' can move conversations, and only to
+			// microcosms they could create the conversation in
+			if !perms.IsModerator {
+				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+				return
+			}
+			if !patch.Int64.Valid || patch.Int64.Int64 <= 0 {
+				c.RespondWithErrorMessage("/meta/microcosmId requires a microcosm ID", http.StatusBadRequest)
+				return
+			}
+			destPerms := models.GetPermission(
+				models.MakeAuthorisationContext(
+					c, patch.Int64.Int64, itemTypeId, 0),
+			)
+			if !destPerms.CanCreate {
+				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+				return
+			}
+			moveTo = patch.Int64.Int64
+			continue
 		case "/meta/flags/sticky":
 			// Only super users' can sticky and unsticky
 			if !perms.IsModerator {
@@ -265,10 +325,16 @@ func (ctl *ConversationController) Patch(c *models.Context) {
 				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
 				return
 			}
+			if !patch.Bool.Valid {
+				c.RespondWithErrorMessage("/meta/flags/moderated requires a bool value", http.StatusBadRequest)
+				return
+			}
 		default:
 			c.RespondWithErrorMessage("Invalid patch operation path", http.StatusBadRequest)
 			return
 		}
+
+		flagPatches = append(flagPatches, patch)
 	}
 	// End Authorisation
 
@@ -278,20 +344,42 @@ func (ctl *ConversationController) Patch(c *models.Context) {
 		return
 	}
 
-	status, err = m.Patch(ac, patches)
-	if err != nil {
-		c.RespondWithErrorDetail(err, status)
-		return
+	if len(flagPatches) > 0 || moveTo > 0 {
+		status, err = m.PatchAndMove(ac, flagPatches, moveTo)
+		if err != nil {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
 	}
 
-	audit.Update(
-		c.Site.Id,
-		h.ItemTypes[h.ItemTypeConversation],
-		m.Id,
-		c.Auth.ProfileId,
-		time.Now(),
-		c.IP,
-	)
+	if watched.Valid || sendEmail.Valid || sendSms.Valid {
+		status, err = models.SetWatcherFlags(
+			c.Site.Id,
+			c.Auth.ProfileId,
+			h.UpdateTypes[h.UpdateTypeNewComment],
+			itemTypeId,
+			m.Id,
+			watched,
+			sendEmail,
+			sendSms,
+		)
+		if err != nil {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+	}
+
+	// Watching doesn't change the conversation
+	if len(flagPatches) > 0 || moveTo > 0 {
+		audit.Update(
+			c.Site.Id,
+			h.ItemTypes[h.ItemTypeConversation],
+			m.Id,
+			c.Auth.ProfileId,
+			time.Now(),
+			c.IP,
+		)
+	}
 
 	c.RespondWithOK()
 }
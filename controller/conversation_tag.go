@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/microcosm-cc/microcosm/audit"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type ConversationTagController struct{}
+
+func ConversationTagHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := ConversationTagController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "PUT", "DELETE"})
+		return
+	case "PUT":
+		ctl.Update(c)
+	case "DELETE":
+		ctl.Delete(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// Adds a tag to a conversation
+func (ctl *ConversationTagController) Update(c *models.Context) {
+	m, ok := ctl.getConversation(c)
+	if !ok {
+		return
+	}
+
+	status, err := m.AddTag(c.Site.Id, c.RouteVars["tag"])
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	audit.Update(
+		c.Site.Id,
+		h.ItemTypes[h.ItemTypeConversation],
+		m.Id,
+		c.Auth.ProfileId,
+		time.Now(),
+		c.IP,
+	)
+
+	c.RespondWithOK()
+}
+
+// Removes a tag from a conversation
+func (ctl *ConversationTagController) Delete(c *models.Context) {
+	m, ok := ctl.getConversation(c)
+	if !ok {
+		return
+	}
+
+	status, err := m.RemoveTag(c.Site.Id, c.RouteVars["tag"])
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	audit.Update(
+		c.Site.Id,
+		h.ItemTypes[h.ItemTypeConversation],
+		m.Id,
+		c.Auth.ProfileId,
+		time.Now(),
+		c.IP,
+	)
+
+	c.RespondWithOK()
+}
+
+// getConversation returns the conversation whose tags are being changed, or
+// responds with an error if it cannot be found or the tags cannot be changed
+// by the current profile
+func (ctl *ConversationTagController) getConversation(
+	c *models.Context,
+) (
+	models.ConversationType,
+	bool,
+) {
+	_, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return models.ConversationType{}, false
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(models.MakeAuthorisationContext(c, 0, itemTypeId, itemId))
+	if !perms.CanUpdate {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return models.ConversationType{}, false
+	}
+	// End Authorisation
+
+	m, status, err := models.GetConversation(c.Site.Id, itemId, c.Auth.ProfileId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return models.ConversationType{}, false
+	}
+
+	return m, true
+}
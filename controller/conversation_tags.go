@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"net/http"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type ConversationTagsController struct{}
+
+func ConversationTagsHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := ConversationTagsController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD"})
+		return
+	case "GET":
+		ctl.ReadMany(c)
+	case "HEAD":
+		ctl.ReadMany(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// Returns the tags of a conversation
+func (ctl *ConversationTagsController) ReadMany(c *models.Context) {
+	_, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	perms := models.GetPermission(models.MakeAuthorisationContext(c, 0, itemTypeId, itemId))
+	if !perms.CanRead {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+
+	m, status, err := models.GetConversation(c.Site.Id, itemId, c.Auth.ProfileId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.RespondWithData(m.Tags)
+}
@@ -58,12 +58,38 @@ func (ctl *ConversationsController) ReadMany(c *models.Context) {
 		return
 	}
 
-	ems, total, pages, status, err := models.GetConversations(c.Site.Id, c.Auth.ProfileId, limit, offset)
+	ems, total, pages, status, err := models.GetConversations(
+		c.Site.Id,
+		c.Auth.ProfileId,
+		c.Request.URL.Query().Get("tag"),
+		limit,
+		offset,
+	)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
 	}
 
+	// The permissions of every conversation on the page are fetched together
+	items := []models.ItemRef{}
+	for _, m := range ems {
+		items = append(
+			items,
+			models.ItemRef{
+				MicrocosmId: m.MicrocosmId,
+				ItemTypeId:  h.ItemTypes[h.ItemTypeConversation],
+				ItemId:      m.Id,
+			},
+		)
+	}
+	itemPerms := models.GetPermissions(
+		models.MakeAuthorisationContext(c, 0, 0, 0),
+		items,
+	)
+	for i := range ems {
+		ems[i].Meta.Permissions = itemPerms[i]
+	}
+
 	// Construct the response
 	thisLink := h.GetLinkToThisPage(*c.Request.URL, offset, limit, total)
 
@@ -91,6 +117,10 @@ func (ctl *ConversationsController) ReadMany(c *models.Context) {
 // Creates a conversations
 func (ctl *ConversationsController) Create(c *models.Context) {
 
+	if c.RespondWithIdempotentReplay() {
+		return
+	}
+
 	// Validate inputs
 	m := models.ConversationType{}
 	m.Meta.Flags.Deleted = false
@@ -147,11 +177,12 @@ func (ctl *ConversationsController) Create(c *models.Context) {
 		c.Site.Id,
 	)
 
-	c.RespondWithSeeOther(
-		fmt.Sprintf(
-			"%s/%d",
-			h.ApiTypeConversation,
-			m.Id,
-		),
+	location := fmt.Sprintf(
+		"%s/%d",
+		h.ApiTypeConversation,
+		m.Id,
 	)
+	c.RecordIdempotentCreate(location)
+
+	c.RespondWithSeeOther(location)
 }
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type CronJobController struct{}
+
+func CronJobHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := CronJobController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "POST"})
+		return
+	case "POST":
+		ctl.Create(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// Create runs the named cron job and responds once it has finished. A job that
+// is already running is not run again.
+func (ctl *CronJobController) Create(c *models.Context) {
+
+	// Jobs work across all sites and can be expensive, so only the owner of
+	// the root site may run them
+	if !c.IsRootSite() || !c.Auth.IsSiteOwner {
+		c.RespondWithErrorMessage(
+			"Only the owner of the root site can run cron jobs",
+			http.StatusForbidden,
+		)
+		return
+	}
+
+	job, status, err := models.GetCronJob(c.RouteVars["job"])
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	err = job.RunAndRecover()
+	if err == models.ErrCronJobRunning {
+		c.RespondWithErrorDetail(err, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("Error running cron job: %+v", err),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	c.RespondWithOK()
+}
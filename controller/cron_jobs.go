@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type CronJobsController struct{}
+
+func CronJobsHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := CronJobsController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "HEAD", "GET"})
+		return
+	case "HEAD":
+		ctl.ReadMany(c)
+	case "GET":
+		ctl.ReadMany(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// ReadMany responds with the status of every cron job: whether it is running,
+// and when and for how long it last ran
+func (ctl *CronJobsController) ReadMany(c *models.Context) {
+
+	if !c.IsRootSite() || !c.Auth.IsSiteOwner {
+		c.RespondWithErrorMessage(
+			"Only the owner of the root site can see cron jobs",
+			http.StatusForbidden,
+		)
+		return
+	}
+
+	c.RespondWithData(models.GetCronJobStatuses())
+}
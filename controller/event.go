@@ -70,7 +70,7 @@ func (ctl *EventController) Read(c *models.Context) {
 	}
 
 	// Get Comments
-	m.Comments, status, err = models.GetComments(c.Site.Id, h.ItemTypeEvent, m.Id, c.Request.URL, c.Auth.ProfileId, m.Meta.Created)
+	m.Comments, status, err = models.GetComments(c.Site.Id, h.ItemTypeEvent, m.Id, c.Request.URL, c.Auth.ProfileId, m.Meta.Created, perms.IsModerator || perms.IsSiteOwner)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
@@ -219,7 +219,7 @@ func (ctl *EventController) Patch(c *models.Context) {
 	// All patches are 'replace'
 	for _, patch := range patches {
 		status, err := patch.ScanRawValue()
-		if !patch.Bool.Valid {
+		if err != nil {
 			c.RespondWithErrorDetail(err, status)
 			return
 		}
@@ -260,6 +260,10 @@ func (ctl *EventController) Patch(c *models.Context) {
 				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
 				return
 			}
+			if !patch.Bool.Valid {
+				c.RespondWithErrorMessage("/meta/flags/moderated requires a bool value", http.StatusBadRequest)
+				return
+			}
 		default:
 			c.RespondWithErrorMessage("Invalid patch operation path", http.StatusBadRequest)
 			return
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func EventICalHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := EventICalController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "HEAD", "GET"})
+		return
+	case "HEAD":
+		ctl.Read(c)
+	case "GET":
+		ctl.Read(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type EventICalController struct{}
+
+// Read responds with the event as a text/calendar document
+func (ctl *EventICalController) Read(c *models.Context) {
+	_, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(
+			c, 0, itemTypeId, itemId),
+	)
+	if !perms.CanRead {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	m, status, err := models.GetEvent(c.Site.Id, itemId, c.Auth.ProfileId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	output := []byte(m.ICal(c.Site))
+
+	c.ResponseWriter.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	c.ResponseWriter.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="event-%d.ics"`, m.Id),
+	)
+	c.ResponseWriter.Header().Set("Content-Length", fmt.Sprintf("%d", len(output)))
+
+	c.WriteResponse(output, http.StatusOK)
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/microcosm-cc/microcosm/audit"
+	e "github.com/microcosm-cc/microcosm/errors"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type EventInvitesController struct{}
+
+func EventInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := EventInvitesController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD", "POST"})
+		return
+	case "GET":
+		ctl.ReadMany(c)
+	case "HEAD":
+		ctl.ReadMany(c)
+	case "POST":
+		ctl.Create(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type EventInviteController struct{}
+
+func EventInviteHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := EventInviteController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "DELETE"})
+		return
+	case "DELETE":
+		ctl.Delete(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// getInvitableEvent returns the event in the URL if the profile may manage
+// its invites, only the event owner, moderators and site owners may do so.
+// A response has been sent if the returned bool is false.
+func getInvitableEvent(c *models.Context) (models.EventType, bool) {
+	eventId, err := strconv.ParseInt(c.RouteVars["event_id"], 10, 64)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The supplied event_id ('%s') is not a number.", c.RouteVars["event_id"]),
+			http.StatusBadRequest,
+		)
+		return models.EventType{}, false
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(
+			c, 0, h.ItemTypes[h.ItemTypeEvent], eventId),
+	)
+	if !(perms.IsOwner || perms.IsModerator || perms.IsSiteOwner) {
+		c.RespondWithErrorCode(e.NotAdmin, h.NoAuthMessage, http.StatusForbidden)
+		return models.EventType{}, false
+	}
+	// End Authorisation
+
+	m, status, err := models.GetEvent(c.Site.Id, eventId, c.Auth.ProfileId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return models.EventType{}, false
+	}
+
+	return m, true
+}
+
+// ReadMany returns the profiles invited to the event
+func (ctl *EventInvitesController) ReadMany(c *models.Context) {
+	event, ok := getInvitableEvent(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, status, err := h.GetLimitAndOffset(c.Request.URL.Query())
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ems, total, pages, status, err :=
+		models.GetEventInvites(c.Site.Id, event.Id, limit, offset)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	m := models.EventInvitesType{}
+	m.Invites = h.ConstructArray(
+		ems,
+		fmt.Sprintf("%s/%d/invites", h.ApiTypeEvent, event.Id),
+		total,
+		limit,
+		offset,
+		pages,
+		c.Request.URL,
+	)
+
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+
+	c.RespondWithData(m)
+}
+
+// Create invites one or more profiles to the event
+func (ctl *EventInvitesController) Create(c *models.Context) {
+	event, ok := getInvitableEvent(c)
+	if !ok {
+		return
+	}
+
+	ems := []models.EventInviteType{}
+	err := c.Fill(&ems)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The post data is invalid: %v", err.Error()),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	profileIds := []int64{}
+	for _, m := range ems {
+		profileIds = append(profileIds, m.ProfileId)
+	}
+
+	status, err := event.AddEventInvites(c.Site.Id, c.Auth.ProfileId, profileIds)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	audit.Update(
+		c.Site.Id,
+		h.ItemTypes[h.ItemTypeEvent],
+		event.Id,
+		c.Auth.ProfileId,
+		time.Now(),
+		c.IP,
+	)
+
+	c.RespondWithSeeOther(
+		fmt.Sprintf("%s/%d/invites", h.ApiTypeEvent, event.Id),
+	)
+}
+
+// Delete removes a profile from the invite list of the event
+func (ctl *EventInviteController) Delete(c *models.Context) {
+	profileId, err := strconv.ParseInt(c.RouteVars["profile_id"], 10, 64)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The supplied profile_id ('%s') is not a number.", c.RouteVars["profile_id"]),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	event, ok := getInvitableEvent(c)
+	if !ok {
+		return
+	}
+
+	status, err := event.RemoveEventInvite(profileId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	audit.Update(
+		c.Site.Id,
+		h.ItemTypes[h.ItemTypeEvent],
+		event.Id,
+		c.Auth.ProfileId,
+		time.Now(),
+		c.IP,
+	)
+
+	c.RespondWithOK()
+}
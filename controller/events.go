@@ -39,6 +39,10 @@ type EventsController struct{}
 
 func (ctl *EventsController) Create(c *models.Context) {
 
+	if c.RespondWithIdempotentReplay() {
+		return
+	}
+
 	m := models.EventType{}
 	m.Meta.Flags.Open = true
 
@@ -91,13 +95,14 @@ func (ctl *EventsController) Create(c *models.Context) {
 		c.Site.Id,
 	)
 
-	c.RespondWithSeeOther(
-		fmt.Sprintf(
-			"%s/%d",
-			h.ApiTypeEvent,
-			m.Id,
-		),
+	location := fmt.Sprintf(
+		"%s/%d",
+		h.ApiTypeEvent,
+		m.Id,
 	)
+	c.RecordIdempotentCreate(location)
+
+	c.RespondWithSeeOther(location)
 }
 
 func (ctl *EventsController) ReadMany(c *models.Context) {
@@ -128,12 +133,50 @@ func (ctl *EventsController) ReadMany(c *models.Context) {
 		return
 	}
 
-	ems, total, pages, status, err := models.GetEvents(c.Site.Id, c.Auth.ProfileId, attending, limit, offset)
+	near, status, err := models.ParseEventsNear(query)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	when, status, err := models.ParseEventsWhen(query)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	order, status, err := models.ParseEventsOrder(query)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
 	}
 
+	ems, total, pages, status, err := models.GetEvents(c.Site.Id, c.Auth.ProfileId, attending, near, when, order, limit, offset)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// The permissions of every event on the page are fetched together
+	items := []models.ItemRef{}
+	for _, m := range ems {
+		items = append(
+			items,
+			models.ItemRef{
+				MicrocosmId: m.MicrocosmId,
+				ItemTypeId:  h.ItemTypes[h.ItemTypeEvent],
+				ItemId:      m.Id,
+			},
+		)
+	}
+	itemPerms := models.GetPermissions(
+		models.MakeAuthorisationContext(c, 0, 0, 0),
+		items,
+	)
+	for i := range ems {
+		ems[i].Meta.Permissions = itemPerms[i]
+	}
+
 	// Construct the response
 	thisLink := h.GetLinkToThisPage(*c.Request.URL, offset, limit, total)
 
@@ -152,6 +195,7 @@ func (ctl *EventsController) ReadMany(c *models.Context) {
 			h.LinkType{Rel: "self", Href: thisLink.String()},
 		}
 	m.Meta.Permissions = perms
+	m.Meta.Order = models.EventsOrderFor(near, when, order)
 
 	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
 
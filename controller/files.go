@@ -1,8 +1,9 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -45,16 +46,50 @@ func FileHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch c.GetHttpMethod() {
 	case "OPTIONS":
-		c.RespondWithOptions([]string{"OPTIONS", "GET"})
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD"})
 		return
 	case "GET":
 		ctl.Read(c)
+	case "HEAD":
+		ctl.Read(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+func FileThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := FileController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD"})
+		return
+	case "GET":
+		ctl.ReadThumbnail(c)
+	case "HEAD":
+		ctl.ReadThumbnail(c)
 	default:
 		c.RespondWithStatus(http.StatusMethodNotAllowed)
 		return
 	}
 }
 
+const (
+	// The number of files that may be uploaded in one request
+	maxFilesPerUpload = 10
+
+	// Allowance for the multipart boundaries and headers of an upload
+	multipartOverhead int64 = 64 * 1024
+)
+
 type FilesController struct{}
 
 func (ctl *FilesController) Create(c *models.Context) {
@@ -64,6 +99,13 @@ func (ctl *FilesController) Create(c *models.Context) {
 		return
 	}
 
+	// Refuse oversized requests as they are read rather than after
+	c.Request.Body = http.MaxBytesReader(
+		c.ResponseWriter,
+		c.Request.Body,
+		maxFilesPerUpload*models.GetMaxFileSize()+multipartOverhead,
+	)
+
 	mr, err := c.Request.MultipartReader()
 	if err != nil {
 		c.RespondWithErrorMessage(
@@ -83,40 +125,19 @@ func (ctl *FilesController) Create(c *models.Context) {
 		if part.FormName() != "" {
 			if part.FileName() != "" {
 
-				// Persist file and metadata
-				md := models.FileMetadataType{}
-				md.FileName = part.FileName()
-				md.Created = time.Now()
-				md.MimeType = part.Header.Get("Content-Type")
-
-				md.Content, err = ioutil.ReadAll(part)
-				if err != nil {
+				if len(files) >= maxFilesPerUpload {
 					c.RespondWithErrorMessage(
-						fmt.Sprintf("Couldn't not read form part: %v", err.Error()),
+						fmt.Sprintf("No more than %d files may be uploaded at once", maxFilesPerUpload),
 						http.StatusBadRequest,
 					)
 					return
 				}
 
-				sha1, err := h.Sha1(md.Content)
-				if err != nil {
-					c.RespondWithErrorMessage(
-						fmt.Sprintf("Couldn't generate SHA-1: %v", err.Error()),
-						http.StatusInternalServerError,
-					)
-					return
-				}
-				md.FileHash = sha1
-				md.FileSize = int32(len(md.Content))
-
-				// Check whether the file size overflowed int32 (over 2GB)
-				if int(md.FileSize) != len(md.Content) {
-					c.RespondWithErrorMessage(
-						"File too large. Max size = 2147482548 bytes (2GB)",
-						http.StatusInternalServerError,
-					)
-					return
-				}
+				// Persist file and metadata
+				md := models.FileMetadataType{}
+				md.FileName = part.FileName()
+				md.Created = time.Now()
+				md.MimeType = part.Header.Get("Content-Type")
 
 				// Resize if needed
 				query := c.Request.URL.Query()
@@ -142,7 +163,9 @@ func (ctl *FilesController) Create(c *models.Context) {
 					maxHeight = max
 				}
 
-				status, err := md.Insert(maxWidth, maxHeight)
+				// The part is streamed, the file hash and size are calculated
+				// as it is read
+				status, err := md.InsertReader(part, maxWidth, maxHeight)
 				if err != nil {
 					c.RespondWithErrorMessage(
 						fmt.Sprintf("Couldn't upload file and metadata: %v", err.Error()),
@@ -173,8 +196,65 @@ func (ctl *FileController) Read(c *models.Context) {
 		return
 	}
 
-	fileBytes, headers, _, err := models.GetFile(fileHash)
+	// Images may be requested resized to fit within ?w= and ?h=
+	maxWidth, status, err := getDimension(c, "w")
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	maxHeight, status, err := getDimension(c, "h")
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	metadata, status, err := models.GetMetadata(fileHash)
+	if err != nil {
+		if status == http.StatusNotFound {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("Could not retrieve file: %v", err.Error()),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	// The length of a variant is not known until it has been made
+	var knownHeaders map[string]string
+	if maxWidth > 0 || maxHeight > 0 {
+		knownHeaders = fileMetadataHeaders(
+			metadata,
+			fmt.Sprintf("-%dx%d", maxWidth, maxHeight),
+		)
+	} else {
+		knownHeaders = fileMetadataHeaders(metadata, "")
+		knownHeaders["Content-Length"] = strconv.FormatInt(int64(metadata.FileSize), 10)
+	}
+	if respondFromMetadata(c, knownHeaders, true) {
+		return
+	}
+
+	var (
+		body    io.ReadCloser
+		headers map[string]string
+	)
+	if maxWidth > 0 || maxHeight > 0 {
+		body, headers, status, err = models.GetFileVariant(
+			fileHash,
+			maxWidth,
+			maxHeight,
+		)
+	} else {
+		body, headers, status, err = models.GetFile(fileHash)
+	}
 	if err != nil {
+		if status == http.StatusNotFound {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
 		c.RespondWithErrorMessage(
 			fmt.Sprintf("Could not retrieve file: %v", err.Error()),
 			http.StatusInternalServerError,
@@ -182,15 +262,192 @@ func (ctl *FileController) Read(c *models.Context) {
 		return
 	}
 
+	defer body.Close()
+
+	for h, v := range knownHeaders {
+		headers[h] = v
+	}
+
+	respondWithFile(c, body, headers)
+}
+
+// Given the file hash of an image, responds with its thumbnail
+func (ctl *FileController) ReadThumbnail(c *models.Context) {
+
+	fileHash := c.RouteVars["fileHash"]
+	if fileHash == "" {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The supplied file hash cannot be zero characters: %s", c.RouteVars["fileHash"]),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	metadata, status, err := models.GetMetadata(fileHash)
+	if err != nil {
+		if status == http.StatusNotFound {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("Could not retrieve thumbnail: %v", err.Error()),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	// The type of a thumbnail may differ from the image, so only conditional
+	// requests are answered without fetching it
+	knownHeaders := fileMetadataHeaders(metadata, "-thumbnail")
+	delete(knownHeaders, "Content-Type")
+	if respondFromMetadata(c, knownHeaders, false) {
+		return
+	}
+
+	body, headers, status, err := models.GetThumbnail(fileHash)
+	if err != nil {
+		if status == http.StatusNotFound {
+			c.RespondWithErrorDetail(err, status)
+			return
+		}
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("Could not retrieve thumbnail: %v", err.Error()),
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	defer body.Close()
+
+	for h, v := range knownHeaders {
+		headers[h] = v
+	}
+
+	respondWithFile(c, body, headers)
+}
+
+// getDimension returns the size in the named query parameter, which must be
+// one of models.VariantSizes, or zero if it is not present
+func getDimension(c *models.Context, name string) (int64, int, error) {
+
+	query := c.Request.URL.Query()
+	if query.Get(name) == "" {
+		return 0, http.StatusOK, nil
+	}
+
+	value, err := strconv.ParseInt(query.Get(name), 10, 64)
+	if err != nil || !models.IsVariantSize(value) {
+		sizes := []string{}
+		for _, size := range models.VariantSizes {
+			sizes = append(sizes, strconv.FormatInt(size, 10))
+		}
+		return 0, http.StatusBadRequest, errors.New(
+			fmt.Sprintf("%s must be one of %s", name, strings.Join(sizes, ", ")),
+		)
+	}
+
+	return value, http.StatusOK, nil
+}
+
+// fileMetadataHeaders returns the headers of the file, or of the variant of it
+// identified by suffix, that are known from its metadata. Files are addressed
+// by their hash and never change, so the ETag is derived from the hash.
+func fileMetadataHeaders(
+	m models.FileMetadataType,
+	suffix string,
+) map[string]string {
+
+	return map[string]string{
+		"Content-Type":  m.MimeType,
+		"ETag":          fmt.Sprintf(`"%s%s"`, m.FileHash, suffix),
+		"Last-Modified": m.Created.UTC().Format(http.TimeFormat),
+	}
+}
+
+// respondFromMetadata answers a conditional request, and a HEAD request if
+// head is true, from the headers known from the file metadata so that the
+// file is not fetched from storage. Returns false if the file is needed.
+func respondFromMetadata(
+	c *models.Context,
+	headers map[string]string,
+	head bool,
+) bool {
+
+	if fileNotModified(c.Request, headers) ||
+		(head && c.GetHttpMethod() == "HEAD") {
+
+		respondWithFile(c, strings.NewReader(""), headers)
+		return true
+	}
+
+	return false
+}
+
+// Files are immutable (addressed by their hash) and can be cached forever. A
+// client that already has the file is told so rather than sent it again.
+func respondWithFile(c *models.Context, body io.Reader, headers map[string]string) {
+
 	oneYear := time.Hour * 24 * 365
 	nextYear := time.Now().Add(oneYear)
-	c.ResponseWriter.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", oneYear/time.Second))
-	c.ResponseWriter.Header().Set("Expires", nextYear.Format(time.RFC1123))
+	c.ResponseWriter.Header().Set(
+		"Cache-Control",
+		fmt.Sprintf("public, max-age=%d, immutable", oneYear/time.Second),
+	)
+	c.ResponseWriter.Header().Set("Expires", nextYear.UTC().Format(http.TimeFormat))
 
 	for h, v := range headers {
 		c.ResponseWriter.Header().Set(h, v)
 	}
 
-	c.WriteResponse(fileBytes, http.StatusOK)
-	return
+	// Only raster images are shown inline, anything else is downloaded so
+	// that it cannot run script on this origin
+	if !models.IsImageMimeType(headers["Content-Type"]) {
+		c.ResponseWriter.Header().Set("Content-Disposition", "attachment")
+		c.ResponseWriter.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+
+	if fileNotModified(c.Request, headers) {
+		c.ResponseWriter.Header().Del("Content-Encoding")
+		c.ResponseWriter.Header().Del("Content-Length")
+		c.ResponseWriter.Header().Del("Content-Type")
+		c.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	c.WriteResponseReader(body, http.StatusOK)
+}
+
+// fileNotModified returns true if the conditional headers of the request
+// match the ETag or Last-Modified of the stored file. As with RFC 7232,
+// If-Modified-Since is only considered when there is no If-None-Match.
+func fileNotModified(r *http.Request, headers map[string]string) bool {
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := strings.TrimPrefix(headers["ETag"], "W/")
+		if etag == "" {
+			return false
+		}
+
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" ||
+				strings.TrimPrefix(candidate, "W/") == etag {
+
+				return true
+			}
+		}
+		return false
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	lastModified, err := http.ParseTime(headers["Last-Modified"])
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(ims)
 }
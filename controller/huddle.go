@@ -22,12 +22,14 @@ func HuddleHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch c.GetHttpMethod() {
 	case "OPTIONS":
-		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD", "DELETE"})
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD", "PATCH", "DELETE"})
 		return
 	case "GET":
 		ctl.Read(c)
 	case "HEAD":
 		ctl.Read(c)
+	case "PATCH":
+		ctl.Patch(c)
 	case "DELETE":
 		ctl.Delete(c)
 	default:
@@ -63,7 +65,7 @@ func (ctl *HuddleController) Read(c *models.Context) {
 	}
 
 	// Get Comments
-	m.Comments, status, err = models.GetComments(c.Site.Id, h.ItemTypeHuddle, m.Id, c.Request.URL, c.Auth.ProfileId, m.Meta.Created)
+	m.Comments, status, err = models.GetComments(c.Site.Id, h.ItemTypeHuddle, m.Id, c.Request.URL, c.Auth.ProfileId, m.Meta.Created, perms.IsModerator || perms.IsSiteOwner)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
@@ -112,6 +114,40 @@ func (ctl *HuddleController) Read(c *models.Context) {
 	c.RespondWithData(m)
 }
 
+// Patch marks a single huddle as read without reading it
+func (ctl *HuddleController) Patch(c *models.Context) {
+	_, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	status, err = fillHuddleReadPatches(c)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(
+			c, 0, itemTypeId, itemId),
+	)
+	if !perms.CanRead || c.Auth.ProfileId <= 0 {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	status, err = models.MarkHuddleAsRead(c.Auth.ProfileId, itemId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.RespondWithOK()
+}
+
 // Deletes a single huddle
 func (ctl *HuddleController) Delete(c *models.Context) {
 	_, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
@@ -147,11 +147,13 @@ func (ctl *HuddleParticipantController) Delete(c *models.Context) {
 	}
 
 	// Start Authorisation
+	//
+	// Participants may only leave a huddle, they cannot remove others
 	perms := models.GetPermission(
 		models.MakeAuthorisationContext(
 			c, 0, h.ItemTypes[h.ItemTypeHuddle], huddleId),
 	)
-	if !perms.CanDelete {
+	if !perms.CanRead {
 		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
 		return
 	}
@@ -28,6 +28,8 @@ func HuddleParticipantsHandler(w http.ResponseWriter, r *http.Request) {
 		ctl.ReadMany(c)
 	case "HEAD":
 		ctl.ReadMany(c)
+	case "POST":
+		ctl.UpdateMany(c)
 	case "PUT":
 		ctl.UpdateMany(c)
 	default:
@@ -131,7 +133,12 @@ func (ctl *HuddleParticipantsController) UpdateMany(c *models.Context) {
 		return
 	}
 
-	status, err = models.UpdateManyHuddleParticipants(c.Site.Id, huddleId, ems)
+	profileIds := []int64{}
+	for _, m := range ems {
+		profileIds = append(profileIds, m.Id)
+	}
+
+	status, err = r.AddParticipants(c.Site.Id, c.Auth.ProfileId, profileIds)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
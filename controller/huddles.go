@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -23,12 +24,14 @@ func HuddlesHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch c.GetHttpMethod() {
 	case "OPTIONS":
-		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD", "POST"})
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD", "PATCH", "POST"})
 		return
 	case "GET":
 		ctl.ReadMany(c)
 	case "HEAD":
 		ctl.ReadMany(c)
+	case "PATCH":
+		ctl.PatchMany(c)
 	case "POST":
 		ctl.Create(c)
 	default:
@@ -82,6 +85,10 @@ func (ctl *HuddlesController) ReadMany(c *models.Context) {
 // Creates a Huddle
 func (ctl *HuddlesController) Create(c *models.Context) {
 
+	if c.RespondWithIdempotentReplay() {
+		return
+	}
+
 	// Validate inputs
 	m := models.HuddleType{}
 
@@ -124,11 +131,69 @@ func (ctl *HuddlesController) Create(c *models.Context) {
 		c.IP,
 	)
 
-	c.RespondWithSeeOther(
-		fmt.Sprintf(
-			"%s/%d",
-			h.ApiTypeHuddle,
-			m.Id,
-		),
+	location := fmt.Sprintf(
+		"%s/%d",
+		h.ApiTypeHuddle,
+		m.Id,
 	)
+	c.RecordIdempotentCreate(location)
+
+	c.RespondWithSeeOther(location)
+}
+
+// PatchMany marks all of the huddles of the profile as read
+func (ctl *HuddlesController) PatchMany(c *models.Context) {
+	if c.Auth.ProfileId <= 0 {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+
+	status, err := fillHuddleReadPatches(c)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	status, err = models.MarkAllHuddlesAsRead(c.Auth.ProfileId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.RespondWithOK()
+}
+
+// fillHuddleReadPatches reads the patches from the request, marking huddles as
+// read by replacing /meta/flags/unread with false is the only patch supported
+func fillHuddleReadPatches(c *models.Context) (int, error) {
+	patches := []h.PatchType{}
+	err := c.Fill(&patches)
+	if err != nil {
+		return http.StatusBadRequest,
+			errors.New(fmt.Sprintf("The post data is invalid: %v", err.Error()))
+	}
+
+	status, err := h.TestPatch(patches)
+	if err != nil {
+		return status, err
+	}
+
+	for _, patch := range patches {
+		status, err := patch.ScanRawValue()
+		if err != nil {
+			return status, err
+		}
+
+		if patch.Path != "/meta/flags/unread" {
+			return http.StatusBadRequest,
+				errors.New(fmt.Sprintf("Unsupported path in patch: %s", patch.Path))
+		}
+
+		if !patch.Bool.Valid || patch.Bool.Bool {
+			return http.StatusBadRequest,
+				errors.New(fmt.Sprintf("%s can only be replaced with false", patch.Path))
+		}
+	}
+
+	return http.StatusOK, nil
 }
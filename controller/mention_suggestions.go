@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type MentionsController struct{}
+
+func MentionsHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := MentionsController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "HEAD", "GET"})
+		return
+	case "GET":
+		ctl.ReadMany(c)
+	case "HEAD":
+		ctl.ReadMany(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// ReadMany returns the profiles and microcosms whose names start with ?q= for
+// the @-mention typeahead. At most ?limit= suggestions are returned, which is
+// capped at models.MaxMentionSuggestions.
+func (ctl *MentionsController) ReadMany(c *models.Context) {
+
+	query := c.Request.URL.Query()
+
+	limit := models.MaxMentionSuggestions
+	if query.Get("limit") != "" {
+		inLimit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+		if err != nil || inLimit < 1 {
+			c.RespondWithErrorMessage(
+				"limit (if supplied) must be a positive integer",
+				http.StatusBadRequest,
+			)
+			return
+		}
+		limit = inLimit
+	}
+
+	ms, status, err := models.GetMentionSuggestions(
+		c.Site.Id,
+		query.Get("q"),
+		limit,
+	)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Suggestions are the same for everyone on the site
+	c.ResponseWriter.Header().Set("Cache-Control", "public, max-age=60")
+	c.RespondWithData(ms)
+}
@@ -200,7 +200,7 @@ func (ctl *MicrocosmController) Patch(c *models.Context) {
 	// All patches are 'replace'
 	for _, patch := range patches {
 		status, err := patch.ScanRawValue()
-		if !patch.Bool.Valid {
+		if err != nil {
 			c.RespondWithErrorDetail(err, status)
 			return
 		}
@@ -229,7 +229,7 @@ func (ctl *MicrocosmController) Patch(c *models.Context) {
 		case "/meta/flags/deleted":
 			// Only super users' can undelete, but super users' and owners can delete
 			if !patch.Bool.Valid {
-				c.RespondWithErrorMessage("/meta/flags/sticky requires a bool value", http.StatusBadRequest)
+				c.RespondWithErrorMessage("/meta/flags/deleted requires a bool value", http.StatusBadRequest)
 				return
 			}
 			if (patch.Bool.Bool == false && !(perms.IsModerator || perms.IsOwner)) || !perms.IsModerator {
@@ -241,6 +241,10 @@ func (ctl *MicrocosmController) Patch(c *models.Context) {
 				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
 				return
 			}
+			if !patch.Bool.Valid {
+				c.RespondWithErrorMessage("/meta/flags/moderated requires a bool value", http.StatusBadRequest)
+				return
+			}
 		default:
 			c.RespondWithErrorMessage("Invalid patch operation path", http.StatusBadRequest)
 			return
@@ -70,7 +70,7 @@ func (ctl *PollController) Read(c *models.Context) {
 	}
 
 	// Get Comments
-	m.Comments, status, err = models.GetComments(c.Site.Id, h.ItemTypePoll, m.Id, c.Request.URL, c.Auth.ProfileId, m.Meta.Created)
+	m.Comments, status, err = models.GetComments(c.Site.Id, h.ItemTypePoll, m.Id, c.Request.URL, c.Auth.ProfileId, m.Meta.Created, perms.IsModerator || perms.IsSiteOwner)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
@@ -220,7 +220,7 @@ func (ctl *PollController) Patch(c *models.Context) {
 	// All patches are 'replace'
 	for _, patch := range patches {
 		status, err := patch.ScanRawValue()
-		if !patch.Bool.Valid {
+		if err != nil {
 			c.RespondWithErrorDetail(err, status)
 			return
 		}
@@ -261,6 +261,10 @@ func (ctl *PollController) Patch(c *models.Context) {
 				c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
 				return
 			}
+			if !patch.Bool.Valid {
+				c.RespondWithErrorMessage("/meta/flags/moderated requires a bool value", http.StatusBadRequest)
+				return
+			}
 		default:
 			c.RespondWithErrorMessage("Invalid patch operation path", http.StatusBadRequest)
 			return
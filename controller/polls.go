@@ -39,6 +39,10 @@ type PollsController struct{}
 
 func (ctl *PollsController) Create(c *models.Context) {
 
+	if c.RespondWithIdempotentReplay() {
+		return
+	}
+
 	// Validate inputs
 	m := models.PollType{}
 	m.PollOpen = true
@@ -92,13 +96,14 @@ func (ctl *PollsController) Create(c *models.Context) {
 		c.Site.Id,
 	)
 
-	c.RespondWithSeeOther(
-		fmt.Sprintf(
-			"%s/%d",
-			h.ApiTypePoll,
-			m.Id,
-		),
+	location := fmt.Sprintf(
+		"%s/%d",
+		h.ApiTypePoll,
+		m.Id,
 	)
+	c.RecordIdempotentCreate(location)
+
+	c.RespondWithSeeOther(location)
 }
 
 func (ctl *PollsController) ReadMany(c *models.Context) {
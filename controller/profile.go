@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/microcosm-cc/microcosm/audit"
+	e "github.com/microcosm-cc/microcosm/errors"
 	h "github.com/microcosm-cc/microcosm/helpers"
 	"github.com/microcosm-cc/microcosm/models"
 )
@@ -117,7 +118,7 @@ func (ctl *ProfileController) Read(c *models.Context) {
 		}
 	}
 	if !perms.CanRead {
-		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		c.RespondWithErrorCode(e.NoRead, h.NoAuthMessage, http.StatusForbidden)
 		return
 	}
 	// End Authorisation
@@ -129,6 +130,10 @@ func (ctl *ProfileController) Read(c *models.Context) {
 	}
 	m.Meta.Permissions = perms
 
+	if !(c.Auth.ProfileId == m.Id || perms.IsModerator || perms.IsSiteOwner) {
+		m.HideLastActive()
+	}
+
 	if c.Auth.ProfileId > 0 {
 		// Get watcher status
 		watcherId, sendEmail, sendSms, ignored, status, err := models.GetWatcherAndIgnoreStatus(
@@ -196,7 +201,7 @@ func (ctl *ProfileController) Update(c *models.Context) {
 			c, 0, itemTypeId, itemId),
 	)
 	if !perms.CanUpdate {
-		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		c.RespondWithErrorCode(e.NoUpdate, h.NoAuthMessage, http.StatusForbidden)
 		return
 	}
 	// End Authorisation
@@ -204,7 +209,7 @@ func (ctl *ProfileController) Update(c *models.Context) {
 	// Populate site and user ID from goweb context
 	m.SiteId = c.Site.Id
 
-	status, err = m.Update()
+	status, err = m.UpdateBy(c.Auth.ProfileId)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
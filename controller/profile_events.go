@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	e "github.com/microcosm-cc/microcosm/errors"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func ProfileEventsHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := ProfileEventsController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "HEAD", "GET"})
+		return
+	case "HEAD":
+		ctl.ReadMany(c)
+	case "GET":
+		ctl.ReadMany(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type ProfileEventsController struct{}
+
+// ReadMany returns the events that a profile is attending, ?upcoming=true
+// restricts these to events that have not yet finished
+func (ctl *ProfileEventsController) ReadMany(c *models.Context) {
+	_, itemTypeId, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(c, 0, itemTypeId, itemId),
+	)
+	if !perms.CanRead {
+		c.RespondWithErrorCode(e.NoRead, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	_, status, err = models.GetProfileSummary(c.Site.Id, itemId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	query := c.Request.URL.Query()
+
+	limit, offset, status, err := h.GetLimitAndOffset(query)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	var upcoming bool
+	if query.Get("upcoming") != "" {
+		upcoming, err = strconv.ParseBool(query.Get("upcoming"))
+		if err != nil {
+			c.RespondWithErrorMessage(
+				fmt.Sprintf("upcoming (%s) is not a boolean.", query.Get("upcoming")),
+				http.StatusBadRequest,
+			)
+			return
+		}
+	}
+
+	ems, total, pages, status, err := models.GetEventsForProfile(
+		c.Site.Id,
+		itemId,
+		c.Auth.ProfileId,
+		upcoming,
+		limit,
+		offset,
+	)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Construct the response
+	thisLink := h.GetLinkToThisPage(*c.Request.URL, offset, limit, total)
+
+	m := models.EventsType{}
+	m.Events = h.ConstructArray(
+		ems,
+		fmt.Sprintf("%s/%d/events", h.ApiTypeProfile, itemId),
+		total,
+		limit,
+		offset,
+		pages,
+		c.Request.URL,
+	)
+	m.Meta.Links =
+		[]h.LinkType{
+			h.LinkType{Rel: "self", Href: thisLink.String()},
+		}
+
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+
+	c.RespondWithData(m)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/golang/glog"
+
+	e "github.com/microcosm-cc/microcosm/errors"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func ProfileExportHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := ProfileExportController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "HEAD", "GET"})
+		return
+	case "HEAD":
+		ctl.Read(c)
+	case "GET":
+		ctl.Read(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type ProfileExportController struct{}
+
+// Read responds with a JSON document of everything the profile has created,
+// only available to the profile itself and site owners. Private messages are
+// only included for the profile itself.
+func (ctl *ProfileExportController) Read(c *models.Context) {
+	_, _, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	if c.Auth.ProfileId < 1 ||
+		!(c.Auth.ProfileId == itemId || c.Auth.IsSiteOwner) {
+
+		c.RespondWithErrorCode(e.NoRead, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	// Check the profile exists while a status can still be returned
+	_, status, err = models.GetProfile(c.Site.Id, itemId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// The export is streamed as it is assembled
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	go func() {
+		err := models.ExportProfileData(pw, c.Site.Id, itemId, c.Auth.ProfileId)
+		if err != nil {
+			glog.Errorf("models.ExportProfileData(pw, %d, %d, %d) %+v",
+				c.Site.Id, itemId, c.Auth.ProfileId, err)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	c.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	c.ResponseWriter.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="profile-%d.json"`, itemId),
+	)
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+
+	c.WriteResponseReader(pr, http.StatusOK)
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/microcosm-cc/microcosm/audit"
+	e "github.com/microcosm-cc/microcosm/errors"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func ProfileImportHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := ProfileImportController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "POST"})
+		return
+	case "POST":
+		ctl.Create(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// The largest request body accepted by the profile import, allowing for a
+// generous profile at the most profiles that may be imported at once
+const maxProfileImportBytes int64 = models.MaxProfileImport * 2048
+
+type ProfileImportController struct{}
+
+// Create imports the posted profiles into the site, only available to site
+// owners. The user of each profile is given by email address, and is created
+// if there is none. The response lists the outcome for each profile as some
+// may be imported while others fail.
+func (ctl *ProfileImportController) Create(c *models.Context) {
+	// Start Authorisation
+	if !c.Auth.IsSiteOwner {
+		c.RespondWithErrorCode(e.NotAdmin, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	// Refuse oversized imports as they are read rather than after
+	c.Request.Body = http.MaxBytesReader(
+		c.ResponseWriter,
+		c.Request.Body,
+		maxProfileImportBytes,
+	)
+
+	ems := []models.ProfileType{}
+	err := c.Fill(&ems)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The post data is invalid: %v", err.Error()),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	m, status, err := models.ImportProfiles(c.Site.Id, ems)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	for _, result := range m.Results {
+		if result.ProfileId == 0 {
+			continue
+		}
+
+		audit.Create(
+			c.Site.Id,
+			h.ItemTypes[h.ItemTypeProfile],
+			result.ProfileId,
+			c.Auth.ProfileId,
+			time.Now(),
+			c.IP,
+		)
+	}
+
+	c.RespondWithData(m)
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/microcosm-cc/microcosm/audit"
+	e "github.com/microcosm-cc/microcosm/errors"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func ProfileMergeHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := ProfileMergeController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "POST"})
+		return
+	case "POST":
+		ctl.Create(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type ProfileMergeController struct{}
+
+// profileMergeRequest names the profile that the profile in the URL is merged
+// into
+type profileMergeRequest struct {
+	TargetProfileId int64 `json:"targetProfileId"`
+}
+
+// Create merges the profile in the URL into another profile, only available
+// to site owners. With ?dryRun=true nothing is changed and the response
+// describes what would have been.
+func (ctl *ProfileMergeController) Create(c *models.Context) {
+	_, _, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	if !c.Auth.IsSiteOwner {
+		c.RespondWithErrorCode(e.NotAdmin, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	m := profileMergeRequest{}
+	err = c.Fill(&m)
+	if err != nil {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("The post data is invalid: %v", err.Error()),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	var dryRun bool
+	if c.Request.URL.Query().Get("dryRun") != "" {
+		dryRun, err = strconv.ParseBool(c.Request.URL.Query().Get("dryRun"))
+		if err != nil {
+			c.RespondWithErrorMessage(
+				fmt.Sprintf("dryRun must be true or false: %v", err.Error()),
+				http.StatusBadRequest,
+			)
+			return
+		}
+	}
+
+	merge, status, err :=
+		models.MergeProfiles(c.Site.Id, itemId, m.TargetProfileId, dryRun)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	if !dryRun {
+		audit.Delete(
+			c.Site.Id,
+			h.ItemTypes[h.ItemTypeProfile],
+			itemId,
+			c.Auth.ProfileId,
+			time.Now(),
+			c.IP,
+		)
+
+		audit.Update(
+			c.Site.Id,
+			h.ItemTypes[h.ItemTypeProfile],
+			m.TargetProfileId,
+			c.Auth.ProfileId,
+			time.Now(),
+			c.IP,
+		)
+	}
+
+	c.RespondWithData(merge)
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"net/http"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+func ProfileNameHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := ProfileNameHistoryController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "HEAD", "GET"})
+		return
+	case "HEAD":
+		ctl.ReadMany(c)
+	case "GET":
+		ctl.ReadMany(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type ProfileNameHistoryController struct{}
+
+// Returns the previous names of a profile, only available to site owners
+func (ctl *ProfileNameHistoryController) ReadMany(c *models.Context) {
+	_, _, itemId, status, err := c.GetItemTypeAndItemId()
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	// Start Authorisation
+	if !c.Auth.IsSiteOwner {
+		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	ems, status, err := models.GetProfileNameHistory(c.Site.Id, itemId)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+	c.RespondWithData(ems)
+}
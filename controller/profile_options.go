@@ -55,8 +55,18 @@ func (ctl *ProfileOptionsController) Read(c *models.Context) {
 
 func (ctl *ProfileOptionsController) Update(c *models.Context) {
 
-	var m = models.ProfileOptionType{}
-	err := c.Fill(&m)
+	// Options that are not in the post data keep their current values, so that
+	// clients unaware of an option do not reset it
+	m, status, err := models.GetProfileOptions(c.Auth.ProfileId)
+	if status == http.StatusNotFound {
+		m, status, err = models.GetProfileOptionsDefaults(c.Site.Id)
+	}
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	err = c.Fill(&m)
 	if err != nil {
 		c.RespondWithErrorMessage(
 			fmt.Sprintf("The post data is invalid: %v", err.Error()),
@@ -68,7 +78,7 @@ func (ctl *ProfileOptionsController) Update(c *models.Context) {
 	// Profile ID cannot be changed
 	m.ProfileId = c.Auth.ProfileId
 
-	status, err := m.Update()
+	status, err = m.Update()
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
@@ -3,9 +3,11 @@ package controller
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/microcosm-cc/microcosm/audit"
+	e "github.com/microcosm-cc/microcosm/errors"
 	h "github.com/microcosm-cc/microcosm/helpers"
 	"github.com/microcosm-cc/microcosm/models"
 )
@@ -37,6 +39,9 @@ func ProfilesHandler(w http.ResponseWriter, r *http.Request) {
 
 type ProfilesController struct{}
 
+// maxProfilesByIds is the most profiles that may be requested by ID at once
+const maxProfilesByIds int = 250
+
 func (ctl *ProfilesController) Create(c *models.Context) {
 
 	m := models.ProfileType{}
@@ -105,11 +110,17 @@ func (ctl *ProfilesController) ReadMany(c *models.Context) {
 			c, 0, h.ItemTypes[h.ItemTypeProfile], 0),
 	)
 	if !perms.CanRead {
-		c.RespondWithErrorMessage(h.NoAuthMessage, http.StatusForbidden)
+		c.RespondWithErrorCode(e.NoRead, h.NoAuthMessage, http.StatusForbidden)
 		return
 	}
 	// End Authorisation
 
+	// A specific set of profiles may be requested, i.e. ?id=1&id=2
+	if len(c.Request.URL.Query()["id"]) > 0 {
+		ctl.readByIds(c, perms)
+		return
+	}
+
 	// Fetch query string args if any exist
 	limit, offset, status, err := h.GetLimitAndOffset(c.Request.URL.Query())
 	if err != nil {
@@ -145,3 +156,51 @@ func (ctl *ProfilesController) ReadMany(c *models.Context) {
 
 	c.RespondWithData(m)
 }
+
+// readByIds returns the profiles identified by the id query string arguments,
+// in the order they were requested
+func (ctl *ProfilesController) readByIds(
+	c *models.Context,
+	perms models.PermissionType,
+) {
+
+	ids := []int64{}
+	for _, sid := range c.Request.URL.Query()["id"] {
+		id, err := strconv.ParseInt(sid, 10, 64)
+		if err != nil || id < 1 {
+			c.RespondWithErrorMessage(
+				fmt.Sprintf("id (%s) is not a valid profile ID.", sid),
+				http.StatusBadRequest,
+			)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) > maxProfilesByIds {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf("No more than %d profiles may be requested.", maxProfilesByIds),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	ems, status, err := models.GetProfilesByIds(c.Site.Id, ids)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	total := int64(len(ems))
+
+	m := models.ProfilesType{}
+	m.Profiles = h.ConstructArray(ems, h.ApiTypeProfile, total, total, 0, 1, nil)
+	m.Meta.Links = []h.LinkType{
+		h.LinkType{Rel: "self", Href: c.Request.URL.String()},
+	}
+	m.Meta.Permissions = perms
+
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+
+	c.RespondWithData(m)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"net/http"
+
+	e "github.com/microcosm-cc/microcosm/errors"
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+type ProfilesOnlineController struct{}
+
+func ProfilesOnlineHandler(w http.ResponseWriter, r *http.Request) {
+	c, status, err := models.MakeContext(r, w)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ctl := ProfilesOnlineController{}
+
+	switch c.GetHttpMethod() {
+	case "OPTIONS":
+		c.RespondWithOptions([]string{"OPTIONS", "GET", "HEAD"})
+		return
+	case "GET":
+		ctl.ReadMany(c)
+	case "HEAD":
+		ctl.ReadMany(c)
+	default:
+		c.RespondWithStatus(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// ReadMany returns the profiles that are currently online
+func (ctl *ProfilesOnlineController) ReadMany(c *models.Context) {
+
+	// Start Authorisation
+	perms := models.GetPermission(
+		models.MakeAuthorisationContext(
+			c, 0, h.ItemTypes[h.ItemTypeProfile], 0),
+	)
+	if !perms.CanRead {
+		c.RespondWithErrorCode(e.NoRead, h.NoAuthMessage, http.StatusForbidden)
+		return
+	}
+	// End Authorisation
+
+	limit, offset, status, err := h.GetLimitAndOffset(c.Request.URL.Query())
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	ems, total, pages, status, err := models.GetOnlineProfiles(
+		c.Site.Id,
+		c.Auth.ProfileId,
+		limit,
+		offset,
+	)
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	thisLink := h.GetLinkToThisPage(*c.Request.URL, offset, limit, total)
+
+	m := models.ProfilesType{}
+	m.Profiles = h.ConstructArray(
+		ems,
+		h.ApiTypeProfile,
+		total,
+		limit,
+		offset,
+		pages,
+		c.Request.URL,
+	)
+	m.Meta.Links = []h.LinkType{
+		h.LinkType{Rel: "self", Href: thisLink.String()},
+	}
+	m.Meta.Permissions = perms
+
+	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
+
+	c.RespondWithData(m)
+}
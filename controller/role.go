@@ -229,7 +229,7 @@ func (ctl *RoleController) Patch(c *models.Context) {
 	// All patches are 'replace'
 	for _, patch := range patches {
 		status, err := patch.ScanRawValue()
-		if !patch.Bool.Valid {
+		if err != nil {
 			c.RespondWithErrorDetail(err, status)
 			return
 		}
@@ -3,6 +3,7 @@ package controller
 import (
 	"net/http"
 
+	h "github.com/microcosm-cc/microcosm/helpers"
 	"github.com/microcosm-cc/microcosm/models"
 )
 
@@ -31,20 +32,48 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Returns information about a single site, the one related to this HTTP context
+// Returns a page of the results of the search described by the query string
 func (ctl *SearchController) Read(c *models.Context) {
 
-	results, status, err := models.Search(
+	// Fetch query string args if any exist
+	limit, offset, status, err := h.GetLimitAndOffset(c.Request.URL.Query())
+	if err != nil {
+		c.RespondWithErrorDetail(err, status)
+		return
+	}
+
+	m, total, pages, status, err := models.Search(
 		c.Site.Id,
 		*c.Request.URL,
 		c.Auth.ProfileId,
+		limit,
+		offset,
 	)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
 	}
 
+	// Construct the response
+	if m.Results != nil {
+		m.Results = h.ConstructArray(
+			m.Results,
+			"result",
+			total,
+			limit,
+			offset,
+			pages,
+			c.Request.URL,
+		)
+	}
+
+	thisLink := h.GetLinkToThisPage(*c.Request.URL, offset, limit, total)
+	m.Meta.Links =
+		[]h.LinkType{
+			h.LinkType{Rel: "self", Href: thisLink.String()},
+		}
+
 	c.ResponseWriter.Header().Set("Cache-Control", `no-cache, max-age=0`)
 
-	c.RespondWithData(results)
+	c.RespondWithData(m)
 }
@@ -31,7 +31,9 @@ func SiteHostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Read responds with a header and body containing microcosm host for site.
+// Read responds with a header and body containing the host of the site that
+// serves the given host. This is the custom domain of the site if it has one,
+// otherwise the microco.sm subdomain. Hosts that match no site are not found.
 func (ctl *SiteHostController) Read(c *models.Context) {
 	host, exists := c.RouteVars["host"]
 	if !exists {
@@ -39,13 +41,11 @@ func (ctl *SiteHostController) Read(c *models.Context) {
 		return
 	}
 
-	// This could be further optimised by only caching host -> microcosm subdomain.
-	site, status, err := models.GetSiteByDomain(host)
+	microcosmHost, status, err := models.GetSiteHostForHost(host)
 	if err != nil {
 		c.RespondWithErrorDetail(err, status)
 		return
 	}
-	microcosmHost := site.SubdomainKey + ".microco.sm"
 
 	contentLen := len(microcosmHost)
 	c.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(contentLen))
@@ -72,6 +72,13 @@ func (wc *WhoAmIController) Read(c *models.Context) {
 		}
 	}
 
+	// Clients that would rather not follow a redirect may ask for the profile
+	if c.Request.URL.Query().Get("expand") == "profile" {
+		c.ResponseWriter.Header().Set("Cache-Control", "no-cache, max-age=0")
+		c.RespondWithData(m)
+		return
+	}
+
 	location := fmt.Sprintf(
 		"%s/%d",
 		h.ApiTypeProfile,
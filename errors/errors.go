@@ -71,6 +71,16 @@ const (
 	// HTTP 500 Internal Server Error.
 	// Search query timed out.
 	SearchTimeout = 24
+
+	// Profile-specific errors.
+	// HTTP 409 Conflict, the profile name belongs to another profile.
+	ProfileNameTaken ErrCode = 25
+	// The profile name is empty, too long, too short or has invalid characters.
+	InvalidProfileName ErrCode = 26
+
+	// Events-specific errors.
+	// HTTP 403 Forbidden, only invited profiles may RSVP to a private event.
+	EventNotInvited ErrCode = 27
 )
 
 // MicrocosmError implements the Error interface.
@@ -88,6 +98,12 @@ func (e MicrocosmError) Error() string {
 	return e.ErrorMessage
 }
 
+// Code returns the error code, it allows errors that embed a MicrocosmError
+// to be recognised by Code
+func (e MicrocosmError) Code() ErrCode {
+	return e.ErrorCode
+}
+
 func New(siteId int64, profileId int64, function string, errCode ErrCode, errMessage string) error {
 	return &MicrocosmError{
 		SiteId:       siteId,
@@ -97,3 +113,15 @@ func New(siteId int64, profileId int64, function string, errCode ErrCode, errMes
 		ErrorMessage: errMessage,
 	}
 }
+
+// Code returns the error code of err, the second value is false if err does
+// not carry one
+func Code(err error) (ErrCode, bool) {
+	if v, ok := err.(interface {
+		Code() ErrCode
+	}); ok {
+		return v.Code(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,38 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCode(t *testing.T) {
+	code, ok := Code(New(1, 2, "TestCode", ProfileNameTaken, "Taken"))
+	if !ok || code != ProfileNameTaken {
+		t.Errorf("Expected %d, got %d (%t)", ProfileNameTaken, code, ok)
+	}
+
+	code, ok = Code(MicrocosmError{ErrorCode: NoRead})
+	if !ok || code != NoRead {
+		t.Errorf("Expected %d, got %d (%t)", NoRead, code, ok)
+	}
+
+	// Errors that embed a MicrocosmError carry its code
+	type suggestion struct {
+		MicrocosmError
+		Suggestion string
+	}
+	code, ok = Code(&suggestion{MicrocosmError: MicrocosmError{ErrorCode: ProfileNameTaken}})
+	if !ok || code != ProfileNameTaken {
+		t.Errorf("Expected %d, got %d (%t)", ProfileNameTaken, code, ok)
+	}
+
+	_, ok = Code(errors.New("Plain"))
+	if ok {
+		t.Errorf("Expected a plain error to have no code")
+	}
+
+	_, ok = Code(nil)
+	if ok {
+		t.Errorf("Expected nil to have no code")
+	}
+}
@@ -0,0 +1,15 @@
+package helpers
+
+import (
+	"strings"
+)
+
+// CsvCell returns the value escaped for a spreadsheet. Cells that begin with
+// =, +, -, @, a tab or a carriage return are run as formulae by spreadsheet
+// applications, so these are prefixed with ' to be shown as text instead.
+func CsvCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,28 @@
+package helpers
+
+import (
+	"testing"
+)
+
+func TestCsvCell(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"", ""},
+		{"buro9", "buro9"},
+		{"a=b", "a=b"},
+		{`=HYPERLINK("http://example.com","x")`, `'=HYPERLINK("http://example.com","x")`},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+	}
+
+	for _, test := range tests {
+		if actual := CsvCell(test.value); actual != test.expected {
+			t.Errorf("CsvCell(%q) = %q, expected %q", test.value, actual, test.expected)
+		}
+	}
+}
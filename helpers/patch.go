@@ -3,6 +3,7 @@ package helpers
 import (
 	"database/sql"
 	"errors"
+	"math"
 	"net/http"
 	"strings"
 
@@ -80,8 +81,15 @@ func (p *PatchType) ScanRawValue() (int, error) {
 		p.Bool = sql.NullBool{Bool: p.RawValue.(bool), Valid: true}
 	case string:
 		p.String = sql.NullString{String: p.RawValue.(string), Valid: true}
+	case float64:
+		// JSON numbers are decoded as float64, only integers are patchable
+		f := p.RawValue.(float64)
+		if f != math.Trunc(f) {
+			return http.StatusBadRequest, errors.New("Patch: Numeric values must be integers")
+		}
+		p.Int64 = sql.NullInt64{Int64: int64(f), Valid: true}
 	default:
-		return http.StatusNotImplemented, errors.New("Patch: Currently only values of type boolean and string patchable")
+		return http.StatusNotImplemented, errors.New("Patch: Currently only values of type boolean, integer and string patchable")
 	}
 
 	return http.StatusOK, nil
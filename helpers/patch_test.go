@@ -0,0 +1,28 @@
+package helpers
+
+import (
+	"testing"
+)
+
+func TestScanRawValue(t *testing.T) {
+	p := PatchType{RawValue: float64(42)}
+	_, err := p.ScanRawValue()
+	if err != nil {
+		t.Fatalf("Expected an integer to be patchable: %+v", err)
+	}
+	if !p.Int64.Valid || p.Int64.Int64 != 42 {
+		t.Errorf("Expected 42, got %+v", p.Int64)
+	}
+
+	p = PatchType{RawValue: float64(4.2)}
+	_, err = p.ScanRawValue()
+	if err == nil || p.Int64.Valid {
+		t.Errorf("Expected a fraction not to be patchable")
+	}
+
+	p = PatchType{RawValue: true}
+	_, err = p.ScanRawValue()
+	if err != nil || !p.Bool.Valid || !p.Bool.Bool {
+		t.Errorf("Expected a bool to be patchable: %+v", err)
+	}
+}
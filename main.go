@@ -17,6 +17,7 @@ import (
 	"github.com/microcosm-cc/microcosm/cache"
 	conf "github.com/microcosm-cc/microcosm/config"
 	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
 	"github.com/microcosm-cc/microcosm/server"
 )
 
@@ -50,6 +51,10 @@ func main() {
 		go func() {
 			for sig := range c {
 				glog.Warningf("Caught %v, stopping profiler and exiting..", sig)
+				err := models.FlushLastActive()
+				if err != nil {
+					glog.Errorf("models.FlushLastActive() %+v", err)
+				}
 				// Heap profiler is run on GC, so make sure it GCs before exiting.
 				runtime.GC()
 				pprof.WriteHeapProfile(f)
@@ -70,6 +75,10 @@ func main() {
 		)
 		go func() {
 			<-sigc
+			err := models.FlushLastActive()
+			if err != nil {
+				glog.Errorf("models.FlushLastActive() %+v", err)
+			}
 			glog.Flush()
 			os.Exit(1)
 		}()
@@ -96,6 +105,11 @@ func main() {
 		conf.CONFIG_INT64[conf.KEY_MEMCACHED_PORT],
 	)
 
+	if glog.V(2) {
+		glog.Info("Loading reserved profile names")
+	}
+	models.LoadReservedProfileNames()
+
 	if glog.V(2) {
 		glog.Infof(
 			"Starting server on port %d",
@@ -14,15 +14,40 @@ import (
 	"github.com/lib/pq"
 
 	c "github.com/microcosm-cc/microcosm/cache"
+	e "github.com/microcosm-cc/microcosm/errors"
 	h "github.com/microcosm-cc/microcosm/helpers"
 )
 
-// The numerical order is implicitly important (it's the sort field)
+const (
+	RsvpYes        string = "yes"
+	RsvpMaybe      string = "maybe"
+	RsvpInvited    string = "invited"
+	RsvpNo         string = "no"
+	RsvpWaitlisted string = "waitlisted"
+)
+
+// The numerical order is implicitly important (it's the sort field). The
+// waitlist would sort better after yes, but the existing states are stored by
+// number so it is added at the end.
 var RsvpStates = map[string]int64{
-	"yes":     1,
-	"maybe":   2,
-	"invited": 3,
-	"no":      4,
+	RsvpYes:        1,
+	RsvpMaybe:      2,
+	RsvpInvited:    3,
+	RsvpNo:         4,
+	RsvpWaitlisted: 5,
+}
+
+// rsvpAliases are the other words that clients use for an RSVP
+var rsvpAliases = map[string]string{
+	"attending":     RsvpYes,
+	"going":         RsvpYes,
+	"interested":    RsvpMaybe,
+	"tentative":     RsvpMaybe,
+	"declined":      RsvpNo,
+	"not attending": RsvpNo,
+	"not going":     RsvpNo,
+	"waitlist":      RsvpWaitlisted,
+	"waiting":       RsvpWaitlisted,
 }
 
 type AttendeesType struct {
@@ -40,6 +65,14 @@ type AttendeeType struct {
 	RSVPd     pq.NullTime `json:"-"`
 	RSVPdOn   string      `json:"rsvpdOn,omitempty"`
 
+	// Guests are brought along by an attending profile and take spaces too
+	Guests int32 `json:"guests"`
+
+	// CheckedIn is set by the event host when an attending profile turns up
+	CheckedIn         bool        `json:"checkedIn"`
+	CheckedInNullable pq.NullTime `json:"-"`
+	CheckedInAt       string      `json:"checkedInAt,omitempty"`
+
 	Meta h.DefaultNoFlagsMetaType `json:"meta"`
 }
 
@@ -56,6 +89,38 @@ func (v AttendeeRequestBySeq) Len() int           { return len(v) }
 func (v AttendeeRequestBySeq) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
 func (v AttendeeRequestBySeq) Less(i, j int) bool { return v[i].Seq < v[j].Seq }
 
+// NormaliseRSVP replaces the RSVP with one of the RsvpStates, ignoring case
+// and accepting common alternatives such as "attending". An empty RSVP is an
+// invitation. This must be called before the RSVP is compared with anything.
+func (m *AttendeeType) NormaliseRSVP() (int, error) {
+	rsvp := strings.ToLower(strings.TrimSpace(m.RSVP))
+	if rsvp == "" {
+		rsvp = RsvpInvited
+	}
+	if alias, ok := rsvpAliases[rsvp]; ok {
+		rsvp = alias
+	}
+
+	if _, inList := RsvpStates[rsvp]; !inList {
+		return http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"rsvp ('%s') must be one of '%s', '%s', '%s', '%s' or '%s'",
+				m.RSVP,
+				RsvpInvited,
+				RsvpYes,
+				RsvpMaybe,
+				RsvpNo,
+				RsvpWaitlisted,
+			),
+		)
+	}
+
+	m.RSVP = rsvp
+	m.RSVPId = RsvpStates[rsvp]
+
+	return http.StatusOK, nil
+}
+
 func (m *AttendeeType) Validate(tx *sql.Tx) (int, error) {
 
 	if m.ProfileId <= 0 {
@@ -64,30 +129,83 @@ func (m *AttendeeType) Validate(tx *sql.Tx) (int, error) {
 			errors.New("You must specify the attendees Profile ID")
 	}
 
-	if strings.Trim(m.RSVP, " ") == "" {
-		m.RSVP = "invited"
+	status, err := m.NormaliseRSVP()
+	if err != nil {
+		return status, err
 	}
 
-	if _, inList := RsvpStates[m.RSVP]; !inList {
-		glog.Infoln("inList := RsvpStates[m.RSVP]; !inList")
-		return http.StatusBadRequest,
-			errors.New("You must specify a valid rsvp value " +
-				"('invited', 'yes', 'maybe', or 'no')")
+	// Only those invited to a private event may RSVP to it
+	var uninvited bool
+	err = tx.QueryRow(`
+SELECT e.is_private IS TRUE
+   AND e.created_by <> $2
+   AND NOT EXISTS (
+           SELECT 1
+             FROM event_invites
+            WHERE event_id = e.event_id
+              AND profile_id = $2
+       )
+  FROM events e
+ WHERE e.event_id = $1`,
+		m.EventId,
+		m.ProfileId,
+	).Scan(
+		&uninvited,
+	)
+	if err != nil {
+		glog.Errorf("tx.QueryRow(%d, %d).Scan() %+v", m.EventId, m.ProfileId, err)
+		return http.StatusInternalServerError,
+			errors.New("Error fetching row")
+	}
+	if uninvited {
+		return http.StatusForbidden, e.New(
+			0,
+			m.ProfileId,
+			"AttendeeType.Validate",
+			e.EventNotInvited,
+			"Only invited profiles may RSVP to a private event",
+		)
 	}
 
-	if m.RSVP == "yes" {
+	if m.Guests < 0 {
+		return http.StatusBadRequest, e.New(
+			0,
+			m.ProfileId,
+			"AttendeeType.Validate",
+			e.OutOfRange,
+			"Guests must be 0 or greater",
+		)
+	}
+
+	// Only a confirmed "yes" takes a space, "maybe" never fills an event
+	if m.RSVP != RsvpYes {
+		m.Guests = 0
+
+	} else {
 		//check to see if event is full
 
-		var spaces, rsvp_limit int64
-		err := tx.QueryRow(`
-SELECT rsvp_spaces
-      ,rsvp_limit
-  FROM events
- WHERE event_id = $1`,
+		var spaces, rsvpLimit, maxGuests, current int32
+		err = tx.QueryRow(`
+SELECT e.rsvp_spaces
+      ,e.rsvp_limit
+      ,e.rsvp_max_guests
+      ,COALESCE((
+           SELECT 1 + a.guests
+             FROM attendees a
+            WHERE a.event_id = e.event_id
+              AND a.profile_id = $2
+              AND a.state_id = $3
+       ), 0)
+  FROM events e
+ WHERE e.event_id = $1`,
 			m.EventId,
+			m.ProfileId,
+			RsvpStates[RsvpYes],
 		).Scan(
 			&spaces,
-			&rsvp_limit,
+			&rsvpLimit,
+			&maxGuests,
+			&current,
 		)
 		if err != nil {
 			glog.Errorf("tx.QueryRow(%d).Scan() %+v", m.EventId, err)
@@ -95,9 +213,28 @@ SELECT rsvp_spaces
 				errors.New("Error fetching row")
 		}
 
-		if spaces <= 0 && rsvp_limit != 0 {
-			glog.Infoln("spaces <= 0 && rsvp_limit != 0")
-			return http.StatusBadRequest, errors.New("Event is full")
+		if m.Guests > maxGuests {
+			return http.StatusBadRequest, e.New(
+				0,
+				m.ProfileId,
+				"AttendeeType.Validate",
+				e.OutOfRange,
+				fmt.Sprintf(
+					"This event allows at most %d guests per attendee",
+					maxGuests,
+				),
+			)
+		}
+
+		if !hasRsvpSpace(rsvpLimit, spaces, current, headCount(m.RSVP, m.Guests)) {
+			glog.Infoln("!hasRsvpSpace()")
+			return http.StatusBadRequest, e.New(
+				0,
+				m.ProfileId,
+				"AttendeeType.Validate",
+				e.EventRSVPFull,
+				"Event is full",
+			)
 		}
 	}
 
@@ -107,6 +244,32 @@ SELECT rsvp_spaces
 	return http.StatusOK, nil
 }
 
+// headCount returns the number of spaces taken by an attendee
+func headCount(rsvp string, guests int32) int32 {
+	if rsvp != RsvpYes {
+		return 0
+	}
+	return 1 + guests
+}
+
+// rsvpSpaces returns the spaces left at an event given the heads counted
+// against its limit. An event without a limit always has zero spaces.
+func rsvpSpaces(rsvpLimit int32, heads int32) int32 {
+	if rsvpLimit == 0 {
+		return 0
+	}
+	return rsvpLimit - heads
+}
+
+// hasRsvpSpace returns true if an attendee currently taking the current
+// number of spaces can change to taking wanted spaces
+func hasRsvpSpace(rsvpLimit int32, spaces int32, current int32, wanted int32) bool {
+	if rsvpLimit == 0 || wanted <= current {
+		return true
+	}
+	return spaces+current >= wanted
+}
+
 func (m *AttendeeType) FetchProfileSummaries(siteId int64) (int, error) {
 
 	profile, status, err := GetProfileSummary(siteId, m.ProfileId)
@@ -152,9 +315,9 @@ func (m *AttendeeType) FetchProfileSummaries(siteId int64) (int, error) {
 }
 
 func UpdateManyAttendees(siteId int64, ems []AttendeeType) (int, error) {
-	event, status, err := GetEvent(siteId, ems[0].EventId, 0)
+	event, status, err := getEvent(siteId, ems[0].EventId, 0)
 	if err != nil {
-		glog.Errorf("GetEvent(%d, %d, 0) %+v", siteId, ems[0].EventId, err)
+		glog.Errorf("getEvent(%d, %d, 0) %+v", siteId, ems[0].EventId, err)
 		return status, err
 	}
 
@@ -191,9 +354,9 @@ func UpdateManyAttendees(siteId int64, ems []AttendeeType) (int, error) {
 }
 
 func (m *AttendeeType) Update(siteId int64) (int, error) {
-	event, status, err := GetEvent(siteId, m.EventId, 0)
+	event, status, err := getEvent(siteId, m.EventId, 0)
 	if err != nil {
-		glog.Errorf("GetEvent(%d, %d, 0) %+v", siteId, m.EventId, err)
+		glog.Errorf("getEvent(%d, %d, 0) %+v", siteId, m.EventId, err)
 		return status, err
 	}
 
@@ -241,7 +404,9 @@ func (m *AttendeeType) upsert(tx *sql.Tx) (int, error) {
 	       state_date = $4,
 	       edited = $5,
 	       edited_by = $6,
-	       edit_reason = $7
+	       edit_reason = $7,
+	       guests = $8,
+	       checked_in = CASE WHEN $3 = 1 THEN checked_in ELSE NULL END
 	 WHERE profile_id = $1
 	   AND event_id = $2
  RETURNING attendee_id`,
@@ -252,6 +417,7 @@ func (m *AttendeeType) upsert(tx *sql.Tx) (int, error) {
 		m.Meta.EditedNullable,
 		m.Meta.EditedByNullable,
 		m.Meta.EditReason,
+		m.Guests,
 	).Scan(
 		&m.Id,
 	)
@@ -277,10 +443,10 @@ func (m *AttendeeType) upsert(tx *sql.Tx) (int, error) {
 	_, err = tx.Exec(`
 INSERT INTO attendees (
     event_id, profile_id, created, created_by, state_id,
-    state_date
+    state_date, guests
 ) VALUES (
     $1, $2, $3, $4, $5,
-    $6
+    $6, $7
 )`,
 		m.EventId,
 		m.ProfileId,
@@ -288,6 +454,7 @@ INSERT INTO attendees (
 		m.Meta.CreatedById,
 		m.RSVPId,
 		m.RSVPd,
+		m.Guests,
 	)
 	if err != nil {
 		glog.Errorf("tx.Exec(...) %+v", err)
@@ -306,11 +473,84 @@ INSERT INTO attendees (
 	return http.StatusOK, nil
 }
 
+// ParseCheckedInPatch returns whether the patches check the attendee in or
+// out, only a bool value of /checkedIn may be replaced
+func ParseCheckedInPatch(patches []h.PatchType) (bool, int, error) {
+	var checkedIn bool
+	for _, patch := range patches {
+		if patch.Path != "/checkedIn" {
+			return false, http.StatusBadRequest,
+				errors.New("Invalid patch operation path")
+		}
+
+		status, err := patch.ScanRawValue()
+		if err != nil {
+			return false, status, err
+		}
+		if !patch.Bool.Valid {
+			return false, http.StatusBadRequest,
+				errors.New("/checkedIn requires a bool value")
+		}
+		checkedIn = patch.Bool.Bool
+	}
+
+	return checkedIn, http.StatusOK, nil
+}
+
+// SetCheckedIn marks whether an attending profile turned up to the event. It
+// does not change the RSVP, so the spaces on the event are unaffected and no
+// updates are sent. Checking in again keeps the time of the first check-in.
+func (m *AttendeeType) SetCheckedIn(checkedIn bool) (int, error) {
+	if m.RSVP != RsvpYes {
+		return http.StatusBadRequest,
+			errors.New("Only attendees who are attending can be checked in")
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return http.StatusInternalServerError, err
+	}
+
+	err = db.QueryRow(`--SetCheckedIn
+UPDATE attendees
+   SET checked_in = CASE WHEN $2 THEN COALESCE(checked_in, NOW()) ELSE NULL END
+ WHERE attendee_id = $1
+   AND state_id = $3
+RETURNING checked_in`,
+		m.Id,
+		checkedIn,
+		RsvpStates[RsvpYes],
+	).Scan(
+		&m.CheckedInNullable,
+	)
+	if err == sql.ErrNoRows {
+		return http.StatusBadRequest,
+			errors.New("Only attendees who are attending can be checked in")
+
+	} else if err != nil {
+		glog.Errorf("db.QueryRow(%d, %t) %+v", m.Id, checkedIn, err)
+		return http.StatusInternalServerError,
+			errors.New("Error updating check-in")
+	}
+
+	m.CheckedIn = m.CheckedInNullable.Valid
+	m.CheckedInAt = ""
+	if m.CheckedIn {
+		m.CheckedInAt = m.CheckedInNullable.Time.Format(time.RFC3339Nano)
+	}
+
+	PurgeCache(h.ItemTypes[h.ItemTypeAttendee], m.Id)
+	PurgeCache(h.ItemTypes[h.ItemTypeEvent], m.EventId)
+
+	return http.StatusOK, nil
+}
+
 func (m *AttendeeType) Delete(siteId int64) (int, error) {
 
-	event, status, err := GetEvent(siteId, m.EventId, 0)
+	event, status, err := getEvent(siteId, m.EventId, 0)
 	if err != nil {
-		glog.Errorf("GetEvent(%d, %d, 0) %+v", siteId, m.EventId, err)
+		glog.Errorf("getEvent(%d, %d, 0) %+v", siteId, m.EventId, err)
 		return status, err
 	}
 
@@ -429,6 +669,8 @@ SELECT attendee_id
       ,edit_reason
       ,state_id
       ,state_date
+      ,guests
+      ,checked_in
  FROM attendees
 WHERE attendee_id = $1`,
 		id,
@@ -443,6 +685,8 @@ WHERE attendee_id = $1`,
 		&m.Meta.EditReasonNullable,
 		&m.RSVPId,
 		&m.RSVPd,
+		&m.Guests,
+		&m.CheckedInNullable,
 	)
 	if err == sql.ErrNoRows {
 		return AttendeeType{}, http.StatusNotFound, errors.New(
@@ -466,6 +710,11 @@ WHERE attendee_id = $1`,
 		m.RSVPdOn = m.RSVPd.Time.Format(time.RFC3339Nano)
 	}
 
+	if m.CheckedInNullable.Valid {
+		m.CheckedIn = true
+		m.CheckedInAt = m.CheckedInNullable.Time.Format(time.RFC3339Nano)
+	}
+
 	m.RSVP, err = h.GetMapStringFromInt(RsvpStates, m.RSVPId)
 	if err != nil {
 		return AttendeeType{}, http.StatusInternalServerError, err
@@ -484,7 +733,7 @@ WHERE attendee_id = $1`,
 	}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeAttendee))
 	m.FetchProfileSummaries(siteId)
 
 	return m, http.StatusOK, nil
@@ -512,8 +761,8 @@ func GetAttendees(
 
 	var where string
 	if attending {
-		where += `
-		 AND state_id = 1`
+		where += fmt.Sprintf(`
+		 AND state_id = %d`, RsvpStates[RsvpYes])
 	}
 
 	rows, err := db.Query(
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+func TestGuestsConsumeSpaces(t *testing.T) {
+	// Two attending profiles, one bringing three guests
+	heads := headCount(RsvpYes, 0) + headCount(RsvpYes, 3)
+	if heads != 5 {
+		t.Fatalf("Expected a head count of 5, got %d", heads)
+	}
+
+	spaces := rsvpSpaces(6, heads)
+	if spaces != 1 {
+		t.Fatalf("Expected 1 space, got %d", spaces)
+	}
+
+	// A new attendee may come alone but not with a guest
+	if !hasRsvpSpace(6, spaces, 0, headCount(RsvpYes, 0)) {
+		t.Errorf("Expected space for an attendee without guests")
+	}
+	if hasRsvpSpace(6, spaces, 0, headCount(RsvpYes, 1)) {
+		t.Errorf("Expected no space for an attendee with a guest")
+	}
+
+	// Those who are not attending bring no guests
+	if headCount(RsvpMaybe, 2) != 0 {
+		t.Errorf("Expected a maybe to take no spaces")
+	}
+
+	// Without a limit there is always space
+	if !hasRsvpSpace(0, 0, 0, headCount(RsvpYes, 10)) {
+		t.Errorf("Expected space at an event without a limit")
+	}
+}
+
+func TestLoweringGuestsFreesSpaces(t *testing.T) {
+	// The event is full, one attendee is bringing three guests
+	current := headCount(RsvpYes, 3)
+	spaces := rsvpSpaces(5, headCount(RsvpYes, 0)+current)
+	if spaces != 0 {
+		t.Fatalf("Expected the event to be full, got %d spaces", spaces)
+	}
+
+	// Keeping or lowering the guests is allowed when the event is full
+	if !hasRsvpSpace(5, spaces, current, headCount(RsvpYes, 3)) {
+		t.Errorf("Expected the attendee to keep their guests")
+	}
+	if !hasRsvpSpace(5, spaces, current, headCount(RsvpYes, 1)) {
+		t.Errorf("Expected the attendee to be able to bring fewer guests")
+	}
+	if hasRsvpSpace(5, spaces, current, headCount(RsvpYes, 4)) {
+		t.Errorf("Expected no space for another guest")
+	}
+
+	// Which frees spaces for others
+	spaces = rsvpSpaces(5, headCount(RsvpYes, 0)+headCount(RsvpYes, 1))
+	if spaces != 2 {
+		t.Fatalf("Expected 2 spaces, got %d", spaces)
+	}
+	if !hasRsvpSpace(5, spaces, 0, headCount(RsvpYes, 1)) {
+		t.Errorf("Expected space for another attendee and their guest")
+	}
+}
+
+func TestNormaliseRSVP(t *testing.T) {
+	tests := []struct {
+		rsvp     string
+		expected string
+		valid    bool
+	}{
+		{rsvp: "yes", expected: RsvpYes, valid: true},
+		{rsvp: " Yes ", expected: RsvpYes, valid: true},
+		{rsvp: "attending", expected: RsvpYes, valid: true},
+		{rsvp: "MAYBE", expected: RsvpMaybe, valid: true},
+		{rsvp: "Not Going", expected: RsvpNo, valid: true},
+		{rsvp: "", expected: RsvpInvited, valid: true},
+		{rsvp: "Waitlisted", expected: RsvpWaitlisted, valid: true},
+		{rsvp: "waitlist", expected: RsvpWaitlisted, valid: true},
+		{rsvp: "perhaps", valid: false},
+		{rsvp: "yes please", valid: false},
+	}
+
+	for _, test := range tests {
+		m := AttendeeType{RSVP: test.rsvp}
+		_, err := m.NormaliseRSVP()
+
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Expected %q to be invalid, got %q", test.rsvp, m.RSVP)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected %q to be valid, got %v", test.rsvp, err)
+			continue
+		}
+		if m.RSVP != test.expected || m.RSVPId != RsvpStates[test.expected] {
+			t.Errorf("Expected %q to be %q, got %q (%d)", test.rsvp, test.expected, m.RSVP, m.RSVPId)
+		}
+	}
+}
+
+func TestParseCheckedInPatch(t *testing.T) {
+	checkedIn, _, err := ParseCheckedInPatch(
+		[]h.PatchType{{Operation: "replace", Path: "/checkedIn", RawValue: true}},
+	)
+	if err != nil || !checkedIn {
+		t.Errorf("Expected to check in, got %t %+v", checkedIn, err)
+	}
+
+	checkedIn, _, err = ParseCheckedInPatch(
+		[]h.PatchType{{Operation: "replace", Path: "/checkedIn", RawValue: false}},
+	)
+	if err != nil || checkedIn {
+		t.Errorf("Expected to check out, got %t %+v", checkedIn, err)
+	}
+
+	for _, value := range []interface{}{float64(1), "true"} {
+		_, status, err := ParseCheckedInPatch(
+			[]h.PatchType{{Operation: "replace", Path: "/checkedIn", RawValue: value}},
+		)
+		if err == nil || status != http.StatusBadRequest {
+			t.Errorf("Expected %#v to be refused, got %d", value, status)
+		}
+	}
+
+	_, status, err := ParseCheckedInPatch(
+		[]h.PatchType{{Operation: "replace", Path: "/rsvp", RawValue: true}},
+	)
+	if err == nil || status != http.StatusBadRequest {
+		t.Errorf("Expected another path to be refused, got %d", status)
+	}
+}
+
+func TestOnlyAttendingCanCheckIn(t *testing.T) {
+	// Refused before the database is reached
+	m := AttendeeType{RSVP: RsvpMaybe}
+	status, err := m.SetCheckedIn(true)
+	if err == nil || status != http.StatusBadRequest {
+		t.Errorf("Expected a maybe to be refused a check in, got %d", status)
+	}
+}
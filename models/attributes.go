@@ -154,6 +154,8 @@ func UpdateManyAttributes(
 			errors.New(fmt.Sprintf("Transaction failed: %v", err.Error()))
 	}
 
+	purgeSiteHTMLPolicyForItem(itemTypeId, itemId)
+
 	return http.StatusOK, nil
 }
 
@@ -175,6 +177,8 @@ func (m *AttributeType) Update(itemTypeId int64, itemId int64) (int, error) {
 			errors.New(fmt.Sprintf("Transaction failed: %v", err.Error()))
 	}
 
+	purgeSiteHTMLPolicyForItem(itemTypeId, itemId)
+
 	return http.StatusOK, nil
 }
 
@@ -334,6 +338,8 @@ func DeleteManyAttributes(
 			errors.New(fmt.Sprintf("Transaction failed: %v", err.Error()))
 	}
 
+	purgeSiteHTMLPolicyForItem(itemTypeId, itemId)
+
 	return http.StatusOK, nil
 }
 
@@ -0,0 +1,223 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/microcosm-cc/microcosm/audit"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// AuditTrailType is a page of the actions recorded against an item
+type AuditTrailType struct {
+	Actions h.ArrayType    `json:"actions"`
+	Meta    h.CoreMetaType `json:"meta"`
+}
+
+// AuditActionType is a single action recorded against an item, Profile is
+// the profile that performed it
+type AuditActionType struct {
+	ItemType  string      `json:"itemType"`
+	ItemId    int64       `json:"itemId"`
+	ProfileId int64       `json:"profileId"`
+	Profile   interface{} `json:"profile,omitempty"`
+	Action    string      `json:"action"`
+	Seen      time.Time   `json:"seen"`
+	IP        string      `json:"ip"`
+}
+
+// AuditTrailFilterType restricts an audit trail to the actions of a profile
+// and to a time range, zero values do not restrict it
+type AuditTrailFilterType struct {
+	ProfileId int64
+	From      time.Time
+	To        time.Time
+}
+
+// ParseAuditTrailFilter reads the profileId, from and to query string
+// arguments. From and to are RFC3339 timestamps.
+func ParseAuditTrailFilter(query url.Values) (AuditTrailFilterType, int, error) {
+	filter := AuditTrailFilterType{}
+
+	if query.Get("profileId") != "" {
+		profileId, err := strconv.ParseInt(query.Get("profileId"), 10, 64)
+		if err != nil || profileId < 1 {
+			return AuditTrailFilterType{}, http.StatusBadRequest, errors.New(
+				fmt.Sprintf(
+					"profileId (%s) must be a positive integer.",
+					query.Get("profileId"),
+				),
+			)
+		}
+		filter.ProfileId = profileId
+	}
+
+	for key, t := range map[string]*time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		if query.Get(key) == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, query.Get(key))
+		if err != nil {
+			return AuditTrailFilterType{}, http.StatusBadRequest, errors.New(
+				fmt.Sprintf(
+					"%s (%s) is not an RFC3339 timestamp.",
+					key,
+					query.Get(key),
+				),
+			)
+		}
+		*t = parsed
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() &&
+		filter.To.Before(filter.From) {
+
+		return AuditTrailFilterType{}, http.StatusBadRequest,
+			errors.New("to cannot be before from.")
+	}
+
+	return filter, http.StatusOK, nil
+}
+
+// GetAuditTrail returns the actions recorded against the item, most recent
+// first
+func GetAuditTrail(
+	siteId int64,
+	itemTypeId int64,
+	itemId int64,
+	filter AuditTrailFilterType,
+	limit int64,
+	offset int64,
+) (
+	[]AuditActionType,
+	int64,
+	int64,
+	int,
+	error,
+) {
+
+	itemType, err := h.GetMapStringFromInt(h.ItemTypes, itemTypeId)
+	if err != nil {
+		return []AuditActionType{}, 0, 0, http.StatusBadRequest, err
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return []AuditActionType{}, 0, 0, http.StatusInternalServerError, err
+	}
+
+	args := []interface{}{siteId, itemTypeId, itemId, limit, offset}
+
+	var where string
+	if filter.ProfileId > 0 {
+		args = append(args, filter.ProfileId)
+		where += `
+   AND profile_id = $` + strconv.Itoa(len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		where += `
+   AND seen >= $` + strconv.Itoa(len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		where += `
+   AND seen <= $` + strconv.Itoa(len(args))
+	}
+
+	rows, err := db.Query(`--GetAuditTrail
+SELECT COUNT(*) OVER() AS total
+      ,profile_id
+      ,action
+      ,seen
+      ,ip
+  FROM ips
+ WHERE site_id = $1
+   AND item_type_id = $2
+   AND item_id = $3`+where+`
+ ORDER BY seen DESC
+ LIMIT $4
+OFFSET $5`,
+		args...,
+	)
+	if err != nil {
+		return []AuditActionType{}, 0, 0, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Database query failed: %v", err.Error()),
+			)
+	}
+	defer rows.Close()
+
+	var total int64
+	ems := []AuditActionType{}
+	for rows.Next() {
+		m := AuditActionType{
+			ItemType: itemType,
+			ItemId:   itemId,
+		}
+
+		var action string
+		err = rows.Scan(
+			&total,
+			&m.ProfileId,
+			&action,
+			&m.Seen,
+			&m.IP,
+		)
+		if err != nil {
+			return []AuditActionType{}, 0, 0, http.StatusInternalServerError,
+				errors.New(
+					fmt.Sprintf("Row parsing error: %v", err.Error()),
+				)
+		}
+
+		m.Action = audit.Actions[action]
+		if m.Action == "" {
+			m.Action = action
+		}
+
+		ems = append(ems, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []AuditActionType{}, 0, 0, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Error fetching rows: %v", err.Error()),
+			)
+	}
+	rows.Close()
+
+	// Profiles that no longer exist are identified only by their ID
+	for i := range ems {
+		profile, status, err := GetProfileSummary(siteId, ems[i].ProfileId)
+		if err != nil {
+			if status == http.StatusNotFound {
+				continue
+			}
+			return []AuditActionType{}, 0, 0, status, err
+		}
+		ems[i].Profile = profile
+	}
+
+	pages := h.GetPageCount(total, limit)
+	maxOffset := h.GetMaxOffset(total, limit)
+
+	if offset > maxOffset {
+		return []AuditActionType{}, 0, 0, http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"not enough records, offset (%d) would return an empty page.",
+				offset,
+			),
+		)
+	}
+
+	return ems, total, pages, http.StatusOK, nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseAuditTrailFilter(t *testing.T) {
+	tests := []struct {
+		query     string
+		profileId int64
+		valid     bool
+	}{
+		{query: "", valid: true},
+		{query: "profileId=7", profileId: 7, valid: true},
+		{query: "from=2014-06-01T00:00:00Z&to=2014-06-30T00:00:00Z", valid: true},
+		{query: "profileId=0", valid: false},
+		{query: "profileId=bob", valid: false},
+		{query: "from=yesterday", valid: false},
+		{query: "from=2014-06-30T00:00:00Z&to=2014-06-01T00:00:00Z", valid: false},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		filter, _, err := ParseAuditTrailFilter(query)
+
+		if test.valid != (err == nil) {
+			t.Errorf("Expected %q valid: %t, got %v", test.query, test.valid, err)
+			continue
+		}
+		if filter.ProfileId != test.profileId {
+			t.Errorf("Expected %q profile %d, got %d", test.query, test.profileId, filter.ProfileId)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	c "github.com/microcosm-cc/microcosm/cache"
+	conf "github.com/microcosm-cc/microcosm/config"
 	h "github.com/microcosm-cc/microcosm/helpers"
 )
 
@@ -46,6 +47,23 @@ type PersonaResponseType struct {
 	Issuer   string
 }
 
+// accessTokenTTL is how long a newly created access token may be used for
+func accessTokenTTL() time.Duration {
+	return time.Duration(conf.CONFIG_INT64[conf.KEY_ACCESS_TOKEN_TTL_DAYS]) *
+		24 * time.Hour
+}
+
+// accessTokenCacheTTL returns how long a token may be cached for, which is
+// until it expires but no longer than the usual cache TTL. Memcache treats
+// TTLs of more than 30 days as timestamps so they must not be used.
+func accessTokenCacheTTL(expires time.Time) int32 {
+	ttl := int32(expires.Sub(time.Now()).Seconds())
+	if ttl > mcTtl {
+		return mcTtl
+	}
+	return ttl
+}
+
 func (m *AccessTokenType) Insert() (int, error) {
 
 	tx, err := h.GetTransaction()
@@ -56,15 +74,33 @@ func (m *AccessTokenType) Insert() (int, error) {
 	}
 	defer tx.Rollback()
 
-	err = tx.QueryRow(`
+	status, err := m.insert(tx)
+	if err != nil {
+		return status, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Transaction failed: %v", err.Error()),
+		)
+	}
+
+	return m.cache()
+}
+
+func (m *AccessTokenType) insert(tx *sql.Tx) (int, error) {
+
+	err := tx.QueryRow(`
 INSERT INTO access_tokens (
-    token_value, user_id, client_id
+    token_value, user_id, client_id, expires
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4
 ) RETURNING access_token_id, created, expires`,
 		m.TokenValue,
 		m.UserId,
 		m.ClientId,
+		time.Now().Add(accessTokenTTL()),
 	).Scan(
 		&m.AccessTokenId,
 		&m.Created,
@@ -77,14 +113,13 @@ INSERT INTO access_tokens (
 			)
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return http.StatusInternalServerError, errors.New(
-			fmt.Sprintf("Transaction failed: %v", err.Error()),
-		)
-	}
+	return http.StatusOK, nil
+}
+
+// cache fetches the user of a newly created token and puts the token into
+// memcache
+func (m *AccessTokenType) cache() (int, error) {
 
-	// Put access token into memcache
 	if m.UserId > 0 {
 		u, status, err := GetUser(m.UserId)
 		if err != nil {
@@ -93,19 +128,91 @@ INSERT INTO access_tokens (
 		m.User = u
 	}
 
-	// Update cache
 	mcKey := fmt.Sprintf(mcAccessTokenKeys[c.CacheDetail], m.TokenValue)
-	c.CacheSet(mcKey, m, int32(m.Expires.Sub(time.Now()).Seconds()))
+	c.CacheSet(mcKey, m, accessTokenCacheTTL(m.Expires))
 
 	return http.StatusOK, nil
 }
 
+// Refresh replaces an unexpired access token with a new token for the same
+// user and client, which has the full TTL. The old token can no longer be used.
+func (m *AccessTokenType) Refresh(tokenValue string) (AccessTokenType, int, error) {
+
+	if !m.Expires.After(time.Now()) {
+		return AccessTokenType{}, http.StatusUnauthorized,
+			errors.New("Token has expired")
+	}
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return AccessTokenType{}, http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Could not start transaction: %v", err.Error()),
+		)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+DELETE FROM access_tokens
+ WHERE token_value = $1
+   AND expires > NOW()`,
+		m.TokenValue,
+	)
+	if err != nil {
+		return AccessTokenType{}, http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Could not delete token: %v", err.Error()),
+		)
+	}
+
+	// The token may have been refreshed or deleted by another request
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return AccessTokenType{}, http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Error fetching rows affected: %v", err.Error()),
+		)
+	}
+	if rowsAffected == 0 {
+		return AccessTokenType{}, http.StatusUnauthorized,
+			errors.New("Token not found")
+	}
+
+	refreshed := AccessTokenType{
+		TokenValue: tokenValue,
+		UserId:     m.UserId,
+		ClientId:   m.ClientId,
+	}
+	status, err := refreshed.insert(tx)
+	if err != nil {
+		return AccessTokenType{}, status, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return AccessTokenType{}, http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Transaction failed: %v", err.Error()),
+		)
+	}
+
+	c.CacheDelete(fmt.Sprintf(mcAccessTokenKeys[c.CacheDetail], m.TokenValue))
+
+	status, err = refreshed.cache()
+	if err != nil {
+		return AccessTokenType{}, status, err
+	}
+
+	return refreshed, http.StatusOK, nil
+}
+
 func GetAccessToken(token string) (AccessTokenType, int, error) {
 
 	// Get from cache if it's available
 	mcKey := fmt.Sprintf(mcAccessTokenKeys[c.CacheDetail], token)
 	if val, ok := c.CacheGet(mcKey, AccessTokenType{}); ok {
-		return val.(AccessTokenType), http.StatusOK, nil
+		m := val.(AccessTokenType)
+		if !m.Expires.After(time.Now()) {
+			return AccessTokenType{}, http.StatusUnauthorized,
+				errors.New("Token has expired")
+		}
+		return m, http.StatusOK, nil
 	}
 
 	db, err := h.GetConnection()
@@ -145,6 +252,11 @@ SELECT access_token_id
 		)
 	}
 
+	if !m.Expires.After(time.Now()) {
+		return AccessTokenType{}, http.StatusUnauthorized,
+			errors.New("Token has expired")
+	}
+
 	if m.UserId > 0 {
 		u, status, err := GetUser(m.UserId)
 		if err != nil {
@@ -154,7 +266,7 @@ SELECT access_token_id
 	}
 
 	// Update cache
-	c.CacheSet(mcKey, m, int32(m.Expires.Sub(time.Now()).Seconds()))
+	c.CacheSet(mcKey, m, accessTokenCacheTTL(m.Expires))
 
 	return m, http.StatusOK, nil
 }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccessTokenCacheTTL(t *testing.T) {
+	ttl := accessTokenCacheTTL(time.Now().Add(90 * 24 * time.Hour))
+	if ttl != mcTtl {
+		t.Errorf("Expected a long lived token to be cached for %d, got %d", mcTtl, ttl)
+	}
+
+	ttl = accessTokenCacheTTL(time.Now().Add(time.Hour))
+	if ttl <= 3590 || ttl > 3600 {
+		t.Errorf("Expected a token expiring in an hour to be cached for an hour, got %d", ttl)
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/golang/glog"
 
 	h "github.com/microcosm-cc/microcosm/helpers"
@@ -13,6 +17,10 @@ type AuthContext struct {
 	MicrocosmId int64
 	ItemTypeId  int64
 	ItemId      int64
+
+	// Set when the context was made from a request, so that permissions are
+	// only fetched once per request
+	permissions *permissionCache
 }
 
 type PermissionType struct {
@@ -46,23 +54,126 @@ func MakeAuthorisationContext(
 		MicrocosmId: m,
 		ItemTypeId:  t,
 		ItemId:      i,
+		permissions: c.permissions,
 	}
 }
 
+// permissionKey is the arguments to get_effective_permissions
+type permissionKey struct {
+	SiteId      int64
+	MicrocosmId int64
+	ItemTypeId  int64
+	ItemId      int64
+	ProfileId   int64
+}
+
+// permissionCache holds the permissions fetched during a single request. List
+// endpoints check the same permissions many times, often concurrently, and
+// within a request the answer will not change.
+type permissionCache struct {
+	sync.RWMutex
+	items map[permissionKey]PermissionType
+}
+
+func newPermissionCache() *permissionCache {
+	return &permissionCache{items: map[permissionKey]PermissionType{}}
+}
+
+func (pc *permissionCache) get(key permissionKey) (PermissionType, bool) {
+	pc.RLock()
+	defer pc.RUnlock()
+
+	m, ok := pc.items[key]
+	return m, ok
+}
+
+func (pc *permissionCache) set(key permissionKey, m PermissionType) {
+	pc.Lock()
+	defer pc.Unlock()
+
+	pc.items[key] = m
+}
+
+// ItemRef identifies an item whose permissions are wanted by GetPermissions
+type ItemRef struct {
+	MicrocosmId int64
+	ItemTypeId  int64
+	ItemId      int64
+}
+
 func GetPermission(ac AuthContext) PermissionType {
+	return GetPermissions(
+		ac,
+		[]ItemRef{
+			ItemRef{
+				MicrocosmId: ac.MicrocosmId,
+				ItemTypeId:  ac.ItemTypeId,
+				ItemId:      ac.ItemId,
+			},
+		},
+	)[0]
+}
+
+// GetPermissions returns the permissions that the profile in the context has
+// on each of the items, in the same order. The microcosm and item of the
+// context are ignored in favour of those of each item. Permissions that have
+// not already been fetched during this request are fetched in a single query.
+//
+// If the query fails then the permissions of every item fetched by it are
+// returned empty, and so deny everything.
+func GetPermissions(ac AuthContext, items []ItemRef) []PermissionType {
+
+	perms := make([]PermissionType, len(items))
+
+	var (
+		misses    []int
+		sqlValues []string
+		sqlArgs   = []interface{}{ac.SiteId, ac.ProfileId}
+	)
+	for i, item := range items {
+		itemAc := ac
+		itemAc.MicrocosmId = item.MicrocosmId
+		itemAc.ItemTypeId = item.ItemTypeId
+		itemAc.ItemId = item.ItemId
+
+		if ac.ProfileId == 0 && item.ItemTypeId == h.ItemTypes[h.ItemTypeSite] {
+			// Guests can read site description, we can save a query
+			perms[i] = PermissionType{Context: itemAc, Valid: true}
+			perms[i].CanRead = true
+			perms[i].IsGuest = true
+			continue
+		}
+
+		if ac.permissions != nil {
+			if m, ok := ac.permissions.get(permissionKeyFor(itemAc)); ok {
+				m.Context = itemAc
+				perms[i] = m
+				continue
+			}
+		}
+
+		perms[i].Context = itemAc
+		misses = append(misses, i)
+
+		n := len(sqlArgs)
+		sqlValues = append(
+			sqlValues,
+			fmt.Sprintf(
+				"(%d, $%d::bigint, $%d::bigint, $%d::bigint)",
+				i, n+1, n+2, n+3,
+			),
+		)
+		sqlArgs = append(sqlArgs, item.MicrocosmId, item.ItemTypeId, item.ItemId)
+	}
 
-	if ac.ProfileId == 0 && ac.ItemTypeId == h.ItemTypes[h.ItemTypeSite] {
-		// Guests can read site description, we can save a query
-		m := PermissionType{Context: ac, Valid: true}
-		m.CanRead = true
-		m.IsGuest = true
-		return m
+	if len(misses) == 0 {
+		return perms
 	}
 
 	tx, err := h.GetTransaction()
 	if err != nil {
 		glog.Errorf("h.GetTransaction() %+v", err)
-		return PermissionType{}
+		return emptyPermissions(perms, misses)
 	}
 	defer tx.Rollback()
 
@@ -73,74 +184,115 @@ func GetPermission(ac AuthContext) PermissionType {
 	// If we don't put this in a transaction it is possible that we have a
 	// race condition on the insert that will cause one of the queries (the
 	// latter) to fail.
-	m := PermissionType{Context: ac, Valid: true}
-	err = tx.QueryRow(`
-SELECT can_create
-      ,can_read
-      ,can_update
-      ,can_delete
-      ,can_close_own
-      ,can_open_own
-      ,can_read_others
-      ,is_guest
-      ,is_banned
-      ,is_owner
-      ,is_superuser AS is_moderator
-      ,is_site_owner
-  FROM get_effective_permissions($1,$2,$3,$4,$5)`,
-		ac.SiteId,
-		ac.MicrocosmId,
-		ac.ItemTypeId,
-		ac.ItemId,
-		ac.ProfileId,
-	).Scan(
-		&m.CanCreate,
-		&m.CanRead,
-		&m.CanUpdate,
-		&m.CanDelete,
-		&m.CanCloseOwn,
-		&m.CanOpenOwn,
-		&m.CanReadOthers,
-		&m.IsGuest,
-		&m.IsBanned,
-		&m.IsOwner,
-		&m.IsModerator,
-		&m.IsSiteOwner,
+	rows, err := tx.Query(`
+SELECT i.seq
+      ,p.can_create
+      ,p.can_read
+      ,p.can_update
+      ,p.can_delete
+      ,p.can_close_own
+      ,p.can_open_own
+      ,p.can_read_others
+      ,p.is_guest
+      ,p.is_banned
+      ,p.is_owner
+      ,p.is_superuser AS is_moderator
+      ,p.is_site_owner
+  FROM (
+           VALUES `+strings.Join(sqlValues, `
+                 ,`)+`
+       ) AS i (seq, microcosm_id, item_type_id, item_id)
+      ,get_effective_permissions($1, i.microcosm_id, i.item_type_id, i.item_id, $2) AS p`,
+		sqlArgs...,
 	)
 	if err != nil {
-		nerr := tx.Rollback()
-		if nerr != nil {
-			glog.Errorf("Cannot rollback: %+v", nerr)
-		}
-
 		glog.Errorf(
-			"stmt.QueryRow(%d, %d, %d, %d, %d).Scan() %+v\n",
+			"tx.Query(%d, %d, %d items) %+v",
 			ac.SiteId,
-			ac.MicrocosmId,
-			ac.ItemTypeId,
-			ac.ItemId,
 			ac.ProfileId,
+			len(misses),
 			err,
 		)
+		return emptyPermissions(perms, misses)
+	}
+	defer rows.Close()
+
+	found := 0
+	for rows.Next() {
+		var seq int
+		m := PermissionType{Valid: true}
+		err = rows.Scan(
+			&seq,
+			&m.CanCreate,
+			&m.CanRead,
+			&m.CanUpdate,
+			&m.CanDelete,
+			&m.CanCloseOwn,
+			&m.CanOpenOwn,
+			&m.CanReadOthers,
+			&m.IsGuest,
+			&m.IsBanned,
+			&m.IsOwner,
+			&m.IsModerator,
+			&m.IsSiteOwner,
+		)
+		if err != nil || seq < 0 || seq >= len(perms) {
+			glog.Errorf("rows.Scan() %+v", err)
+			return emptyPermissions(perms, misses)
+		}
 
-		return PermissionType{}
+		m.Context = perms[seq].Context
+		perms[seq] = m
+		found++
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return emptyPermissions(perms, misses)
+	}
+	rows.Close()
+
+	if found != len(misses) {
+		glog.Errorf("Expected %d permissions, got %d", len(misses), found)
+		return emptyPermissions(perms, misses)
 	}
 
 	err = tx.Commit()
 	if err != nil {
-
 		glog.Errorf(
-			"tx.Commit() after stmt.QueryRow(%d, %d, %d, %d, %d) %+v\n",
+			"tx.Commit() after get_effective_permissions(%d, %d) %+v",
 			ac.SiteId,
-			ac.MicrocosmId,
-			ac.ItemTypeId,
-			ac.ItemId,
 			ac.ProfileId,
 			err,
 		)
+		return emptyPermissions(perms, misses)
+	}
 
-		return PermissionType{}
+	// Only successful lookups are kept, failures are tried again
+	if ac.permissions != nil {
+		for _, i := range misses {
+			ac.permissions.set(permissionKeyFor(perms[i].Context), perms[i])
+		}
 	}
 
-	return m
+	return perms
+}
+
+func permissionKeyFor(ac AuthContext) permissionKey {
+	return permissionKey{
+		SiteId:      ac.SiteId,
+		MicrocosmId: ac.MicrocosmId,
+		ItemTypeId:  ac.ItemTypeId,
+		ItemId:      ac.ItemId,
+		ProfileId:   ac.ProfileId,
+	}
+}
+
+// emptyPermissions replaces the permissions at the given indexes with empty
+// (invalid, deny everything) permissions
+func emptyPermissions(perms []PermissionType, indexes []int) []PermissionType {
+	for _, i := range indexes {
+		perms[i] = PermissionType{}
+	}
+	return perms
 }
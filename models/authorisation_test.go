@@ -0,0 +1,62 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestGetPermissionUsesRequestCache(t *testing.T) {
+	c := &Context{permissions: newPermissionCache()}
+	c.Site.Id = 1
+	c.Auth.ProfileId = 2
+
+	ac := MakeAuthorisationContext(c, 3, 6, 4)
+	c.permissions.set(
+		permissionKey{
+			SiteId:      1,
+			MicrocosmId: 3,
+			ItemTypeId:  6,
+			ItemId:      4,
+			ProfileId:   2,
+		},
+		PermissionType{CanRead: true, Valid: true},
+	)
+
+	// There is no database in tests, so this can only succeed from the cache
+	m := GetPermission(ac)
+	if !m.Valid || !m.CanRead {
+		t.Errorf("Expected the cached permission, got %+v", m)
+	}
+	if m.Context.ItemId != 4 {
+		t.Errorf("Expected the permission to carry its context, got %+v", m.Context)
+	}
+}
+
+func TestGetPermissionsWithoutQuerying(t *testing.T) {
+	c := &Context{permissions: newPermissionCache()}
+	c.Site.Id = 1
+	c.Auth.ProfileId = 0
+
+	ac := MakeAuthorisationContext(c, 0, 0, 0)
+	c.permissions.set(
+		permissionKey{SiteId: 1, MicrocosmId: 3, ItemTypeId: 6, ItemId: 4},
+		PermissionType{CanRead: true, Valid: true},
+	)
+
+	perms := GetPermissions(
+		ac,
+		[]ItemRef{
+			ItemRef{MicrocosmId: 3, ItemTypeId: 6, ItemId: 4},
+			ItemRef{ItemTypeId: 1, ItemId: 1},
+		},
+	)
+
+	if len(perms) != 2 {
+		t.Fatalf("Expected 2 permissions, got %d", len(perms))
+	}
+	if !perms[0].CanRead || perms[0].Context.ItemId != 4 {
+		t.Errorf("Expected the cached permission first, got %+v", perms[0])
+	}
+	if !perms[1].CanRead || !perms[1].IsGuest {
+		t.Errorf("Expected a guest to be able to read the site, got %+v", perms[1])
+	}
+}
@@ -50,7 +50,7 @@ SELECT 1
 		return false
 	}
 
-	c.CacheSetBool(mcKey, isBanned, mcTtl)
+	c.CacheSetBool(mcKey, isBanned, cacheTtl(h.ItemTypeProfile))
 
 	return isBanned
 }
@@ -0,0 +1,321 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// CommentReactions are the reactions that may be made to a comment
+var CommentReactions = []string{"like", "love", "laugh", "thanks"}
+
+// CommentReactionType is the number of profiles that have made a reaction to
+// a comment
+type CommentReactionType struct {
+	Reaction string `json:"reaction"`
+	Count    int64  `json:"count"`
+}
+
+// CommentReactionRequestType is the body of a request to react to a comment
+type CommentReactionRequestType struct {
+	Reaction string `json:"reaction"`
+}
+
+// normaliseReaction returns the reaction as it appears in CommentReactions
+func normaliseReaction(reaction string) (string, int, error) {
+	reaction = strings.ToLower(strings.TrimSpace(reaction))
+
+	for _, r := range CommentReactions {
+		if r == reaction {
+			return r, http.StatusOK, nil
+		}
+	}
+
+	return "", http.StatusBadRequest, errors.New(
+		fmt.Sprintf(
+			"Reaction ('%s') must be one of: %s",
+			reaction,
+			strings.Join(CommentReactions, ", "),
+		),
+	)
+}
+
+// AddReaction records the reaction of the profile to the comment. Reacting in
+// the same way twice has no further effect. A profile may not react to the
+// comment of someone who is ignoring them.
+func AddReaction(
+	siteId int64,
+	profileId int64,
+	commentId int64,
+	reaction string,
+) (
+	int,
+	error,
+) {
+
+	reaction, status, err := normaliseReaction(reaction)
+	if err != nil {
+		return status, err
+	}
+
+	comment, status, err := GetCommentSummary(siteId, commentId)
+	if err != nil {
+		return status, err
+	}
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	ignored, err := isMentionIgnored(
+		tx,
+		comment.Meta.CreatedById,
+		profileId,
+		comment.ItemTypeId,
+		comment.ItemId,
+	)
+	if err != nil {
+		glog.Errorf("isMentionIgnored(tx, %d, %d, %d, %d) %+v",
+			comment.Meta.CreatedById,
+			profileId,
+			comment.ItemTypeId,
+			comment.ItemId,
+			err,
+		)
+		return http.StatusInternalServerError,
+			errors.New("Error checking ignores")
+	}
+	if ignored {
+		return http.StatusForbidden,
+			errors.New("You may not react to this comment")
+	}
+
+	// Reacting in the same way again leaves the existing reaction in place
+	_, err = tx.Exec(`--AddReaction
+INSERT INTO comment_reactions (
+    comment_id, profile_id, reaction, created
+)
+SELECT $1, $2, $3, NOW()
+ WHERE NOT EXISTS (
+           SELECT 1
+             FROM comment_reactions
+            WHERE comment_id = $1
+              AND profile_id = $2
+              AND reaction = $3
+       )`,
+		commentId,
+		profileId,
+		reaction,
+	)
+	if err != nil {
+		glog.Errorf("tx.Exec(%d, %d, %s) %+v", commentId, profileId, reaction, err)
+		return http.StatusInternalServerError,
+			errors.New("Insert of reaction failed")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		glog.Errorf("tx.Commit() %+v", err)
+		return http.StatusInternalServerError,
+			errors.New("Transaction failed")
+	}
+
+	PurgeCache(h.ItemTypes[h.ItemTypeComment], commentId)
+
+	return http.StatusOK, nil
+}
+
+// RemoveReaction removes the reaction of the profile to the comment, removing
+// a reaction that was never made is not an error
+func RemoveReaction(
+	profileId int64,
+	commentId int64,
+	reaction string,
+) (
+	int,
+	error,
+) {
+
+	reaction, status, err := normaliseReaction(reaction)
+	if err != nil {
+		return status, err
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	_, err = db.Exec(`--RemoveReaction
+DELETE FROM comment_reactions
+ WHERE comment_id = $1
+   AND profile_id = $2
+   AND reaction = $3`,
+		commentId,
+		profileId,
+		reaction,
+	)
+	if err != nil {
+		glog.Errorf("db.Exec(%d, %d, %s) %+v", commentId, profileId, reaction, err)
+		return http.StatusInternalServerError,
+			errors.New("Delete of reaction failed")
+	}
+
+	PurgeCache(h.ItemTypes[h.ItemTypeComment], commentId)
+
+	return http.StatusOK, nil
+}
+
+// getCommentReactions returns how many times each reaction has been made to
+// the comment, most frequent first
+func getCommentReactions(commentId int64) ([]CommentReactionType, int, error) {
+	db, err := h.GetConnection()
+	if err != nil {
+		return []CommentReactionType{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--getCommentReactions
+SELECT reaction
+      ,COUNT(*) AS total
+  FROM comment_reactions
+ WHERE comment_id = $1
+ GROUP BY reaction
+ ORDER BY total DESC, reaction ASC`,
+		commentId,
+	)
+	if err != nil {
+		glog.Errorf("db.Query(%d) %+v", commentId, err)
+		return []CommentReactionType{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	ems := []CommentReactionType{}
+	for rows.Next() {
+		m := CommentReactionType{}
+		err = rows.Scan(&m.Reaction, &m.Count)
+		if err != nil {
+			return []CommentReactionType{}, http.StatusInternalServerError,
+				errors.New(
+					fmt.Sprintf("Row parsing error: %v", err.Error()),
+				)
+		}
+		ems = append(ems, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []CommentReactionType{}, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Error fetching rows: %v", err.Error()),
+			)
+	}
+	rows.Close()
+
+	return ems, http.StatusOK, nil
+}
+
+// getProfileReactions returns the reactions the profile has made to each of
+// the comments
+func getProfileReactions(
+	profileId int64,
+	commentIds []int64,
+) (
+	map[int64][]string,
+	int,
+	error,
+) {
+	reactions := map[int64][]string{}
+	if profileId == 0 || len(commentIds) == 0 {
+		return reactions, http.StatusOK, nil
+	}
+
+	ids := []string{}
+	for _, id := range commentIds {
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return map[int64][]string{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--getProfileReactions
+SELECT comment_id
+      ,reaction
+  FROM comment_reactions
+ WHERE comment_id = ANY($1::bigint[])
+   AND profile_id = $2
+ ORDER BY comment_id ASC
+         ,reaction ASC`,
+		`{`+strings.Join(ids, `,`)+`}`,
+		profileId,
+	)
+	if err != nil {
+		glog.Errorf("db.Query(%v, %d) %+v", commentIds, profileId, err)
+		return map[int64][]string{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			commentId int64
+			reaction  string
+		)
+		err = rows.Scan(&commentId, &reaction)
+		if err != nil {
+			return map[int64][]string{}, http.StatusInternalServerError,
+				errors.New(
+					fmt.Sprintf("Row parsing error: %v", err.Error()),
+				)
+		}
+		reactions[commentId] = append(reactions[commentId], reaction)
+	}
+	err = rows.Err()
+	if err != nil {
+		return map[int64][]string{}, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Error fetching rows: %v", err.Error()),
+			)
+	}
+	rows.Close()
+
+	return reactions, http.StatusOK, nil
+}
+
+// setMyReactions gives each comment the reactions the viewer has made to it,
+// comments that have been redacted show none
+func setMyReactions(ems []CommentSummaryType, reactions map[int64][]string) {
+	for i := range ems {
+		if ems[i].Meta.Flags.Redacted {
+			ems[i].MyReactions = nil
+			continue
+		}
+		ems[i].MyReactions = reactions[ems[i].Id]
+	}
+}
+
+// fillMyReactions fetches and sets the reactions the viewer has made to each
+// of the comments
+func fillMyReactions(ems []CommentSummaryType, profileId int64) (int, error) {
+	ids := []int64{}
+	for _, m := range ems {
+		ids = append(ids, m.Id)
+	}
+
+	reactions, status, err := getProfileReactions(profileId, ids)
+	if err != nil {
+		return status, err
+	}
+	setMyReactions(ems, reactions)
+
+	return http.StatusOK, nil
+}
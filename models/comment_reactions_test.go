@@ -0,0 +1,51 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNormaliseReaction(t *testing.T) {
+	for input, expected := range map[string]string{
+		"like":     "like",
+		" Thanks ": "thanks",
+		"LAUGH":    "laugh",
+	} {
+		reaction, _, err := normaliseReaction(input)
+		if err != nil {
+			t.Errorf("Expected %q to be allowed, got %+v", input, err)
+			continue
+		}
+		if reaction != expected {
+			t.Errorf("Expected %q to be %q, got %q", input, expected, reaction)
+		}
+	}
+
+	for _, input := range []string{"", "dislike", "like,love"} {
+		_, status, err := normaliseReaction(input)
+		if err == nil || status != http.StatusBadRequest {
+			t.Errorf("Expected %q to be rejected, got %d %+v", input, status, err)
+		}
+	}
+}
+
+func TestSetMyReactions(t *testing.T) {
+	ems := []CommentSummaryType{{Id: 1}, {Id: 2}, {Id: 3}}
+	ems[2].Meta.Flags.Redacted = true
+
+	setMyReactions(ems, map[int64][]string{
+		1: {"like", "thanks"},
+		3: {"love"},
+	})
+
+	if len(ems[0].MyReactions) != 2 || ems[0].MyReactions[1] != "thanks" {
+		t.Errorf("Expected like and thanks, got %v", ems[0].MyReactions)
+	}
+	if len(ems[1].MyReactions) != 0 {
+		t.Errorf("Expected no reactions, got %v", ems[1].MyReactions)
+	}
+	if len(ems[2].MyReactions) != 0 {
+		t.Errorf("Expected a redacted comment to show no reactions, got %v",
+			ems[2].MyReactions)
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// CommentRevisionType is a version of a comment, created when the comment was
+// posted or edited
+type CommentRevisionType struct {
+	Id         int64              `json:"id"`
+	Markdown   string             `json:"markdown"`
+	HTML       string             `json:"html"`
+	Current    bool               `json:"current"`
+	EditedById int64              `json:"-"`
+	EditedBy   ProfileSummaryType `json:"editedBy"`
+	EditedTime time.Time          `json:"-"`
+	Edited     string             `json:"edited"`
+}
+
+// GetCommentRevisions returns the revisions of a comment, oldest first. The
+// HTML of each revision is sanitised before it is returned as older revisions
+// may have been processed by an earlier and less strict policy, or not at
+// all, in which case the HTML is empty.
+func GetCommentRevisions(
+	siteId int64,
+	commentId int64,
+) (
+	[]CommentRevisionType,
+	int,
+	error,
+) {
+
+	if commentId == 0 {
+		return []CommentRevisionType{}, http.StatusNotFound,
+			errors.New("Comment not found")
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return []CommentRevisionType{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetCommentRevisions
+SELECT r.revision_id
+      ,r.raw
+      ,COALESCE(r.html, '')
+      ,r.is_current IS NOT FALSE
+      ,r.profile_id
+      ,r.created
+  FROM comments c
+       JOIN revisions r ON r.comment_id = c.comment_id
+ WHERE c.comment_id = $1
+   AND is_deleted(4, c.comment_id) IS FALSE
+ ORDER BY r.created ASC
+         ,r.revision_id ASC`,
+		commentId,
+	)
+	if err != nil {
+		glog.Errorf("db.Query(%d) %+v", commentId, err)
+		return []CommentRevisionType{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	// Revisions are sanitised as they would be rendered today, keeping video
+	// embeds only where the site allows them
+	videoEmbeds := AllowVideoEmbeds(siteId)
+
+	ems := []CommentRevisionType{}
+	for rows.Next() {
+		m := CommentRevisionType{}
+		err = rows.Scan(
+			&m.Id,
+			&m.Markdown,
+			&m.HTML,
+			&m.Current,
+			&m.EditedById,
+			&m.EditedTime,
+		)
+		if err != nil {
+			glog.Errorf("rows.Scan() %+v", err)
+			return []CommentRevisionType{}, http.StatusInternalServerError,
+				errors.New("Row parsing error")
+		}
+
+		if videoEmbeds {
+			m.HTML = string(SanitiseHTMLWithEmbeds(siteId, []byte(m.HTML)))
+		} else {
+			m.HTML = string(SanitiseHTML(siteId, []byte(m.HTML)))
+		}
+		m.Edited = m.EditedTime.Format(time.RFC3339Nano)
+
+		ems = append(ems, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return []CommentRevisionType{}, http.StatusInternalServerError,
+			errors.New("Error fetching rows")
+	}
+	rows.Close()
+
+	if len(ems) == 0 {
+		return []CommentRevisionType{}, http.StatusNotFound,
+			errors.New("Comment not found")
+	}
+
+	for i, m := range ems {
+		profile, status, err := GetProfileSummary(siteId, m.EditedById)
+		if err != nil {
+			return []CommentRevisionType{}, status, err
+		}
+		ems[i].EditedBy = profile
+	}
+
+	return ems, http.StatusOK, nil
+}
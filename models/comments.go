@@ -42,8 +42,10 @@ type CommentType struct {
 	HTMLNullable      sql.NullString `json:"-"`
 	HTML              string         `json:"html"`
 
-	Files []h.AttachmentType   `json:"files,omitempty"`
-	Meta  CommentFlagsMetaType `json:"meta"`
+	Files       []h.AttachmentType    `json:"files,omitempty"`
+	Reactions   []CommentReactionType `json:"reactions,omitempty"`
+	MyReactions []string              `json:"myReactions,omitempty"`
+	Meta        CommentFlagsMetaType  `json:"meta"`
 }
 
 type CommentSummaryType struct {
@@ -61,8 +63,10 @@ type CommentSummaryType struct {
 	HTMLNullable      sql.NullString `json:"-"`
 	HTML              string         `json:"html"`
 
-	Files []h.AttachmentType `json:"files,omitempty"`
-	Meta  CommentMetaType    `json:"meta"`
+	Files       []h.AttachmentType    `json:"files,omitempty"`
+	Reactions   []CommentReactionType `json:"reactions,omitempty"`
+	MyReactions []string              `json:"myReactions,omitempty"`
+	Meta        CommentMetaType       `json:"meta"`
 }
 
 type CommentMetaType struct {
@@ -77,6 +81,7 @@ type CommentFlagsType struct {
 	Moderated bool `json:"moderated"`
 	Visible   bool `json:"visible"`
 	Unread    bool `json:"unread"`
+	Redacted  bool `json:"redacted,omitempty"`
 }
 
 type ThreadedMetaType struct {
@@ -112,16 +117,30 @@ func (m *CommentSummaryType) Validate(siteId int64, exists bool) (int, error) {
 		m.ItemTypeId = h.ItemTypesCommentable[m.ItemType]
 	}
 	if !exists && m.InReplyTo > 0 {
-		parent, _, err := GetCommentSummary(siteId, m.InReplyTo)
+		// A reply must quote a comment that exists on the same item
+		parent, status, err := GetCommentSummary(siteId, m.InReplyTo)
 		if err != nil {
-			m.InReplyTo = 0
+			if status == http.StatusNotFound {
+				return http.StatusBadRequest, errors.New(
+					fmt.Sprintf(
+						"The comment being replied to (%d) does not exist",
+						m.InReplyTo,
+					),
+				)
+			}
+			return status, err
 		}
 
-		if m.ItemTypeId == parent.ItemTypeId && m.ItemId == parent.ItemId {
-			m.InReplyToNullable = sql.NullInt64{Int64: m.InReplyTo, Valid: true}
-		} else {
-			m.InReplyTo = 0
+		if m.ItemTypeId != parent.ItemTypeId || m.ItemId != parent.ItemId {
+			return http.StatusBadRequest, errors.New(
+				fmt.Sprintf(
+					"The comment being replied to (%d) is not on this item",
+					m.InReplyTo,
+				),
+			)
 		}
+
+		m.InReplyToNullable = sql.NullInt64{Int64: m.InReplyTo, Valid: true}
 	}
 
 	if m.ItemId <= 0 {
@@ -144,6 +163,17 @@ func (m *CommentSummaryType) Validate(siteId int64, exists bool) (int, error) {
 	// Prevent shouting on text fields
 	m.Markdown = ShoutToWhisper(m.Markdown)
 
+	// The item must be visible to the author, which a private event may not be
+	status, err := CanSeeComments(
+		siteId,
+		m.ItemTypeId,
+		m.ItemId,
+		m.Meta.CreatedById,
+	)
+	if err != nil {
+		return status, err
+	}
+
 	return http.StatusOK, nil
 }
 
@@ -320,6 +350,7 @@ INSERT INTO comments (
 
 	PurgeCache(h.ItemTypes[h.ItemTypeComment], m.Id)
 	PurgeCache(m.ItemTypeId, m.ItemId)
+	purgeCommentCount(m.ItemTypeId, m.ItemId)
 
 	if !isImport {
 		go IncrementProfileCommentCount(m.Meta.CreatedById)
@@ -582,6 +613,7 @@ UPDATE comments
 
 	PurgeCache(h.ItemTypes[h.ItemTypeComment], m.Id)
 	PurgeCache(m.ItemTypeId, m.ItemId)
+	purgeCommentCount(m.ItemTypeId, m.ItemId)
 
 	summary, status, err := GetSummary(
 		siteId,
@@ -689,6 +721,7 @@ SELECT c1.item_type_id
 	defer tx2.Rollback()
 
 	PurgeCache(m.ItemTypeId, m.ItemId)
+	purgeCommentCount(m.ItemTypeId, m.ItemId)
 
 	summary, status, err := GetSummary(
 		siteId,
@@ -723,6 +756,14 @@ SELECT c1.item_type_id
 	return http.StatusOK, nil
 }
 
+// purgeCommentCount purges the cached count of comments on the item, which
+// PurgeCache leaves alone. Only events cache the count apart from the item.
+func purgeCommentCount(itemTypeId int64, itemId int64) {
+	if itemTypeId == h.ItemTypes[h.ItemTypeEvent] {
+		PurgeCacheByScope(c.CacheCounts, itemTypeId, itemId)
+	}
+}
+
 func GetPageNumber(
 	commentId int64,
 	limit int64,
@@ -746,21 +787,19 @@ SELECT oc.item_type_id
       ,(CEIL(COUNT(*)::real / $2) - 1) * $2 AS offset
   FROM comments oc
   LEFT JOIN ignores i ON i.profile_id = $3
+                     AND (i.expires IS NULL OR i.expires > NOW())
                      AND i.item_type_id = 3
                      AND i.item_id = oc.profile_id
       ,(
         SELECT item_type_id
               ,item_id
               ,created
-              ,is_deleted
               ,is_moderated
           FROM comments
          WHERE comment_id = $1
-           AND is_deleted = False
            AND is_moderated = False
        ) AS ic
  WHERE i.profile_id IS NULL
-   AND oc.is_deleted = ic.is_deleted
    AND oc.is_moderated = ic.is_moderated
    AND oc.item_type_id = ic.item_type_id
    AND oc.item_id = ic.item_id
@@ -859,11 +898,12 @@ func (m *CommentSummaryType) GetPageLink(
 func HandleCommentRequest(
 	siteId int64,
 	commentId int64,
+	canModerate bool,
 	seq int,
 	out chan<- CommentSummaryRequest,
 ) {
 
-	item, status, err := GetCommentSummary(siteId, commentId)
+	item, status, err := GetVisibleCommentSummary(siteId, commentId, canModerate)
 
 	response := CommentSummaryRequest{
 		Item:   item,
@@ -874,6 +914,67 @@ func HandleCommentRequest(
 	out <- response
 }
 
+// CommentDeletedPlaceholder replaces the content of a deleted comment for
+// those who may not moderate it
+const CommentDeletedPlaceholder string = "[deleted]"
+
+// Redact removes the content and author of a deleted comment, leaving only
+// its place in the conversation
+func (m *CommentSummaryType) Redact() {
+	m.FirstLine = CommentDeletedPlaceholder
+	m.Markdown = CommentDeletedPlaceholder
+	m.HTML = CommentDeletedPlaceholder
+	m.HTMLNullable = sql.NullString{String: CommentDeletedPlaceholder, Valid: true}
+	m.Attachments = 0
+	m.Files = nil
+	m.Reactions = nil
+	m.MyReactions = nil
+	m.Revisions = 0
+
+	m.Meta.CreatedById = 0
+	m.Meta.CreatedBy = nil
+	m.Meta.EditedByNullable = sql.NullInt64{}
+	m.Meta.EditedBy = nil
+	m.Meta.EditReasonNullable = sql.NullString{}
+	m.Meta.EditReason = ""
+
+	m.Meta.Flags.Redacted = true
+}
+
+// GetVisibleCommentSummary returns the comment as the viewer may see it. A
+// deleted comment is returned to those who may moderate it and is redacted for
+// everyone else.
+func GetVisibleCommentSummary(
+	siteId int64,
+	commentId int64,
+	canModerate bool,
+) (
+	CommentSummaryType,
+	int,
+	error,
+) {
+
+	m, status, err := loadCommentSummary(siteId, commentId)
+	if err != nil {
+		return CommentSummaryType{}, status, err
+	}
+
+	m.redactUnlessModerator(canModerate)
+
+	return m, http.StatusOK, nil
+}
+
+// redactUnlessModerator redacts the comment if it has been deleted and the
+// viewer may not moderate it
+func (m *CommentSummaryType) redactUnlessModerator(canModerate bool) {
+	if m.Meta.Flags.Deleted && !canModerate {
+		m.Redact()
+	}
+}
+
+// GetCommentSummary returns the comment, comments that have been deleted are
+// not found. Comments that are being shown to someone should be fetched with
+// GetVisibleCommentSummary.
 func GetCommentSummary(
 	siteId int64,
 	commentId int64,
@@ -883,6 +984,31 @@ func GetCommentSummary(
 	error,
 ) {
 
+	m, status, err := loadCommentSummary(siteId, commentId)
+	if err != nil {
+		return CommentSummaryType{}, status, err
+	}
+
+	if m.Meta.Flags.Deleted {
+		return CommentSummaryType{}, http.StatusNotFound, errors.New(
+			fmt.Sprintf("Comment with ID %d not found", commentId),
+		)
+	}
+
+	return m, http.StatusOK, nil
+}
+
+// loadCommentSummary returns the comment even if it has been deleted, so
+// long as the item it is on has not been
+func loadCommentSummary(
+	siteId int64,
+	commentId int64,
+) (
+	CommentSummaryType,
+	int,
+	error,
+) {
+
 	if commentId == 0 {
 		return CommentSummaryType{}, http.StatusNotFound,
 			errors.New("Comment not found")
@@ -905,7 +1031,7 @@ func GetCommentSummary(
 	//
 	// This is what commentTtl stores... the default TTL to be over-ridden
 	// with a shorter TTL is we cannot parse the Markdown.
-	commentTtl := mcTtl
+	commentTtl := cacheTtl(h.ItemTypeComment)
 
 	db, err := h.GetConnection()
 	if err != nil {
@@ -913,9 +1039,6 @@ func GetCommentSummary(
 		return CommentSummaryType{}, http.StatusInternalServerError, err
 	}
 
-	// TODO(buro9): admins and mods could see this with isDeleted=true in the
-	// querystring
-
 	var revisionId int64
 	m := CommentSummaryType{}
 	err = db.QueryRow(`
@@ -943,7 +1066,7 @@ SELECT c.comment_id
   FROM comments c
       ,revisions r
  WHERE c.comment_id = $1
-   AND is_deleted(4, c.comment_id) IS FALSE
+   AND is_deleted(c.item_type_id, c.item_id) IS FALSE
    AND c.comment_id = r.comment_id
    AND r.is_current IS NOT FALSE
  ORDER BY r.created DESC
@@ -1082,6 +1205,12 @@ UPDATE revisions
 		)
 	}
 
+	reactions, status, err := getCommentReactions(m.Id)
+	if err != nil {
+		return CommentSummaryType{}, status, err
+	}
+	m.Reactions = reactions
+
 	itemTitle, _, err := GetTitle(siteId, h.ItemTypes[m.ItemType], m.ItemId, 0)
 	if err != nil {
 		glog.Warningf(
@@ -1115,6 +1244,7 @@ func GetComments(
 	reqUrl *url.URL,
 	profileId int64,
 	itemCreated time.Time,
+	canModerate bool,
 ) (
 	h.ArrayType,
 	int,
@@ -1135,6 +1265,7 @@ func GetComments(
 		offset,
 		profileId,
 		itemCreated,
+		canModerate,
 	)
 	if err != nil {
 		return h.ArrayType{}, status, err
@@ -1187,6 +1318,9 @@ func GetLatestComments(
 	return offset, commentId, http.StatusOK, nil
 }
 
+// GetItemComments returns a page of comments. Deleted comments keep their
+// place so that replies to them make sense, and are redacted for those who
+// may not moderate them.
 func GetItemComments(
 	siteId int64,
 	itemType string,
@@ -1195,6 +1329,7 @@ func GetItemComments(
 	offset int64,
 	profileId int64,
 	itemCreated time.Time,
+	canModerate bool,
 ) (
 	[]CommentSummaryType,
 	int64,
@@ -1253,6 +1388,7 @@ SELECT total
                  ,f.last_modified
              FROM flags f
              LEFT JOIN ignores i ON i.profile_id = $3
+                                AND (i.expires IS NULL OR i.expires > NOW())
                                 AND i.item_type_id = 3
                                 AND i.item_id = f.created_by
             WHERE f.item_type_id = 4
@@ -1261,7 +1397,6 @@ SELECT total
               AND f.microcosm_is_moderated IS NOT TRUE
               AND f.parent_is_deleted IS NOT TRUE
               AND f.parent_is_moderated IS NOT TRUE
-              AND f.item_is_deleted IS NOT TRUE
               AND f.item_is_moderated IS NOT TRUE
             ORDER BY f.last_modified` + sqlLimit + `
        ) AS r`
@@ -1329,7 +1464,7 @@ SELECT total
 	defer close(req)
 
 	for seq, id := range ids {
-		go HandleCommentRequest(siteId, id, seq, req)
+		go HandleCommentRequest(siteId, id, canModerate, seq, req)
 		wg1.Add(1)
 	}
 
@@ -1359,6 +1494,11 @@ SELECT total
 		ems = append(ems, m)
 	}
 
+	status, err := fillMyReactions(ems, profileId)
+	if err != nil {
+		return []CommentSummaryType{}, 0, 0, status, err
+	}
+
 	pages := h.GetPageCount(total, limit)
 	maxOffset := h.GetMaxOffset(total, limit)
 
@@ -1375,11 +1515,15 @@ SELECT total
 	return ems, total, pages, http.StatusOK, nil
 }
 
+// GetComment returns the comment with its immediate parent and replies. A
+// deleted comment is returned to those who may moderate it so that it can be
+// reviewed, and is redacted for everyone else.
 func GetComment(
 	siteId int64,
 	commentId int64,
 	profileId int64,
 	limit int64,
+	canModerate bool,
 ) (
 	CommentType,
 	int,
@@ -1392,7 +1536,18 @@ func GetComment(
 	}
 
 	var m CommentType
-	commentsummary, status, err := GetCommentSummary(siteId, commentId)
+	commentsummary, status, err :=
+		GetVisibleCommentSummary(siteId, commentId, canModerate)
+	if err != nil {
+		return CommentType{}, status, err
+	}
+
+	status, err = CanSeeComments(
+		siteId,
+		commentsummary.ItemTypeId,
+		commentsummary.ItemId,
+		profileId,
+	)
 	if err != nil {
 		return CommentType{}, status, err
 	}
@@ -1412,6 +1567,7 @@ func GetComment(
 	m.HTMLNullable = commentsummary.HTMLNullable
 	m.HTML = commentsummary.HTML
 	m.Files = commentsummary.Files
+	m.Reactions = commentsummary.Reactions
 	m.Meta.Created = commentsummary.Meta.Created
 	m.Meta.CreatedById = commentsummary.Meta.CreatedById
 	m.Meta.CreatedBy = commentsummary.Meta.CreatedBy
@@ -1432,9 +1588,18 @@ func GetComment(
 	}
 	m.Meta.Links = append(m.Meta.Links, link)
 
+	if !m.Meta.Flags.Redacted {
+		reactions, status, err := getProfileReactions(profileId, []int64{m.Id})
+		if err != nil {
+			return CommentType{}, status, err
+		}
+		m.MyReactions = reactions[m.Id]
+	}
+
 	// We only fetch the immediate parent
 	if m.InReplyTo != 0 {
-		commentsummary, status, _ = GetCommentSummary(siteId, m.InReplyTo)
+		commentsummary, status, _ =
+			GetVisibleCommentSummary(siteId, m.InReplyTo, canModerate)
 		if status == http.StatusOK {
 			m.Meta.InReplyTo = commentsummary
 		}
@@ -1450,12 +1615,12 @@ func GetComment(
 SELECT c.comment_id
   FROM comments c
   LEFT JOIN ignores i ON i.profile_id = $2
+                     AND (i.expires IS NULL OR i.expires > NOW())
                      AND i.item_type_id = 3
                      AND i.item_id = c.profile_id
  WHERE c.in_reply_to = $1
    AND i.profile_id IS NULL
    AND c.is_moderated IS NOT TRUE
-   AND c.is_deleted IS NOT TRUE
  ORDER BY c.created ASC`,
 		commentId,
 		profileId,
@@ -1494,7 +1659,7 @@ SELECT c.comment_id
 	defer close(req)
 
 	for seq, id := range ids {
-		go HandleCommentRequest(siteId, id, seq, req)
+		go HandleCommentRequest(siteId, id, canModerate, seq, req)
 		wg1.Add(1)
 	}
 
@@ -1517,8 +1682,18 @@ SELECT c.comment_id
 	sort.Sort(CommentRequestBySeq(resps))
 
 	// Extract the values
+	replies := []CommentSummaryType{}
 	for _, resp := range resps {
-		m.Meta.Replies = append(m.Meta.Replies, resp.Item)
+		replies = append(replies, resp.Item)
+	}
+
+	status, err = fillMyReactions(replies, profileId)
+	if err != nil {
+		return CommentType{}, status, err
+	}
+
+	for _, reply := range replies {
+		m.Meta.Replies = append(m.Meta.Replies, reply)
 	}
 
 	return m, http.StatusOK, nil
@@ -1553,6 +1728,7 @@ SELECT comment_id
                     ,f.last_modified AS created
                 FROM flags f
                 LEFT JOIN ignores i ON i.profile_id = $4
+                                   AND (i.expires IS NULL OR i.expires > NOW())
                                    AND i.item_type_id = 3
                                    AND i.item_id = f.created_by
                WHERE i.profile_id IS NULL
@@ -1576,6 +1752,7 @@ SELECT comment_id
                     ,f.last_modified AS created
                 FROM flags f
                 LEFT JOIN ignores i ON i.profile_id = $4
+                                   AND (i.expires IS NULL OR i.expires > NOW())
                                    AND i.item_type_id = 3
                                    AND i.item_id = f.created_by
                WHERE i.profile_id IS NULL
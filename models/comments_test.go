@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestRedactComment(t *testing.T) {
+	m := CommentSummaryType{
+		FirstLine:    "Something regrettable",
+		Markdown:     "Something regrettable",
+		HTML:         "<p>Something regrettable</p>",
+		HTMLNullable: sql.NullString{String: "<p>Something regrettable</p>", Valid: true},
+		Attachments:  1,
+	}
+	m.Meta.CreatedById = 7
+	m.Meta.Flags.Deleted = true
+
+	m.Redact()
+
+	for _, s := range []string{m.FirstLine, m.Markdown, m.HTML, m.HTMLNullable.String} {
+		if strings.Contains(s, "regrettable") {
+			t.Errorf("Expected the content to be redacted, got %q", s)
+		}
+	}
+	if m.Meta.CreatedById != 0 {
+		t.Errorf("Expected the author to be redacted, got %d", m.Meta.CreatedById)
+	}
+	if m.Attachments != 0 {
+		t.Errorf("Expected the attachments to be redacted, got %d", m.Attachments)
+	}
+	if !m.Meta.Flags.Redacted || !m.Meta.Flags.Deleted {
+		t.Errorf("Expected the comment to be flagged as deleted and redacted")
+	}
+}
+
+func TestDeletedCommentSummaryByViewer(t *testing.T) {
+	deleted := func() CommentSummaryType {
+		m := CommentSummaryType{Markdown: "Something regrettable"}
+		m.Id = 3
+		m.Meta.CreatedById = 7
+		m.Meta.Flags.Deleted = true
+		return m
+	}
+
+	m := deleted()
+	m.redactUnlessModerator(true)
+	if m.Meta.Flags.Redacted || m.Markdown != "Something regrettable" {
+		t.Errorf("Expected a moderator to see the deleted comment, got %+v", m)
+	}
+
+	m = deleted()
+	m.redactUnlessModerator(false)
+	if !m.Meta.Flags.Redacted || m.Markdown != CommentDeletedPlaceholder {
+		t.Errorf("Expected the deleted comment to be redacted, got %+v", m)
+	}
+
+	m = CommentSummaryType{Markdown: "Something fine"}
+	m.redactUnlessModerator(false)
+	if m.Meta.Flags.Redacted || m.Markdown != "Something fine" {
+		t.Errorf("Expected a comment that is not deleted to be shown, got %+v", m)
+	}
+}
@@ -1,9 +1,11 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -16,7 +18,6 @@ import (
 	"github.com/golang/glog"
 	"github.com/gorilla/mux"
 
-	"github.com/microcosm-cc/microcosm/cache"
 	conf "github.com/microcosm-cc/microcosm/config"
 	e "github.com/microcosm-cc/microcosm/errors"
 	h "github.com/microcosm-cc/microcosm/helpers"
@@ -32,6 +33,12 @@ type Context struct {
 	RouteVars      map[string]string
 	StartTime      time.Time
 	IP             net.IP
+
+	// Permissions already fetched during this request
+	permissions *permissionCache
+
+	// The Idempotency-Key reserved by this request, if any
+	idempotencyKey string
 }
 
 type AuthType struct {
@@ -48,6 +55,10 @@ type StandardResponse struct {
 	Status  int         `json:"status"`
 	Data    interface{} `json:"data"`
 	Errors  []string    `json:"error"`
+
+	// ErrorCode is set when the error has a code from the errors package so
+	// that clients need not match on the error messages
+	ErrorCode e.ErrCode `json:"errorCode,omitempty"`
 }
 
 func (c *Context) GetItemTypeAndItemId() (string, int64, int64, int, error) {
@@ -128,6 +139,7 @@ func MakeContext(
 	c.RouteVars = mux.Vars(request)
 	c.StartTime = time.Now()
 	c.IP = GetRequestIP(request)
+	c.permissions = newPermissionCache()
 
 	// Which site is this request for?
 	err := c.getSiteContext()
@@ -198,12 +210,24 @@ func (c *Context) authenticate() (int, error) {
 
 	if accessToken != "" {
 		// Verify access token by fetching it from storage
-		storedToken, _, err := GetAccessToken(accessToken)
+		storedToken, status, err := GetAccessToken(accessToken)
 		if err != nil {
 			c.Auth.UserId = -1
+
+			// The token could not be checked, which does not make it invalid
+			if status >= http.StatusInternalServerError {
+				glog.Errorf(`GetAccessToken(%s) %+v`, accessToken, err)
+				return status, errors.New("Could not verify the access token")
+			}
+
 			glog.Warningf(`Invalid access token: %s  %+v`, accessToken, err)
-			return http.StatusUnauthorized,
-				errors.New("Invalid (bad or expired) access token")
+			return http.StatusUnauthorized, e.New(
+				c.Site.Id,
+				0,
+				"context.authenticate",
+				e.InvalidToken,
+				"Invalid (bad or expired) access token",
+			)
 		}
 
 		c.Auth.AccessToken = storedToken
@@ -242,21 +266,18 @@ func (c *Context) authenticate() (int, error) {
 
 			c.Auth.IsBanned = true
 			c.Auth.UserId = -1
-			return http.StatusForbidden, fmt.Errorf("Banned")
+			return http.StatusForbidden, e.New(
+				c.Site.Id,
+				profile.Id,
+				"context.authenticate",
+				e.UserBanned,
+				"Banned",
+			)
 		}
 
 		// Update entry for user's last activity
 		if c.Auth.ProfileId > 0 {
-			lastActiveKey := fmt.Sprintf("la_%d", c.Auth.ProfileId)
-			_, ok := cache.CacheGetInt64(lastActiveKey)
-			if !ok {
-				// Background as the first call to this is likely a whoami which
-				// is a blocking call
-				go UpdateLastActive(c.Auth.ProfileId, c.StartTime)
-
-				// Only update every 60 seconds at most
-				cache.CacheSetInt64(lastActiveKey, 1, 60)
-			}
+			RecordLastActive(c.Auth.ProfileId, c.StartTime)
 		}
 
 		// Determine whether user is site owner
@@ -283,6 +304,7 @@ func MakeEmptyContext(
 	c.RouteVars = mux.Vars(request)
 	c.StartTime = time.Now()
 	c.IP = GetRequestIP(request)
+	c.permissions = newPermissionCache()
 
 	return c, http.StatusOK, nil
 }
@@ -383,6 +405,9 @@ func (c *Context) Respond(
 		Data:    data,
 		Errors:  errors,
 	}
+	if err, ok := data.(error); ok {
+		obj.ErrorCode, _ = e.Code(err)
+	}
 
 	// Prevent content type detection, a.k.a. sniffing
 	c.ResponseWriter.Header().Set("Content-Type", "application/json")
@@ -399,6 +424,12 @@ func (c *Context) Respond(
 	contentLength := len(string(output))
 	c.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(contentLength))
 
+	// A create that failed may be retried with the same Idempotency-Key
+	if statusCode >= http.StatusBadRequest && c.idempotencyKey != "" {
+		releaseIdempotencyKey(c.idempotencyKey)
+		c.idempotencyKey = ""
+	}
+
 	// Debugging info
 	dur := time.Now().Sub(c.StartTime)
 	go SendUsage(c, statusCode, contentLength, dur, errors)
@@ -408,6 +439,12 @@ func (c *Context) Respond(
 
 // This ultimately does the job of writing the response
 func (c *Context) WriteResponse(output []byte, statusCode int) error {
+	return c.WriteResponseReader(bytes.NewReader(output), statusCode)
+}
+
+// WriteResponseReader writes the response body from a reader, so that large
+// responses (such as files) need not be held in memory
+func (c *Context) WriteResponseReader(output io.Reader, statusCode int) error {
 
 	// Set status and write (finalise) all headers
 	if strings.Index(c.Request.URL.String(), "always200") > -1 ||
@@ -424,7 +461,7 @@ func (c *Context) WriteResponse(output []byte, statusCode int) error {
 		return nil
 	}
 
-	_, err := c.ResponseWriter.Write(output)
+	_, err := io.Copy(c.ResponseWriter, output)
 
 	// We only log at error severity when an error is not the result of the
 	// client disconnecting. "broken pipe" is a syscall.EPIPE error that
@@ -493,6 +530,20 @@ func (c *Context) RespondWithErrorDetail(err error, statusCode int) error {
 	return c.Respond(err, statusCode, []string{err.Error()}, c)
 }
 
+// RespondWithErrorCode responds with an error message and an error code from
+// the errors package
+func (c *Context) RespondWithErrorCode(
+	errCode e.ErrCode,
+	message string,
+	statusCode int,
+) error {
+
+	return c.RespondWithErrorDetail(
+		e.New(c.Site.Id, c.Auth.ProfileId, "", errCode, message),
+		statusCode,
+	)
+}
+
 // Responds with the specified data
 func (c *Context) RespondWithData(data interface{}) error {
 	return c.Respond(data, http.StatusOK, nil, c)
@@ -512,10 +563,91 @@ func (c *Context) RespondWithMoved(location string) error {
 // Responds with 303 See Other (created redirect)
 func (c *Context) RespondWithSeeOther(location string) error {
 	c.ResponseWriter.Header().Set("Location", location)
-
 	return c.RespondWithStatus(http.StatusFound)
 }
 
+// getIdempotencyKey returns the cache key of the Idempotency-Key header sent
+// with the request, if one was sent
+func (c *Context) getIdempotencyKey() (string, bool) {
+	key := strings.TrimSpace(c.Request.Header.Get(IdempotencyKeyHeader))
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return "", false
+	}
+
+	return getIdempotencyKey(
+		c.Site.Id,
+		c.Auth.ProfileId,
+		c.GetHttpMethod(),
+		c.Request.URL.Path,
+		key,
+	), true
+}
+
+// RespondWithIdempotentReplay responds with the location of the resource that
+// was created by an earlier request with the same Idempotency-Key, create
+// handlers should call this before creating anything and stop if it returns
+// true as a response has been sent. Otherwise the key is reserved until the
+// handler calls RecordIdempotentCreate, and a concurrent request with the same
+// key is refused.
+func (c *Context) RespondWithIdempotentReplay() bool {
+	key := strings.TrimSpace(c.Request.Header.Get(IdempotencyKeyHeader))
+	if len(key) > maxIdempotencyKeyLength {
+		c.RespondWithErrorMessage(
+			fmt.Sprintf(
+				"%s must be no longer than %d characters",
+				IdempotencyKeyHeader,
+				maxIdempotencyKeyLength,
+			),
+			http.StatusBadRequest,
+		)
+		return true
+	}
+
+	mcKey, ok := c.getIdempotencyKey()
+	if !ok {
+		return false
+	}
+
+	location, ok := getIdempotentLocation(mcKey)
+	if ok {
+		c.RespondWithSeeOther(location)
+		return true
+	}
+
+	if !reserveIdempotencyKey(mcKey) {
+		// The other request may have finished since we looked
+		location, ok = getIdempotentLocation(mcKey)
+		if ok {
+			c.RespondWithSeeOther(location)
+			return true
+		}
+
+		c.RespondWithErrorMessage(
+			fmt.Sprintf(
+				"A request with this %s is already in progress",
+				IdempotencyKeyHeader,
+			),
+			http.StatusConflict,
+		)
+		return true
+	}
+	c.idempotencyKey = mcKey
+
+	return false
+}
+
+// RecordIdempotentCreate remembers the location of what the request created,
+// so that a retry with the same Idempotency-Key is sent there rather than
+// creating it again
+func (c *Context) RecordIdempotentCreate(location string) {
+	if c.idempotencyKey == "" {
+		return
+	}
+
+	setIdempotentLocation(c.idempotencyKey, location)
+	c.idempotencyKey = ""
+}
+
 // Responds with 307 Temporarily Moved (temp redirect)
 func (c *Context) RespondWithLocation(location string) error {
 	c.ResponseWriter.Header().Set("Location", location)
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+const (
+	// MaxTagLength is the longest a tag may be, in characters
+	MaxTagLength int = 30
+
+	// MaxTagsPerConversation is the most tags a conversation may have
+	MaxTagsPerConversation int = 10
+)
+
+// NormaliseTag sanitises a tag and converts it to the form it is stored in,
+// which is lower case with runs of whitespace replaced by a single space
+func NormaliseTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(SanitiseText(tag)), " "))
+
+	if tag == "" {
+		return "", errors.New("A tag cannot be empty")
+	}
+
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", errors.New(
+			fmt.Sprintf("A tag cannot be longer than %d characters", MaxTagLength),
+		)
+	}
+
+	return tag, nil
+}
+
+// AddTag tags the conversation. Tags are unique per conversation, so adding a
+// tag that the conversation already has does nothing.
+func (m *ConversationType) AddTag(siteId int64, tag string) (int, error) {
+	tag, err := NormaliseTag(tag)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	var (
+		tags   int
+		tagged bool
+	)
+	err = tx.QueryRow(`--AddTag Count
+SELECT COUNT(*)
+      ,COALESCE(BOOL_OR(tag = $3), false)
+  FROM conversation_tags
+ WHERE site_id = $1
+   AND conversation_id = $2`,
+		siteId,
+		m.Id,
+		tag,
+	).Scan(&tags, &tagged)
+	if err != nil {
+		glog.Errorf("tx.QueryRow(%d, %d, `%s`) %+v", siteId, m.Id, tag, err)
+		return http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+
+	if tagged {
+		return http.StatusOK, nil
+	}
+
+	if tags >= MaxTagsPerConversation {
+		return http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"A conversation cannot have more than %d tags",
+				MaxTagsPerConversation,
+			),
+		)
+	}
+
+	_, err = tx.Exec(`--AddTag
+INSERT INTO conversation_tags (
+    site_id, conversation_id, tag, created
+) VALUES (
+    $1, $2, $3, NOW()
+)`,
+		siteId,
+		m.Id,
+		tag,
+	)
+	if err != nil {
+		glog.Errorf("tx.Exec(%d, %d, `%s`) %+v", siteId, m.Id, tag, err)
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Error inserting data: %v", err.Error()),
+		)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Transaction failed: %v", err.Error()),
+		)
+	}
+
+	PurgeCache(h.ItemTypes[h.ItemTypeConversation], m.Id)
+
+	return http.StatusOK, nil
+}
+
+// RemoveTag removes the tag from the conversation, if it has it
+func (m *ConversationType) RemoveTag(siteId int64, tag string) (int, error) {
+	tag, err := NormaliseTag(tag)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`--RemoveTag
+DELETE FROM conversation_tags
+ WHERE site_id = $1
+   AND conversation_id = $2
+   AND tag = $3`,
+		siteId,
+		m.Id,
+		tag,
+	)
+	if err != nil {
+		glog.Errorf("tx.Exec(%d, %d, `%s`) %+v", siteId, m.Id, tag, err)
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Delete failed: %v", err.Error()),
+		)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Transaction failed: %v", err.Error()),
+		)
+	}
+
+	PurgeCache(h.ItemTypes[h.ItemTypeConversation], m.Id)
+
+	return http.StatusOK, nil
+}
+
+// GetConversationTags returns the tags of a conversation in alphabetical order
+func GetConversationTags(
+	siteId int64,
+	conversationId int64,
+) (
+	[]string,
+	int,
+	error,
+) {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return []string{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetConversationTags
+SELECT tag
+  FROM conversation_tags
+ WHERE site_id = $1
+   AND conversation_id = $2
+ ORDER BY tag ASC`,
+		siteId,
+		conversationId,
+	)
+	if err != nil {
+		glog.Errorf("db.Query(%d, %d) %+v", siteId, conversationId, err)
+		return []string{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			glog.Errorf("rows.Scan() %+v", err)
+			return []string{}, http.StatusInternalServerError,
+				errors.New("Row parsing error")
+		}
+		tags = append(tags, tag)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return []string{}, http.StatusInternalServerError,
+			errors.New("Error fetching rows")
+	}
+	rows.Close()
+
+	return tags, http.StatusOK, nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormaliseTag(t *testing.T) {
+	tests := map[string]string{
+		"announcement":          "announcement",
+		"  Help   Wanted ":      "help wanted",
+		"<b>bold</b>":           "bold",
+		"café":                  "café",
+		strings.Repeat("x", 30): strings.Repeat("x", 30),
+	}
+
+	for in, expected := range tests {
+		out, err := NormaliseTag(in)
+		if err != nil {
+			t.Errorf("NormaliseTag(%q) failed: %+v", in, err)
+			continue
+		}
+		if out != expected {
+			t.Errorf("NormaliseTag(%q) = %q, expected %q", in, out, expected)
+		}
+	}
+
+	for _, in := range []string{"", "   ", "<br>", strings.Repeat("x", 31)} {
+		if _, err := NormaliseTag(in); err == nil {
+			t.Errorf("Expected NormaliseTag(%q) to fail", in)
+		}
+	}
+}
@@ -23,11 +23,13 @@ type ConversationsType struct {
 
 type ConversationSummaryType struct {
 	ItemSummary
+	Tags []string `json:"tags"`
 	ItemSummaryMeta
 }
 
 type ConversationType struct {
 	ItemDetail
+	Tags []string `json:"tags"`
 	ItemDetailCommentsAndMeta
 }
 
@@ -286,6 +288,22 @@ func (m *ConversationType) Patch(
 	int,
 	error,
 ) {
+	return m.PatchAndMove(ac, patches, 0)
+}
+
+// PatchAndMove applies the flag patches and, if newMicrocosmId is given, moves
+// the conversation and its comments to another microcosm on the same site.
+// Both are done in a single transaction so that a move that fails leaves the
+// flags unchanged. The caller must check that the editor may create
+// conversations in the destination.
+func (m *ConversationType) PatchAndMove(
+	ac AuthContext,
+	patches []h.PatchType,
+	newMicrocosmId int64,
+) (
+	int,
+	error,
+) {
 
 	tx, err := h.GetTransaction()
 	if err != nil {
@@ -293,6 +311,45 @@ func (m *ConversationType) Patch(
 	}
 	defer tx.Rollback()
 
+	oldMicrocosmId := m.MicrocosmId
+
+	status, err := m.patch(tx, ac, patches)
+	if err != nil {
+		return status, err
+	}
+
+	if newMicrocosmId > 0 && newMicrocosmId != m.MicrocosmId {
+		status, err = m.move(tx, newMicrocosmId, ac.ProfileId)
+		if err != nil {
+			return status, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Transaction failed: %v", err.Error()),
+		)
+	}
+
+	PurgeCache(h.ItemTypes[h.ItemTypeConversation], m.Id)
+	PurgeCache(h.ItemTypes[h.ItemTypeMicrocosm], oldMicrocosmId)
+	if m.MicrocosmId != oldMicrocosmId {
+		PurgeCache(h.ItemTypes[h.ItemTypeMicrocosm], m.MicrocosmId)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (m *ConversationType) patch(
+	tx *sql.Tx,
+	ac AuthContext,
+	patches []h.PatchType,
+) (
+	int,
+	error,
+) {
+
 	for _, patch := range patches {
 
 		m.Meta.EditedNullable = pq.NullTime{Time: time.Now(), Valid: true}
@@ -328,7 +385,7 @@ func (m *ConversationType) Patch(
 
 		m.Meta.Flags.SetVisible()
 
-		_, err = tx.Exec(`--Update Conversation Flags
+		_, err := tx.Exec(`--Update Conversation Flags
 UPDATE conversations
    SET `+column+` = $2
       ,is_visible = $3
@@ -350,15 +407,99 @@ UPDATE conversations
 		}
 	}
 
-	err = tx.Commit()
+	return http.StatusOK, nil
+}
+
+// move moves the conversation and its comments to another microcosm on the
+// same site within the transaction
+func (m *ConversationType) move(
+	tx *sql.Tx,
+	newMicrocosmId int64,
+	editedBy int64,
+) (
+	int,
+	error,
+) {
+
+	oldMicrocosmId := m.MicrocosmId
+
+	// The destination must be on the same site as the conversation
+	var exists bool
+	err := tx.QueryRow(`--Move Conversation Check Destination
+SELECT EXISTS(
+           SELECT 1
+             FROM microcosms d
+                  JOIN microcosms s ON s.site_id = d.site_id
+            WHERE d.microcosm_id = $1
+              AND s.microcosm_id = $2
+              AND d.is_deleted IS NOT TRUE
+              AND d.is_moderated IS NOT TRUE
+       )`,
+		newMicrocosmId,
+		oldMicrocosmId,
+	).Scan(&exists)
 	if err != nil {
 		return http.StatusInternalServerError, errors.New(
-			fmt.Sprintf("Transaction failed: %v", err.Error()),
+			fmt.Sprintf("Database query failed: %v", err.Error()),
 		)
 	}
+	if !exists {
+		return http.StatusBadRequest,
+			errors.New("The destination microcosm does not exist")
+	}
 
-	PurgeCache(h.ItemTypes[h.ItemTypeConversation], m.Id)
-	PurgeCache(h.ItemTypes[h.ItemTypeMicrocosm], m.MicrocosmId)
+	m.MicrocosmId = newMicrocosmId
+	m.Meta.EditedNullable = pq.NullTime{Time: time.Now(), Valid: true}
+	m.Meta.EditedByNullable = sql.NullInt64{Int64: editedBy, Valid: true}
+	m.Meta.EditReason = fmt.Sprintf(
+		"Moved from microcosm %d to %d",
+		oldMicrocosmId,
+		newMicrocosmId,
+	)
+
+	_, err = tx.Exec(`--Move Conversation
+UPDATE conversations
+   SET microcosm_id = $2
+      ,edited = $3
+      ,edited_by = $4
+      ,edit_reason = $5
+ WHERE conversation_id = $1`,
+		m.Id,
+		m.MicrocosmId,
+		m.Meta.EditedNullable,
+		m.Meta.EditedByNullable,
+		m.Meta.EditReason,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Update failed: %v", err.Error()),
+		)
+	}
+
+	// Comments belong to the conversation, so move with it
+	_, err = tx.Exec(`--Move Conversation Flags
+UPDATE flags
+   SET microcosm_id = $2
+ WHERE (item_type_id = 6 AND item_id = $1)
+    OR (item_type_id = 4 AND parent_item_type_id = 6 AND parent_item_id = $1)`,
+		m.Id,
+		m.MicrocosmId,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Update failed: %v", err.Error()),
+		)
+	}
+
+	err = DecrementMicrocosmItemCount(tx, oldMicrocosmId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = IncrementMicrocosmItemCount(tx, m.MicrocosmId)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
 
 	return http.StatusOK, nil
 }
@@ -503,6 +644,12 @@ SELECT c.conversation_id
 			m.Meta.EditedNullable.Time.Format(time.RFC3339Nano)
 	}
 
+	tags, status, err := GetConversationTags(siteId, m.Id)
+	if err != nil {
+		return ConversationType{}, status, err
+	}
+	m.Tags = tags
+
 	m.Meta.Links =
 		[]h.LinkType{
 			h.GetLink("self", "", h.ItemTypeConversation, m.Id),
@@ -515,7 +662,7 @@ SELECT c.conversation_id
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeConversation))
 
 	m.FetchSummaries(siteId)
 	return m, http.StatusOK, nil
@@ -613,6 +760,11 @@ SELECT conversation_id
 		m.LastComment = lastComment
 	}
 
+	m.Tags, status, err = GetConversationTags(siteId, m.Id)
+	if err != nil {
+		return ConversationSummaryType{}, status, err
+	}
+
 	m.Meta.Links =
 		[]h.LinkType{
 			h.GetLink("self", "", h.ItemTypeConversation, m.Id),
@@ -625,15 +777,19 @@ SELECT conversation_id
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeConversation))
 
 	m.FetchProfileSummaries(siteId)
 	return m, http.StatusOK, nil
 }
 
+// GetConversations returns a page of the conversations on a site that the
+// profile may read. If a tag is given only conversations with that tag are
+// returned.
 func GetConversations(
 	siteId int64,
 	profileId int64,
+	tag string,
 	limit int64,
 	offset int64,
 ) (
@@ -644,6 +800,32 @@ func GetConversations(
 	error,
 ) {
 
+	args := []interface{}{
+		siteId,
+		h.ItemTypes[h.ItemTypeConversation],
+		profileId,
+		limit,
+		offset,
+	}
+
+	var filterTag string
+	if tag != "" {
+		tag, err := NormaliseTag(tag)
+		if err != nil {
+			return []ConversationSummaryType{}, 0, 0,
+				http.StatusBadRequest, err
+		}
+
+		args = append(args, tag)
+		filterTag = `
+   AND f.item_id IN (
+           SELECT conversation_id
+             FROM conversation_tags
+            WHERE site_id = $1
+              AND tag = $` + strconv.Itoa(len(args)) + `
+       )`
+	}
+
 	// Retrieve resources
 	db, err := h.GetConnection()
 	if err != nil {
@@ -656,6 +838,7 @@ WITH m AS (
     SELECT m.microcosm_id
       FROM microcosms m
       LEFT JOIN ignores i ON i.profile_id = $3
+                         AND (i.expires IS NULL OR i.expires > NOW())
                          AND i.item_type_id = 2
                          AND i.item_id = m.microcosm_id
      WHERE i.profile_id IS NULL
@@ -665,6 +848,7 @@ SELECT COUNT(*) OVER() AS total
       ,f.item_id
   FROM flags f
   LEFT JOIN ignores i ON i.profile_id = $3
+                     AND (i.expires IS NULL OR i.expires > NOW())
                      AND i.item_type_id = f.item_type_id
                      AND i.item_id = f.item_id
  WHERE f.site_id = $1
@@ -676,16 +860,12 @@ SELECT COUNT(*) OVER() AS total
    AND f.parent_is_moderated IS NOT TRUE
    AND f.item_is_deleted IS NOT TRUE
    AND f.item_is_moderated IS NOT TRUE
-   AND f.microcosm_id IN (SELECT * FROM m)
+   AND f.microcosm_id IN (SELECT * FROM m)`+filterTag+`
  ORDER BY f.item_is_sticky DESC
          ,f.last_modified DESC
  LIMIT $4
 OFFSET $5`,
-		siteId,
-		h.ItemTypes[h.ItemTypeConversation],
-		profileId,
-		limit,
-		offset,
+		args...,
 	)
 	if err != nil {
 		return []ConversationSummaryType{}, 0, 0,
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/golang/glog"
 
 	c "github.com/microcosm-cc/microcosm/cache"
@@ -111,6 +114,180 @@ HAVING COUNT(hp.huddle_id) = 0`)
 	tx.Commit()
 }
 
+// Deletes ignores that have expired. Expired ignores are already disregarded
+// by queries, this keeps the table from growing.
+func DeleteExpiredIgnores() {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	res, err := db.Exec(`--DeleteExpiredIgnores
+DELETE
+  FROM ignores
+ WHERE expires <= NOW()`)
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	glog.Infof("Deleted %d expired ignores", rowsAffected)
+}
+
+// Deletes access tokens that have expired, which can no longer be used
+func DeleteExpiredAccessTokens() {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	res, err := db.Exec(`--DeleteExpiredAccessTokens
+DELETE
+  FROM access_tokens
+ WHERE expires <= NOW()`)
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	glog.Infof("Deleted %d expired access tokens", rowsAffected)
+}
+
+// Finds uploaded files that nothing refers to and deletes them from storage
+// along with their metadata.
+//
+// A file is orphaned when no attachment refers to it and no profile uses it as
+// an avatar. Files are uploaded before they are attached, so only files older
+// than a day are considered. The attachments of deleted events are removed
+// first so that their files become orphaned.
+func DeleteOrphanedAttachments() {
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Files attached to deleted events are no longer needed
+	_, err = tx.Exec(`
+DELETE
+  FROM attachments a
+ USING events e
+ WHERE a.item_type_id = 9
+   AND a.item_id = e.event_id
+   AND e.is_deleted IS TRUE`)
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	rows, err := tx.Query(`
+DELETE
+  FROM attachment_meta
+ WHERE attachment_meta_id IN (
+           SELECT m.attachment_meta_id
+             FROM attachment_meta m
+            WHERE m.created < NOW() - INTERVAL '1 day'
+              AND NOT EXISTS (
+                      SELECT 1
+                        FROM attachments a
+                       WHERE a.attachment_meta_id = m.attachment_meta_id
+                  )
+              AND NOT EXISTS (
+                      SELECT 1
+                        FROM profiles p
+                       WHERE p.avatar_url LIKE '%' || m.file_sha1 || '%'
+                  )
+              FOR UPDATE
+       )
+RETURNING file_sha1`)
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var fileHash string
+		err = rows.Scan(&fileHash)
+		if err != nil {
+			glog.Error(err)
+			return
+		}
+		hashes = append(hashes, fileHash)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+	rows.Close()
+
+	err = tx.Commit()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	if len(hashes) == 0 {
+		return
+	}
+
+	// Files are only removed from storage once the metadata is gone, so that
+	// at worst a failure leaves an unreferenced object behind rather than
+	// metadata pointing at nothing. Metadata and files are not cached so there
+	// is nothing to purge.
+	storage, err := GetStorage()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	for _, fileHash := range hashes {
+
+		// The same file may have been uploaded again since the metadata was
+		// deleted, in which case it must be kept
+		_, status, _ := GetMetadata(fileHash)
+		if status != http.StatusNotFound {
+			continue
+		}
+
+		// The thumbnail and any resized variants share the prefix
+		keys, err := storage.List(fileHash + "_")
+		if err != nil {
+			glog.Errorf("storage.List(`%s_`) %+v", fileHash, err)
+			keys = []string{ThumbnailKey(fileHash)}
+		}
+
+		for _, key := range append(keys, fileHash) {
+			err = storage.Delete(key)
+			if err != nil {
+				glog.Errorf("storage.Delete(`%s`) %+v", key, err)
+			}
+		}
+	}
+
+	glog.Infof("Deleted %d orphaned attachments", len(hashes))
+}
+
 // Updates the site stats across all sites.
 func UpdateAllSiteStats() {
 
@@ -284,18 +461,74 @@ func UpdateProfileCounts() {
 	}
 }
 
+// UpdateAllUnreadHuddleCounts recalculates the unread huddle count of every
+// profile on an active site that participates in a huddle (or that has an
+// unread count to clear). The counts are otherwise only updated when a
+// profile reads or is sent a huddle, and can drift.
+func UpdateAllUnreadHuddleCounts() {
+
+	// No transaction as the counts are recalculated from scratch each time.
+	// Failures are logged and skipped, the next run will correct them
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	rows, err := db.Query(`
+SELECT p.profile_id
+  FROM profiles p
+       JOIN sites s ON s.site_id = p.site_id
+ WHERE s.is_deleted IS NOT TRUE
+   AND (
+           p.unread_huddles > 0
+        OR EXISTS (
+               SELECT 1
+                 FROM huddle_profiles hp
+                WHERE hp.profile_id = p.profile_id
+           )
+       )`,
+	)
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+
+		var profileId int64
+		err = rows.Scan(&profileId)
+		if err != nil {
+			glog.Error(err)
+			return
+		}
+
+		ids = append(ids, profileId)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+	rows.Close()
+
+	// Purges the counts cache of each profile
+	for _, profileId := range ids {
+		UpdateUnreadHuddleCount(profileId)
+	}
+}
+
 // UpdateViewsCounts reads from the views table and will SUM the number of views
 // and update all of the associated conversations and events with the new view
-// count.
-func UpdateViewCounts() {
+// count. Errors are returned so that the cron job status shows why views are
+// not being counted.
+func UpdateViewCounts() error {
 
-	// No transaction as we don't care for accuracy on these updates
-	// Note: This function doesn't even return errors, we don't even care
-	// if the occasional UPDATE fails.
 	tx, err := h.GetTransaction()
 	if err != nil {
-		glog.Error(err)
-		return
+		return err
 	}
 	defer tx.Rollback()
 
@@ -310,8 +543,7 @@ SELECT item_type_id
   FROM views
  GROUP BY item_type_id, item_id`)
 	if err != nil {
-		glog.Error(err)
-		return
+		return err
 	}
 	defer rows.Close()
 
@@ -328,8 +560,7 @@ SELECT item_type_id
 			&view.ItemId,
 		)
 		if err != nil {
-			glog.Error(err)
-			return
+			return err
 		}
 
 		switch view.ItemTypeId {
@@ -345,14 +576,13 @@ SELECT item_type_id
 	}
 	err = rows.Err()
 	if err != nil {
-		glog.Error(err)
-		return
+		return err
 	}
 	rows.Close()
 
 	if len(views) == 0 {
 		// No views to update
-		return
+		return nil
 	}
 
 	// Our updates are a series of updates in the database, we don't even
@@ -372,8 +602,7 @@ UPDATE conversations c
        ) AS v
  WHERE c.conversation_id = v.item_id`)
 		if err != nil {
-			glog.Error(err)
-			return
+			return err
 		}
 	}
 
@@ -391,8 +620,7 @@ UPDATE events e
        ) AS v
  WHERE e.event_id = v.item_id`)
 		if err != nil {
-			glog.Error(err)
-			return
+			return err
 		}
 	}
 
@@ -410,25 +638,26 @@ UPDATE polls p
        ) AS v
  WHERE p.poll_id = v.item_id;`)
 		if err != nil {
-			glog.Error(err)
-			return
+			return err
 		}
 	}
 
 	// Clear views, and the quickest way to do that is just truncate the table
 	_, err = tx.Exec(`TRUNCATE TABLE views`)
 	if err != nil {
-		glog.Error(err)
-		return
+		return err
 	}
 
-	tx.Commit()
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
 
 	for _, view := range views {
 		PurgeCacheByScope(c.CacheItem, view.ItemTypeId, view.ItemId)
 	}
 
-	return
+	return nil
 }
 
 // Updates the site_stats with the current number of people online on a site
@@ -446,11 +675,14 @@ UPDATE site_stats s
   FROM (
            SELECT site_id
                  ,COUNT(*) AS online
-             FROM profiles
-            WHERE last_active > NOW() - interval '90 minute'
+             FROM profiles p
+            WHERE p.last_active > $1
+              AND `+notHidingOnline+`
             GROUP BY site_id
        ) p
- WHERE p.site_id = s.site_id`)
+ WHERE p.site_id = s.site_id`,
+		OnlineSince(time.Now(), GetOnlineWindow()),
+	)
 	if err != nil {
 		glog.Error(err)
 		return
@@ -490,3 +722,76 @@ UPDATE site_stats s
 		go PurgeCacheByScope(c.CacheCounts, h.ItemTypes[h.ItemTypeSite], siteId)
 	}
 }
+
+// Moves events between the 'proposed', 'upcoming' and 'past' statuses
+// according to when they are and how long they last. An event with an end is
+// past once it has ended.
+//
+// Cancelled and postponed events are set explicitly by the organiser and are
+// left alone. Recurring events become 'past' after the last occurrence before
+// their UNTIL, those bounded by COUNT or without end never do here as their
+// final occurrence isn't known to the database. Only rows whose status
+// actually changes are updated, and caches are purged for just those.
+func UpdateEventStatuses() {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+
+	rows, err := db.Query(`
+UPDATE events e
+   SET status = s.status
+  FROM (
+           SELECT event_id
+                 ,CASE
+                      WHEN ev."when" IS NULL THEN $1
+                      WHEN NOT `+sqlEventNotFinished("ev")+` THEN $3
+                      ELSE $2
+                  END AS status
+             FROM events ev
+            WHERE status NOT IN ($4, $5)
+       ) s
+ WHERE e.event_id = s.event_id
+   AND e.status <> s.status
+RETURNING e.event_id
+         ,e.microcosm_id`,
+		EventStatusProposed,
+		EventStatusUpcoming,
+		EventStatusPast,
+		EventStatusCancelled,
+		EventStatusPostponed,
+	)
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+	defer rows.Close()
+
+	var (
+		eventIds     []int64
+		microcosmIds []int64
+	)
+	for rows.Next() {
+		var eventId, microcosmId int64
+		err = rows.Scan(&eventId, &microcosmId)
+		if err != nil {
+			glog.Error(err)
+			return
+		}
+		eventIds = append(eventIds, eventId)
+		microcosmIds = append(microcosmIds, microcosmId)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+	rows.Close()
+
+	for i, eventId := range eventIds {
+		PurgeCache(h.ItemTypes[h.ItemTypeEvent], eventId)
+		PurgeCache(h.ItemTypes[h.ItemTypeMicrocosm], microcosmIds[i])
+	}
+}
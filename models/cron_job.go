@@ -0,0 +1,144 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+var ErrCronJobRunning = errors.New("The cron job is already running")
+
+// CronJob is a housekeeping function that is run on a schedule and may also be
+// run on demand by an administrator. A job never runs twice at once.
+type CronJob struct {
+	Name     string
+	Schedule string
+	Func     func() error
+
+	mutex  sync.Mutex
+	status CronJobStatus
+}
+
+// CronJobStatus describes the current and most recent runs of a job. LastError
+// records the error returned by the last run, or its panic. An empty Schedule
+// means that the job is only run on demand.
+type CronJobStatus struct {
+	Name         string    `json:"name"`
+	Schedule     string    `json:"schedule"`
+	Running      bool      `json:"running"`
+	Runs         int64     `json:"runs"`
+	LastStarted  time.Time `json:"lastStarted"`
+	LastDuration float64   `json:"lastDurationSeconds"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+var (
+	cronJobs      = map[string]*CronJob{}
+	cronJobsMutex sync.RWMutex
+)
+
+// RegisterCronJob makes a job available to be run by name. The schedule is
+// only recorded for the job's status.
+func RegisterCronJob(name string, schedule string, f func() error) *CronJob {
+	cronJobsMutex.Lock()
+	defer cronJobsMutex.Unlock()
+
+	job := &CronJob{Name: name, Schedule: schedule, Func: f}
+	job.status.Name = name
+	job.status.Schedule = schedule
+	cronJobs[name] = job
+
+	return job
+}
+
+// GetCronJob returns the registered job with the given name
+func GetCronJob(name string) (*CronJob, int, error) {
+	cronJobsMutex.RLock()
+	defer cronJobsMutex.RUnlock()
+
+	job, ok := cronJobs[name]
+	if !ok {
+		return nil, http.StatusNotFound, errors.New(
+			fmt.Sprintf("Cron job not found: %s", name),
+		)
+	}
+
+	return job, http.StatusOK, nil
+}
+
+// GetCronJobStatuses returns the status of every registered job, ordered by
+// name
+func GetCronJobStatuses() []CronJobStatus {
+	cronJobsMutex.RLock()
+	defer cronJobsMutex.RUnlock()
+
+	names := []string{}
+	for name := range cronJobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := []CronJobStatus{}
+	for _, name := range names {
+		statuses = append(statuses, cronJobs[name].Status())
+	}
+
+	return statuses
+}
+
+// Status returns the status of the job
+func (j *CronJob) Status() CronJobStatus {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.status
+}
+
+// Run is called by the scheduler. If the previous run has not finished then
+// this run is skipped, so that a slow job does not pile up behind itself.
+func (j *CronJob) Run() {
+	err := j.RunAndRecover()
+	if err == ErrCronJobRunning {
+		glog.Warningf("Cron job %s is still running, skipping this run", j.Name)
+	} else if err != nil {
+		glog.Error(err)
+	}
+}
+
+// RunAndRecover runs the job unless it is already running, in which case
+// ErrCronJobRunning is returned. The error returned by the job, or a panic, is
+// recorded in the status and returned.
+func (j *CronJob) RunAndRecover() (err error) {
+	j.mutex.Lock()
+	if j.status.Running {
+		j.mutex.Unlock()
+		return ErrCronJobRunning
+	}
+	started := time.Now()
+	j.status.Running = true
+	j.status.Runs++
+	j.status.LastStarted = started
+	j.mutex.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("Cron job %s panicked: %v", j.Name, r))
+		}
+
+		j.mutex.Lock()
+		j.status.Running = false
+		j.status.LastDuration = time.Since(started).Seconds()
+		j.status.LastError = ""
+		if err != nil {
+			j.status.LastError = err.Error()
+		}
+		j.mutex.Unlock()
+	}()
+
+	return j.Func()
+}
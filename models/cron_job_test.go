@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCronJobSkipsOverlappingRuns(t *testing.T) {
+	started := make(chan bool)
+	finish := make(chan bool)
+
+	job := RegisterCronJob("test_overlap", "", func() error {
+		started <- true
+		<-finish
+		return nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- job.RunAndRecover()
+	}()
+	<-started
+
+	if !job.Status().Running {
+		t.Errorf("Expected the job to be running")
+	}
+
+	err := job.RunAndRecover()
+	if err != ErrCronJobRunning {
+		t.Errorf("Expected ErrCronJobRunning, got %+v", err)
+	}
+
+	finish <- true
+	err = <-done
+	if err != nil {
+		t.Errorf("Expected the first run to succeed, got %+v", err)
+	}
+
+	status := job.Status()
+	if status.Running || status.LastStarted.IsZero() || status.LastError != "" {
+		t.Errorf("Unexpected status after the run: %+v", status)
+	}
+}
+
+func TestCronJobRecoversPanic(t *testing.T) {
+	job := RegisterCronJob("test_panic", "", func() error {
+		panic("oops")
+	})
+
+	err := job.RunAndRecover()
+	if err == nil {
+		t.Fatalf("Expected the panic to be returned as an error")
+	}
+
+	if job.Status().LastError != err.Error() {
+		t.Errorf("Expected LastError to be %q, got %q", err.Error(), job.Status().LastError)
+	}
+}
+
+func TestCronJobRecordsErrors(t *testing.T) {
+	fail := true
+	job := RegisterCronJob("test_error", "0 * * * * *", func() error {
+		if fail {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	err := job.RunAndRecover()
+	if err == nil || job.Status().LastError != "failed" {
+		t.Errorf("Expected the error to be recorded, got %+v", job.Status())
+	}
+
+	fail = false
+	err = job.RunAndRecover()
+	if err != nil || job.Status().LastError != "" {
+		t.Errorf("Expected a successful run to clear the error, got %+v", job.Status())
+	}
+
+	status := job.Status()
+	if status.Runs != 2 || status.Schedule != "0 * * * * *" {
+		t.Errorf("Unexpected status after two runs: %+v", status)
+	}
+}
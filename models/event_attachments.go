@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// maxEventAttachments is the most files that may be attached to an event, all
+// of which are returned with the event
+const maxEventAttachments int64 = 50
+
+// getEventAttachments returns the files attached to the event
+func getEventAttachments(eventId int64) ([]AttachmentType, int, error) {
+	ems, _, _, status, err := GetAttachments(
+		h.ItemTypes[h.ItemTypeEvent],
+		eventId,
+		maxEventAttachments,
+		0,
+	)
+	if err != nil {
+		return []AttachmentType{}, status, err
+	}
+
+	return ems, http.StatusOK, nil
+}
+
+// AttachToEvent attaches an uploaded file, such as an agenda or flyer, to the
+// event. Attaching a file that is already attached returns the existing
+// attachment.
+func (m *EventType) AttachToEvent(
+	profileId int64,
+	fileHash string,
+	fileName string,
+) (
+	AttachmentType,
+	int,
+	error,
+) {
+
+	if fileHash == "" {
+		return AttachmentType{}, http.StatusBadRequest,
+			errors.New("You must supply a file hash")
+	}
+
+	metadata, status, err := GetMetadata(fileHash)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return AttachmentType{}, http.StatusBadRequest,
+				errors.New("File does not have a metadata record")
+		}
+		return AttachmentType{}, status, err
+	}
+
+	existing, status, err := GetAttachment(
+		h.ItemTypes[h.ItemTypeEvent],
+		m.Id,
+		fileHash,
+		false,
+	)
+	if err == nil {
+		return existing, http.StatusOK, nil
+	}
+	if status != http.StatusNotFound {
+		return AttachmentType{}, status, err
+	}
+
+	attachments, status, err := getEventAttachments(m.Id)
+	if err != nil {
+		return AttachmentType{}, status, err
+	}
+	if int64(len(attachments)) >= maxEventAttachments {
+		return AttachmentType{}, http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"An event may have at most %d attachments",
+				maxEventAttachments,
+			),
+		)
+	}
+
+	metadata.AttachCount += 1
+	status, err = metadata.Update()
+	if err != nil {
+		return AttachmentType{}, status, err
+	}
+
+	attachment := AttachmentType{
+		ProfileId:        profileId,
+		AttachmentMetaId: metadata.AttachmentMetaId,
+		ItemTypeId:       h.ItemTypes[h.ItemTypeEvent],
+		ItemId:           m.Id,
+		FileHash:         fileHash,
+		FileName:         fileName,
+		Created:          time.Now(),
+	}
+	status, err = attachment.Insert()
+	if err != nil {
+		return AttachmentType{}, status, err
+	}
+
+	return attachment, http.StatusOK, nil
+}
+
+// DetachFromEvent removes a file from the event. The file itself is deleted
+// by DeleteOrphanedAttachments once nothing else refers to it.
+func (m *EventType) DetachFromEvent(fileHash string) (int, error) {
+
+	_, status, err := GetAttachment(
+		h.ItemTypes[h.ItemTypeEvent],
+		m.Id,
+		fileHash,
+		false,
+	)
+	if err != nil {
+		return status, err
+	}
+
+	metadata, status, err := GetMetadata(fileHash)
+	if err != nil {
+		return status, err
+	}
+
+	status, err = DeleteAttachment(h.ItemTypes[h.ItemTypeEvent], m.Id, fileHash)
+	if err != nil {
+		return status, err
+	}
+
+	metadata.AttachCount -= 1
+	return metadata.Update()
+}
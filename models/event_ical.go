@@ -0,0 +1,287 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Date-time format for UTC values in iCalendar (RFC 5545 3.3.5)
+	icalTimeFormat string = "20060102T150405Z"
+
+	// Date-time format for local values, which are qualified by a TZID
+	icalLocalTimeFormat string = "20060102T150405"
+
+	// icalTimezoneYears bounds how far ahead the VTIMEZONE describes the
+	// time zone of an event that recurs without end
+	icalTimezoneYears int = 10
+
+	// Lines longer than this many octets must be folded (RFC 5545 3.1)
+	icalMaxLineOctets int = 75
+)
+
+var icalTextEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// ICal renders the event as an iCalendar document containing a single
+// VEVENT. The UID is derived from the site and event IDs so that calendar
+// clients recognise subsequent exports as updates to the same event.
+//
+// Proposed events have no start time and produce a VEVENT without DTSTART
+// or DTEND. Events with a time zone are given in the local time of that zone,
+// which is described by a VTIMEZONE, so that recurrences keep their local
+// time across daylight saving changes.
+func (m EventType) ICal(site SiteType) string {
+	var buf bytes.Buffer
+
+	var (
+		start time.Time
+		end   time.Time
+		loc   *time.Location
+	)
+	if m.WhenNullable.Valid {
+		start = m.WhenNullable.Time.UTC()
+		end = start.Add(time.Duration(m.Duration) * time.Minute)
+		if m.EndNullable.Valid {
+			end = m.EndNullable.Time.UTC()
+		}
+
+		if m.Timezone != "" {
+			l, err := loadEventLocation(m.Timezone)
+			if err == nil {
+				loc = l
+			}
+		}
+	}
+
+	writeICalLine(&buf, "BEGIN:VCALENDAR")
+	writeICalLine(&buf, "VERSION:2.0")
+	writeICalLine(&buf, "PRODID:-//Microcosm//Events//EN")
+	writeICalLine(&buf, "CALSCALE:GREGORIAN")
+	if loc != nil {
+		writeICalTimezone(&buf, loc, start, m.lastEnd(start, end))
+	}
+	writeICalLine(&buf, "BEGIN:VEVENT")
+	writeICalLine(
+		&buf,
+		fmt.Sprintf("UID:event-%d-site-%d@%s", m.Id, site.Id, site.SubdomainKey),
+	)
+
+	stamp := m.Meta.Created
+	if m.Meta.EditedNullable.Valid {
+		stamp = m.Meta.EditedNullable.Time
+	}
+	writeICalLine(&buf, "DTSTAMP:"+stamp.UTC().Format(icalTimeFormat))
+
+	if m.WhenNullable.Valid {
+		if loc != nil {
+			tzid := ";TZID=" + loc.String() + ":"
+			writeICalLine(
+				&buf,
+				"DTSTART"+tzid+start.In(loc).Format(icalLocalTimeFormat),
+			)
+			writeICalLine(
+				&buf,
+				"DTEND"+tzid+end.In(loc).Format(icalLocalTimeFormat),
+			)
+		} else {
+			writeICalLine(&buf, "DTSTART:"+start.Format(icalTimeFormat))
+			writeICalLine(&buf, "DTEND:"+end.Format(icalTimeFormat))
+		}
+
+		if m.Recurrence != "" {
+			rule, err := ParseRecurrenceRule(m.Recurrence)
+			if err == nil {
+				if loc != nil {
+					rule = rule.InLocation(loc)
+				}
+				writeICalLine(&buf, "RRULE:"+rule.String())
+			}
+		}
+	}
+
+	writeICalLine(&buf, "SUMMARY:"+icalTextEscaper.Replace(m.Title))
+
+	if m.Where != "" {
+		writeICalLine(&buf, "LOCATION:"+icalTextEscaper.Replace(m.Where))
+	}
+
+	if m.Lat != 0 || m.Lon != 0 {
+		writeICalLine(&buf, fmt.Sprintf("GEO:%.6f;%.6f", m.Lat, m.Lon))
+	}
+
+	if m.Status == EventStatusCancelled {
+		writeICalLine(&buf, "STATUS:CANCELLED")
+	} else if m.Status == EventStatusProposed {
+		writeICalLine(&buf, "STATUS:TENTATIVE")
+	} else {
+		writeICalLine(&buf, "STATUS:CONFIRMED")
+	}
+
+	writeICalLine(&buf, "END:VEVENT")
+	writeICalLine(&buf, "END:VCALENDAR")
+
+	return buf.String()
+}
+
+// lastEnd returns when the last occurrence of the event ends, an event that
+// recurs without end is treated as ending after icalTimezoneYears
+func (m EventType) lastEnd(start time.Time, end time.Time) time.Time {
+	if m.Recurrence == "" {
+		return end
+	}
+
+	limit := start.AddDate(icalTimezoneYears, 0, 0)
+	rule, err := ParseRecurrenceRule(m.Recurrence)
+	if err != nil {
+		return limit
+	}
+
+	last := limit
+	if !rule.Until.IsZero() && rule.Until.Before(limit) {
+		last = rule.Until
+	} else if rule.Count > 0 {
+		occurrences := rule.Occurrences(start, start, rule.Count)
+		if len(occurrences) > 0 && occurrences[len(occurrences)-1].Before(limit) {
+			last = occurrences[len(occurrences)-1]
+		}
+	}
+
+	return last.Add(end.Sub(start))
+}
+
+// writeICalTimezone writes a VTIMEZONE describing the offsets of the location
+// from the year before start until end. The year before is included so that
+// the observance in effect at the start is described.
+func writeICalTimezone(
+	buf *bytes.Buffer,
+	loc *time.Location,
+	start time.Time,
+	end time.Time,
+) {
+	from := time.Date(start.Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := end.Add(24 * time.Hour)
+
+	writeICalLine(buf, "BEGIN:VTIMEZONE")
+	writeICalLine(buf, "TZID:"+loc.String())
+
+	transitions := icalTimezoneTransitions(loc, from, to)
+	if len(transitions) == 0 {
+		// The zone has a single offset over the period
+		name, offset := start.In(loc).Zone()
+		writeICalObservance(buf, "STANDARD", time.Unix(0, 0), name, offset, offset)
+	}
+
+	for _, t := range transitions {
+		_, fromOffset := t.Add(-time.Second).In(loc).Zone()
+		name, toOffset := t.In(loc).Zone()
+
+		component := "STANDARD"
+		if toOffset > fromOffset {
+			component = "DAYLIGHT"
+		}
+		writeICalObservance(buf, component, t, name, fromOffset, toOffset)
+	}
+
+	writeICalLine(buf, "END:VTIMEZONE")
+}
+
+// writeICalObservance writes a STANDARD or DAYLIGHT observance that begins
+// at the given time. Its DTSTART is in the local time before it begins.
+func writeICalObservance(
+	buf *bytes.Buffer,
+	component string,
+	onset time.Time,
+	name string,
+	fromOffset int,
+	toOffset int,
+) {
+	local := onset.In(time.FixedZone("", fromOffset))
+
+	writeICalLine(buf, "BEGIN:"+component)
+	writeICalLine(buf, "DTSTART:"+local.Format(icalLocalTimeFormat))
+	writeICalLine(buf, "TZOFFSETFROM:"+icalOffset(fromOffset))
+	writeICalLine(buf, "TZOFFSETTO:"+icalOffset(toOffset))
+	writeICalLine(buf, "TZNAME:"+icalTextEscaper.Replace(name))
+	writeICalLine(buf, "END:"+component)
+}
+
+// icalTimezoneTransitions returns the times between from and to at which the
+// offset of the location changes. Go does not expose the transitions of a
+// location, so each day is compared with the next and a change is narrowed
+// down to the second.
+func icalTimezoneTransitions(
+	loc *time.Location,
+	from time.Time,
+	to time.Time,
+) []time.Time {
+
+	transitions := []time.Time{}
+
+	_, offset := from.In(loc).Zone()
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		_, nextOffset := next.In(loc).Zone()
+		if nextOffset == offset {
+			continue
+		}
+
+		lo, hi := day.Unix(), next.Unix()
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if _, o := time.Unix(mid, 0).In(loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transitions = append(transitions, time.Unix(hi, 0).UTC())
+		offset = nextOffset
+	}
+
+	return transitions
+}
+
+// icalOffset formats an offset in seconds east of UTC as +HHMM
+func icalOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	return fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// writeICalLine writes a content line terminated by CRLF, folding it onto
+// continuation lines if it exceeds the permitted length. Folds never split
+// a multi-byte UTF-8 sequence.
+func writeICalLine(buf *bytes.Buffer, line string) {
+	limit := icalMaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isUTF8Start(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+
+		// Continuation lines begin with a space which counts to the limit
+		limit = icalMaxLineOctets - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+func isUTF8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+func TestEventICal(t *testing.T) {
+	m := EventType{}
+	m.Id = 42
+	m.Title = "Ride; then pub, maybe"
+	m.Where = "The Crown\nIslington"
+	m.WhenNullable = pq.NullTime{
+		Time:  time.Date(2014, 6, 1, 9, 0, 0, 0, time.UTC),
+		Valid: true,
+	}
+	m.Duration = 90
+	m.Lat = 51.5
+	m.Lon = -0.1
+	m.Status = EventStatusCancelled
+
+	site := SiteType{Id: 1, SubdomainKey: "lfgss"}
+	ical := m.ICal(site)
+
+	for _, want := range []string{
+		"UID:event-42-site-1@lfgss\r\n",
+		"DTSTART:20140601T090000Z\r\n",
+		"DTEND:20140601T103000Z\r\n",
+		`SUMMARY:Ride\; then pub\, maybe` + "\r\n",
+		`LOCATION:The Crown\nIslington` + "\r\n",
+		"GEO:51.500000;-0.100000\r\n",
+		"STATUS:CANCELLED\r\n",
+	} {
+		if !strings.Contains(ical, want) {
+			t.Errorf("Expected %q in:\n%s", want, ical)
+		}
+	}
+
+	// Rules stored before they were canonicalised are exported canonically
+	m.Recurrence = "RRULE:freq=weekly;count=4"
+	ical = m.ICal(site)
+	if !strings.Contains(ical, "\r\nRRULE:FREQ=WEEKLY;COUNT=4\r\n") {
+		t.Errorf("Expected a canonical RRULE in:\n%s", ical)
+	}
+
+	m.WhenNullable = pq.NullTime{}
+	m.Status = EventStatusProposed
+	ical = m.ICal(site)
+	if strings.Contains(ical, "DTSTART") {
+		t.Errorf("Proposed event should not have DTSTART:\n%s", ical)
+	}
+}
+
+func TestEventICalTimezone(t *testing.T) {
+	m := EventType{}
+	m.Id = 42
+	m.Title = "Ride"
+	m.WhenNullable = pq.NullTime{
+		Time:  time.Date(2014, 6, 1, 9, 0, 0, 0, time.UTC),
+		Valid: true,
+	}
+	m.Duration = 90
+	m.Timezone = "Europe/London"
+	m.Recurrence = "FREQ=WEEKLY;COUNT=30"
+
+	ical := m.ICal(SiteType{Id: 1, SubdomainKey: "lfgss"})
+
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/London\r\n",
+		// British Summer Time and the return to GMT during the series
+		"BEGIN:DAYLIGHT\r\nDTSTART:20140330T010000\r\n" +
+			"TZOFFSETFROM:+0000\r\nTZOFFSETTO:+0100\r\nTZNAME:BST\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20141026T020000\r\n" +
+			"TZOFFSETFROM:+0100\r\nTZOFFSETTO:+0000\r\nTZNAME:GMT\r\n",
+		"DTSTART;TZID=Europe/London:20140601T100000\r\n",
+		"DTEND;TZID=Europe/London:20140601T113000\r\n",
+	} {
+		if !strings.Contains(ical, want) {
+			t.Errorf("Expected %q in:\n%s", want, ical)
+		}
+	}
+	if strings.Contains(ical, "DTSTART:20140601T090000Z") {
+		t.Errorf("Expected the start in local time:\n%s", ical)
+	}
+	if strings.Index(ical, "END:VTIMEZONE") > strings.Index(ical, "BEGIN:VEVENT") {
+		t.Errorf("Expected the VTIMEZONE before the VEVENT:\n%s", ical)
+	}
+
+	// A zone without daylight saving has a single observance
+	m.Timezone = "Asia/Tokyo"
+	m.Recurrence = ""
+	ical = m.ICal(SiteType{Id: 1, SubdomainKey: "lfgss"})
+	for _, want := range []string{
+		"TZOFFSETFROM:+0900\r\nTZOFFSETTO:+0900\r\nTZNAME:JST\r\n",
+		"DTSTART;TZID=Asia/Tokyo:20140601T180000\r\n",
+	} {
+		if !strings.Contains(ical, want) {
+			t.Errorf("Expected %q in:\n%s", want, ical)
+		}
+	}
+}
+
+func TestWriteICalLineFolds(t *testing.T) {
+	var buf bytes.Buffer
+	writeICalLine(&buf, "SUMMARY:"+strings.Repeat("é", 100))
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > icalMaxLineOctets {
+			t.Errorf("Line exceeds %d octets: %q", icalMaxLineOctets, line)
+		}
+		if !utf8.ValidString(strings.TrimPrefix(line, " ")) {
+			t.Errorf("Fold split a UTF-8 sequence: %q", line)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// mcEventInvitesKey holds the profile IDs invited to a private event
+const mcEventInvitesKey = "ev_v%d"
+
+type EventInvitesType struct {
+	Invites h.ArrayType    `json:"invites"`
+	Meta    h.CoreMetaType `json:"meta"`
+}
+
+type EventInviteType struct {
+	ProfileId int64       `json:"profileId"`
+	Profile   interface{} `json:"profile,omitempty"`
+}
+
+// sqlCanSeeEvent returns a SQL condition that is false for a private event
+// that the profile neither created nor was invited to, and true for every
+// other item. Moderators are not exempt, private events they were not
+// invited to are left out of lists but may still be fetched directly.
+func sqlCanSeeEvent(itemTypeId string, itemId string, profileId string) string {
+	return `(
+       ` + itemTypeId + ` IS DISTINCT FROM 9
+    OR NOT EXISTS (
+           SELECT 1
+             FROM events pe
+            WHERE pe.event_id = ` + itemId + `
+              AND pe.is_private IS TRUE
+              AND pe.created_by <> ` + profileId + `
+              AND NOT EXISTS (
+                      SELECT 1
+                        FROM event_invites pi
+                       WHERE pi.event_id = pe.event_id
+                         AND pi.profile_id = ` + profileId + `
+                  )
+       )
+   )`
+}
+
+// getEventInviteIds returns the profiles invited to the event
+func getEventInviteIds(eventId int64) ([]int64, error) {
+	mcKey := fmt.Sprintf(mcEventInvitesKey, eventId)
+	if ids, ok := c.CacheGetInt64Slice(mcKey); ok {
+		return ids, nil
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return []int64{}, err
+	}
+
+	rows, err := db.Query(`--getEventInviteIds
+SELECT profile_id
+  FROM event_invites
+ WHERE event_id = $1`,
+		eventId,
+	)
+	if err != nil {
+		return []int64{}, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return []int64{}, err
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []int64{}, err
+	}
+	rows.Close()
+
+	c.CacheSetInt64Slice(mcKey, ids, cacheTtl(h.ItemTypeEvent))
+
+	return ids, nil
+}
+
+// IsInvitedToEvent returns true if the profile is on the invite list of the
+// event
+func IsInvitedToEvent(eventId int64, profileId int64) (bool, error) {
+	if profileId == 0 || eventId == 0 {
+		return false, nil
+	}
+
+	ids, err := getEventInviteIds(eventId)
+	if err != nil {
+		return false, err
+	}
+
+	return isInvited(ids, profileId), nil
+}
+
+// isInvited returns true if the profile is one of the invited profiles
+func isInvited(invitedIds []int64, profileId int64) bool {
+	for _, id := range invitedIds {
+		if id == profileId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canSeePrivateEvent returns true if the profile created the private event,
+// was invited to it, or moderates it. Guests may never see a private event.
+func canSeePrivateEvent(
+	siteId int64,
+	eventId int64,
+	createdById int64,
+	profileId int64,
+) (
+	bool,
+	error,
+) {
+	if profileId == 0 {
+		return false, nil
+	}
+
+	if profileId == createdById {
+		return true, nil
+	}
+
+	invited, err := IsInvitedToEvent(eventId, profileId)
+	if err != nil || invited {
+		return invited, err
+	}
+
+	perms := GetPermission(AuthContext{
+		SiteId:     siteId,
+		ProfileId:  profileId,
+		ItemTypeId: h.ItemTypes[h.ItemTypeEvent],
+		ItemId:     eventId,
+	})
+
+	return perms.IsModerator || perms.IsSiteOwner, nil
+}
+
+// CanSeeComments returns http.StatusOK if the profile may see the comments on
+// the item. The comments on a private event are not found by profiles that
+// may not see the event.
+func CanSeeComments(
+	siteId int64,
+	itemTypeId int64,
+	itemId int64,
+	profileId int64,
+) (
+	int,
+	error,
+) {
+	if itemTypeId != h.ItemTypes[h.ItemTypeEvent] {
+		return http.StatusOK, nil
+	}
+
+	_, status, err := GetEventSummary(siteId, itemId, profileId)
+	if err != nil {
+		return status, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// GetEventInvites returns the profiles invited to the event
+func GetEventInvites(
+	siteId int64,
+	eventId int64,
+	limit int64,
+	offset int64,
+) (
+	[]EventInviteType,
+	int64,
+	int64,
+	int,
+	error,
+) {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return []EventInviteType{}, 0, 0, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetEventInvites
+SELECT COUNT(*) OVER() AS total
+      ,profile_id
+  FROM event_invites
+ WHERE event_id = $1
+ ORDER BY created ASC, profile_id ASC
+ LIMIT $2
+OFFSET $3`,
+		eventId,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return []EventInviteType{}, 0, 0, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Database query failed: %v", err.Error()),
+			)
+	}
+	defer rows.Close()
+
+	var total int64
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(
+			&total,
+			&id,
+		)
+		if err != nil {
+			return []EventInviteType{}, 0, 0, http.StatusInternalServerError,
+				errors.New(
+					fmt.Sprintf("Row parsing error: %v", err.Error()),
+				)
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []EventInviteType{}, 0, 0, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Error fetching rows: %v", err.Error()),
+			)
+	}
+	rows.Close()
+
+	ems := []EventInviteType{}
+	for _, id := range ids {
+		profile, status, err := GetProfileSummary(siteId, id)
+		if err != nil {
+			return []EventInviteType{}, 0, 0, status, err
+		}
+		ems = append(ems, EventInviteType{ProfileId: id, Profile: profile})
+	}
+
+	pages := h.GetPageCount(total, limit)
+	maxOffset := h.GetMaxOffset(total, limit)
+
+	if offset > maxOffset {
+		return []EventInviteType{}, 0, 0, http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"not enough records, offset (%d) would return an empty page.",
+				offset,
+			),
+		)
+	}
+
+	return ems, total, pages, http.StatusOK, nil
+}
+
+// AddEventInvites invites the profiles to the event, profiles that are
+// already invited are left alone
+func (m *EventType) AddEventInvites(
+	siteId int64,
+	actorId int64,
+	profileIds []int64,
+) (
+	int,
+	error,
+) {
+
+	if len(profileIds) == 0 {
+		return http.StatusBadRequest,
+			errors.New("You must specify at least one profile to invite")
+	}
+
+	invalid := []string{}
+	for _, profileId := range profileIds {
+		if profileId < 1 {
+			invalid = append(invalid, strconv.FormatInt(profileId, 10))
+			continue
+		}
+
+		_, status, err := GetProfileSummary(siteId, profileId)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				return status, err
+			}
+			invalid = append(invalid, strconv.FormatInt(profileId, 10))
+		}
+	}
+	if len(invalid) > 0 {
+		return http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"These profiles do not exist on this site: %s",
+				strings.Join(invalid, ", "),
+			),
+		)
+	}
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	for _, profileId := range profileIds {
+		_, err := tx.Exec(`--AddEventInvites
+INSERT INTO event_invites (
+    event_id, profile_id, created, created_by
+)
+SELECT $1, $2, NOW(), $3
+ WHERE NOT EXISTS (
+       SELECT 1
+         FROM event_invites
+        WHERE event_id = $1
+          AND profile_id = $2
+       )`,
+			m.Id,
+			profileId,
+			actorId,
+		)
+		if err != nil {
+			return http.StatusInternalServerError, errors.New(
+				fmt.Sprintf("Error executing insert: %v", err.Error()),
+			)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Transaction failed: %v", err.Error()))
+	}
+
+	c.CacheDelete(fmt.Sprintf(mcEventInvitesKey, m.Id))
+
+	return http.StatusOK, nil
+}
+
+// RemoveEventInvite takes the profile off the invite list of the event. A
+// profile that is no longer invited to a private event cannot attend or watch
+// it, so their RSVP and watchers are removed too.
+func (m *EventType) RemoveEventInvite(profileId int64) (int, error) {
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`--RemoveEventInvite
+DELETE FROM event_invites
+ WHERE event_id = $1
+   AND profile_id = $2`,
+		m.Id,
+		profileId,
+	)
+	if err != nil {
+		return http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Delete failed: %v", err.Error()))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Error fetching rows affected: %v", err.Error()),
+		)
+	}
+	if rowsAffected == 0 {
+		return http.StatusNotFound,
+			errors.New("That profile is not invited to this event")
+	}
+
+	attendeeIds := []int64{}
+	watcherIds := []int64{}
+	if m.IsPrivate {
+		rows, err := tx.Query(`--RemoveEventInvite
+DELETE FROM attendees
+ WHERE event_id = $1
+   AND profile_id = $2
+RETURNING attendee_id`,
+			m.Id,
+			profileId,
+		)
+		if err != nil {
+			return http.StatusInternalServerError,
+				errors.New(fmt.Sprintf("Delete failed: %v", err.Error()))
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int64
+			err = rows.Scan(&id)
+			if err != nil {
+				return http.StatusInternalServerError, errors.New(
+					fmt.Sprintf("Row parsing error: %v", err.Error()),
+				)
+			}
+			attendeeIds = append(attendeeIds, id)
+		}
+		err = rows.Err()
+		if err != nil {
+			return http.StatusInternalServerError, errors.New(
+				fmt.Sprintf("Error fetching rows: %v", err.Error()),
+			)
+		}
+		rows.Close()
+
+		if len(attendeeIds) > 0 {
+			status, err := m.UpdateAttendees(tx)
+			if err != nil {
+				return status, err
+			}
+		}
+
+		rows, err = tx.Query(`--RemoveEventInvite
+DELETE FROM watchers
+ WHERE item_type_id = $1
+   AND item_id = $2
+   AND profile_id = $3
+RETURNING watcher_id`,
+			h.ItemTypes[h.ItemTypeEvent],
+			m.Id,
+			profileId,
+		)
+		if err != nil {
+			return http.StatusInternalServerError,
+				errors.New(fmt.Sprintf("Delete failed: %v", err.Error()))
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int64
+			err = rows.Scan(&id)
+			if err != nil {
+				return http.StatusInternalServerError, errors.New(
+					fmt.Sprintf("Row parsing error: %v", err.Error()),
+				)
+			}
+			watcherIds = append(watcherIds, id)
+		}
+		err = rows.Err()
+		if err != nil {
+			return http.StatusInternalServerError, errors.New(
+				fmt.Sprintf("Error fetching rows: %v", err.Error()),
+			)
+		}
+		rows.Close()
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Transaction failed: %v", err.Error()))
+	}
+
+	c.CacheDelete(fmt.Sprintf(mcEventInvitesKey, m.Id))
+	for _, id := range attendeeIds {
+		PurgeCache(h.ItemTypes[h.ItemTypeAttendee], id)
+	}
+	for _, id := range watcherIds {
+		PurgeCache(h.ItemTypes[h.ItemTypeWatcher], id)
+	}
+	if len(attendeeIds) > 0 {
+		PurgeCache(h.ItemTypes[h.ItemTypeEvent], m.Id)
+	}
+
+	glog.Infof("Removed invite of profile %d to event %d", profileId, m.Id)
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+func TestIsInvited(t *testing.T) {
+	if isInvited([]int64{}, 2) {
+		t.Errorf("Expected nobody to be invited to an empty list")
+	}
+	if !isInvited([]int64{1, 2, 3}, 2) {
+		t.Errorf("Expected profile 2 to be invited")
+	}
+	if isInvited([]int64{1, 3}, 2) {
+		t.Errorf("Expected profile 2 not to be invited")
+	}
+}
+
+func TestCanSeePrivateEventWithoutLookups(t *testing.T) {
+	ok, err := canSeePrivateEvent(1, 5, 1, 0)
+	if ok || err != nil {
+		t.Errorf("Expected a guest to be refused, got %t %v", ok, err)
+	}
+
+	ok, err = canSeePrivateEvent(1, 5, 1, 1)
+	if !ok || err != nil {
+		t.Errorf("Expected the creator to see the event, got %t %v", ok, err)
+	}
+}
+
+func TestCanSeeCommentsOnOtherItems(t *testing.T) {
+	// Only events are private, the event lookups are not needed for the rest
+	status, err := CanSeeComments(1, h.ItemTypes[h.ItemTypeConversation], 5, 0)
+	if err != nil {
+		t.Errorf("Expected a conversation to be visible, got %d", status)
+	}
+}
@@ -0,0 +1,358 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurring events are described by a subset of the RFC 5545 RRULE syntax:
+//
+//	FREQ=DAILY|WEEKLY|MONTHLY|YEARLY (required)
+//	INTERVAL=n                       (optional, default 1)
+//	BYDAY=MO,WE,...                  (optional, WEEKLY only, no ordinals)
+//	COUNT=n or UNTIL=yyyymmdd[Thhmmss[Z]] (optional, mutually exclusive)
+//
+// e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;COUNT=10"
+//
+// Rules are stored as String() produces them, with UNTIL as a UTC date-time,
+// as RFC 5545 3.3.10 requires UNTIL to be a date-time when DTSTART is one.
+//
+// The event 'when' is the first occurrence (DTSTART) and each occurrence
+// lasts for the event duration. The RSVP limit applies per occurrence, an
+// attendee is attending the series and the limit is the number of people
+// expected at any one occurrence.
+const (
+	RecurrenceDaily   string = "DAILY"
+	RecurrenceWeekly  string = "WEEKLY"
+	RecurrenceMonthly string = "MONTHLY"
+	RecurrenceYearly  string = "YEARLY"
+
+	// Upper bound on the periods examined when looking for occurrences, this
+	// prevents unbounded loops for rules that never produce a match
+	maxRecurrencePeriods int = 10000
+
+	// The number of upcoming occurrences returned on an event
+	eventOccurrencesShown int = 5
+)
+
+var recurrenceWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+type RecurrenceRule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    time.Time
+
+	// untilFloating is true if UNTIL was given as a date or a date-time
+	// without a time zone, which are in the local time of the event
+	untilFloating bool
+}
+
+var recurrenceWeekdayNames = map[time.Weekday]string{
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+	time.Sunday:    "SU",
+}
+
+// ParseRecurrenceRule parses an RRULE, returning a descriptive error for any
+// part of the rule that is malformed or outside of the supported subset
+func ParseRecurrenceRule(rule string) (RecurrenceRule, error) {
+
+	r := RecurrenceRule{Interval: 1}
+
+	rule = strings.TrimPrefix(strings.ToUpper(strings.Trim(rule, " ")), "RRULE:")
+	if rule == "" {
+		return RecurrenceRule{}, errors.New("Recurrence rule is empty")
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return RecurrenceRule{}, errors.New(
+				fmt.Sprintf("Recurrence rule part '%s' is not of the form NAME=VALUE", part),
+			)
+		}
+
+		name, value := kv[0], kv[1]
+		if seen[name] {
+			return RecurrenceRule{}, errors.New(
+				fmt.Sprintf("Recurrence rule repeats %s", name),
+			)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			switch value {
+			case RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly, RecurrenceYearly:
+				r.Freq = value
+			default:
+				return RecurrenceRule{}, errors.New(
+					fmt.Sprintf("Recurrence FREQ '%s' is not supported", value),
+				)
+			}
+
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return RecurrenceRule{}, errors.New(
+					"Recurrence INTERVAL must be a positive integer",
+				)
+			}
+			r.Interval = interval
+
+		case "BYDAY":
+			days := map[time.Weekday]bool{}
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := recurrenceWeekdays[day]
+				if !ok {
+					return RecurrenceRule{}, errors.New(
+						fmt.Sprintf("Recurrence BYDAY '%s' is not a supported day", day),
+					)
+				}
+				if days[weekday] {
+					return RecurrenceRule{}, errors.New(
+						fmt.Sprintf("Recurrence BYDAY repeats %s", day),
+					)
+				}
+				days[weekday] = true
+				r.ByDay = append(r.ByDay, weekday)
+			}
+
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return RecurrenceRule{}, errors.New(
+					"Recurrence COUNT must be a positive integer",
+				)
+			}
+			r.Count = count
+
+		case "UNTIL":
+			until, floating, err := parseRecurrenceUntil(value)
+			if err != nil {
+				return RecurrenceRule{}, err
+			}
+			r.Until = until
+			r.untilFloating = floating
+
+		default:
+			return RecurrenceRule{}, errors.New(
+				fmt.Sprintf("Recurrence rule part %s is not supported", name),
+			)
+		}
+	}
+
+	if r.Freq == "" {
+		return RecurrenceRule{}, errors.New("Recurrence rule must specify FREQ")
+	}
+
+	if r.Count > 0 && !r.Until.IsZero() {
+		return RecurrenceRule{}, errors.New(
+			"Recurrence rule cannot specify both COUNT and UNTIL",
+		)
+	}
+
+	if len(r.ByDay) > 0 && r.Freq != RecurrenceWeekly {
+		return RecurrenceRule{}, errors.New(
+			"Recurrence BYDAY is only supported for FREQ=WEEKLY",
+		)
+	}
+
+	// Order days from the start of the week (Monday) so that occurrences
+	// within a week are generated in order
+	sort.Sort(weekdaysFromMonday(r.ByDay))
+
+	return r, nil
+}
+
+// parseRecurrenceUntil parses an UNTIL value, returning whether it is floating
+// (a date or a date-time without a time zone). Floating values are returned
+// as though they were UTC until placed in a location by InLocation.
+func parseRecurrenceUntil(value string) (time.Time, bool, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		until, err := time.Parse(layout, value)
+		if err == nil {
+			if layout == "20060102" {
+				// A date includes the whole of that day
+				until = until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			return until, layout != "20060102T150405Z", nil
+		}
+	}
+
+	return time.Time{}, false, errors.New(
+		fmt.Sprintf("Recurrence UNTIL '%s' is not a valid date or date-time", value),
+	)
+}
+
+// InLocation returns the rule with a floating UNTIL taken to be the wall clock
+// time in loc, an UNTIL that is already UTC is unchanged
+func (r RecurrenceRule) InLocation(loc *time.Location) RecurrenceRule {
+	if !r.untilFloating || r.Until.IsZero() || loc == nil {
+		return r
+	}
+
+	r.Until = time.Date(
+		r.Until.Year(),
+		r.Until.Month(),
+		r.Until.Day(),
+		r.Until.Hour(),
+		r.Until.Minute(),
+		r.Until.Second(),
+		0,
+		loc,
+	)
+	r.untilFloating = false
+
+	return r
+}
+
+// String returns the canonical form of the rule, without the RRULE: prefix
+// and with UNTIL as a UTC date-time
+func (r RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		days := []string{}
+		for _, day := range r.ByDay {
+			days = append(days, recurrenceWeekdayNames[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns up to n occurrences of the rule that start after the
+// given time, for a series whose first occurrence is start
+func (r RecurrenceRule) Occurrences(
+	start time.Time,
+	after time.Time,
+	n int,
+) []time.Time {
+
+	occurrences := []time.Time{}
+	if n < 1 {
+		return occurrences
+	}
+
+	var emitted int
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		for _, t := range r.periodCandidates(start, period) {
+			if t.Before(start) {
+				continue
+			}
+
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return occurrences
+			}
+
+			emitted++
+			if r.Count > 0 && emitted > r.Count {
+				return occurrences
+			}
+
+			if t.After(after) {
+				occurrences = append(occurrences, t)
+				if len(occurrences) == n {
+					return occurrences
+				}
+			}
+		}
+	}
+
+	return occurrences
+}
+
+// periodCandidates returns the possible occurrences within the nth period
+// (day, week, month or year, multiplied by the interval) of the series
+func (r RecurrenceRule) periodCandidates(start time.Time, period int) []time.Time {
+	step := period * r.Interval
+
+	switch r.Freq {
+	case RecurrenceDaily:
+		return []time.Time{start.AddDate(0, 0, step)}
+
+	case RecurrenceWeekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{start.AddDate(0, 0, 7*step)}
+		}
+
+		weekStart := start.AddDate(0, 0, -daysSinceMonday(start.Weekday())+7*step)
+		candidates := []time.Time{}
+		for _, day := range r.ByDay {
+			candidates = append(
+				candidates,
+				weekStart.AddDate(0, 0, daysSinceMonday(day)),
+			)
+		}
+		return candidates
+
+	case RecurrenceMonthly:
+		// Months without the start day (i.e. the 31st) are skipped
+		t := start.AddDate(0, step, 0)
+		if t.Day() != start.Day() {
+			return []time.Time{}
+		}
+		return []time.Time{t}
+
+	case RecurrenceYearly:
+		// Years without the start day (i.e. 29th Feb) are skipped
+		t := start.AddDate(step, 0, 0)
+		if t.Day() != start.Day() {
+			return []time.Time{}
+		}
+		return []time.Time{t}
+	}
+
+	return []time.Time{}
+}
+
+func daysSinceMonday(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+type weekdaysFromMonday []time.Weekday
+
+func (v weekdaysFromMonday) Len() int {
+	return len(v)
+}
+
+func (v weekdaysFromMonday) Swap(i, j int) {
+	v[i], v[j] = v[j], v[i]
+}
+
+func (v weekdaysFromMonday) Less(i, j int) bool {
+	return daysSinceMonday(v[i]) < daysSinceMonday(v[j])
+}
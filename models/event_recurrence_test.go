@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrenceRule(t *testing.T) {
+	r, err := ParseRecurrenceRule("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TH,TU;COUNT=4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	if r.Freq != RecurrenceWeekly || r.Interval != 2 || r.Count != 4 {
+		t.Errorf("Rule not parsed correctly: %+v", r)
+	}
+
+	if len(r.ByDay) != 2 || r.ByDay[0] != time.Tuesday || r.ByDay[1] != time.Thursday {
+		t.Errorf("BYDAY not parsed and ordered correctly: %+v", r.ByDay)
+	}
+
+	malformed := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=MO,MO;COUNT=4",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;COUNT=2;UNTIL=20150101",
+		"FREQ=WEEKLY;UNTIL=tomorrow",
+		"FREQ=WEEKLY;FREQ=DAILY",
+		"FREQ",
+	}
+	for _, rule := range malformed {
+		if _, err := ParseRecurrenceRule(rule); err == nil {
+			t.Errorf("Expected an error for rule '%s'", rule)
+		}
+	}
+}
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	// A Tuesday
+	start := time.Date(2015, 1, 6, 19, 0, 0, 0, time.UTC)
+
+	r, _ := ParseRecurrenceRule("FREQ=WEEKLY;BYDAY=TU,TH;COUNT=3")
+	occurrences := r.Occurrences(start, start.Add(-time.Second), 10)
+
+	expected := []time.Time{
+		start,
+		time.Date(2015, 1, 8, 19, 0, 0, 0, time.UTC),
+		time.Date(2015, 1, 13, 19, 0, 0, 0, time.UTC),
+	}
+	if len(occurrences) != len(expected) {
+		t.Fatalf("Expected %d occurrences, got %d", len(expected), len(occurrences))
+	}
+	for i, o := range occurrences {
+		if !o.Equal(expected[i]) {
+			t.Errorf("Occurrence %d expected %s, got %s", i, expected[i], o)
+		}
+	}
+
+	// Only the next occurrence after a given time
+	r, _ = ParseRecurrenceRule("FREQ=MONTHLY;UNTIL=20150630")
+	occurrences = r.Occurrences(start, time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC), 1)
+	if len(occurrences) != 1 ||
+		!occurrences[0].Equal(time.Date(2015, 3, 6, 19, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected next monthly occurrence: %+v", occurrences)
+	}
+
+	// Nothing after UNTIL
+	occurrences = r.Occurrences(start, time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC), 1)
+	if len(occurrences) != 0 {
+		t.Errorf("Expected no occurrences after UNTIL, got %+v", occurrences)
+	}
+
+	// Months without the start day are skipped
+	start = time.Date(2015, 1, 31, 19, 0, 0, 0, time.UTC)
+	r, _ = ParseRecurrenceRule("FREQ=MONTHLY")
+	occurrences = r.Occurrences(start, start, 1)
+	if len(occurrences) != 1 ||
+		!occurrences[0].Equal(time.Date(2015, 3, 31, 19, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected February to be skipped, got %+v", occurrences)
+	}
+}
+
+func TestRecurrenceOccurrencesAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("Time zone data not available: %+v", err)
+	}
+
+	// 19:00 local on the Tuesday before the clocks go forward
+	start := time.Date(2015, 3, 24, 19, 0, 0, 0, loc)
+
+	r, _ := ParseRecurrenceRule("FREQ=WEEKLY")
+	occurrences := r.Occurrences(start, start, 1)
+	if len(occurrences) != 1 {
+		t.Fatalf("Expected 1 occurrence, got %d", len(occurrences))
+	}
+
+	if occurrences[0].Hour() != 19 {
+		t.Errorf("Expected 19:00 local after DST, got %s", occurrences[0])
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -26,7 +27,244 @@ const (
 
 type EventsType struct {
 	Events h.ArrayType    `json:"events"`
-	Meta   h.CoreMetaType `json:"meta"`
+	Meta   EventsMetaType `json:"meta"`
+}
+
+// EventsMetaType is the meta of a list of events, Order is the order that
+// the list is sorted by
+type EventsMetaType struct {
+	Order string `json:"order,omitempty"`
+	h.CoreMetaType
+}
+
+// EventsNearType restricts a list of events to those within RadiusKm of a
+// point. A RadiusKm of zero does not restrict the list.
+type EventsNearType struct {
+	Lat      float64
+	Lon      float64
+	RadiusKm float64
+}
+
+// defaultEventsNearRadiusKm is used when a point is given without a radius
+const defaultEventsNearRadiusKm float64 = 5
+
+// maxEventsNearRadiusKm is half of the circumference of the Earth, beyond
+// which every event is near
+const maxEventsNearRadiusKm float64 = 20038
+
+// ParseEventsNear reads the lat, lon and radius (in kilometres) query string
+// arguments. Both lat and lon are required if either is given, and the radius
+// defaults to 5km.
+func ParseEventsNear(query url.Values) (EventsNearType, int, error) {
+	near := EventsNearType{}
+
+	if query.Get("lat") == "" && query.Get("lon") == "" {
+		if query.Get("radius") != "" {
+			return EventsNearType{}, http.StatusBadRequest,
+				errors.New("radius requires lat and lon")
+		}
+		return near, http.StatusOK, nil
+	}
+
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return EventsNearType{}, http.StatusBadRequest, errors.New(
+			fmt.Sprintf("lat (%s) is not a latitude.", query.Get("lat")),
+		)
+	}
+
+	lon, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return EventsNearType{}, http.StatusBadRequest, errors.New(
+			fmt.Sprintf("lon (%s) is not a longitude.", query.Get("lon")),
+		)
+	}
+
+	radius := defaultEventsNearRadiusKm
+	if query.Get("radius") != "" {
+		radius, err = strconv.ParseFloat(query.Get("radius"), 64)
+		if err != nil || radius <= 0 || radius > maxEventsNearRadiusKm {
+			return EventsNearType{}, http.StatusBadRequest, errors.New(
+				fmt.Sprintf(
+					"radius (%s) must be a number of kilometres greater "+
+						"than zero and no more than %.0f.",
+					query.Get("radius"),
+					maxEventsNearRadiusKm,
+				),
+			)
+		}
+	}
+
+	near.Lat = lat
+	near.Lon = lon
+	near.RadiusKm = radius
+
+	return near, http.StatusOK, nil
+}
+
+// EventsWhenType restricts a list of events by when they happen, for calendar
+// views. The zero value does not restrict the list.
+type EventsWhenType struct {
+	// Upcoming excludes events that have finished, events that are in
+	// progress are still upcoming
+	Upcoming bool
+
+	// From and To exclude events that start outside of the range, either
+	// may be zero for an open range
+	From time.Time
+	To   time.Time
+
+	// ExcludeProposed excludes events that do not yet have a start time,
+	// these are always excluded when filtering by time
+	ExcludeProposed bool
+}
+
+// ByWhen returns true if the events should be ordered by when they start
+func (w EventsWhenType) ByWhen() bool {
+	return w.Upcoming || !w.From.IsZero() || !w.To.IsZero()
+}
+
+// The orders that a list of events may be sorted by
+const (
+	// EventsOrderActivity puts sticky events first, then the most recently
+	// active
+	EventsOrderActivity string = "activity"
+
+	// EventsOrderWhen is chronological, proposed events that do not yet have
+	// a start time are last
+	EventsOrderWhen string = "when"
+
+	// EventsOrderCreated puts the newest events first
+	EventsOrderCreated string = "created"
+
+	// EventsOrderDistance puts the nearest events first. It cannot be
+	// requested, nearby events are sorted by distance unless another order
+	// is requested.
+	EventsOrderDistance string = "distance"
+)
+
+// EventsOrderFor returns the order that GetEvents sorts the events by for the
+// filters and requested order
+func EventsOrderFor(near EventsNearType, when EventsWhenType, order string) string {
+	order, _ = eventsOrder(near, when, order)
+	return order
+}
+
+// eventsOrder returns the order that the events are sorted by and the ORDER BY
+// clause that sorts them. An explicit order replaces the distance ordering of
+// nearby events, and sticky events are only put first when ordered by
+// activity. Filtering by time orders events by when they start, after
+// distance for nearby events.
+func eventsOrder(
+	near EventsNearType,
+	when EventsWhenType,
+	order string,
+) (
+	string,
+	string,
+) {
+	switch order {
+	case EventsOrderActivity:
+		return order, `f.item_is_sticky DESC
+         ,f.last_modified DESC`
+	case EventsOrderWhen:
+		return order, `e."when" ASC
+         ,f.item_id ASC`
+	case EventsOrderCreated:
+		return order, `e.created DESC
+         ,f.item_id DESC`
+	}
+
+	if near.RadiusKm > 0 {
+		if when.ByWhen() {
+			return EventsOrderDistance, `d.distance_km ASC
+         ,e."when" ASC`
+		}
+		return EventsOrderDistance, `d.distance_km ASC
+         ,f.last_modified DESC`
+	}
+
+	if when.ByWhen() {
+		return EventsOrderWhen, `e."when" ASC
+         ,f.item_id ASC`
+	}
+
+	return EventsOrderActivity, `f.item_is_sticky DESC
+         ,f.last_modified DESC`
+}
+
+// ParseEventsOrder reads the order query string argument, which must be one
+// of the EventsOrder constants. An empty string is returned if no order was
+// requested, in which case GetEvents chooses one to suit the filters.
+func ParseEventsOrder(query url.Values) (string, int, error) {
+	order := query.Get("order")
+
+	switch order {
+	case "", EventsOrderActivity, EventsOrderWhen, EventsOrderCreated:
+		return order, http.StatusOK, nil
+	default:
+		return "", http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"order (%s) must be one of %s, %s or %s.",
+				order,
+				EventsOrderActivity,
+				EventsOrderWhen,
+				EventsOrderCreated,
+			),
+		)
+	}
+}
+
+// ParseEventsWhen reads the upcoming, from, to and proposed query string
+// arguments. From and to are RFC3339 timestamps, and proposed=false excludes
+// events that do not yet have a start time.
+func ParseEventsWhen(query url.Values) (EventsWhenType, int, error) {
+	w := EventsWhenType{}
+
+	if query.Get("upcoming") != "" {
+		upcoming, err := strconv.ParseBool(query.Get("upcoming"))
+		if err != nil {
+			return EventsWhenType{}, http.StatusBadRequest, errors.New(
+				fmt.Sprintf("upcoming (%s) is not a boolean.", query.Get("upcoming")),
+			)
+		}
+		w.Upcoming = upcoming
+	}
+
+	for key, t := range map[string]*time.Time{"from": &w.From, "to": &w.To} {
+		if query.Get(key) == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, query.Get(key))
+		if err != nil {
+			return EventsWhenType{}, http.StatusBadRequest, errors.New(
+				fmt.Sprintf(
+					"%s (%s) is not an RFC3339 timestamp.",
+					key,
+					query.Get(key),
+				),
+			)
+		}
+		*t = parsed
+	}
+
+	if !w.From.IsZero() && !w.To.IsZero() && w.To.Before(w.From) {
+		return EventsWhenType{}, http.StatusBadRequest,
+			errors.New("to cannot be before from.")
+	}
+
+	if query.Get("proposed") != "" {
+		proposed, err := strconv.ParseBool(query.Get("proposed"))
+		if err != nil {
+			return EventsWhenType{}, http.StatusBadRequest, errors.New(
+				fmt.Sprintf("proposed (%s) is not a boolean.", query.Get("proposed")),
+			)
+		}
+		w.ExcludeProposed = !proposed
+	}
+
+	return w, http.StatusOK, nil
 }
 
 type EventSummaryType struct {
@@ -36,6 +274,8 @@ type EventSummaryType struct {
 	WhenNullable  pq.NullTime    `json:"-"`
 	When          string         `json:"when,omitempty"`
 	Duration      int64          `json:"duration,omitempty"`
+	EndNullable   pq.NullTime    `json:"-"`
+	End           string         `json:"end,omitempty"`
 	WhereNullable sql.NullString `json:"-"`
 	Where         string         `json:"where,omitempty"`
 	Lat           float64        `json:"lat,omitempty"`
@@ -48,6 +288,26 @@ type EventSummaryType struct {
 	RSVPLimit     int32          `json:"rsvpLimit"`
 	RSVPAttending int32          `json:"rsvpAttend,omitempty"`
 	RSVPSpaces    int32          `json:"rsvpSpaces,omitempty"`
+	RSVPMaybe     int32          `json:"rsvpMaybe,omitempty"`
+	RSVPCheckedIn int32          `json:"rsvpCheckedIn,omitempty"`
+	RSVPMaxGuests int32          `json:"rsvpMaxGuests,omitempty"`
+
+	// RSVPHeadCount is the attending profiles and the guests they bring
+	RSVPHeadCount int32 `json:"rsvpHeadCount,omitempty"`
+
+	// A private event may only be seen by, and RSVP'd to by, the profiles
+	// invited to it
+	IsPrivate bool `json:"isPrivate"`
+
+	RecurrenceNullable sql.NullString `json:"-"`
+	Recurrence         string         `json:"recurrence,omitempty"`
+	NextOccurrence     string         `json:"nextOccurrence,omitempty"`
+	TimezoneNullable   sql.NullString `json:"-"`
+	Timezone           string         `json:"timezone,omitempty"`
+
+	// DistanceKm is the distance from the point of a nearby query, it is only
+	// set on the events returned by such a query and is never cached
+	DistanceKm float64 `json:"distanceKm,omitempty"`
 
 	ItemSummaryMeta
 }
@@ -59,6 +319,8 @@ type EventType struct {
 	WhenNullable  pq.NullTime    `json:"-"`
 	When          string         `json:"when,omitempty"`
 	Duration      int32          `json:"duration,omitempty"`
+	EndNullable   pq.NullTime    `json:"-"`
+	End           string         `json:"end,omitempty"`
 	Where         string         `json:"where,omitempty"`
 	WhereNullable sql.NullString `json:"-"`
 	Lat           float64        `json:"lat,omitempty"`
@@ -71,6 +333,24 @@ type EventType struct {
 	RSVPLimit     int32          `json:"rsvpLimit"`
 	RSVPAttending int32          `json:"rsvpAttend,omitempty"`
 	RSVPSpaces    int32          `json:"rsvpSpaces,omitempty"`
+	RSVPMaybe     int32          `json:"rsvpMaybe,omitempty"`
+	RSVPMaxGuests int32          `json:"rsvpMaxGuests"`
+
+	// RSVPHeadCount is the attending profiles and the guests they bring
+	RSVPHeadCount int32 `json:"rsvpHeadCount,omitempty"`
+
+	// A private event may only be seen by, and RSVP'd to by, the profiles
+	// invited to it
+	IsPrivate bool `json:"isPrivate"`
+
+	RecurrenceNullable sql.NullString `json:"-"`
+	Recurrence         string         `json:"recurrence,omitempty"`
+	Occurrences        []string       `json:"occurrences,omitempty"`
+	TimezoneNullable   sql.NullString `json:"-"`
+	Timezone           string         `json:"timezone,omitempty"`
+
+	// Files such as an agenda or flyer, see AttachToEvent
+	Attachments []AttachmentType `json:"attachments,omitempty"`
 
 	ItemDetailCommentsAndMeta
 }
@@ -136,15 +416,93 @@ func (m *EventType) Validate(
 		m.WhenNullable = pq.NullTime{Time: eventTimestamp, Valid: true}
 	}
 
-	// If no duration is specified, default to 1 hour.
+	m.Timezone = strings.Trim(m.Timezone, ` `)
+	if m.Timezone != `` {
+		loc, err := loadEventLocation(m.Timezone)
+		if err != nil {
+			glog.Infof(`loadEventLocation err for %s, %+v`, m.Timezone, err)
+			return http.StatusBadRequest, errors.New(
+				fmt.Sprintf("Timezone '%s' is not a valid IANA time zone", m.Timezone),
+			)
+		}
+
+		// The canonical name
+		m.Timezone = loc.String()
+		m.TimezoneNullable = sql.NullString{String: m.Timezone, Valid: true}
+
+		if m.WhenNullable.Valid {
+			m.WhenNullable.Time = m.WhenNullable.Time.In(loc)
+		}
+	} else {
+		m.TimezoneNullable = sql.NullString{}
+	}
+
+	// End is an alternative to Duration for events that span several days.
+	// If both are given then End is used and Duration is derived from it, so
+	// that clients which only understand Duration still see how long it is.
+	m.End = strings.Trim(m.End, ` `)
+	if m.End != `` {
+		if !m.WhenNullable.Valid {
+			glog.Info(`End given without when`)
+			return http.StatusBadRequest,
+				errors.New("An event with an end must specify when it starts")
+		}
+
+		end, err := time.Parse(time.RFC3339, m.End)
+		if err != nil {
+			glog.Infof(`time.Parse err for %s, %+v`, m.End, err)
+			return http.StatusBadRequest, err
+		}
+
+		if !end.After(m.WhenNullable.Time) {
+			return http.StatusBadRequest,
+				errors.New("An event must end after it starts")
+		}
+
+		m.EndNullable = pq.NullTime{Time: end, Valid: true}
+		m.Duration = eventDuration(m.WhenNullable.Time, end)
+	} else {
+		m.EndNullable = pq.NullTime{}
+	}
+
+	m.Recurrence = strings.Trim(m.Recurrence, ` `)
+	if m.Recurrence != `` {
+		if !m.WhenNullable.Valid {
+			glog.Info(`Recurrence given without when`)
+			return http.StatusBadRequest,
+				errors.New("A recurring event must specify when it starts")
+		}
+
+		rule, err := ParseRecurrenceRule(m.Recurrence)
+		if err != nil {
+			glog.Infof(`ParseRecurrenceRule err for %s, %+v`, m.Recurrence, err)
+			return http.StatusBadRequest, err
+		}
+
+		// A floating UNTIL is in the same local time as the start
+		rule = rule.InLocation(m.WhenNullable.Time.Location())
+		if !rule.Until.IsZero() && rule.Until.Before(m.WhenNullable.Time) {
+			return http.StatusBadRequest,
+				errors.New("Recurrence UNTIL cannot be before the event starts")
+		}
+
+		m.Recurrence = rule.String()
+		m.RecurrenceNullable = sql.NullString{String: m.Recurrence, Valid: true}
+	} else {
+		m.RecurrenceNullable = sql.NullString{}
+	}
+
+	// If no duration is specified, default to 1 hour. Clients that omit it
+	// send zero, which would otherwise be stored as a zero-length event.
 	// Value is in minutes
-	if m.Duration < 0 {
+	if m.Duration <= 0 {
 		m.Duration = 60 * 1
 	}
 
 	if m.Where != `` {
 		m.Where = ShoutToWhisper(m.Where)
 		m.WhereNullable = sql.NullString{String: m.Where, Valid: true}
+		m.geocode()
 	}
 
 	if m.RSVPLimit < 0 {
@@ -153,6 +511,12 @@ func (m *EventType) Validate(
 			errors.New("RSVPLimit must be 0 (unlimited) or greater")
 	}
 
+	if m.RSVPMaxGuests < 0 {
+		glog.Infof(`RSVPMaxGuests (%d) below zero`, m.RSVPMaxGuests)
+		return http.StatusBadRequest,
+			errors.New("RSVPMaxGuests must be 0 (no guests) or greater")
+	}
+
 	// If a limit is specified, there are initially the same number of
 	// spaces. Otherwise, both will be initialized to zero which
 	// indicates that there is no RSVP limit
@@ -163,6 +527,86 @@ func (m *EventType) Validate(
 	return http.StatusOK, nil
 }
 
+// inEventLocation returns the time in the time zone of the event, so that it
+// is presented (and recurs) in the organiser's local time across DST changes.
+// The time is returned unchanged if the event has no time zone.
+func inEventLocation(t time.Time, timezone sql.NullString) time.Time {
+	if !timezone.Valid || timezone.String == `` {
+		return t
+	}
+
+	loc, err := loadEventLocation(timezone.String)
+	if err != nil {
+		glog.Warningf("loadEventLocation(%s) %+v", timezone.String, err)
+		return t
+	}
+
+	return t.In(loc)
+}
+
+// loadEventLocation returns the IANA time zone with the name. Unlike
+// time.LoadLocation, "" and "Local" are refused as they are the time zone of
+// the server rather than of the event.
+func loadEventLocation(name string) (*time.Location, error) {
+	if name == `` || name == `Local` {
+		return nil, errors.New(
+			fmt.Sprintf("%q is not an IANA time zone", name),
+		)
+	}
+
+	return time.LoadLocation(name)
+}
+
+// eventDuration returns the minutes from start to end, rounded up so that an
+// event never appears to finish before its end
+func eventDuration(start time.Time, end time.Time) int32 {
+	return int32((end.Sub(start) + time.Minute - 1) / time.Minute)
+}
+
+// setOccurrences populates the upcoming occurrences of a recurring event.
+// These depend on the current time and so are never cached.
+func (m *EventType) setOccurrences(now time.Time) {
+	m.Occurrences = []string{}
+	if !m.RecurrenceNullable.Valid || !m.WhenNullable.Valid {
+		return
+	}
+
+	rule, err := ParseRecurrenceRule(m.RecurrenceNullable.String)
+	if err != nil {
+		glog.Warningf("ParseRecurrenceRule(%s) %+v", m.RecurrenceNullable.String, err)
+		return
+	}
+
+	// An occurrence that is in progress is still upcoming
+	after := now.Add(-time.Duration(m.Duration) * time.Minute)
+	start := inEventLocation(m.WhenNullable.Time, m.TimezoneNullable)
+	for _, t := range rule.Occurrences(start, after, eventOccurrencesShown) {
+		m.Occurrences = append(m.Occurrences, t.Format(time.RFC3339Nano))
+	}
+}
+
+// setNextOccurrence populates the next occurrence of a recurring event. This
+// depends on the current time and so is never cached.
+func (m *EventSummaryType) setNextOccurrence(now time.Time) {
+	m.NextOccurrence = ``
+	if !m.RecurrenceNullable.Valid || !m.WhenNullable.Valid {
+		return
+	}
+
+	rule, err := ParseRecurrenceRule(m.RecurrenceNullable.String)
+	if err != nil {
+		glog.Warningf("ParseRecurrenceRule(%s) %+v", m.RecurrenceNullable.String, err)
+		return
+	}
+
+	after := now.Add(-time.Duration(m.Duration) * time.Minute)
+	start := inEventLocation(m.WhenNullable.Time, m.TimezoneNullable)
+	occurrences := rule.Occurrences(start, after, 1)
+	if len(occurrences) > 0 {
+		m.NextOccurrence = occurrences[0].Format(time.RFC3339Nano)
+	}
+}
+
 func (m *EventType) FetchProfileSummaries(siteId int64) (int, error) {
 
 	profile, status, err := GetProfileSummary(siteId, m.Meta.CreatedById)
@@ -242,8 +686,9 @@ func IsAttending(profileId int64, eventId int64) (bool, error) {
 SELECT profile_id
   FROM attendees
  WHERE event_id = $1
-   AND state_id = 1`,
+   AND state_id = $2`,
 			eventId,
+			RsvpStates[RsvpYes],
 		)
 		if err != nil {
 			return false, err
@@ -255,7 +700,7 @@ SELECT profile_id
 			attendeeIds = append(attendeeIds, attendeeId)
 		}
 
-		c.CacheSetInt64Slice(key, attendeeIds, mcTtl)
+		c.CacheSetInt64Slice(key, attendeeIds, cacheTtl(h.ItemTypeEvent))
 	}
 
 	for _, Id := range attendeeIds {
@@ -293,6 +738,50 @@ func (m *EventSummaryType) GetAttending(profileId int64) (int, error) {
 	return http.StatusOK, nil
 }
 
+// dupeKey identifies an event submission so that a repeated identical
+// submission within the dupe window returns the original event. Floats are
+// formatted at a fixed precision so that the key is stable.
+func (m *EventType) dupeKey() string {
+	var when, end string
+	if m.WhenNullable.Valid {
+		when = m.WhenNullable.Time.UTC().Format(time.RFC3339)
+	}
+	if m.EndNullable.Valid {
+		end = m.EndNullable.Time.UTC().Format(time.RFC3339)
+	}
+
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 6, 64)
+	}
+
+	return "dupe_" + h.Md5sum(
+		strings.Join(
+			[]string{
+				strconv.FormatInt(m.MicrocosmId, 10),
+				m.Title,
+				when,
+				end,
+				strconv.FormatInt(int64(m.Duration), 10),
+				m.Where,
+				formatFloat(m.Lat),
+				formatFloat(m.Lon),
+				formatFloat(m.North),
+				formatFloat(m.East),
+				formatFloat(m.South),
+				formatFloat(m.West),
+				m.Status,
+				strconv.FormatInt(int64(m.RSVPLimit), 10),
+				strconv.FormatInt(int64(m.RSVPMaxGuests), 10),
+				m.Recurrence,
+				m.Timezone,
+				strconv.FormatBool(m.IsPrivate),
+				strconv.FormatInt(m.Meta.CreatedById, 10),
+			},
+			"|",
+		),
+	)
+}
+
 func (m *EventType) Insert(siteId int64, profileId int64) (int, error) {
 
 	status, err := m.Validate(siteId, profileId, false)
@@ -300,33 +789,7 @@ func (m *EventType) Insert(siteId int64, profileId int64) (int, error) {
 		return status, err
 	}
 
-	var (
-		when  string
-		where string
-	)
-
-	if m.WhenNullable.Valid {
-		when = m.WhenNullable.Time.String()
-	}
-	if m.WhereNullable.Valid {
-		where = m.WhereNullable.String
-	}
-
-	dupeKey := "dupe_" + h.Md5sum(
-		strconv.FormatInt(m.MicrocosmId, 10)+
-			m.Title+
-			when+
-			where+
-			fmt.Sprintf("%b", m.Lat)+
-			fmt.Sprintf("%b", m.Lon)+
-			fmt.Sprintf("%b", m.North)+
-			fmt.Sprintf("%b", m.East)+
-			fmt.Sprintf("%b", m.South)+
-			fmt.Sprintf("%b", m.West)+
-			m.Status+
-			fmt.Sprintf("%d", m.RSVPLimit)+
-			strconv.FormatInt(m.Meta.CreatedById, 10),
-	)
+	dupeKey := m.dupeKey()
 
 	v, ok := c.CacheGetInt64(dupeKey)
 	if ok {
@@ -340,6 +803,11 @@ func (m *EventType) Insert(siteId int64, profileId int64) (int, error) {
 	}
 	defer tx.Rollback()
 
+	status, err = checkEventRateLimit(tx, siteId, profileId, m.MicrocosmId)
+	if err != nil {
+		return status, err
+	}
+
 	var insertId int64
 
 	err = tx.QueryRow(`
@@ -347,12 +815,14 @@ INSERT INTO events (
     microcosm_id, title, created, created_by, "when",
     duration, "where", lat, lon, bounds_north,
     bounds_east, bounds_south, bounds_west, status, rsvp_limit,
-    rsvp_spaces
+    rsvp_spaces, recurrence, timezone, is_private, rsvp_max_guests,
+    "end"
 ) VALUES (
     $1, $2, $3, $4, $5,
     $6, $7, $8, $9, $10,
     $11, $12, $13, $14, $15,
-    $16
+    $16, $17, $18, $19, $20,
+    $21
 ) RETURNING event_id`,
 		m.MicrocosmId,
 		m.Title,
@@ -370,6 +840,11 @@ INSERT INTO events (
 		m.Status,
 		m.RSVPLimit,
 		m.RSVPSpaces,
+		m.RecurrenceNullable,
+		m.TimezoneNullable,
+		m.IsPrivate,
+		m.RSVPMaxGuests,
+		m.EndNullable,
 	).Scan(
 		&insertId,
 	)
@@ -433,6 +908,11 @@ UPDATE events
       ,bounds_west = $15
       ,status = $16
       ,rsvp_limit = $17
+      ,recurrence = $18
+      ,timezone = $19
+      ,is_private = $20
+      ,rsvp_max_guests = $21
+      ,"end" = $22
  WHERE event_id = $1`,
 
 		m.Id,
@@ -455,6 +935,11 @@ UPDATE events
 
 		m.Status,
 		m.RSVPLimit,
+		m.RecurrenceNullable,
+		m.TimezoneNullable,
+		m.IsPrivate,
+		m.RSVPMaxGuests,
+		m.EndNullable,
 	)
 	if err != nil {
 		tx.Rollback()
@@ -484,26 +969,48 @@ UPDATE events
 
 func (m *EventType) UpdateAttendees(tx *sql.Tx) (int, error) {
 
-	_, err := tx.Exec(`
+	// Only confirmed attendees and their guests consume a space, those who
+	// have said "maybe" are counted separately to help organisers plan
+	var rsvpLimit, attending, guests, maybe int32
+	err := tx.QueryRow(`
+SELECT e.rsvp_limit
+      ,COUNT(a.*) FILTER (WHERE a.state_id = $2) AS attending
+      ,COALESCE(SUM(a.guests) FILTER (WHERE a.state_id = $2), 0) AS guests
+      ,COUNT(a.*) FILTER (WHERE a.state_id = $3) AS maybe
+  FROM events e
+       LEFT OUTER JOIN attendees a ON e.event_id = a.event_id
+ WHERE e.event_id = $1
+ GROUP BY e.event_id`,
+		m.Id,
+		RsvpStates[RsvpYes],
+		RsvpStates[RsvpMaybe],
+	).Scan(
+		&rsvpLimit,
+		&attending,
+		&guests,
+		&maybe,
+	)
+	if err != nil {
+		tx.Rollback()
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Count of event attendees failed: %v", err.Error()),
+		)
+	}
+
+	heads := attending + guests
+
+	_, err = tx.Exec(`
 UPDATE events
-   SET rsvp_attending = att.attending
-      ,rsvp_spaces = CASE rsvp_limit WHEN 0 THEN 0 ELSE (rsvp_limit - att.attending) END
-  FROM (
-        SELECT e.event_id
-              ,a.state_id
-              ,COUNT(a.*) as attending
-          FROM events e
-               LEFT OUTER JOIN (
-                     SELECT *
-                       FROM attendees
-                      WHERE state_id = 1
-               ) a ON e.event_id = a.event_id
-         WHERE e.event_id = $1
-         GROUP BY e.event_id
-                 ,a.state_id
-       ) AS att
- WHERE events.event_id = att.event_id`,
+   SET rsvp_attending = $2
+      ,rsvp_maybe = $3
+      ,rsvp_head_count = $4
+      ,rsvp_spaces = $5
+ WHERE event_id = $1`,
 		m.Id,
+		attending,
+		maybe,
+		heads,
+		rsvpSpaces(rsvpLimit, heads),
 	)
 	if err != nil {
 		tx.Rollback()
@@ -633,7 +1140,33 @@ UPDATE events
 	return http.StatusOK, nil
 }
 
+// GetEvent returns the event, a private event is not found by profiles that
+// may not see it
 func GetEvent(siteId int64, id int64, profileId int64) (EventType, int, error) {
+	m, status, err := getEvent(siteId, id, profileId)
+	if err != nil {
+		return EventType{}, status, err
+	}
+
+	if m.IsPrivate {
+		ok, err := canSeePrivateEvent(siteId, m.Id, m.Meta.CreatedById, profileId)
+		if err != nil {
+			glog.Errorf("canSeePrivateEvent(%d, %d) %+v", m.Id, profileId, err)
+			return EventType{}, http.StatusInternalServerError,
+				errors.New("Database query failed")
+		}
+		if !ok {
+			return EventType{}, http.StatusNotFound,
+				errors.New("Event not found")
+		}
+	}
+
+	return m, status, nil
+}
+
+// getEvent returns the event whether or not it is private, it is used when
+// the profile has already been authorised
+func getEvent(siteId int64, id int64, profileId int64) (EventType, int, error) {
 
 	if id == 0 {
 		return EventType{}, http.StatusNotFound, errors.New("Event not found")
@@ -660,6 +1193,8 @@ func GetEvent(siteId int64, id int64, profileId int64) (EventType, int, error) {
 			return EventType{}, status, err
 		}
 
+		m.setOccurrences(time.Now())
+
 		return m, 0, nil
 	}
 
@@ -703,6 +1238,13 @@ SELECT e.event_id
       ,e.rsvp_attending
 
       ,e.rsvp_spaces
+      ,e.rsvp_maybe
+      ,e.rsvp_max_guests
+      ,e.rsvp_head_count
+      ,e.recurrence
+      ,e.timezone
+      ,e.is_private
+      ,e."end"
   FROM events e
        JOIN flags f ON f.site_id = $2
                    AND f.item_type_id = 9
@@ -748,6 +1290,13 @@ SELECT e.event_id
 		&m.RSVPAttending,
 
 		&m.RSVPSpaces,
+		&m.RSVPMaybe,
+		&m.RSVPMaxGuests,
+		&m.RSVPHeadCount,
+		&m.RecurrenceNullable,
+		&m.TimezoneNullable,
+		&m.IsPrivate,
+		&m.EndNullable,
 	)
 	if err == sql.ErrNoRows {
 		return EventType{}, http.StatusNotFound,
@@ -764,12 +1313,23 @@ SELECT e.event_id
 	if m.Meta.EditedNullable.Valid {
 		m.Meta.Edited = m.Meta.EditedNullable.Time.Format(time.RFC3339Nano)
 	}
+	if m.TimezoneNullable.Valid {
+		m.Timezone = m.TimezoneNullable.String
+	}
 	if m.WhenNullable.Valid {
+		m.WhenNullable.Time = inEventLocation(m.WhenNullable.Time, m.TimezoneNullable)
 		m.When = m.WhenNullable.Time.Format(time.RFC3339Nano)
 	}
+	if m.EndNullable.Valid {
+		m.EndNullable.Time = inEventLocation(m.EndNullable.Time, m.TimezoneNullable)
+		m.End = m.EndNullable.Time.Format(time.RFC3339Nano)
+	}
 	if m.WhereNullable.Valid {
 		m.Where = m.WhereNullable.String
 	}
+	if m.RecurrenceNullable.Valid {
+		m.Recurrence = m.RecurrenceNullable.String
+	}
 
 	m.Meta.Links =
 		[]h.LinkType{
@@ -794,10 +1354,17 @@ SELECT e.event_id
 			),
 		}
 
+	attachments, status, err := getEventAttachments(m.Id)
+	if err != nil {
+		glog.Errorf("getEventAttachments(%d) %+v", m.Id, err)
+		return EventType{}, status, err
+	}
+	m.Attachments = attachments
+
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeEvent))
 
-	status, err := m.FetchProfileSummaries(siteId)
+	status, err = m.FetchProfileSummaries(siteId)
 	if err != nil {
 		glog.Errorf("m.FetchProfileSummaries(%d) %+v", siteId, err)
 		return EventType{}, status, err
@@ -808,9 +1375,13 @@ SELECT e.event_id
 		return EventType{}, status, err
 	}
 
+	m.setOccurrences(time.Now())
+
 	return m, http.StatusOK, nil
 }
 
+// GetEventSummary returns the summary of the event, a private event is not
+// found by profiles that may not see it
 func GetEventSummary(
 	siteId int64,
 	id int64,
@@ -820,6 +1391,36 @@ func GetEventSummary(
 	int,
 	error,
 ) {
+	m, status, err := getEventSummary(siteId, id, profileId)
+	if err != nil {
+		return EventSummaryType{}, status, err
+	}
+
+	if m.IsPrivate {
+		ok, err := canSeePrivateEvent(siteId, m.Id, m.Meta.CreatedById, profileId)
+		if err != nil {
+			glog.Errorf("canSeePrivateEvent(%d, %d) %+v", m.Id, profileId, err)
+			return EventSummaryType{}, http.StatusInternalServerError,
+				errors.New("Database query failed")
+		}
+		if !ok {
+			return EventSummaryType{}, http.StatusNotFound,
+				errors.New("Event not found")
+		}
+	}
+
+	return m, status, nil
+}
+
+func getEventSummary(
+	siteId int64,
+	id int64,
+	profileId int64,
+) (
+	EventSummaryType,
+	int,
+	error,
+) {
 
 	if id == 0 {
 		return EventSummaryType{}, http.StatusNotFound,
@@ -844,9 +1445,84 @@ func GetEventSummary(
 			return EventSummaryType{}, status, err
 		}
 
+		m.setNextOccurrence(time.Now())
+
 		return m, http.StatusOK, nil
 	}
 
+	val, status, err := c.CacheLoadOnce(
+		mcKey,
+		func() (interface{}, int, error) {
+			m, status, err := loadEventSummary(id, mcKey)
+			return m, status, err
+		},
+	)
+	if err != nil {
+		return EventSummaryType{}, status, err
+	}
+	m := val.(EventSummaryType)
+
+	status, err = m.FetchProfileSummaries(siteId)
+	if err != nil {
+		glog.Errorf("m.FetchProfileSummaries(%d) %+v", siteId, err)
+		return EventSummaryType{}, status, err
+	}
+
+	status, err = m.GetAttending(profileId)
+	if err != nil {
+		glog.Errorf("m.GetAttending(%d) %+v", profileId, err)
+		return EventSummaryType{}, status, err
+	}
+
+	m.setNextOccurrence(time.Now())
+
+	return m, http.StatusOK, nil
+}
+
+// getEventCommentCount returns the number of visible comments on the event.
+// Counting is expensive for events with many comments, so the count is cached
+// until a comment on the event is created, deleted or moderated.
+func getEventCommentCount(id int64) (int64, error) {
+	mcKey := fmt.Sprintf(mcEventCountKeys[c.CacheCounts], id)
+	if count, ok := c.CacheGetInt64(mcKey); ok {
+		return count, nil
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = db.QueryRow(`--getEventCommentCount
+SELECT COUNT(*)
+  FROM flags
+ WHERE parent_item_type_id = 9
+   AND parent_item_id = $1
+   AND item_is_deleted IS NOT TRUE
+   AND item_is_moderated IS NOT TRUE`,
+		id,
+	).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	c.CacheSetInt64(mcKey, count, cacheTtl(h.ItemTypeEvent))
+
+	return count, nil
+}
+
+// loadEventSummary fetches an event summary from the database and caches it,
+// the parts of the summary that depend on the site or profile are not fetched
+func loadEventSummary(
+	id int64,
+	mcKey string,
+) (
+	EventSummaryType,
+	int,
+	error,
+) {
+
 	// Open db connection and retrieve resource
 	db, err := h.GetConnection()
 	if err != nil {
@@ -883,12 +1559,18 @@ SELECT event_id
       ,rsvp_limit
       ,rsvp_attending
       ,rsvp_spaces
-      ,(SELECT COUNT(*) AS total_comments
-          FROM flags
-         WHERE parent_item_type_id = 9
-           AND parent_item_id = $1
-           AND item_is_deleted IS NOT TRUE
-           AND item_is_moderated IS NOT TRUE) AS comment_count
+      ,rsvp_maybe
+      ,rsvp_max_guests
+      ,rsvp_head_count
+      ,(SELECT COUNT(*)
+          FROM attendees
+         WHERE event_id = $1
+           AND state_id = 1
+           AND checked_in IS NOT NULL) AS checked_in
+      ,recurrence
+      ,timezone
+      ,is_private
+      ,"end"
       ,view_count
  FROM events
 WHERE event_id = $1
@@ -922,7 +1604,14 @@ WHERE event_id = $1
 		&m.RSVPLimit,
 		&m.RSVPAttending,
 		&m.RSVPSpaces,
-		&m.CommentCount,
+		&m.RSVPMaybe,
+		&m.RSVPMaxGuests,
+		&m.RSVPHeadCount,
+		&m.RSVPCheckedIn,
+		&m.RecurrenceNullable,
+		&m.TimezoneNullable,
+		&m.IsPrivate,
+		&m.EndNullable,
 		&m.ViewCount,
 	)
 	if err == sql.ErrNoRows {
@@ -930,19 +1619,39 @@ WHERE event_id = $1
 			errors.New(fmt.Sprintf("Event with ID %d not found", id))
 
 	} else if err != nil {
-		glog.Errorf("db.QueryRow(%d, %d) %+v", siteId, id, err)
+		glog.Errorf("db.QueryRow(%d) %+v", id, err)
 		return EventSummaryType{}, http.StatusInternalServerError,
 			errors.New("Database query failed")
 	}
 
+	if m.TimezoneNullable.Valid {
+		m.Timezone = m.TimezoneNullable.String
+	}
+
 	if m.WhenNullable.Valid {
+		m.WhenNullable.Time = inEventLocation(m.WhenNullable.Time, m.TimezoneNullable)
 		m.When = m.WhenNullable.Time.Format(time.RFC3339Nano)
 	}
+	if m.EndNullable.Valid {
+		m.EndNullable.Time = inEventLocation(m.EndNullable.Time, m.TimezoneNullable)
+		m.End = m.EndNullable.Time.Format(time.RFC3339Nano)
+	}
 
 	if m.WhereNullable.Valid {
 		m.Where = m.WhereNullable.String
 	}
 
+	if m.RecurrenceNullable.Valid {
+		m.Recurrence = m.RecurrenceNullable.String
+	}
+
+	m.CommentCount, err = getEventCommentCount(m.Id)
+	if err != nil {
+		glog.Errorf("getEventCommentCount(%d) %+v", m.Id, err)
+		return EventSummaryType{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+
 	lastComment, status, err :=
 		GetLastComment(h.ItemTypes[h.ItemTypeEvent], m.Id)
 	if err != nil {
@@ -967,27 +1676,44 @@ WHERE event_id = $1
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
-
-	status, err = m.FetchProfileSummaries(siteId)
-	if err != nil {
-		glog.Errorf("m.FetchProfileSummaries(%d) %+v", siteId, err)
-		return EventSummaryType{}, status, err
-	}
-
-	status, err = m.GetAttending(profileId)
-	if err != nil {
-		glog.Errorf("m.GetAttending(%d) %+v", profileId, err)
-		return EventSummaryType{}, status, err
-	}
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeEvent))
 
 	return m, http.StatusOK, nil
 }
 
+// sqlEventNotFinished returns a SQL condition on the events table that is true
+// until the event has ended. A recurring event has not ended until its last
+// occurrence, which is only known to the database for rules with an UNTIL
+// (always stored as a UTC date-time), so series bounded by COUNT or without
+// end are never considered finished.
+func sqlEventNotFinished(alias string) string {
+	return `(
+           COALESCE(
+               ` + alias + `."end",
+               ` + alias + `."when" + (` + alias + `.duration * INTERVAL '1 minute')
+           ) >= NOW()
+        OR (
+               ` + alias + `.recurrence IS NOT NULL
+           AND COALESCE(
+                   (
+                       TO_TIMESTAMP(
+                           SUBSTRING(` + alias + `.recurrence FROM 'UNTIL=([0-9]{8}T[0-9]{6})Z'),
+                           'YYYYMMDD"T"HH24MISS'
+                       )::TIMESTAMP AT TIME ZONE 'UTC'
+                   ) + (` + alias + `.duration * INTERVAL '1 minute'),
+                   'infinity'
+               ) >= NOW()
+           )
+       )`
+}
+
 func GetEvents(
 	siteId int64,
 	profileId int64,
 	attending bool,
+	near EventsNearType,
+	when EventsWhenType,
+	order string,
 	limit int64,
 	offset int64,
 ) (
@@ -1010,11 +1736,77 @@ func GetEvents(
    AND is_attending(item_id, $3)`
 	}
 
+	args := []interface{}{
+		siteId,
+		h.ItemTypes[h.ItemTypeEvent],
+		profileId,
+		limit,
+		offset,
+	}
+
+	// Nearby events are ordered by their distance from the point, which is
+	// calculated using the haversine formula. Events without a location are
+	// stored at 0,0 and are excluded.
+	selectDistance := `
+      ,NULL::DOUBLE PRECISION AS distance_km`
+	var joinNear, whereNear string
+	if near.RadiusKm > 0 {
+		selectDistance = `
+      ,d.distance_km`
+		joinNear = `
+  JOIN (
+           SELECT event_id
+                 ,6371 * 2 * ASIN(LEAST(1, SQRT(
+                      POWER(SIN(RADIANS(lat - $6) / 2), 2) +
+                      COS(RADIANS($6)) * COS(RADIANS(lat)) *
+                      POWER(SIN(RADIANS(lon - $7) / 2), 2)
+                  ))) AS distance_km
+             FROM events
+            WHERE lat IS NOT NULL
+              AND lon IS NOT NULL
+              AND NOT (lat = 0 AND lon = 0)
+       ) d ON d.event_id = f.item_id`
+		whereNear = `
+   AND d.distance_km <= $8`
+		args = append(args, near.Lat, near.Lon, near.RadiusKm)
+	}
+
+	// Filtering by time orders events by when they start, after distance for
+	// nearby events
+	var joinWhen, whereWhen string
+	if when.ByWhen() || when.ExcludeProposed ||
+		order == EventsOrderWhen || order == EventsOrderCreated {
+
+		joinWhen = `
+  JOIN events e ON e.event_id = f.item_id`
+	}
+	if when.ByWhen() || when.ExcludeProposed {
+		whereWhen = `
+   AND e."when" IS NOT NULL`
+	}
+	if when.Upcoming {
+		whereWhen += `
+   AND ` + sqlEventNotFinished("e")
+	}
+	if !when.From.IsZero() {
+		args = append(args, when.From)
+		whereWhen += `
+   AND e."when" >= $` + strconv.Itoa(len(args))
+	}
+	if !when.To.IsZero() {
+		args = append(args, when.To)
+		whereWhen += `
+   AND e."when" <= $` + strconv.Itoa(len(args))
+	}
+
+	_, orderBy := eventsOrder(near, when, order)
+
 	rows, err := db.Query(`--GetEvents
 WITH m AS (
     SELECT m.microcosm_id
       FROM microcosms m
       LEFT JOIN ignores i ON i.profile_id = $3
+                         AND (i.expires IS NULL OR i.expires > NOW())
                          AND i.item_type_id = 2
                          AND i.item_id = m.microcosm_id
      WHERE i.profile_id IS NULL
@@ -1022,9 +1814,10 @@ WITH m AS (
 )
 SELECT COUNT(*) OVER() AS total
       ,f.item_id
-	  ,f.is_attending(f.item_id, $3)
-  FROM flags f
+	  ,f.is_attending(f.item_id, $3)`+selectDistance+`
+  FROM flags f`+joinNear+joinWhen+`
   LEFT JOIN ignores i ON i.profile_id = $3
+                     AND (i.expires IS NULL OR i.expires > NOW())
                      AND i.item_type_id = f.item_type_id
                      AND i.item_id = f.item_id
  WHERE f.site_id = $1
@@ -1035,17 +1828,13 @@ SELECT COUNT(*) OVER() AS total
    AND f.parent_is_deleted IS NOT TRUE
    AND f.parent_is_moderated IS NOT TRUE
    AND f.item_is_deleted IS NOT TRUE
-   AND f.item_is_moderated IS NOT TRUE`+whereAttending+`
+   AND f.item_is_moderated IS NOT TRUE`+whereAttending+whereNear+whereWhen+`
+   AND `+sqlCanSeeEvent(`f.item_type_id`, `f.item_id`, `$3`)+`
    AND f.microcosm_id IN (SELECT * FROM m)
- ORDER BY f.item_is_sticky DESC
-         ,f.last_modified DESC
+ ORDER BY `+orderBy+`
  LIMIT $4
 OFFSET $5`,
-		siteId,
-		h.ItemTypes[h.ItemTypeEvent],
-		profileId,
-		limit,
-		offset,
+		args...,
 	)
 	if err != nil {
 		return []EventSummaryType{}, 0, 0, http.StatusInternalServerError,
@@ -1062,11 +1851,13 @@ OFFSET $5`,
 		var (
 			id          int64
 			isAttending bool
+			distanceKm  sql.NullFloat64
 		)
 		err = rows.Scan(
 			&total,
 			&id,
 			&isAttending,
+			&distanceKm,
 		)
 		if err != nil {
 			return []EventSummaryType{}, 0, 0, http.StatusInternalServerError,
@@ -1081,6 +1872,9 @@ OFFSET $5`,
 		}
 
 		m.Meta.Flags.Attending = isAttending
+		if distanceKm.Valid {
+			m.DistanceKm = distanceKm.Float64
+		}
 		ems = append(ems, m)
 	}
 	err = rows.Err()
@@ -1106,3 +1900,145 @@ OFFSET $5`,
 
 	return ems, total, pages, http.StatusOK, nil
 }
+
+// GetEventsForProfile returns the events that the profile is attending, as
+// seen by the viewer, ordered by when they happen. If upcomingOnly is true
+// only events that have not yet finished are returned, soonest first,
+// otherwise all events are returned with the most recent first.
+func GetEventsForProfile(
+	siteId int64,
+	profileId int64,
+	viewerId int64,
+	upcomingOnly bool,
+	limit int64,
+	offset int64,
+) (
+	[]EventSummaryType,
+	int64,
+	int64,
+	int,
+	error,
+) {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return []EventSummaryType{}, 0, 0, http.StatusInternalServerError, err
+	}
+
+	var whereUpcoming string
+	orderBy := `e."when" DESC NULLS LAST`
+	if upcomingOnly {
+		whereUpcoming = `
+   AND ` + sqlEventNotFinished("e")
+		orderBy = `e."when" ASC`
+	}
+
+	rows, err := db.Query(`--GetEventsForProfile
+WITH m AS (
+    SELECT m.microcosm_id
+      FROM microcosms m
+      LEFT JOIN ignores i ON i.profile_id = $3
+                         AND (i.expires IS NULL OR i.expires > NOW())
+                         AND i.item_type_id = 2
+                         AND i.item_id = m.microcosm_id
+     WHERE i.profile_id IS NULL
+       AND (get_effective_permissions(m.site_id, m.microcosm_id, 2, m.microcosm_id, $3)).can_read IS TRUE
+)
+SELECT COUNT(*) OVER() AS total
+      ,f.item_id
+      ,is_attending(f.item_id, $3)
+  FROM flags f
+  JOIN events e ON e.event_id = f.item_id
+  JOIN attendees a ON a.event_id = e.event_id
+                  AND a.profile_id = $4
+                  AND a.state_id = $5
+  LEFT JOIN ignores i ON i.profile_id = $3
+                     AND (i.expires IS NULL OR i.expires > NOW())
+                     AND i.item_type_id = f.item_type_id
+                     AND i.item_id = f.item_id
+ WHERE f.site_id = $1
+   AND i.profile_id IS NULL
+   AND f.item_type_id = $2
+   AND f.microcosm_is_deleted IS NOT TRUE
+   AND f.microcosm_is_moderated IS NOT TRUE
+   AND f.parent_is_deleted IS NOT TRUE
+   AND f.parent_is_moderated IS NOT TRUE
+   AND f.item_is_deleted IS NOT TRUE
+   AND f.item_is_moderated IS NOT TRUE`+whereUpcoming+`
+   AND `+sqlCanSeeEvent(`f.item_type_id`, `f.item_id`, `$3`)+`
+   AND f.microcosm_id IN (SELECT * FROM m)
+ ORDER BY `+orderBy+`
+         ,f.item_id ASC
+ LIMIT $6
+OFFSET $7`,
+		siteId,
+		h.ItemTypes[h.ItemTypeEvent],
+		viewerId,
+		profileId,
+		RsvpStates[RsvpYes],
+		limit,
+		offset,
+	)
+	if err != nil {
+		return []EventSummaryType{}, 0, 0, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Database query failed: %v", err.Error()),
+			)
+	}
+	defer rows.Close()
+
+	type eventRow struct {
+		Id          int64
+		IsAttending bool
+	}
+
+	var total int64
+	ids := []eventRow{}
+	for rows.Next() {
+		var row eventRow
+		err = rows.Scan(
+			&total,
+			&row.Id,
+			&row.IsAttending,
+		)
+		if err != nil {
+			return []EventSummaryType{}, 0, 0, http.StatusInternalServerError,
+				errors.New(
+					fmt.Sprintf("Row parsing error: %v", err.Error()),
+				)
+		}
+		ids = append(ids, row)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []EventSummaryType{}, 0, 0, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Error fetching rows: %v", err.Error()),
+			)
+	}
+	rows.Close()
+
+	ems := []EventSummaryType{}
+	for _, row := range ids {
+		m, status, err := GetEventSummary(siteId, row.Id, viewerId)
+		if err != nil {
+			return []EventSummaryType{}, 0, 0, status, err
+		}
+		m.Meta.Flags.Attending = row.IsAttending
+		ems = append(ems, m)
+	}
+
+	pages := h.GetPageCount(total, limit)
+	maxOffset := h.GetMaxOffset(total, limit)
+
+	if offset > maxOffset {
+		return []EventSummaryType{}, 0, 0, http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"not enough records, offset (%d) would return an empty page.",
+				offset,
+			),
+		)
+	}
+
+	return ems, total, pages, http.StatusOK, nil
+}
@@ -0,0 +1,372 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestEventValidateDuration(t *testing.T) {
+	tests := []struct {
+		duration int32
+		expected int32
+	}{
+		{duration: 0, expected: 60},
+		{duration: -30, expected: 60},
+		{duration: 90, expected: 90},
+	}
+
+	for _, test := range tests {
+		m := EventType{}
+		m.MicrocosmId = 1
+		m.Title = "Monthly ride"
+		m.When = "2014-06-01T09:00:00Z"
+		m.Duration = test.duration
+		m.Meta.EditReason = "Testing"
+
+		_, err := m.Validate(1, 1, true)
+		if err != nil {
+			t.Fatalf("Validate() with duration %d failed: %+v", test.duration, err)
+		}
+
+		if m.Duration != test.expected {
+			t.Errorf(
+				"Duration %d validated to %d, expected %d",
+				test.duration,
+				m.Duration,
+				test.expected,
+			)
+		}
+	}
+}
+
+func TestEventValidateEnd(t *testing.T) {
+	tests := []struct {
+		when     string
+		end      string
+		duration int32
+		valid    bool
+		expected int32
+	}{
+		// A three day festival
+		{when: "2014-06-01T09:00:00Z", end: "2014-06-04T09:00:00Z", valid: true, expected: 4320},
+		// End takes precedence over duration
+		{when: "2014-06-01T09:00:00Z", end: "2014-06-01T10:30:00Z", duration: 30, valid: true, expected: 90},
+		// Part minutes are rounded up
+		{when: "2014-06-01T09:00:00Z", end: "2014-06-01T09:00:01Z", valid: true, expected: 1},
+		{when: "2014-06-01T09:00:00Z", end: "2014-06-01T09:00:00Z", valid: false},
+		{when: "2014-06-01T09:00:00Z", end: "2014-05-31T09:00:00Z", valid: false},
+		{when: "", end: "2014-06-01T09:00:00Z", valid: false},
+	}
+
+	for _, test := range tests {
+		m := EventType{}
+		m.MicrocosmId = 1
+		m.Title = "Festival"
+		m.When = test.when
+		m.End = test.end
+		m.Duration = test.duration
+		m.Meta.EditReason = "Testing"
+
+		_, err := m.Validate(1, 1, true)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Expected %s to %s to be invalid", test.when, test.end)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Validate() of %s to %s failed: %+v", test.when, test.end, err)
+		}
+
+		if m.Duration != test.expected {
+			t.Errorf(
+				"%s to %s validated to a duration of %d, expected %d",
+				test.when,
+				test.end,
+				m.Duration,
+				test.expected,
+			)
+		}
+	}
+}
+
+func TestEventValidateRecurrence(t *testing.T) {
+	tests := []struct {
+		recurrence string
+		timezone   string
+		expected   string
+	}{
+		{
+			recurrence: "rrule:freq=weekly;byday=th,tu;interval=1",
+			expected:   "FREQ=WEEKLY;BYDAY=TU,TH",
+		},
+		{
+			recurrence: "RRULE:FREQ=MONTHLY;COUNT=6",
+			expected:   "FREQ=MONTHLY;COUNT=6",
+		},
+		// A date is the end of that day where the event is
+		{
+			recurrence: "FREQ=WEEKLY;UNTIL=20140701",
+			expected:   "FREQ=WEEKLY;UNTIL=20140701T235959Z",
+		},
+		{
+			recurrence: "FREQ=WEEKLY;UNTIL=20140701",
+			timezone:   "Europe/London",
+			expected:   "FREQ=WEEKLY;UNTIL=20140701T225959Z",
+		},
+		{
+			recurrence: "FREQ=WEEKLY;UNTIL=20140701T190000",
+			timezone:   "America/New_York",
+			expected:   "FREQ=WEEKLY;UNTIL=20140701T230000Z",
+		},
+		{
+			recurrence: "FREQ=WEEKLY;UNTIL=20140701T190000Z",
+			timezone:   "America/New_York",
+			expected:   "FREQ=WEEKLY;UNTIL=20140701T190000Z",
+		},
+	}
+
+	for _, test := range tests {
+		m := EventType{}
+		m.MicrocosmId = 1
+		m.Title = "Weekly ride"
+		m.When = "2014-06-01T09:00:00Z"
+		m.Timezone = test.timezone
+		m.Recurrence = test.recurrence
+		m.Meta.EditReason = "Testing"
+
+		_, err := m.Validate(1, 1, true)
+		if err != nil {
+			t.Fatalf("Validate() of %s failed: %+v", test.recurrence, err)
+		}
+
+		if m.Recurrence != test.expected ||
+			m.RecurrenceNullable.String != test.expected {
+
+			t.Errorf(
+				"%s in %s validated to %s, expected %s",
+				test.recurrence,
+				test.timezone,
+				m.Recurrence,
+				test.expected,
+			)
+		}
+	}
+}
+
+func TestEventDupeKey(t *testing.T) {
+	makeEvent := func() EventType {
+		m := EventType{}
+		m.MicrocosmId = 1
+		m.Title = "Monthly ride"
+		m.When = "2014-06-01T09:00:00Z"
+		m.Duration = 90
+		m.Where = "Islington"
+		m.Lat = 51.5362
+		m.Lon = -0.1033
+		m.Meta.CreatedById = 1
+		m.Meta.EditReason = "Testing"
+
+		_, err := m.Validate(1, 1, true)
+		if err != nil {
+			t.Fatalf("Validate() failed: %+v", err)
+		}
+		return m
+	}
+
+	first := makeEvent()
+	second := makeEvent()
+	if first.dupeKey() != second.dupeKey() {
+		t.Errorf("Identical submissions produced different dupe keys")
+	}
+
+	retitled := makeEvent()
+	retitled.Title = "Monthly ride (rescheduled)"
+	if first.dupeKey() == retitled.dupeKey() {
+		t.Errorf("A changed title produced the same dupe key")
+	}
+
+	lengthened := makeEvent()
+	lengthened.Duration = 120
+	if first.dupeKey() == lengthened.dupeKey() {
+		t.Errorf("A changed duration produced the same dupe key")
+	}
+
+	// Ends within the same minute have the same duration
+	ended := makeEvent()
+	ended.End = "2014-06-03T09:00:01Z"
+	_, err := ended.Validate(1, 1, true)
+	if err != nil {
+		t.Fatalf("Validate() failed: %+v", err)
+	}
+	extended := makeEvent()
+	extended.End = "2014-06-03T09:00:30Z"
+	_, err = extended.Validate(1, 1, true)
+	if err != nil {
+		t.Fatalf("Validate() failed: %+v", err)
+	}
+	if ended.dupeKey() == extended.dupeKey() {
+		t.Errorf("A changed end produced the same dupe key")
+	}
+
+	private := makeEvent()
+	private.IsPrivate = true
+	if first.dupeKey() == private.dupeKey() {
+		t.Errorf("Making an event private produced the same dupe key")
+	}
+}
+
+func TestParseEventsNear(t *testing.T) {
+	tests := []struct {
+		query  string
+		valid  bool
+		radius float64
+	}{
+		{query: "", valid: true, radius: 0},
+		{query: "lat=51.5&lon=-0.12", valid: true, radius: 5},
+		{query: "lat=51.5&lon=-0.12&radius=2.5", valid: true, radius: 2.5},
+		{query: "lat=51.5", valid: false},
+		{query: "radius=10", valid: false},
+		{query: "lat=91&lon=0", valid: false},
+		{query: "lat=0&lon=181", valid: false},
+		{query: "lat=51.5&lon=-0.12&radius=0", valid: false},
+		{query: "lat=51.5&lon=-0.12&radius=north", valid: false},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		near, _, err := ParseEventsNear(query)
+
+		if test.valid && err != nil {
+			t.Errorf("Expected %q to be valid, got %v", test.query, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("Expected %q to be invalid", test.query)
+		} else if test.valid && near.RadiusKm != test.radius {
+			t.Errorf("Expected %q to have a radius of %f, got %f",
+				test.query, test.radius, near.RadiusKm)
+		}
+	}
+}
+
+func TestParseEventsWhen(t *testing.T) {
+	tests := []struct {
+		query    string
+		valid    bool
+		byWhen   bool
+		proposed bool
+	}{
+		{query: "", valid: true, byWhen: false, proposed: true},
+		{query: "upcoming=true", valid: true, byWhen: true, proposed: true},
+		{query: "upcoming=false", valid: true, byWhen: false, proposed: true},
+		{query: "from=2014-06-01T00:00:00Z", valid: true, byWhen: true, proposed: true},
+		{query: "from=2014-06-01T00:00:00Z&to=2014-06-30T00:00:00Z", valid: true, byWhen: true, proposed: true},
+		{query: "proposed=false", valid: true, byWhen: false, proposed: false},
+		{query: "from=2014-06-30T00:00:00Z&to=2014-06-01T00:00:00Z", valid: false},
+		{query: "from=june", valid: false},
+		{query: "upcoming=soon", valid: false},
+		{query: "proposed=maybe", valid: false},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		when, _, err := ParseEventsWhen(query)
+
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Expected %q to be invalid", test.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected %q to be valid, got %v", test.query, err)
+			continue
+		}
+
+		if when.ByWhen() != test.byWhen {
+			t.Errorf("Expected %q to order by when: %t", test.query, test.byWhen)
+		}
+		if when.ExcludeProposed == test.proposed {
+			t.Errorf("Expected %q to include proposed events: %t", test.query, test.proposed)
+		}
+	}
+}
+
+func TestParseEventsOrder(t *testing.T) {
+	tests := []struct {
+		query string
+		order string
+		valid bool
+	}{
+		{query: "", order: "", valid: true},
+		{query: "order=activity", order: EventsOrderActivity, valid: true},
+		{query: "order=when", order: EventsOrderWhen, valid: true},
+		{query: "order=created", order: EventsOrderCreated, valid: true},
+		{query: "order=created%20DESC", valid: false},
+		{query: "order=f.item_id", valid: false},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		order, _, err := ParseEventsOrder(query)
+
+		if test.valid != (err == nil) {
+			t.Errorf("Expected %q valid: %t, got %v", test.query, test.valid, err)
+			continue
+		}
+		if order != test.order {
+			t.Errorf("Expected %q to be %q, got %q", test.query, test.order, order)
+		}
+	}
+}
+
+func TestEventsOrderFor(t *testing.T) {
+	near := EventsNearType{Lat: 51.5, Lon: -0.1, RadiusKm: 10}
+	upcoming := EventsWhenType{Upcoming: true}
+
+	tests := []struct {
+		near  EventsNearType
+		when  EventsWhenType
+		order string
+		want  string
+	}{
+		{want: EventsOrderActivity},
+		{when: upcoming, want: EventsOrderWhen},
+		{near: near, want: EventsOrderDistance},
+		{near: near, when: upcoming, want: EventsOrderDistance},
+		{near: near, order: EventsOrderCreated, want: EventsOrderCreated},
+		{when: upcoming, order: EventsOrderActivity, want: EventsOrderActivity},
+	}
+
+	for _, test := range tests {
+		if order := EventsOrderFor(test.near, test.when, test.order); order != test.want {
+			t.Errorf("Expected %+v to be ordered by %q, got %q", test, test.want, order)
+		}
+	}
+}
+
+func TestEventValidateTimezone(t *testing.T) {
+	tests := map[string]bool{
+		"Europe/London": true,
+		"UTC":           true,
+		"Local":         false,
+		"Mars/Olympus":  false,
+	}
+
+	for timezone, valid := range tests {
+		m := EventType{}
+		m.MicrocosmId = 1
+		m.Title = "Weekly ride"
+		m.When = "2014-06-01T09:00:00Z"
+		m.Timezone = timezone
+		m.Meta.EditReason = "Testing"
+
+		_, err := m.Validate(1, 1, true)
+		if valid != (err == nil) {
+			t.Errorf("Expected %q valid: %t, got %v", timezone, valid, err)
+		}
+	}
+
+	if _, err := loadEventLocation(""); err == nil {
+		t.Errorf("Expected the empty time zone to be refused")
+	}
+}
@@ -1,25 +1,31 @@
 package models
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha1"
 	"database/sql"
 	"errors"
 	"fmt"
 	"image"
+	"image/draw"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/disintegration/imaging"
 	"github.com/golang/glog"
 	"github.com/microcosm-cc/exifutil"
-	"github.com/mitchellh/goamz/aws"
-	"github.com/mitchellh/goamz/s3"
 	"github.com/rwcarlsen/goexif/exif"
+	_ "golang.org/x/image/webp"
 
 	conf "github.com/microcosm-cc/microcosm/config"
 	h "github.com/microcosm-cc/microcosm/helpers"
@@ -28,13 +34,46 @@ import (
 const (
 	AvatarMaxWidth    int64  = 100
 	AvatarMaxHeight   int64  = 100
-	MaxFileSize       int32  = 5242880 * 2 // 10MB
+	ThumbnailMaxSize  int64  = 200
+	VariantMaxSize    int64  = 2000
 	ImageGifMimeType  string = "image/gif"
 	ImageJpegMimeType string = "image/jpeg"
 	ImagePngMimeType  string = "image/png"
 	ImageSvgMimeType  string = "image/svg+xml"
+	ImageWebpMimeType string = "image/webp"
 )
 
+// The number of bytes that http.DetectContentType considers
+const sniffLength int = 512
+
+// The raster image types that we can decode, resize and make thumbnails of
+var imageExtensions = map[string]string{
+	ImageGifMimeType:  "gif",
+	ImageJpegMimeType: "jpg",
+	ImagePngMimeType:  "png",
+	ImageWebpMimeType: "webp",
+}
+
+// Types that a browser would render as a document, and so run any script in,
+// if the file were opened directly. These are never stored as declared.
+var activeContentTypes = map[string]struct{}{
+	"application/xhtml+xml": struct{}{},
+	"application/xml":       struct{}{},
+	"text/html":             struct{}{},
+	"text/xml":              struct{}{},
+	ImageSvgMimeType:        struct{}{},
+}
+
+// IsImageMimeType returns true if the MIME type is one of the raster image
+// types that may be displayed inline
+func IsImageMimeType(mimeType string) bool {
+	if i := strings.Index(mimeType, ";"); i > -1 {
+		mimeType = mimeType[:i]
+	}
+	_, ok := imageExtensions[strings.ToLower(strings.TrimSpace(mimeType))]
+	return ok
+}
+
 // Represents the 'attachment_meta' table
 type FileMetadataType struct {
 	AttachmentMetaId        int64         `json:"-"`
@@ -49,11 +88,36 @@ type FileMetadataType struct {
 	HeightNullable          sql.NullInt64 `json:"-"`
 	Height                  int64         `json:"height,omitempty"`
 	ThumbnailWidthNullable  sql.NullInt64 `json:"-"`
-	ThumbnailWidth          int64         `json:"thumbnailHeight,omitempty"`
+	ThumbnailWidth          int64         `json:"thumbnailWidth,omitempty"`
 	ThumbnailHeightNullable sql.NullInt64 `json:"-"`
-	ThumbnailHeight         int64         `json:"thumbnailWidth,omitempty"`
+	ThumbnailHeight         int64         `json:"thumbnailHeight,omitempty"`
 	AttachCount             int64         `json:"-"`
 	Content                 []byte        `json:"-"`
+	ThumbnailContent        []byte        `json:"-"`
+	ThumbnailMimeType       string        `json:"-"`
+}
+
+// GetMaxFileSize returns the largest file, in bytes, that may be uploaded as
+// configured for this deployment
+func GetMaxFileSize() int64 {
+	max := conf.CONFIG_INT64[conf.KEY_MAX_FILE_SIZE_BYTES]
+
+	// FileSize is stored as an int32
+	if max > math.MaxInt32 {
+		return math.MaxInt32
+	}
+
+	return max
+}
+
+func errFileTooLarge() error {
+	return errors.New(
+		fmt.Sprintf(
+			"Files must be no larger than %d bytes (%.1fMB)",
+			GetMaxFileSize(),
+			float64(GetMaxFileSize())/(1024*1024),
+		),
+	)
 }
 
 func (f *FileMetadataType) Validate() (int, error) {
@@ -67,9 +131,8 @@ func (f *FileMetadataType) Validate() (int, error) {
 			errors.New("File size (in bytes) must be set")
 	}
 
-	if f.FileSize > MaxFileSize {
-		return http.StatusBadRequest,
-			errors.New("Files must be below 5MB in size")
+	if int64(f.FileSize) > GetMaxFileSize() {
+		return http.StatusBadRequest, errFileTooLarge()
 	}
 
 	// SHA-1 output encoded as string is 40 characters
@@ -117,7 +180,21 @@ func (f *FileMetadataType) Insert(
 	int,
 	error,
 ) {
-	return f.insert(maxWidth, maxHeight, false)
+	return f.insert(bytes.NewReader(f.Content), maxWidth, maxHeight, false)
+}
+
+// InsertReader is Insert for content that is read from r rather than held in
+// f.Content. Only images are held in memory, other files are streamed to
+// storage.
+func (f *FileMetadataType) InsertReader(
+	r io.Reader,
+	maxWidth int64,
+	maxHeight int64,
+) (
+	int,
+	error,
+) {
+	return f.insert(r, maxWidth, maxHeight, false)
 }
 
 func (f *FileMetadataType) Import(
@@ -127,11 +204,12 @@ func (f *FileMetadataType) Import(
 	int,
 	error,
 ) {
-	return f.insert(maxWidth, maxHeight, true)
+	return f.insert(bytes.NewReader(f.Content), maxWidth, maxHeight, true)
 }
 
-// Uploads the file to S3 and inserts the metadata into attachment_meta
+// Uploads the file to storage and inserts the metadata into attachment_meta
 func (f *FileMetadataType) insert(
+	r io.Reader,
 	maxWidth int64,
 	maxHeight int64,
 	isImport bool,
@@ -152,39 +230,83 @@ func (f *FileMetadataType) insert(
 		f.FileExt = fileNameBits[len(fileNameBits)-1]
 	}
 
-	var isImage bool
 	switch strings.ToLower(f.MimeType) {
 	case "application/octet-stream":
 		switch f.FileExt {
 		case "gif":
 			f.MimeType = ImageGifMimeType
-			isImage = true
 		case "jpeg":
 			f.MimeType = ImageJpegMimeType
-			isImage = true
 		case "jpg":
 			f.MimeType = ImageJpegMimeType
-			isImage = true
 		case "png":
 			f.MimeType = ImagePngMimeType
-			isImage = true
 		case "svg":
 			f.MimeType = ImageSvgMimeType
+		case "webp":
+			f.MimeType = ImageWebpMimeType
 		}
 	case ImageGifMimeType:
 		f.FileExt = "gif"
-		isImage = true
 	case ImageJpegMimeType:
 		f.FileExt = "jpg"
-		isImage = true
 	case ImagePngMimeType:
 		f.FileExt = "png"
-		isImage = true
 	case ImageSvgMimeType:
 		f.FileExt = "svg"
+	case ImageWebpMimeType:
+		f.FileExt = "webp"
 	}
 
-	if isImage {
+	// Don't trust the client, check that the content is what it claims to be
+	br := bufio.NewReaderSize(r, sniffLength)
+	head, err := br.Peek(sniffLength)
+	if err != nil && err != io.EOF {
+		glog.Errorf("br.Peek(%d) %+v", sniffLength, err)
+		return http.StatusBadRequest, err
+	}
+	r = br
+
+	declared := strings.ToLower(f.MimeType)
+	mimeType, isImage, err := reconcileContentType(declared, head)
+	if err != nil {
+		glog.Infof("reconcileContentType(`%s`, head) %+v", f.MimeType, err)
+		return http.StatusBadRequest, err
+	}
+	if mimeType != f.MimeType {
+		f.MimeType = mimeType
+		if ext, ok := imageExtensions[mimeType]; ok {
+			f.FileExt = ext
+		}
+	}
+
+	var body io.Reader
+
+	if declared == ImageSvgMimeType {
+
+		// SVG is held in memory so that it can be checked for script. It is
+		// stored as text regardless, so this only refuses what would be
+		// dangerous were it ever served as SVG
+		status, err := f.readContent(r)
+		if err != nil {
+			return status, err
+		}
+
+		err = checkSvg(f.Content)
+		if err != nil {
+			glog.Infof("checkSvg(f.Content) %+v", err)
+			return http.StatusBadRequest, err
+		}
+
+		body = bytes.NewReader(f.Content)
+
+	} else if isImage {
+
+		// Images are held in memory as they may need to be resized or rotated
+		status, err := f.readContent(r)
+		if err != nil {
+			return status, err
+		}
 
 		// See image format imports above for supported image types
 		// If a match is not made, we assume the upload is bad
@@ -199,7 +321,22 @@ func (f *FileMetadataType) insert(
 		f.Height = int64(im.Height)
 		f.Width = int64(im.Width)
 
-		// Resize if we've been told the image must fit within a certain size
+		switch format {
+		case "gif":
+			f.MimeType = ImageGifMimeType
+		case "jpeg":
+			f.MimeType = ImageJpegMimeType
+		case "jpg":
+			f.MimeType = ImageJpegMimeType
+		case "png":
+			f.MimeType = ImagePngMimeType
+		case "webp":
+			f.MimeType = ImageWebpMimeType
+		}
+
+		// Resize if we've been told the image must fit within a certain size.
+		// This may change the format (WebP is re-encoded as PNG) so it must
+		// come after the format has been determined from the content
 		if (maxWidth > 0 && f.Width > maxWidth) ||
 			(maxHeight > 0 && f.Height > maxHeight) {
 
@@ -215,17 +352,6 @@ func (f *FileMetadataType) insert(
 			}
 		}
 
-		switch format {
-		case "gif":
-			f.MimeType = ImageGifMimeType
-		case "jpeg":
-			f.MimeType = ImageJpegMimeType
-		case "jpg":
-			f.MimeType = ImageJpegMimeType
-		case "png":
-			f.MimeType = ImagePngMimeType
-		}
-
 		// If the image is a jpeg, process the exif data, replace the image,
 		// and update the width and height as necessary.
 		if f.MimeType == ImageJpegMimeType {
@@ -234,6 +360,36 @@ func (f *FileMetadataType) insert(
 				glog.Errorf("Error processing exif data: %s", err)
 			}
 		}
+
+		// Remove location and other identifying metadata. This comes after
+		// the EXIF orientation has been used as it is removed too
+		status, err = f.stripSensitiveExif()
+		if err != nil {
+			glog.Errorf("f.stripSensitiveExif() %+v", err)
+			return status, err
+		}
+
+		status, err = f.MakeThumbnail(ThumbnailMaxSize)
+		if err != nil {
+			glog.Errorf("f.MakeThumbnail(%d) %+v", ThumbnailMaxSize, err)
+			return status, err
+		}
+
+		body = bytes.NewReader(f.Content)
+
+	} else {
+
+		// Everything else is spooled to disk, hashing it as it goes, so that
+		// it can be sent to storage (which requires the length up front)
+		// without being held in memory
+		spool, status, err := f.spoolContent(r)
+		if err != nil {
+			return status, err
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+
+		body = spool
 	}
 
 	status, err := f.Validate()
@@ -255,32 +411,44 @@ func (f *FileMetadataType) insert(
 		}
 	}
 
-	// Check whether we've already uploaded this image as we can save ourselves
-	// some network effort if we have.
-	auth := aws.Auth{
-		AccessKey: conf.CONFIG_STRING[conf.KEY_AWS_ACCESS_KEY_ID],
-		SecretKey: conf.CONFIG_STRING[conf.KEY_AWS_SECRET_ACCESS_KEY],
+	storage, err := GetStorage()
+	if err != nil {
+		glog.Errorf("GetStorage() %+v", err)
+		return http.StatusInternalServerError, err
 	}
 
-	s3Instance := s3.New(auth, aws.EUWest)
-	bucket := s3Instance.Bucket(conf.CONFIG_STRING[conf.KEY_S3_BUCKET])
-
-	uploaded := false
-	key, _ := bucket.GetKey(f.FileHash)
-	// TODO: verify the file content is the same, rather than just
-	// having the expected SHA-1 filename and non-zero size (e.g. a
-	// previous failed uploaded could have partially uploaded the file)
-	if key != nil && key.Size > 0 {
-		uploaded = true
+	// Files are stored by their hash so we can save ourselves some network
+	// effort if this one has already been uploaded
+	_, err = putUnlessStored(
+		storage,
+		f.FileHash,
+		body,
+		int64(f.FileSize),
+		f.MimeType,
+	)
+	if err != nil {
+		glog.Errorf(
+			"putUnlessStored(storage, `%s`, body, %d, `%s`) %+v",
+			f.FileHash,
+			f.FileSize,
+			f.MimeType,
+			err,
+		)
+		return http.StatusInternalServerError, err
 	}
 
-	if !uploaded {
-		err = bucket.Put(f.FileHash, f.Content, f.MimeType, s3.Private)
+	if len(f.ThumbnailContent) > 0 {
+		err = storage.Put(
+			ThumbnailKey(f.FileHash),
+			bytes.NewReader(f.ThumbnailContent),
+			int64(len(f.ThumbnailContent)),
+			f.ThumbnailMimeType,
+		)
 		if err != nil {
 			glog.Errorf(
-				"bucket.Put(`%s`, f.Content, `%s`, s3.Private) %+v",
-				f.FileHash,
-				f.MimeType,
+				"storage.Put(`%s`, f.ThumbnailContent, `%s`) %+v",
+				ThumbnailKey(f.FileHash),
+				f.ThumbnailMimeType,
 				err,
 			)
 			return http.StatusInternalServerError, err
@@ -336,6 +504,152 @@ INSERT INTO attachment_meta (
 	return http.StatusOK, nil
 }
 
+// reconcileContentType compares the declared MIME type of an upload with the
+// type sniffed from the first bytes of its content, returning the MIME type to
+// store it as and whether it is a raster image. The combinations allowed are:
+//
+//   - A declared GIF, JPEG, PNG or WebP must sniff as that same type
+//   - A declared SVG must sniff as text (it is checked for script later)
+//   - Anything else that sniffs as a GIF, JPEG, PNG or WebP is stored as that
+//     image type
+//   - A declared HTML, XHTML, XML or SVG type, or anything that sniffs as HTML
+//     or XML, is stored as text/plain if it sniffs as text and as
+//     application/octet-stream otherwise, so that it is never rendered by a
+//     browser
+//   - Anything else is stored with the declared type
+func reconcileContentType(declared string, head []byte) (string, bool, error) {
+
+	sniffed := http.DetectContentType(head)
+	if i := strings.Index(sniffed, ";"); i > -1 {
+		sniffed = sniffed[:i]
+	}
+
+	declaredType := declared
+	if i := strings.Index(declaredType, ";"); i > -1 {
+		declaredType = strings.TrimSpace(declaredType[:i])
+	}
+
+	if _, ok := imageExtensions[declaredType]; ok {
+		if sniffed != declaredType {
+			return declared, false, errors.New(
+				fmt.Sprintf(
+					"File content is %s but was declared as %s",
+					sniffed,
+					declared,
+				),
+			)
+		}
+		return declaredType, true, nil
+	}
+
+	if declaredType == ImageSvgMimeType &&
+		sniffed != "text/xml" && sniffed != "text/plain" {
+
+		return declared, false, errors.New(
+			fmt.Sprintf("File content is %s but was declared as SVG", sniffed),
+		)
+	}
+
+	if _, ok := imageExtensions[sniffed]; ok {
+		return sniffed, true, nil
+	}
+
+	_, active := activeContentTypes[declaredType]
+	if !active {
+		_, active = activeContentTypes[sniffed]
+	}
+	if active {
+		if strings.HasPrefix(sniffed, "text/") {
+			return "text/plain; charset=utf-8", false, nil
+		}
+		return "application/octet-stream", false, nil
+	}
+
+	return declared, false, nil
+}
+
+// SVG can carry script which would run if the file were opened directly
+var svgUnsafe = regexp.MustCompile(
+	`(?i)<\s*(script|foreignobject|iframe|embed|object)\b|\son[a-z]+\s*=|javascript\s*:|&#`,
+)
+
+// checkSvg refuses SVG that contains script, event handlers, javascript: URLs
+// or embedded documents. Character references are refused too as they can be
+// used to disguise any of these.
+func checkSvg(content []byte) error {
+	if !bytes.Contains(bytes.ToLower(content), []byte("<svg")) {
+		return errors.New("File content is not SVG")
+	}
+
+	if svgUnsafe.Match(content) {
+		return errors.New("SVG files may not contain script")
+	}
+
+	return nil
+}
+
+// readContent reads the whole file into f.Content, setting the size and hash
+func (f *FileMetadataType) readContent(r io.Reader) (int, error) {
+
+	content, err := ioutil.ReadAll(io.LimitReader(r, GetMaxFileSize()+1))
+	if err != nil {
+		glog.Errorf("ioutil.ReadAll(r) %+v", err)
+		return http.StatusBadRequest, err
+	}
+
+	if int64(len(content)) > GetMaxFileSize() {
+		return http.StatusBadRequest, errFileTooLarge()
+	}
+
+	fileHash, err := h.Sha1(content)
+	if err != nil {
+		glog.Errorf("h.Sha1(content) %+v", err)
+		return http.StatusInternalServerError,
+			errors.New("Couldn't generate SHA-1")
+	}
+
+	f.Content = content
+	f.FileHash = fileHash
+	f.FileSize = int32(len(content))
+
+	return http.StatusOK, nil
+}
+
+// spoolContent copies the file into a temporary file, setting the size and
+// hash as it does so. The temporary file is returned positioned at the start
+// and the caller must close and remove it.
+func (f *FileMetadataType) spoolContent(r io.Reader) (*os.File, int, error) {
+
+	spool, err := ioutil.TempFile("", "microcosm-upload-")
+	if err != nil {
+		glog.Errorf("ioutil.TempFile() %+v", err)
+		return nil, http.StatusInternalServerError, err
+	}
+
+	hash := sha1.New()
+	n, err := io.Copy(
+		spool,
+		io.TeeReader(io.LimitReader(r, GetMaxFileSize()+1), hash),
+	)
+	if err == nil && n > GetMaxFileSize() {
+		err = errFileTooLarge()
+	}
+	if err == nil {
+		_, err = spool.Seek(0, 0)
+	}
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, http.StatusBadRequest, err
+	}
+
+	f.Content = nil
+	f.FileHash = fmt.Sprintf("%x", hash.Sum(nil))
+	f.FileSize = int32(n)
+
+	return spool, http.StatusOK, nil
+}
+
 func (f *FileMetadataType) Update() (int, error) {
 
 	status, err := f.Validate()
@@ -393,47 +707,40 @@ UPDATE attachment_meta
 	return http.StatusOK, nil
 }
 
-// Retrieve a file by its file hash
-func GetFile(fileHash string) ([]byte, map[string]string, int, error) {
+// ThumbnailKey is the storage key of the thumbnail of the given file
+func ThumbnailKey(fileHash string) string {
+	return fileHash + "_thumbnail"
+}
 
-	headersOut := map[string]string{}
+// Retrieve a file by its file hash. The caller must close the returned body.
+func GetFile(fileHash string) (io.ReadCloser, map[string]string, int, error) {
+	return getStoredObject(fileHash)
+}
 
-	auth := aws.Auth{
-		AccessKey: conf.CONFIG_STRING[conf.KEY_AWS_ACCESS_KEY_ID],
-		SecretKey: conf.CONFIG_STRING[conf.KEY_AWS_SECRET_ACCESS_KEY],
-	}
+// Retrieve the thumbnail of an image by the file hash of the full image. The
+// caller must close the returned body.
+func GetThumbnail(fileHash string) (io.ReadCloser, map[string]string, int, error) {
+	return getStoredObject(ThumbnailKey(fileHash))
+}
 
-	s3Instance := s3.New(auth, aws.EUWest)
-	bucket := s3Instance.Bucket(conf.CONFIG_STRING[conf.KEY_S3_BUCKET])
+func getStoredObject(key string) (io.ReadCloser, map[string]string, int, error) {
 
-	resp, err := bucket.GetResponse(fileHash)
+	storage, err := GetStorage()
 	if err != nil {
-		return []byte{}, headersOut, http.StatusInternalServerError, err
+		return nil, map[string]string{}, http.StatusInternalServerError, err
 	}
 
-	headers := []string{
-		"Content-Disposition",
-		"Content-Encoding",
-		"Content-Length",
-		"Content-Type",
-		"ETag",
-		"Last-Modified",
-	}
-
-	for _, h := range headers {
-		v := resp.Header.Get(h)
-		if v != "" {
-			headersOut[h] = v
-		}
-	}
-
-	data, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
+	headers, body, err := storage.Get(key)
 	if err != nil {
-		return []byte{}, headersOut, http.StatusInternalServerError, err
+		if isStorageNotFound(err) {
+			return nil, headers, http.StatusNotFound, errors.New(
+				fmt.Sprintf("File %s not found", key),
+			)
+		}
+		return nil, headers, http.StatusInternalServerError, err
 	}
 
-	return data, headersOut, http.StatusOK, nil
+	return body, headers, http.StatusOK, nil
 }
 
 func GetMetadata(fileHash string) (FileMetadataType, int, error) {
@@ -532,10 +839,27 @@ func (f *FileMetadataType) ResizeImage(
 		return http.StatusOK, nil
 	}
 
+	// Animations are kept, except for avatars which are too small for
+	// animation to be worthwhile
+	return f.resizeTo(width, height, !isAvatarSize(maxWidth, maxHeight))
+}
+
+// resizeTo resizes the image to the given width or height, a zero dimension
+// being calculated to preserve the aspect ratio. The content, hash, size,
+// dimensions and type of the file are updated to those of the resized image.
+func (f *FileMetadataType) resizeTo(
+	width int,
+	height int,
+	keepAnimation bool,
+) (
+	int,
+	error,
+) {
+
 	r := bytes.NewReader(f.Content)
 
-	// middle var is format, i.e. which decoder was used: "gif", "jpeg", "png"
-	// in the case of "gif", only the first frame is extracted
+	// middle var is format, i.e. which decoder was used: "gif", "jpeg", "png",
+	// "webp". In the case of "gif", only the first frame is extracted
 	img, format, err := image.Decode(r)
 	if err != nil {
 		glog.Errorf("image.Decode(r) %+v", err)
@@ -548,12 +872,27 @@ func (f *FileMetadataType) ResizeImage(
 
 	switch format {
 	case "gif":
+		if keepAnimation {
+			g, err := gif.DecodeAll(bytes.NewReader(f.Content))
+			if err == nil && len(g.Image) > 1 {
+				err = gif.EncodeAll(&buf, resizeAnimatedGif(g, width, height))
+				if err != nil {
+					glog.Errorf("gif.EncodeAll(&buf, g) %+v", err)
+					return http.StatusBadRequest, err
+				}
+				f.MimeType = ImageGifMimeType
+				f.FileExt = "gif"
+				break
+			}
+		}
+
 		err = gif.Encode(&buf, m, nil)
 		if err != nil {
 			glog.Errorf("gif.Encode(&buf, m, nil) %+v", err)
 			return http.StatusBadRequest, err
 		}
 		f.MimeType = ImageGifMimeType
+		f.FileExt = "gif"
 	case "jpeg":
 		err = jpeg.Encode(&buf, m, nil)
 		if err != nil {
@@ -561,13 +900,17 @@ func (f *FileMetadataType) ResizeImage(
 			return http.StatusBadRequest, err
 		}
 		f.MimeType = ImageJpegMimeType
+		f.FileExt = "jpg"
 	default:
+		// There is no WebP encoder, so WebP (and anything else) becomes a
+		// lossless PNG
 		err = png.Encode(&buf, m)
 		if err != nil {
 			glog.Errorf("png.Encode(&buf, m, nil) %+v", err)
 			return http.StatusBadRequest, err
 		}
 		f.MimeType = ImagePngMimeType
+		f.FileExt = "png"
 	}
 
 	// Update the file meta data
@@ -596,6 +939,275 @@ func (f *FileMetadataType) ResizeImage(
 	return http.StatusOK, nil
 }
 
+// VariantSizes are the widths and heights that variants may be requested at.
+// Only a fixed set is made so that a file cannot be resized, and stored, at
+// every size.
+var VariantSizes = []int64{50, 100, 200, 400, 800, 1200, 1600, VariantMaxSize}
+
+// IsVariantSize returns true if variants may be requested at the size
+func IsVariantSize(size int64) bool {
+	for _, s := range VariantSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// VariantKey is the storage key of a resized variant of the given file
+func VariantKey(fileHash string, width int64, height int64) string {
+	return fmt.Sprintf("%s_%dx%d", fileHash, width, height)
+}
+
+// variantSize returns the width or height to resize an image to so that it
+// fits within the requested dimensions, a zero dimension being unconstrained.
+// Requests are clamped to VariantMaxSize and to the size of the image, and if
+// no resize is needed both values returned are zero.
+func variantSize(
+	imageWidth int64,
+	imageHeight int64,
+	maxWidth int64,
+	maxHeight int64,
+) (
+	int64,
+	int64,
+) {
+
+	if maxWidth > VariantMaxSize {
+		maxWidth = VariantMaxSize
+	}
+	if maxHeight > VariantMaxSize {
+		maxHeight = VariantMaxSize
+	}
+
+	widthBound := maxWidth > 0 && maxWidth < imageWidth
+	heightBound := maxHeight > 0 && maxHeight < imageHeight
+
+	switch {
+	case widthBound && heightBound:
+		// Whichever dimension is the more constrained
+		if imageWidth*maxHeight > imageHeight*maxWidth {
+			return maxWidth, 0
+		}
+		return 0, maxHeight
+	case widthBound:
+		return maxWidth, 0
+	case heightBound:
+		return 0, maxHeight
+	default:
+		return 0, 0
+	}
+}
+
+// GetFileVariant retrieves a copy of an image resized to fit within the given
+// dimensions, either of which may be zero to leave it unconstrained. Variants
+// are made on first request and stored under a key derived from the file hash
+// and size so that subsequent requests are served from storage. The original
+// file is returned if it is not an image or already fits. The caller must
+// close the returned body.
+func GetFileVariant(
+	fileHash string,
+	maxWidth int64,
+	maxHeight int64,
+) (
+	io.ReadCloser,
+	map[string]string,
+	int,
+	error,
+) {
+
+	m, status, err := GetMetadata(fileHash)
+	if err != nil {
+		return nil, map[string]string{}, status, err
+	}
+
+	if _, ok := imageExtensions[m.MimeType]; !ok {
+		return GetFile(fileHash)
+	}
+
+	width, height := variantSize(m.Width, m.Height, maxWidth, maxHeight)
+	if width == 0 && height == 0 {
+		return GetFile(fileHash)
+	}
+
+	storage, err := GetStorage()
+	if err != nil {
+		return nil, map[string]string{}, http.StatusInternalServerError, err
+	}
+
+	key := VariantKey(fileHash, width, height)
+
+	size, err := storage.Size(key)
+	if err != nil {
+		// Not fatal, the variant is made again
+		glog.Warningf("storage.Size(`%s`) %+v", key, err)
+	} else if size > 0 {
+		return getStoredObject(key)
+	}
+
+	body, _, status, err := GetFile(fileHash)
+	if err != nil {
+		return nil, map[string]string{}, status, err
+	}
+	defer body.Close()
+
+	m.Content, err = ioutil.ReadAll(body)
+	if err != nil {
+		return nil, map[string]string{}, http.StatusInternalServerError, err
+	}
+
+	status, err = m.resizeTo(int(width), int(height), true)
+	if err != nil {
+		return nil, map[string]string{}, status, err
+	}
+
+	err = storage.Put(
+		key,
+		bytes.NewReader(m.Content),
+		int64(len(m.Content)),
+		m.MimeType,
+	)
+	if err != nil {
+		// Not fatal, the variant is made again next time
+		glog.Errorf("storage.Put(`%s`) %+v", key, err)
+	}
+
+	headers := map[string]string{
+		"Content-Length": fmt.Sprintf("%d", len(m.Content)),
+		"Content-Type":   m.MimeType,
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(m.Content)), headers, http.StatusOK, nil
+}
+
+// isAvatarSize returns true if the maximum dimensions are those of an avatar
+func isAvatarSize(maxWidth int64, maxHeight int64) bool {
+	return maxWidth > 0 && maxWidth <= AvatarMaxWidth &&
+		maxHeight > 0 && maxHeight <= AvatarMaxHeight
+}
+
+// resizeAnimatedGif resizes every frame of an animated GIF. Frames may only
+// cover part of the image and rely on the frames before them, so each is
+// composited onto the full image (honouring the disposal of the previous
+// frame) before being resized. The delays and disposals are preserved.
+func resizeAnimatedGif(g *gif.GIF, width int, height int) *gif.GIF {
+
+	canvas := image.NewRGBA(
+		image.Rect(0, 0, g.Config.Width, g.Config.Height),
+	)
+
+	frames := make([]*image.Paletted, 0, len(g.Image))
+	for i, frame := range g.Image {
+
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(canvas.Bounds())
+			draw.Draw(previous, previous.Bounds(), canvas, image.ZP, draw.Src)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		resized := imaging.Resize(canvas, width, height, imaging.Lanczos)
+		out := image.NewPaletted(resized.Bounds(), frame.Palette)
+		draw.Draw(out, out.Bounds(), resized, image.ZP, draw.Src)
+		frames = append(frames, out)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	g.Image = frames
+	if len(frames) > 0 {
+		g.Config.Width = frames[0].Bounds().Dx()
+		g.Config.Height = frames[0].Bounds().Dy()
+	}
+
+	return g
+}
+
+// MakeThumbnail produces a copy of the image that fits within a square of
+// maxSize pixels, leaving the full-size image in f.Content untouched. Images
+// that already fit are used as their own thumbnail.
+func (f *FileMetadataType) MakeThumbnail(maxSize int64) (int, error) {
+
+	img, format, err := image.Decode(bytes.NewReader(f.Content))
+	if err != nil {
+		glog.Errorf("image.Decode(bytes.NewReader(f.Content)) %+v", err)
+		return http.StatusBadRequest, err
+	}
+
+	bounds := img.Bounds()
+	if int64(bounds.Dx()) <= maxSize && int64(bounds.Dy()) <= maxSize {
+		f.ThumbnailContent = f.Content
+		f.ThumbnailMimeType = f.MimeType
+		f.ThumbnailWidth = int64(bounds.Dx())
+		f.ThumbnailHeight = int64(bounds.Dy())
+		return http.StatusOK, nil
+	}
+
+	thumb := imaging.Fit(img, int(maxSize), int(maxSize), imaging.Lanczos)
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, thumb, nil)
+		if err != nil {
+			glog.Errorf("jpeg.Encode(&buf, thumb, nil) %+v", err)
+			return http.StatusInternalServerError, err
+		}
+		f.ThumbnailMimeType = ImageJpegMimeType
+	default:
+		err = png.Encode(&buf, thumb)
+		if err != nil {
+			glog.Errorf("png.Encode(&buf, thumb) %+v", err)
+			return http.StatusInternalServerError, err
+		}
+		f.ThumbnailMimeType = ImagePngMimeType
+	}
+
+	f.ThumbnailContent = buf.Bytes()
+	f.ThumbnailWidth = int64(thumb.Bounds().Dx())
+	f.ThumbnailHeight = int64(thumb.Bounds().Dy())
+
+	return http.StatusOK, nil
+}
+
+// stripSensitiveExif removes metadata from the image, updating the hash and
+// size if anything was removed
+func (f *FileMetadataType) stripSensitiveExif() (int, error) {
+
+	content, err := StripSensitiveExif(f.Content, f.MimeType)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if len(content) == len(f.Content) {
+		return http.StatusOK, nil
+	}
+
+	fileHash, err := h.Sha1(content)
+	if err != nil {
+		glog.Errorf("h.Sha1(content) %+v", err)
+		return http.StatusInternalServerError,
+			errors.New("Couldn't generate SHA-1")
+	}
+
+	f.Content = content
+	f.FileHash = fileHash
+	f.FileSize = int32(len(content))
+
+	return http.StatusOK, nil
+}
+
 // processExif attempts to rotate a JPEG based on the exif data. If the exif data
 // cannot be decoded or the orientation tag not read, we return nil so that the image
 // may continue to be uploaded. If there is an error encoding the image after
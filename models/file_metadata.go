@@ -0,0 +1,192 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	jpegExifPrefix = []byte("Exif\x00\x00")
+	jpegXmpPrefix  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	// PNG chunks that carry EXIF or free text (which may include XMP)
+	pngMetadataChunks = map[string]bool{
+		"eXIf": true,
+		"iTXt": true,
+		"tEXt": true,
+		"zTXt": true,
+	}
+
+	errMalformedImage = errors.New("Image data is malformed")
+)
+
+// StripSensitiveExif removes the EXIF, XMP and IPTC metadata from JPEG, PNG
+// and WebP images. This metadata can include the GPS coordinates of where a
+// photo was taken, and the make and serial number of the camera.
+//
+// The image data is not decoded or re-encoded so there is no loss of quality.
+// Colour profiles are kept. Content of other types is returned unchanged.
+func StripSensitiveExif(content []byte, mimeType string) ([]byte, error) {
+	switch mimeType {
+	case ImageJpegMimeType:
+		return stripJpegMetadata(content)
+	case ImagePngMimeType:
+		return stripPngMetadata(content)
+	case ImageWebpMimeType:
+		return stripWebpMetadata(content)
+	default:
+		return content, nil
+	}
+}
+
+// stripJpegMetadata drops the APP1 (EXIF and XMP) and APP13 (IPTC) segments
+// that precede the image data
+func stripJpegMetadata(content []byte) ([]byte, error) {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return nil, errMalformedImage
+	}
+
+	var out bytes.Buffer
+	out.Write(content[:2])
+
+	i := 2
+	for i < len(content) {
+		if content[i] != 0xFF {
+			return nil, errMalformedImage
+		}
+
+		// Markers may be preceded by any number of fill bytes
+		for i < len(content) && content[i] == 0xFF {
+			i++
+		}
+		if i >= len(content) {
+			return nil, errMalformedImage
+		}
+		marker := content[i]
+		i++
+
+		// Markers without a payload
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD9) {
+			out.Write([]byte{0xFF, marker})
+			continue
+		}
+
+		if i+2 > len(content) {
+			return nil, errMalformedImage
+		}
+		length := int(binary.BigEndian.Uint16(content[i : i+2]))
+		if length < 2 || i+length > len(content) {
+			return nil, errMalformedImage
+		}
+		payload := content[i+2 : i+length]
+
+		// Start of scan, everything that follows is image data
+		if marker == 0xDA {
+			out.Write([]byte{0xFF, marker})
+			out.Write(content[i:])
+			return out.Bytes(), nil
+		}
+
+		strip := marker == 0xED ||
+			(marker == 0xE1 &&
+				(bytes.HasPrefix(payload, jpegExifPrefix) ||
+					bytes.HasPrefix(payload, jpegXmpPrefix)))
+
+		if !strip {
+			out.Write([]byte{0xFF, marker})
+			out.Write(content[i : i+length])
+		}
+
+		i += length
+	}
+
+	return nil, errMalformedImage
+}
+
+// stripPngMetadata drops the chunks that carry EXIF or text
+func stripPngMetadata(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, pngSignature) {
+		return nil, errMalformedImage
+	}
+
+	var out bytes.Buffer
+	out.Write(pngSignature)
+
+	i := len(pngSignature)
+	for i < len(content) {
+		// Length, type, data, CRC
+		if i+8 > len(content) {
+			return nil, errMalformedImage
+		}
+		length := int(binary.BigEndian.Uint32(content[i : i+4]))
+		end := i + 12 + length
+		if length < 0 || end > len(content) || end < i {
+			return nil, errMalformedImage
+		}
+
+		if !pngMetadataChunks[string(content[i+4:i+8])] {
+			out.Write(content[i:end])
+		}
+
+		i = end
+	}
+
+	return out.Bytes(), nil
+}
+
+// stripWebpMetadata drops the EXIF and XMP chunks of the RIFF container, and
+// clears the flags in the VP8X header that announce them
+func stripWebpMetadata(content []byte) ([]byte, error) {
+	if len(content) < 12 ||
+		string(content[0:4]) != "RIFF" ||
+		string(content[8:12]) != "WEBP" {
+
+		return nil, errMalformedImage
+	}
+
+	var chunks bytes.Buffer
+
+	i := 12
+	for i < len(content) {
+		if i+8 > len(content) {
+			return nil, errMalformedImage
+		}
+		fourCC := string(content[i : i+4])
+		length := int(binary.LittleEndian.Uint32(content[i+4 : i+8]))
+
+		// Chunks are padded to an even length
+		end := i + 8 + length + length%2
+		if length < 0 || end > len(content) || end < i {
+			return nil, errMalformedImage
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := make([]byte, end-i)
+			copy(chunk, content[i:end])
+			if length > 0 {
+				// Bit 3 is EXIF, bit 2 is XMP
+				chunk[8] &^= 0x08 | 0x04
+			}
+			chunks.Write(chunk)
+		default:
+			chunks.Write(content[i:end])
+		}
+
+		i = end
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(4+chunks.Len()))
+	out.Write(size)
+	out.WriteString("WEBP")
+	out.Write(chunks.Bytes())
+
+	return out.Bytes(), nil
+}
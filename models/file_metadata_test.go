@@ -0,0 +1,123 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// makeGpsExif returns an APP1 segment holding EXIF with a GPS latitude
+func makeGpsExif() []byte {
+	var tiff bytes.Buffer
+	le := binary.LittleEndian
+
+	tiff.WriteString("II")
+	binary.Write(&tiff, le, uint16(42))
+	binary.Write(&tiff, le, uint32(8))
+
+	// IFD0 with a pointer to the GPS IFD
+	binary.Write(&tiff, le, uint16(1))
+	binary.Write(&tiff, le, uint16(0x8825))
+	binary.Write(&tiff, le, uint16(4))
+	binary.Write(&tiff, le, uint32(1))
+	binary.Write(&tiff, le, uint32(26))
+	binary.Write(&tiff, le, uint32(0))
+
+	// GPS IFD with GPSLatitudeRef = "N"
+	binary.Write(&tiff, le, uint16(1))
+	binary.Write(&tiff, le, uint16(0x0001))
+	binary.Write(&tiff, le, uint16(2))
+	binary.Write(&tiff, le, uint32(2))
+	tiff.Write([]byte{'N', 0, 0, 0})
+	binary.Write(&tiff, le, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var segment bytes.Buffer
+	segment.Write([]byte{0xFF, 0xE1})
+	binary.Write(&segment, binary.BigEndian, uint16(len(payload)+2))
+	segment.Write(payload)
+
+	return segment.Bytes()
+}
+
+func TestStripSensitiveExif(t *testing.T) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := buf.Bytes()
+
+	// Insert the EXIF straight after the start of image marker
+	withGps := append([]byte{}, plain[:2]...)
+	withGps = append(withGps, makeGpsExif()...)
+	withGps = append(withGps, plain[2:]...)
+
+	ex, err := exif.Decode(bytes.NewReader(withGps))
+	if err != nil {
+		t.Fatalf("Test image has no EXIF: %+v", err)
+	}
+	_, err = ex.Get(exif.GPSLatitudeRef)
+	if err != nil {
+		t.Fatalf("Test image has no GPS: %+v", err)
+	}
+
+	stripped, err := StripSensitiveExif(withGps, ImageJpegMimeType)
+	if err != nil {
+		t.Fatalf("StripSensitiveExif() failed: %+v", err)
+	}
+
+	ex, err = exif.Decode(bytes.NewReader(stripped))
+	if err == nil {
+		_, err = ex.Get(exif.GPSLatitudeRef)
+		if err == nil {
+			t.Errorf("GPS tags remain after StripSensitiveExif()")
+		}
+	}
+
+	if !bytes.Equal(stripped, plain) {
+		t.Errorf("StripSensitiveExif() altered more than the EXIF")
+	}
+
+	_, err = jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Errorf("Stripped image cannot be decoded: %+v", err)
+	}
+}
+
+func TestStripSensitiveExifPng(t *testing.T) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := buf.Bytes()
+
+	// A tEXt chunk straight after IHDR, which is 8 + 25 bytes in
+	text := []byte("Comment\x00Taken at home")
+	var chunk bytes.Buffer
+	binary.Write(&chunk, binary.BigEndian, uint32(len(text)))
+	chunk.WriteString("tEXt")
+	chunk.Write(text)
+	binary.Write(&chunk, binary.BigEndian, crc32.ChecksumIEEE(append([]byte("tEXt"), text...)))
+
+	withText := append([]byte{}, plain[:33]...)
+	withText = append(withText, chunk.Bytes()...)
+	withText = append(withText, plain[33:]...)
+
+	stripped, err := StripSensitiveExif(withText, ImagePngMimeType)
+	if err != nil {
+		t.Fatalf("StripSensitiveExif() failed: %+v", err)
+	}
+
+	if !bytes.Equal(stripped, plain) {
+		t.Errorf("StripSensitiveExif() did not remove exactly the tEXt chunk")
+	}
+}
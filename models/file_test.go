@@ -0,0 +1,275 @@
+package models
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func TestMakeThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 400, 300)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := FileMetadataType{
+		Content:  buf.Bytes(),
+		MimeType: ImagePngMimeType,
+		Width:    400,
+		Height:   300,
+	}
+
+	_, err = f.MakeThumbnail(ThumbnailMaxSize)
+	if err != nil {
+		t.Fatalf("MakeThumbnail() failed: %+v", err)
+	}
+
+	if f.ThumbnailWidth != 200 || f.ThumbnailHeight != 150 {
+		t.Errorf(
+			"Expected a 200x150 thumbnail, got %dx%d",
+			f.ThumbnailWidth,
+			f.ThumbnailHeight,
+		)
+	}
+
+	if !bytes.Equal(f.Content, buf.Bytes()) {
+		t.Errorf("The full-size image was modified")
+	}
+
+	im, _, err := image.DecodeConfig(bytes.NewReader(f.ThumbnailContent))
+	if err != nil {
+		t.Fatalf("Thumbnail could not be decoded: %+v", err)
+	}
+	if int64(im.Width) != f.ThumbnailWidth || int64(im.Height) != f.ThumbnailHeight {
+		t.Errorf("Recorded thumbnail dimensions don't match the thumbnail")
+	}
+}
+
+func makeAnimatedGif(t *testing.T, width int, height int, frames int) []byte {
+	palette := color.Palette{color.Black, color.White}
+
+	g := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+		frame.SetColorIndex(i, i, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10*(i+1))
+		g.Disposal = append(g.Disposal, gif.DisposalNone)
+	}
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResizeImageKeepsGifAnimation(t *testing.T) {
+	content := makeAnimatedGif(t, 400, 400, 3)
+	f := FileMetadataType{
+		Content:  content,
+		MimeType: ImageGifMimeType,
+		Width:    400,
+		Height:   400,
+	}
+
+	_, err := f.ResizeImage(200, 200)
+	if err != nil {
+		t.Fatalf("ResizeImage() failed: %+v", err)
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(f.Content))
+	if err != nil {
+		t.Fatalf("gif.DecodeAll() failed: %+v", err)
+	}
+	if len(g.Image) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", len(g.Image))
+	}
+	for i, frame := range g.Image {
+		if frame.Bounds().Dx() != 200 || frame.Bounds().Dy() != 200 {
+			t.Errorf("Frame %d is %v, expected 200x200", i, frame.Bounds())
+		}
+		if g.Delay[i] != 10*(i+1) {
+			t.Errorf("Frame %d has delay %d, expected %d", i, g.Delay[i], 10*(i+1))
+		}
+	}
+
+	// Avatars are flattened
+	f = FileMetadataType{
+		Content:  content,
+		MimeType: ImageGifMimeType,
+		Width:    400,
+		Height:   400,
+	}
+
+	_, err = f.ResizeImage(AvatarMaxWidth, AvatarMaxHeight)
+	if err != nil {
+		t.Fatalf("ResizeImage() failed: %+v", err)
+	}
+
+	g, err = gif.DecodeAll(bytes.NewReader(f.Content))
+	if err != nil {
+		t.Fatalf("gif.DecodeAll() failed: %+v", err)
+	}
+	if len(g.Image) != 1 {
+		t.Errorf("Expected an avatar to have 1 frame, got %d", len(g.Image))
+	}
+}
+
+func TestReconcileContentType(t *testing.T) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pngContent := buf.Bytes()
+	htmlContent := []byte("<html><body><script>alert(1)</script></body></html>")
+	svgContent := []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+
+	tests := []struct {
+		declared string
+		content  []byte
+		mimeType string
+		isImage  bool
+		valid    bool
+	}{
+		{ImagePngMimeType, pngContent, ImagePngMimeType, true, true},
+		{ImageJpegMimeType, pngContent, "", false, false},
+		{ImageJpegMimeType, htmlContent, "", false, false},
+		{"application/octet-stream", pngContent, ImagePngMimeType, true, true},
+		{"application/octet-stream", htmlContent, "text/plain; charset=utf-8", false, true},
+		{"text/html", htmlContent, "text/plain; charset=utf-8", false, true},
+		{"text/html", []byte("alert(1)"), "text/plain; charset=utf-8", false, true},
+		{"text/html; charset=utf-8", []byte{0, 1, 2, 3}, "application/octet-stream", false, true},
+		{"application/xhtml+xml", []byte("alert(1)"), "text/plain; charset=utf-8", false, true},
+		{"text/plain", htmlContent, "text/plain; charset=utf-8", false, true},
+		{ImageSvgMimeType, svgContent, "text/plain; charset=utf-8", false, true},
+		{ImageSvgMimeType, []byte("<svg></svg>"), "text/plain; charset=utf-8", false, true},
+		{ImageSvgMimeType, pngContent, "", false, false},
+		{"application/pdf", []byte("%PDF-1.4"), "application/pdf", false, true},
+	}
+
+	for _, test := range tests {
+		mimeType, isImage, err := reconcileContentType(test.declared, test.content)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s with content %q was accepted", test.declared, test.content[:8])
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s was rejected: %+v", test.declared, err)
+			continue
+		}
+		if mimeType != test.mimeType || isImage != test.isImage {
+			t.Errorf(
+				"%s gave (%s, %t), expected (%s, %t)",
+				test.declared,
+				mimeType,
+				isImage,
+				test.mimeType,
+				test.isImage,
+			)
+		}
+	}
+}
+
+func TestIsImageMimeType(t *testing.T) {
+	for _, mimeType := range []string{
+		ImageGifMimeType,
+		ImageJpegMimeType,
+		"image/PNG",
+		ImageWebpMimeType + "; charset=binary",
+	} {
+		if !IsImageMimeType(mimeType) {
+			t.Errorf("%s was not an image type", mimeType)
+		}
+	}
+
+	for _, mimeType := range []string{
+		ImageSvgMimeType,
+		"text/html",
+		"text/plain; charset=utf-8",
+		"application/octet-stream",
+		"",
+	} {
+		if IsImageMimeType(mimeType) {
+			t.Errorf("%s was an image type", mimeType)
+		}
+	}
+}
+
+func TestCheckSvg(t *testing.T) {
+	safe := `<svg xmlns="http://www.w3.org/2000/svg"><circle r="1"/></svg>`
+	if err := checkSvg([]byte(safe)); err != nil {
+		t.Errorf("Safe SVG was refused: %+v", err)
+	}
+
+	for _, unsafe := range []string{
+		`<svg><script>alert(1)</script></svg>`,
+		`<svg onload="alert(1)"></svg>`,
+		`<svg><a xlink:href="javascript:alert(1)"/></svg>`,
+		`<svg><foreignObject><iframe/></foreignObject></svg>`,
+		`<svg><a href="&#106;avascript:alert(1)"/></svg>`,
+	} {
+		if err := checkSvg([]byte(unsafe)); err == nil {
+			t.Errorf("Unsafe SVG was accepted: %s", unsafe)
+		}
+	}
+}
+
+func TestIsVariantSize(t *testing.T) {
+	for _, size := range []int64{100, ThumbnailMaxSize, VariantMaxSize} {
+		if !IsVariantSize(size) {
+			t.Errorf("Expected %d to be a variant size", size)
+		}
+	}
+	for _, size := range []int64{-100, 0, 1, 101, VariantMaxSize + 1} {
+		if IsVariantSize(size) {
+			t.Errorf("Expected %d not to be a variant size", size)
+		}
+	}
+}
+
+func TestVariantSize(t *testing.T) {
+	tests := []struct {
+		imageWidth, imageHeight int64
+		maxWidth, maxHeight     int64
+		width, height           int64
+	}{
+		{800, 600, 400, 0, 400, 0},
+		{800, 600, 0, 300, 0, 300},
+		{800, 600, 400, 100, 0, 100},
+		{800, 600, 100, 400, 100, 0},
+		{800, 600, 1000, 1000, 0, 0},
+		{800, 600, 0, 0, 0, 0},
+		{5000, 5000, 9999, 0, VariantMaxSize, 0},
+	}
+
+	for _, test := range tests {
+		width, height := variantSize(
+			test.imageWidth,
+			test.imageHeight,
+			test.maxWidth,
+			test.maxHeight,
+		)
+		if width != test.width || height != test.height {
+			t.Errorf(
+				"%dx%d within %dx%d gave %dx%d, expected %dx%d",
+				test.imageWidth,
+				test.imageHeight,
+				test.maxWidth,
+				test.maxHeight,
+				width,
+				height,
+				test.width,
+				test.height,
+			)
+		}
+	}
+}
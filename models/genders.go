@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+
+	conf "github.com/microcosm-cc/microcosm/config"
+)
+
+// ProfileGenderUnspecified is the gender of a profile that has not chosen
+// one, it is stored as NULL
+const ProfileGenderUnspecified string = "unspecified"
+
+// ProfileGenders returns the genders that a profile may choose from, as
+// configured by profile_genders. Unspecified is always included.
+func ProfileGenders() []string {
+	genders := []string{}
+	hasUnspecified := false
+
+	for _, gender := range strings.Split(
+		conf.CONFIG_STRING[conf.KEY_PROFILE_GENDERS],
+		",",
+	) {
+		gender = strings.ToLower(strings.TrimSpace(gender))
+		if gender == "" {
+			continue
+		}
+		if gender == ProfileGenderUnspecified {
+			hasUnspecified = true
+		}
+		genders = append(genders, gender)
+	}
+
+	if !hasUnspecified {
+		genders = append(genders, ProfileGenderUnspecified)
+	}
+
+	return genders
+}
+
+// normaliseGender returns the gender as it appears in ProfileGenders, an
+// empty gender is unspecified. The second value is false if the gender is not
+// one of ProfileGenders.
+func normaliseGender(gender string) (string, bool) {
+	gender = strings.ToLower(strings.TrimSpace(gender))
+	if gender == "" {
+		return ProfileGenderUnspecified, true
+	}
+
+	for _, g := range ProfileGenders() {
+		if g == gender {
+			return g, true
+		}
+	}
+
+	return gender, false
+}
+
+// readGender returns the gender of a profile as stored in the database.
+// Profiles created before the genders were restricted may have free text that
+// is not one of ProfileGenders, these are unspecified.
+func readGender(gender string) string {
+	g, ok := normaliseGender(gender)
+	if !ok {
+		return ProfileGenderUnspecified
+	}
+
+	return g
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	conf "github.com/microcosm-cc/microcosm/config"
+)
+
+// GeocodeType is the location of a place, Found is false if the geocoder did
+// not recognise the place
+type GeocodeType struct {
+	Found bool
+	Lat   float64
+	Lon   float64
+	North float64
+	East  float64
+	South float64
+	West  float64
+}
+
+const (
+	mcGeocodeKey = "gc_%x"
+
+	// Places rarely move, but those that were not found may be added to the
+	// geocoder's data
+	geocodeTtl         int32 = 60 * 60 * 24 * 30 // 30 Days
+	geocodeNotFoundTtl int32 = 60 * 60 * 24      // 1 Day
+)
+
+var geocodeClient = &http.Client{Timeout: 5 * time.Second}
+
+// Geocode returns the location of the place described by where using the
+// geocoder configured by geocoder_url, which must be a Nominatim compatible
+// search API. Results are cached by where. The second value is false if no
+// geocoder is configured, the place was not found or the lookup failed.
+func Geocode(where string) (GeocodeType, bool) {
+	where = strings.TrimSpace(where)
+	if where == "" || conf.CONFIG_STRING[conf.KEY_GEOCODER_URL] == "" {
+		return GeocodeType{}, false
+	}
+
+	mcKey := fmt.Sprintf(mcGeocodeKey, sha1.Sum([]byte(strings.ToLower(where))))
+	if val, ok := c.CacheGet(mcKey, GeocodeType{}); ok {
+		m := val.(GeocodeType)
+		return m, m.Found
+	}
+
+	m, err := lookupGeocode(where)
+	if err != nil {
+		// Not cached, the geocoder may be working again next time
+		glog.Warningf("lookupGeocode(%s) %+v", where, err)
+		return GeocodeType{}, false
+	}
+
+	if m.Found {
+		c.CacheSet(mcKey, m, geocodeTtl)
+	} else {
+		c.CacheSet(mcKey, m, geocodeNotFoundTtl)
+	}
+
+	return m, m.Found
+}
+
+// nominatimPlace is a search result from a Nominatim compatible API, the
+// bounding box is south, north, west, east
+type nominatimPlace struct {
+	Lat         string   `json:"lat"`
+	Lon         string   `json:"lon"`
+	BoundingBox []string `json:"boundingbox"`
+}
+
+func lookupGeocode(where string) (GeocodeType, error) {
+	u, err := url.Parse(conf.CONFIG_STRING[conf.KEY_GEOCODER_URL])
+	if err != nil {
+		return GeocodeType{}, err
+	}
+
+	q := u.Query()
+	q.Set("q", where)
+	q.Set("format", "json")
+	q.Set("limit", "1")
+	if conf.CONFIG_STRING[conf.KEY_GEOCODER_KEY] != "" {
+		q.Set("key", conf.CONFIG_STRING[conf.KEY_GEOCODER_KEY])
+	}
+	u.RawQuery = q.Encode()
+
+	resp, err := geocodeClient.Get(u.String())
+	if err != nil {
+		return GeocodeType{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return GeocodeType{}, errors.New(
+			fmt.Sprintf("Geocoder returned %d", resp.StatusCode),
+		)
+	}
+
+	places := []nominatimPlace{}
+	err = json.NewDecoder(resp.Body).Decode(&places)
+	if err != nil {
+		return GeocodeType{}, err
+	}
+
+	return parseNominatimPlaces(places)
+}
+
+func parseNominatimPlaces(places []nominatimPlace) (GeocodeType, error) {
+	if len(places) == 0 {
+		return GeocodeType{}, nil
+	}
+
+	floats := []string{places[0].Lat, places[0].Lon}
+	if len(places[0].BoundingBox) == 4 {
+		floats = append(floats, places[0].BoundingBox...)
+	}
+
+	values := []float64{}
+	for _, s := range floats {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return GeocodeType{}, err
+		}
+		values = append(values, f)
+	}
+
+	m := GeocodeType{Found: true, Lat: values[0], Lon: values[1]}
+	if len(values) == 6 {
+		m.South = values[2]
+		m.North = values[3]
+		m.West = values[4]
+		m.East = values[5]
+	}
+
+	return m, nil
+}
+
+// geocode sets the location of the event from Where if it was not given. It
+// is best effort, the location is left empty if it cannot be found.
+func (m *EventType) geocode() {
+	if m.Where == "" || m.Lat != 0 || m.Lon != 0 {
+		return
+	}
+
+	g, ok := Geocode(m.Where)
+	if !ok {
+		return
+	}
+
+	m.setGeocode(g)
+}
+
+// setGeocode sets the location of the event to that of the place, keeping any
+// bounds that were given
+func (m *EventType) setGeocode(g GeocodeType) {
+	m.Lat = g.Lat
+	m.Lon = g.Lon
+	if m.North == 0 && m.East == 0 && m.South == 0 && m.West == 0 {
+		m.North = g.North
+		m.East = g.East
+		m.South = g.South
+		m.West = g.West
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+
+	conf "github.com/microcosm-cc/microcosm/config"
+)
+
+func TestParseNominatimPlaces(t *testing.T) {
+	m, err := parseNominatimPlaces([]nominatimPlace{{
+		Lat:         "51.5073",
+		Lon:         "-0.1276",
+		BoundingBox: []string{"51.28", "51.69", "-0.51", "0.33"},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !m.Found || m.Lat != 51.5073 || m.Lon != -0.1276 ||
+		m.South != 51.28 || m.North != 51.69 || m.West != -0.51 || m.East != 0.33 {
+
+		t.Errorf("Unexpected location %+v", m)
+	}
+
+	m, err = parseNominatimPlaces([]nominatimPlace{})
+	if err != nil || m.Found {
+		t.Errorf("Expected no places to not be found, got %+v %v", m, err)
+	}
+}
+
+func TestEventGeocode(t *testing.T) {
+	geocoderURL := conf.CONFIG_STRING[conf.KEY_GEOCODER_URL]
+	defer func() {
+		conf.CONFIG_STRING[conf.KEY_GEOCODER_URL] = geocoderURL
+	}()
+
+	// Given coordinates are kept without a lookup
+	conf.CONFIG_STRING[conf.KEY_GEOCODER_URL] = "http://geocoder.invalid/search"
+	m := EventType{Where: "London", Lat: 1, Lon: 2}
+	m.geocode()
+	if m.Lat != 1 || m.Lon != 2 {
+		t.Errorf("Expected the given location to be kept, got %f,%f", m.Lat, m.Lon)
+	}
+
+	// Without a geocoder the location is left empty
+	conf.CONFIG_STRING[conf.KEY_GEOCODER_URL] = ""
+	m = EventType{Where: "London"}
+	m.geocode()
+	if m.Lat != 0 || m.Lon != 0 {
+		t.Errorf("Expected no location, got %f,%f", m.Lat, m.Lon)
+	}
+}
+
+func TestEventSetGeocode(t *testing.T) {
+	g := GeocodeType{Found: true, Lat: 51.5, Lon: -0.1, North: 51.7, South: 51.3}
+
+	m := EventType{Where: "London"}
+	m.setGeocode(g)
+	if m.Lat != 51.5 || m.Lon != -0.1 || m.North != 51.7 || m.South != 51.3 {
+		t.Errorf("Expected the location to be geocoded, got %+v", m)
+	}
+
+	// Given bounds are kept
+	m = EventType{Where: "London", North: 52}
+	m.setGeocode(g)
+	if m.Lat != 51.5 || m.North != 52 || m.South != 0 {
+		t.Errorf("Expected the given bounds to be kept, got %f,%f", m.North, m.South)
+	}
+}
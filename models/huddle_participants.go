@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/golang/glog"
+
 	h "github.com/microcosm-cc/microcosm/helpers"
 )
 
@@ -40,35 +44,125 @@ func (m *HuddleParticipantType) Validate(siteId int64) (int, error) {
 	return http.StatusOK, nil
 }
 
-func UpdateManyHuddleParticipants(
+// IsParticipant returns true if the profile is a participant of the huddle
+func (m *HuddleType) IsParticipant(profileId int64) bool {
+	for _, p := range m.Participants {
+		if p.Id == profileId {
+			return true
+		}
+	}
+	return false
+}
+
+// AddParticipants adds profiles to the huddle on behalf of the actor, who must
+// already be a participant. Profiles that are already participants are
+// ignored. Nothing is added if any of the profiles does not exist on the site.
+//
+// New participants see the whole huddle, including the comments made before
+// they were added.
+func (m *HuddleType) AddParticipants(
 	siteId int64,
-	huddleId int64,
-	ems []HuddleParticipantType,
+	actorId int64,
+	profileIds []int64,
 ) (
 	int,
 	error,
 ) {
 
+	if !m.IsParticipant(actorId) {
+		return http.StatusForbidden,
+			errors.New("Only participants of a huddle may add others to it")
+	}
+
+	if len(profileIds) == 0 {
+		return http.StatusBadRequest,
+			errors.New("You must specify at least one profile to add")
+	}
+
+	invalid := []string{}
+	for _, profileId := range profileIds {
+		if profileId < 1 {
+			invalid = append(invalid, strconv.FormatInt(profileId, 10))
+			continue
+		}
+
+		_, status, err := GetProfileSummary(siteId, profileId)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				return status, err
+			}
+			invalid = append(invalid, strconv.FormatInt(profileId, 10))
+		}
+	}
+	if len(invalid) > 0 {
+		return http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"These profiles do not exist on this site: %s",
+				strings.Join(invalid, ", "),
+			),
+		)
+	}
+
 	tx, err := h.GetTransaction()
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
 	defer tx.Rollback()
 
-	for _, m := range ems {
-		status, err := m.insert(tx, siteId, huddleId)
+	added := []int64{}
+	for _, profileId := range profileIds {
+		res, err := tx.Exec(`--AddParticipants
+INSERT INTO huddle_profiles
+SELECT $1, $2
+ WHERE NOT EXISTS (
+       SELECT huddle_id
+             ,profile_id
+         FROM huddle_profiles
+        WHERE huddle_id = $1
+          AND profile_id = $2
+       )`,
+			m.Id,
+			profileId,
+		)
+		if err != nil {
+			return http.StatusInternalServerError, errors.New(
+				fmt.Sprintf("Error executing upsert: %v", err.Error()),
+			)
+		}
+
+		rowsAffected, err := res.RowsAffected()
 		if err != nil {
-			return status, err
+			return http.StatusInternalServerError, errors.New(
+				fmt.Sprintf("Error fetching rows affected: %v", err.Error()),
+			)
+		}
+		if rowsAffected > 0 {
+			added = append(added, profileId)
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
 		return http.StatusInternalServerError,
-			errors.New(fmt.Sprintf("Transaction failed: %+v", err))
+			errors.New(fmt.Sprintf("Transaction failed: %v", err.Error()))
 	}
 
-	go PurgeCache(h.ItemTypes[h.ItemTypeHuddle], huddleId)
+	PurgeCache(h.ItemTypes[h.ItemTypeHuddle], m.Id)
+
+	for _, profileId := range added {
+		_, _, err = RegisterWatcher(
+			profileId,
+			h.UpdateTypes[h.UpdateTypeNewCommentInHuddle],
+			m.Id,
+			h.ItemTypes[h.ItemTypeHuddle],
+			siteId,
+		)
+		if err != nil {
+			glog.Errorf("RegisterWatcher(%d, %d) %+v", profileId, m.Id, err)
+		}
+
+		UpdateUnreadHuddleCount(profileId)
+	}
 
 	return http.StatusOK, nil
 }
@@ -140,51 +234,12 @@ SELECT $1, $2
 	return http.StatusOK, nil
 }
 
+// Delete removes the participant from the huddle, see HuddleType.LeaveHuddle
 func (m *HuddleParticipantType) Delete(huddleId int64) (int, error) {
+	huddle := HuddleType{}
+	huddle.Id = huddleId
 
-	tx, err := h.GetTransaction()
-	if err != nil {
-		return http.StatusInternalServerError, err
-	}
-	defer tx.Rollback()
-
-	status, err := m.delete(tx, huddleId)
-	if err != nil {
-		return status, err
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		return http.StatusInternalServerError,
-			errors.New(fmt.Sprintf("Transaction failed: %v", err.Error()))
-	}
-
-	return http.StatusOK, nil
-}
-
-func (m *HuddleParticipantType) delete(
-	tx *sql.Tx,
-	huddleId int64,
-) (
-	int,
-	error,
-) {
-
-	_, err := tx.Exec(`
-DELETE FROM huddle_profiles
- WHERE huddle_id = $1
-   AND profile_id = $2`,
-		huddleId,
-		m.Id,
-	)
-	if err != nil {
-		return http.StatusInternalServerError,
-			errors.New(fmt.Sprintf("Error executing delete: %+v", err))
-	}
-
-	go UpdateUnreadHuddleCount(m.Id)
-
-	return http.StatusOK, nil
+	return huddle.LeaveHuddle(m.Id)
 }
 
 func GetHuddleParticipant(
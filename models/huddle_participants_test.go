@@ -0,0 +1,33 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAddParticipantsRequiresParticipant(t *testing.T) {
+	m := HuddleType{
+		Id:           1,
+		Participants: []ProfileSummaryType{{Id: 2}, {Id: 3}},
+	}
+
+	if !m.IsParticipant(3) || m.IsParticipant(4) {
+		t.Fatalf("Expected only profiles 2 and 3 to be participants")
+	}
+
+	status, err := m.AddParticipants(1, 4, []int64{5})
+	if err == nil || status != http.StatusForbidden {
+		t.Errorf("Expected a non-participant to be forbidden, got %d %v", status, err)
+	}
+
+	status, err = m.AddParticipants(1, 2, []int64{})
+	if err == nil || status != http.StatusBadRequest {
+		t.Errorf("Expected no profiles to be a bad request, got %d %v", status, err)
+	}
+
+	status, err = m.AddParticipants(1, 2, []int64{0, -1})
+	if err == nil || status != http.StatusBadRequest ||
+		err.Error() != "These profiles do not exist on this site: 0, -1" {
+		t.Errorf("Expected the invalid IDs to be listed, got %d %v", status, err)
+	}
+}
@@ -250,10 +250,16 @@ INSERT INTO huddle_profiles (
 	return http.StatusOK, nil
 }
 
+// Delete is a synonym for the profile leaving the huddle
 func (m *HuddleType) Delete(siteId int64, profileId int64) (int, error) {
+	return m.LeaveHuddle(profileId)
+}
 
-	// Synonym for removing participant. If all participants are removed then
-	// this will physically delete the huddle
+// LeaveHuddle removes the profile from the participants of the huddle, and
+// stops them watching it so that they are no longer sent its comments. A
+// profile that is not a participant cannot leave. When every participant has
+// left the huddle is deleted by DeleteOrphanedHuddles.
+func (m *HuddleType) LeaveHuddle(profileId int64) (int, error) {
 
 	tx, err := h.GetTransaction()
 	if err != nil {
@@ -261,7 +267,7 @@ func (m *HuddleType) Delete(siteId int64, profileId int64) (int, error) {
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`
+	res, err := tx.Exec(`--LeaveHuddle
 DELETE FROM huddle_profiles
  WHERE huddle_id = $1
    AND profile_id = $2`,
@@ -274,6 +280,53 @@ DELETE FROM huddle_profiles
 		)
 	}
 
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Error fetching rows affected: %v", err.Error()),
+		)
+	}
+	if rowsAffected == 0 {
+		return http.StatusNotFound,
+			errors.New("You are not a participant of this huddle")
+	}
+
+	rows, err := tx.Query(`--LeaveHuddle
+DELETE FROM watchers
+ WHERE profile_id = $1
+   AND item_type_id = $2
+   AND item_id = $3
+RETURNING watcher_id`,
+		profileId,
+		h.ItemTypes[h.ItemTypeHuddle],
+		m.Id,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Delete of watcher failed: %v", err.Error()),
+		)
+	}
+	defer rows.Close()
+
+	watcherIds := []int64{}
+	for rows.Next() {
+		var watcherId int64
+		err = rows.Scan(&watcherId)
+		if err != nil {
+			return http.StatusInternalServerError, errors.New(
+				fmt.Sprintf("Row parsing error: %v", err.Error()),
+			)
+		}
+		watcherIds = append(watcherIds, watcherId)
+	}
+	err = rows.Err()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Error fetching rows: %v", err.Error()),
+		)
+	}
+	rows.Close()
+
 	err = tx.Commit()
 	if err != nil {
 		return http.StatusInternalServerError, errors.New(
@@ -282,6 +335,13 @@ DELETE FROM huddle_profiles
 	}
 
 	PurgeCache(h.ItemTypes[h.ItemTypeHuddle], m.Id)
+	for _, watcherId := range watcherIds {
+		PurgeCache(h.ItemTypes[h.ItemTypeWatcher], watcherId)
+	}
+
+	// The huddle no longer counts towards the unread huddles of the profile,
+	// this also purges the cached count
+	UpdateUnreadHuddleCount(profileId)
 
 	return http.StatusOK, nil
 }
@@ -378,7 +438,7 @@ SELECT h.huddle_id
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeHuddle))
 
 	m.FetchProfileSummaries(siteId)
 
@@ -415,7 +475,7 @@ SELECT title
 	}
 
 	// Update cache
-	c.CacheSetString(mcKey, title, mcTtl)
+	c.CacheSetString(mcKey, title, cacheTtl(h.ItemTypeHuddle))
 
 	return title
 }
@@ -564,7 +624,7 @@ SELECT h.huddle_id
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeHuddle))
 
 	m.FetchProfileSummaries(siteId)
 
@@ -597,6 +657,7 @@ SELECT COUNT(*) AS total
   FROM huddles h
   JOIN huddle_profiles hp ON hp.huddle_id = h.huddle_id
   LEFT JOIN ignores i ON i.profile_id = $1
+                     AND (i.expires IS NULL OR i.expires > NOW())
                      AND i.item_type_id = 3
                      AND i.item_id = h.created_by
  WHERE hp.profile_id = $1
@@ -619,6 +680,7 @@ SELECT huddle_id
               JOIN flags f ON f.item_type_id = 5
                           AND f.item_id = h.huddle_id
               LEFT JOIN ignores i ON i.profile_id = $1
+                                 AND (i.expires IS NULL OR i.expires > NOW())
                                  AND i.item_type_id = 3
                                  AND i.item_id = h.created_by
              WHERE hp.profile_id = $1
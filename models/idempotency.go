@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header that identifies a create
+	// request, so that a retry of it returns what the first request created
+	IdempotencyKeyHeader string = "Idempotency-Key"
+
+	// maxIdempotencyKeyLength is the longest Idempotency-Key accepted
+	maxIdempotencyKeyLength int = 255
+
+	// Idempotency keys are scoped by site and profile, and by the method and
+	// path they were sent to within the hashed part
+	mcIdempotencyKey string = "idem_%d_%d_%s"
+
+	// idempotencyTtl is how long a retried request will be recognised
+	idempotencyTtl int32 = 60 * 60 * 24 // 1 day
+
+	// idempotencyReserveTtl is how long a key is held for a request that is
+	// still creating, should it never finish
+	idempotencyReserveTtl int32 = 60 // 1 minute
+)
+
+// getIdempotencyKey returns the cache key of an Idempotency-Key sent by a
+// profile on a site to an endpoint
+func getIdempotencyKey(
+	siteId int64,
+	profileId int64,
+	method string,
+	path string,
+	key string,
+) string {
+	return fmt.Sprintf(
+		mcIdempotencyKey,
+		siteId,
+		profileId,
+		h.Md5sum(method+" "+path+" "+key),
+	)
+}
+
+// reserveIdempotencyKey marks the key as in use by a request that is creating
+// something, and returns false if another request already holds it
+func reserveIdempotencyKey(mcKey string) bool {
+	return c.CacheAdd(mcKey, "", idempotencyReserveTtl)
+}
+
+// releaseIdempotencyKey frees a key whose request failed so that it may be
+// retried
+func releaseIdempotencyKey(mcKey string) {
+	c.CacheDelete(mcKey)
+}
+
+// getIdempotentLocation returns the location of the resource created by the
+// first request with the key, nothing is returned while it is still creating
+func getIdempotentLocation(mcKey string) (string, bool) {
+	val, ok := c.CacheGet(mcKey, "")
+	if !ok {
+		return "", false
+	}
+
+	location, ok := val.(string)
+	return location, ok && location != ""
+}
+
+// setIdempotentLocation records the location of the resource created by a
+// request with the key
+func setIdempotentLocation(mcKey string, location string) {
+	c.CacheSet(mcKey, location, idempotencyTtl)
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+)
+
+func TestIdempotencyKeyScope(t *testing.T) {
+	key := getIdempotencyKey(1, 2, "POST", "/api/v1/comments", "retry-me")
+
+	if key != getIdempotencyKey(1, 2, "POST", "/api/v1/comments", "retry-me") {
+		t.Error("Expected the same key to be recognised")
+	}
+	if key == getIdempotencyKey(1, 3, "POST", "/api/v1/comments", "retry-me") {
+		t.Error("Expected keys to be scoped by profile")
+	}
+	if key == getIdempotencyKey(4, 2, "POST", "/api/v1/comments", "retry-me") {
+		t.Error("Expected keys to be scoped by site")
+	}
+	if key == getIdempotencyKey(1, 2, "POST", "/api/v1/events", "retry-me") {
+		t.Error("Expected keys to be scoped by path")
+	}
+	if key == getIdempotencyKey(1, 2, "PUT", "/api/v1/comments", "retry-me") {
+		t.Error("Expected keys to be scoped by method")
+	}
+	if len(getIdempotencyKey(1, 2, "POST", "/api/v1/comments", string(make([]byte, 1000)))) > 250 {
+		t.Error("Expected long keys to fit within a memcache key")
+	}
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	startTestCache(t)
+
+	first, _ := newIdempotentContext("replay")
+	if first.RespondWithIdempotentReplay() {
+		t.Fatal("Expected the first request to create")
+	}
+	first.RecordIdempotentCreate("/api/v1/comments/1")
+
+	retry, w := newIdempotentContext("replay")
+	if !retry.RespondWithIdempotentReplay() {
+		t.Fatal("Expected the retry to be answered")
+	}
+	if w.Code != http.StatusFound {
+		t.Errorf("Expected %d, got %d", http.StatusFound, w.Code)
+	}
+	if l := w.Header().Get("Location"); l != "/api/v1/comments/1" {
+		t.Errorf("Expected the first location, got %s", l)
+	}
+}
+
+func TestIdempotentConcurrent(t *testing.T) {
+	startTestCache(t)
+
+	first, _ := newIdempotentContext("concurrent")
+	if first.RespondWithIdempotentReplay() {
+		t.Fatal("Expected the first request to create")
+	}
+
+	// The first request is still creating
+	second, w := newIdempotentContext("concurrent")
+	if !second.RespondWithIdempotentReplay() {
+		t.Fatal("Expected the concurrent request to be answered")
+	}
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestIdempotentFailureReleases(t *testing.T) {
+	startTestCache(t)
+
+	for _, status := range []int{
+		http.StatusBadRequest,
+		http.StatusInternalServerError,
+	} {
+		key := fmt.Sprintf("failed-%d", status)
+
+		first, _ := newIdempotentContext(key)
+		if first.RespondWithIdempotentReplay() {
+			t.Fatal("Expected the first request to create")
+		}
+		first.RespondWithErrorMessage("Failed", status)
+
+		retry, w := newIdempotentContext(key)
+		if retry.RespondWithIdempotentReplay() {
+			t.Errorf(
+				"Expected a retry after a %d to create, got %d",
+				status,
+				w.Code,
+			)
+		}
+	}
+}
+
+// newIdempotentContext returns the context of a create request sent with the
+// Idempotency-Key and the recorder of its response
+func newIdempotentContext(key string) (*Context, *httptest.ResponseRecorder) {
+	r, _ := http.NewRequest("POST", "/api/v1/comments", nil)
+	r.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+
+	return &Context{
+		Request:        r,
+		ResponseWriter: w,
+		Site:           SiteType{Id: 1},
+		Auth:           AuthType{ProfileId: 2},
+		StartTime:      time.Now(),
+	}, w
+}
+
+var testCacheOnce sync.Once
+
+// startTestCache enables the cache against a memcache server in this process
+// that understands just enough of the protocol for the idempotency keys
+func startTestCache(t *testing.T) {
+	testCacheOnce.Do(func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("net.Listen() %+v", err)
+		}
+
+		s := &testMemcache{items: map[string][]byte{}}
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				go s.serve(conn)
+			}
+		}()
+
+		addr := l.Addr().(*net.TCPAddr)
+		c.InitCache(addr.IP.String(), int64(addr.Port))
+	})
+}
+
+type testMemcache struct {
+	sync.Mutex
+	items map[string][]byte
+}
+
+func (s *testMemcache) serve(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) < 2 {
+			return
+		}
+
+		s.Lock()
+		switch args[0] {
+		case "get", "gets":
+			for _, key := range args[1:] {
+				if val, ok := s.items[key]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d\r\n%s\r\n", key, len(val), val)
+				}
+			}
+			fmt.Fprint(rw, "END\r\n")
+
+		case "set", "add":
+			var size int
+			fmt.Sscan(args[4], &size)
+			val := make([]byte, size+2)
+			_, err = io.ReadFull(rw, val)
+			if err != nil {
+				s.Unlock()
+				return
+			}
+
+			if _, ok := s.items[args[1]]; ok && args[0] == "add" {
+				fmt.Fprint(rw, "NOT_STORED\r\n")
+			} else {
+				s.items[args[1]] = val[:size]
+				fmt.Fprint(rw, "STORED\r\n")
+			}
+
+		case "delete":
+			if _, ok := s.items[args[1]]; ok {
+				delete(s.items, args[1])
+				fmt.Fprint(rw, "DELETED\r\n")
+			} else {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			}
+
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		s.Unlock()
+
+		rw.Flush()
+	}
+}
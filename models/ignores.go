@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/glog"
+	"github.com/lib/pq"
 
 	h "github.com/microcosm-cc/microcosm/helpers"
 )
@@ -23,6 +26,10 @@ type IgnoreType struct {
 	ItemType   string      `json:"itemType,omitempty"`
 	ItemId     int64       `json:"itemId,omitempty"`
 	Item       interface{} `json:"item,omitempty"`
+
+	// An ignore without an expiry lasts until it is deleted
+	ExpiresAtNullable pq.NullTime `json:"-"`
+	ExpiresAt         string      `json:"expiresAt,omitempty"`
 }
 
 func (m *IgnoreType) Validate() (int, error) {
@@ -49,6 +56,25 @@ func (m *IgnoreType) Validate() (int, error) {
 			errors.New("You must specify an Item ID this comment belongs to")
 	}
 
+	if strings.Trim(m.ExpiresAt, " ") != "" {
+		expiresAt, err := time.Parse(time.RFC3339, m.ExpiresAt)
+		if err != nil {
+			return http.StatusBadRequest, errors.New(
+				fmt.Sprintf(
+					"expiresAt ('%s') must be a RFC3339 timestamp",
+					m.ExpiresAt,
+				),
+			)
+		}
+
+		if !expiresAt.After(time.Now()) {
+			return http.StatusBadRequest,
+				errors.New("expiresAt must be in the future")
+		}
+
+		m.ExpiresAtNullable = pq.NullTime{Time: expiresAt, Valid: true}
+	}
+
 	return http.StatusOK, nil
 }
 
@@ -65,20 +91,58 @@ func (m *IgnoreType) Update() (int, error) {
 	}
 	defer tx.Rollback()
 
-	// Lack of error checking, it can only fail if it has been inserted already
-	// and our answer "OK" remains the same if it exists after this action.
-	tx.Exec(`--Create Ignore
-INSERT INTO ignores (
-    profile_id, item_type_id, item_id
-) VALUES (
-    $1, $2, $3
-)`,
+	// Ignoring something that is already ignored replaces the expiry, so that
+	// a temporary ignore can be extended or made permanent
+	res, err := tx.Exec(`--Update Ignore
+UPDATE ignores
+   SET expires = $4
+ WHERE profile_id = $1
+   AND item_type_id = $2
+   AND item_id = $3`,
 		m.ProfileId,
 		m.ItemTypeId,
 		m.ItemId,
+		m.ExpiresAtNullable,
 	)
-	if err == nil {
-		tx.Commit()
+	if err != nil {
+		glog.Errorf("tx.Exec(%d, %d, %d) %+v", m.ProfileId, m.ItemTypeId, m.ItemId, err)
+		return http.StatusInternalServerError,
+			errors.New("Update of ignore failed")
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		glog.Errorf("res.RowsAffected() %+v", err)
+		return http.StatusInternalServerError,
+			errors.New("Error fetching rows affected")
+	}
+
+	if rowsAffected == 0 {
+		// Lack of error checking, it can only fail if it has been inserted
+		// already and our answer "OK" remains the same if it exists after this
+		// action.
+		tx.Exec(`--Create Ignore
+INSERT INTO ignores (
+    profile_id, item_type_id, item_id, expires
+) VALUES (
+    $1, $2, $3, $4
+)`,
+			m.ProfileId,
+			m.ItemTypeId,
+			m.ItemId,
+			m.ExpiresAtNullable,
+		)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		glog.Errorf("tx.Commit() %+v", err)
+		return http.StatusInternalServerError,
+			errors.New("Transaction failed")
+	}
+
+	if m.ItemTypeId == h.ItemTypes[h.ItemTypeProfile] {
+		purgeProfilesCacheOfProfile(m.ProfileId)
 	}
 
 	return http.StatusOK, nil
@@ -112,6 +176,10 @@ DELETE
 		tx.Commit()
 	}
 
+	if m.ItemTypeId == h.ItemTypes[h.ItemTypeProfile] {
+		purgeProfilesCacheOfProfile(m.ProfileId)
+	}
+
 	return http.StatusOK, nil
 }
 
@@ -142,34 +210,41 @@ SELECT COUNT(*) OVER() AS total
       ,profile_id
       ,item_type_id
       ,item_id
+      ,expires
   FROM (
            SELECT i.profile_id
                  ,i.item_type_id
                  ,i.item_id
+                 ,i.expires
                  ,m.title
              FROM ignores i
              JOIN microcosms m ON m.microcosm_id = i.item_id
             WHERE i.profile_id = $1
               AND i.item_type_id = 2
+              AND (i.expires IS NULL OR i.expires > NOW())
             UNION
            SELECT i.profile_id
                  ,i.item_type_id
                  ,i.item_id
+                 ,i.expires
                  ,p.profile_name AS title
              FROM ignores i
              JOIN profiles p ON p.profile_id = i.item_id
             WHERE i.profile_id = $1
               AND i.item_type_id = 3
+              AND (i.expires IS NULL OR i.expires > NOW())
             UNION
            SELECT i.profile_id
                  ,i.item_type_id
                  ,i.item_id
+                 ,i.expires
                  ,si.title_text AS title
              FROM ignores i
              JOIN search_index si ON si.item_type_id = i.item_type_id
                                  AND si.item_id = i.item_id
             WHERE i.profile_id = $1
               AND i.item_type_id NOT IN (2,3)
+              AND (i.expires IS NULL OR i.expires > NOW())
        ) a
  ORDER BY item_type_id ASC
          ,title ASC
@@ -199,6 +274,7 @@ OFFSET $3`
 			&m.ProfileId,
 			&m.ItemTypeId,
 			&m.ItemId,
+			&m.ExpiresAtNullable,
 		)
 		if err != nil {
 			glog.Errorf("rows.Scan() %+v", err)
@@ -213,6 +289,10 @@ OFFSET $3`
 		}
 		m.ItemType = itemType
 
+		if m.ExpiresAtNullable.Valid {
+			m.ExpiresAt = m.ExpiresAtNullable.Time.Format(time.RFC3339Nano)
+		}
+
 		ems = append(ems, m)
 	}
 	err = rows.Err()
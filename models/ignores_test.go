@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIgnoreValidateExpiresAt(t *testing.T) {
+	m := IgnoreType{
+		ProfileId: 1,
+		ItemType:  "profile",
+		ItemId:    2,
+		ExpiresAt: time.Now().Add(-time.Hour).Format(time.RFC3339),
+	}
+	if _, err := m.Validate(); err == nil {
+		t.Errorf("An expiry in the past was accepted")
+	}
+
+	m.ExpiresAt = "next week"
+	if _, err := m.Validate(); err == nil {
+		t.Errorf("An expiry that isn't a timestamp was accepted")
+	}
+
+	m.ExpiresAt = time.Now().Add(7 * 24 * time.Hour).Format(time.RFC3339)
+	if _, err := m.Validate(); err != nil {
+		t.Errorf("An expiry in the future was refused: %+v", err)
+	}
+	if !m.ExpiresAtNullable.Valid {
+		t.Errorf("ExpiresAtNullable was not set")
+	}
+
+	m = IgnoreType{ProfileId: 1, ItemType: "profile", ItemId: 2}
+	if _, err := m.Validate(); err != nil || m.ExpiresAtNullable.Valid {
+		t.Errorf("A permanent ignore was refused or given an expiry: %+v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 func init() {
 	// Required by the cache stuff
+	gob.Register([]MentionSuggestionType{})
 	gob.Register([]h.StatType{})
 	gob.Register(AccessTokenType{})
 	gob.Register(AttendeeType{})
@@ -16,6 +17,8 @@ func init() {
 	gob.Register(ConversationType{})
 	gob.Register(EventSummaryType{})
 	gob.Register(EventType{})
+	gob.Register(FollowCountsType{})
+	gob.Register(GeocodeType{})
 	gob.Register(HuddleSummaryType{})
 	gob.Register(HuddleType{})
 	gob.Register(Item{})
@@ -33,4 +36,5 @@ func init() {
 	gob.Register(UpdateType{})
 	gob.Register(UserType{})
 	gob.Register(WatcherType{})
+	gob.Register(profileSummaryPage{})
 }
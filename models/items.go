@@ -293,6 +293,7 @@ func GetAllItems(
 	sqlFromWhere := `
           FROM flags f
           LEFT JOIN ignores i ON i.profile_id = $3
+                             AND (i.expires IS NULL OR i.expires > NOW())
                              AND i.item_type_id = f.item_type_id
                              AND i.item_id = f.item_id
          WHERE f.microcosm_id = (
@@ -300,6 +301,7 @@ func GetAllItems(
                     WHERE (get_effective_permissions($1, $2, 2, $2, $3)).can_read IS TRUE
                )
            AND (f.item_type_id = 6 OR f.item_type_id = 9)
+           AND ` + sqlCanSeeEvent(`f.item_type_id`, `f.item_id`, `$3`) + `
            AND f.site_id = $1
            AND i.profile_id IS NULL
            AND f.microcosm_is_deleted IS NOT TRUE
@@ -512,6 +514,7 @@ SELECT item_type_id
    AND item_is_moderated IS NOT TRUE
    AND (item_type_id = 6
     OR item_type_id = 9)
+   AND `+sqlCanSeeEvent(`item_type_id`, `item_id`, `0`)+`
  ORDER BY last_modified DESC
  LIMIT 1`,
 		microcosmId,
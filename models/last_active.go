@@ -0,0 +1,210 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// maxLastActiveBuffer is the number of profiles whose last activity is held
+// before the buffer is flushed without waiting for the cron job
+const maxLastActiveBuffer = 10000
+
+// lastActiveBatchSize is the number of profiles updated by each statement
+const lastActiveBatchSize = 500
+
+// lastActiveRetryDelay is how long after a failed flush a full buffer waits
+// for the cron job rather than starting another flush
+const lastActiveRetryDelay = time.Minute
+
+var (
+	lastActive   = map[int64]time.Time{}
+	lastActiveMu sync.Mutex
+
+	// Guarded by lastActiveMu, a full buffer starts a single flush at a time
+	// and none until lastActiveRetry after one has failed
+	lastActiveFlushing bool
+	lastActiveRetry    time.Time
+
+	// Held for the duration of a flush so that flushes do not overlap
+	lastActiveFlushMu sync.Mutex
+)
+
+type byProfileId []int64
+
+func (a byProfileId) Len() int           { return len(a) }
+func (a byProfileId) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byProfileId) Less(i, j int) bool { return a[i] < a[j] }
+
+// RecordLastActive notes that the profile was active at the given time. The
+// time is held in memory and written to the database by FlushLastActive, so
+// that busy sites do not update a profile on every request.
+//
+// The buffer never holds more than maxLastActiveBuffer profiles. When it is
+// full, as it will be while the database is unavailable, the activity of
+// profiles not already held is dropped until a flush has made room.
+func RecordLastActive(profileId int64, t time.Time) {
+	if profileId <= 0 {
+		return
+	}
+
+	lastActiveMu.Lock()
+	if !bufferLastActive(lastActive, profileId, t) {
+		lastActiveMu.Unlock()
+		return
+	}
+	flush := shouldFlushLastActive(
+		len(lastActive),
+		lastActiveFlushing,
+		lastActiveRetry,
+		time.Now(),
+	)
+	if flush {
+		lastActiveFlushing = true
+	}
+	lastActiveMu.Unlock()
+
+	if flush {
+		go func() {
+			FlushLastActive()
+
+			lastActiveMu.Lock()
+			lastActiveFlushing = false
+			lastActiveMu.Unlock()
+		}()
+	}
+}
+
+// bufferLastActive holds the latest activity of the profile in the buffer,
+// returning false if the buffer is full and does not already hold the profile
+func bufferLastActive(
+	buffer map[int64]time.Time,
+	profileId int64,
+	t time.Time,
+) bool {
+	prev, ok := buffer[profileId]
+	if !ok && len(buffer) >= maxLastActiveBuffer {
+		return false
+	}
+	if !ok || t.After(prev) {
+		buffer[profileId] = t
+	}
+	return true
+}
+
+// shouldFlushLastActive returns true if a buffer of n profiles should be
+// flushed now, which it is once full unless a flush is already running or
+// the last one failed less than lastActiveRetryDelay ago
+func shouldFlushLastActive(
+	n int,
+	flushing bool,
+	retry time.Time,
+	now time.Time,
+) bool {
+	return n >= maxLastActiveBuffer && !flushing && now.After(retry)
+}
+
+// FlushLastActive writes the buffered last activity of profiles to the
+// database and purges the profiles from the cache. If the update fails the
+// times are returned to the buffer to be tried again by the next flush.
+func FlushLastActive() error {
+	return flushLastActive(execLastActive)
+}
+
+// flushLastActive flushes the buffer using exec to write each batch
+func flushLastActive(exec func([]int64, []time.Time) error) error {
+	lastActiveFlushMu.Lock()
+	defer lastActiveFlushMu.Unlock()
+
+	lastActiveMu.Lock()
+	pending := lastActive
+	lastActive = map[int64]time.Time{}
+	lastActiveMu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ids := []int64{}
+	for id := range pending {
+		ids = append(ids, id)
+	}
+	// Rows are updated in the order of their IDs so that concurrent flushes
+	// cannot deadlock
+	sort.Sort(byProfileId(ids))
+
+	for start := 0; start < len(ids); start += lastActiveBatchSize {
+		end := start + lastActiveBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		times := []time.Time{}
+		for _, id := range batch {
+			times = append(times, pending[id])
+		}
+
+		err := exec(batch, times)
+		if err != nil {
+			glog.Errorf("exec() %+v", err)
+
+			// Keep the profiles that were not written, unless they have
+			// been active again since, for as long as there is room
+			lastActiveMu.Lock()
+			lastActiveRetry = time.Now().Add(lastActiveRetryDelay)
+			for _, id := range ids[start:] {
+				bufferLastActive(lastActive, id, pending[id])
+			}
+			lastActiveMu.Unlock()
+
+			return err
+		}
+
+		for _, id := range batch {
+			PurgeCacheByScope(c.CacheDetail, h.ItemTypes[h.ItemTypeProfile], id)
+		}
+	}
+
+	return nil
+}
+
+// execLastActive updates the last activity of the profiles in a single
+// statement. A last_active that is already later is left alone.
+func execLastActive(profileIds []int64, times []time.Time) error {
+	db, err := h.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	values := []string{}
+	args := []interface{}{}
+	for i, profileId := range profileIds {
+		values = append(
+			values,
+			fmt.Sprintf("($%d::BIGINT, $%d::TIMESTAMP WITH TIME ZONE)", i*2+1, i*2+2),
+		)
+		args = append(args, profileId, times[i])
+	}
+
+	_, err = db.Exec(`--FlushLastActive
+UPDATE profiles AS p
+   SET last_active = v.last_active
+  FROM (VALUES `+strings.Join(values, `,`)+`) AS v(profile_id, last_active)
+ WHERE p.profile_id = v.profile_id
+   AND (p.last_active IS NULL OR p.last_active < v.last_active)`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("Update of last active failed: %v", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFlushLastActive(t *testing.T) {
+	defer func() {
+		lastActive = map[int64]time.Time{}
+		lastActiveRetry = time.Time{}
+	}()
+
+	written := map[int64]time.Time{}
+	fail := false
+	exec := func(profileIds []int64, times []time.Time) error {
+		if fail {
+			return errors.New("Update failed")
+		}
+		for i, id := range profileIds {
+			written[id] = times[i]
+		}
+		return nil
+	}
+
+	now := time.Now()
+	RecordLastActive(1, now.Add(-time.Minute))
+	RecordLastActive(1, now)
+	RecordLastActive(1, now.Add(-2*time.Minute))
+	RecordLastActive(2, now)
+	RecordLastActive(0, now)
+
+	// A failed flush keeps the times for the next flush
+	fail = true
+	if err := flushLastActive(exec); err == nil {
+		t.Fatalf("Expected the failed update to be returned")
+	}
+
+	fail = false
+	if err := flushLastActive(exec); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+
+	if len(written) != 2 {
+		t.Fatalf("Expected 2 profiles to be written, got %d", len(written))
+	}
+	if !written[1].Equal(now) {
+		t.Errorf("Expected the latest activity to be written, got %v", written[1])
+	}
+	if len(lastActive) != 0 {
+		t.Errorf("Expected the buffer to be empty, got %d", len(lastActive))
+	}
+}
+
+func TestBufferLastActiveWhenFull(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	buffer := map[int64]time.Time{}
+	var id int64
+	for id = 1; id <= maxLastActiveBuffer; id++ {
+		if !bufferLastActive(buffer, id, now) {
+			t.Fatalf("Expected profile %d to be held", id)
+		}
+	}
+
+	// A full buffer drops new profiles but still updates those it holds
+	if bufferLastActive(buffer, id, now) {
+		t.Errorf("Expected a new profile to be dropped by a full buffer")
+	}
+	if !bufferLastActive(buffer, 1, now.Add(time.Minute)) {
+		t.Errorf("Expected a held profile to be updated by a full buffer")
+	}
+	if !buffer[1].Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected the later activity to be held, got %v", buffer[1])
+	}
+	if len(buffer) != maxLastActiveBuffer {
+		t.Errorf("Expected the buffer to hold %d, got %d", maxLastActiveBuffer, len(buffer))
+	}
+}
+
+func TestShouldFlushLastActive(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	full := maxLastActiveBuffer
+
+	if shouldFlushLastActive(full-1, false, time.Time{}, now) {
+		t.Errorf("Expected a buffer with room not to be flushed")
+	}
+	if !shouldFlushLastActive(full, false, time.Time{}, now) {
+		t.Errorf("Expected a full buffer to be flushed")
+	}
+	if shouldFlushLastActive(full, true, time.Time{}, now) {
+		t.Errorf("Expected no flush while one is running")
+	}
+
+	// A failed flush holds off the next until the retry delay has passed
+	retry := now.Add(lastActiveRetryDelay)
+	if shouldFlushLastActive(full, false, retry, now) {
+		t.Errorf("Expected no flush straight after a failure")
+	}
+	if !shouldFlushLastActive(full, false, retry, retry.Add(time.Second)) {
+		t.Errorf("Expected a flush once the retry delay has passed")
+	}
+}
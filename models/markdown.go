@@ -20,6 +20,12 @@ var (
 	linkURLs       = []byte(`${1}http://${2}`)
 )
 
+// ProcessCommentMarkdown renders the Markdown of a comment revision as HTML.
+// The raw Markdown is stored on the revision alongside the HTML so that edits
+// start from what was written, and the HTML is always sanitised last: by
+// SanitiseHTMLWithEmbeds on sites that allow video embeds, and by SanitiseHTML
+// otherwise. HTML within the Markdown survives only as far as the sanitiser
+// allows.
 func ProcessCommentMarkdown(
 	tx *sql.Tx,
 	revisionId int64,
@@ -51,6 +57,13 @@ func ProcessCommentMarkdown(
 	// Use blackfriday to convert MarkDown to HTML
 	src = MarkdownToHTML(src)
 
+	// Videos are embedded before links are shortened, as only links to the
+	// video itself are recognised. The embeds are kept by the final scrub.
+	videoEmbeds := AllowVideoEmbeds(siteId)
+	if videoEmbeds {
+		src = SanitiseHTMLWithEmbeds(siteId, src)
+	}
+
 	// Convert all links to shortened URLs and record which links are in
 	// which revisions (of a comment)
 	src, err := ProcessLinks(revisionId, src, siteId)
@@ -83,7 +96,11 @@ func ProcessCommentMarkdown(
 	// Scrub the generated HTML of anything nasty
 	// NOTE: This *MUST* always be the last thing to avoid introducing a
 	// security vulnerability
-	src = SanitiseHTML(src)
+	if videoEmbeds {
+		src = SanitiseHTMLWithEmbeds(siteId, src)
+	} else {
+		src = SanitiseHTML(siteId, src)
+	}
 
 	return string(src), nil
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/golang/glog"
 
 	c "github.com/microcosm-cc/microcosm/cache"
+	conf "github.com/microcosm-cc/microcosm/config"
 	h "github.com/microcosm-cc/microcosm/helpers"
 )
 
@@ -34,6 +35,11 @@ var (
 		c.CacheItem:       "ev_i%d",
 		c.CacheProfileIds: "ev_l%d",
 	}
+	// mcEventCountKeys are only purged by scope, PurgeCache leaves them as
+	// they are expensive to rebuild and change less often than the event
+	mcEventCountKeys = map[int]string{
+		c.CacheCounts: "ev_c%d",
+	}
 	mcHuddleKeys = map[int]string{
 		c.CacheDetail:  "hd_d%d",
 		c.CacheSummary: "hd_s%d",
@@ -51,10 +57,11 @@ var (
 		c.CacheItem:    "po_i%d",
 	}
 	mcProfileKeys = map[int]string{
-		c.CacheDetail:  "pr_d%d",
-		c.CacheSummary: "pr_s%d",
-		c.CacheUser:    "us_d%d",
-		c.CacheCounts:  "pr_c%d",
+		c.CacheDetail:       "pr_d%d",
+		c.CacheSummary:      "pr_s%d",
+		c.CacheUser:         "us_d%d",
+		c.CacheCounts:       "pr_c%d",
+		c.CacheFollowCounts: "pr_f%d",
 	}
 	mcRoleKeys = map[int]string{
 		c.CacheDetail: "r_d%d",
@@ -82,6 +89,28 @@ var (
 
 const mcTtl int32 = 60 * 60 * 24 * 7 // 1 Week
 
+// mcMaxTtl is the longest TTL memcache accepts, longer TTLs are treated as
+// timestamps and would expire the item immediately
+const mcMaxTtl int32 = 60 * 60 * 24 * 30 // 30 Days
+
+// cacheTtl returns the TTL for cached items of the given type. The [cache]
+// section of the config file may override the TTL of each type, or the default
+// TTL for all types, otherwise mcTtl is used.
+func cacheTtl(itemType string) int32 {
+	ttl, ok := conf.CONFIG_CACHE_TTL[itemType]
+	if !ok || ttl <= 0 {
+		ttl, ok = conf.CONFIG_CACHE_TTL[conf.CACHE_TTL_DEFAULT]
+	}
+	if !ok || ttl <= 0 {
+		return mcTtl
+	}
+
+	if ttl > int64(mcMaxTtl) {
+		return mcMaxTtl
+	}
+	return int32(ttl)
+}
+
 func PurgeCache(itemTypeId int64, itemId int64) {
 	switch itemTypeId {
 
@@ -215,6 +244,11 @@ func PurgeCacheByScope(scope int, itemTypeId int64, itemId int64) {
 				c.CacheDelete(fmt.Sprintf(mcKeyFmt, itemId))
 			}
 		}
+		for mcKey, mcKeyFmt := range mcEventCountKeys {
+			if mcKey == scope {
+				c.CacheDelete(fmt.Sprintf(mcKeyFmt, itemId))
+			}
+		}
 
 	case h.ItemTypes[h.ItemTypeHuddle]:
 		for mcKey, mcKeyFmt := range mcHuddleKeys {
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	conf "github.com/microcosm-cc/microcosm/config"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+func TestCacheTtl(t *testing.T) {
+	ttls := conf.CONFIG_CACHE_TTL
+	defer func() { conf.CONFIG_CACHE_TTL = ttls }()
+
+	conf.CONFIG_CACHE_TTL = map[string]int64{}
+	if ttl := cacheTtl(h.ItemTypeProfile); ttl != mcTtl {
+		t.Errorf("Expected %d when nothing is configured, got %d", mcTtl, ttl)
+	}
+
+	conf.CONFIG_CACHE_TTL = map[string]int64{
+		conf.CACHE_TTL_DEFAULT: 3600,
+		h.ItemTypeProfile:      86400,
+		h.ItemTypeEvent:        0,
+		h.ItemTypeHuddle:       60 * 60 * 24 * 365,
+	}
+
+	tests := map[string]int32{
+		h.ItemTypeProfile:      86400,
+		h.ItemTypeConversation: 3600,
+		h.ItemTypeEvent:        3600,
+		h.ItemTypeHuddle:       mcMaxTtl,
+	}
+	for itemType, expected := range tests {
+		if ttl := cacheTtl(itemType); ttl != expected {
+			t.Errorf("Expected %s to be cached for %d, got %d", itemType, expected, ttl)
+		}
+	}
+}
+
+func TestPurgeCacheKeepsEventCommentCount(t *testing.T) {
+	// PurgeCache deletes the keys of mcEventKeys, the count is only purged by
+	// scope
+	for scope, mcKeyFmt := range mcEventKeys {
+		if mcKeyFmt == mcEventCountKeys[c.CacheCounts] {
+			t.Errorf("Expected the comment count to survive a purge of the event, found in scope %d", scope)
+		}
+	}
+	if _, ok := mcEventKeys[c.CacheCounts]; ok {
+		t.Errorf("Expected no event key in the counts scope")
+	}
+	if mcEventCountKeys[c.CacheCounts] != "ev_c%d" {
+		t.Errorf("Expected the comment count in the counts scope, got %v", mcEventCountKeys)
+	}
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+const (
+	// MaxMentionSuggestions is the most suggestions that will be returned
+	MaxMentionSuggestions int64 = 10
+
+	// Suggestions change slowly and are not purged, so are only cached briefly
+	mentionSuggestionsTtl int32 = 60 * 5
+)
+
+// MentionSuggestionType is a profile or microcosm that may be mentioned
+type MentionSuggestionType struct {
+	ItemType string `json:"type"`
+	Id       int64  `json:"id"`
+	Name     string `json:"name"`
+	Avatar   string `json:"avatar,omitempty"`
+}
+
+// escapeLikePattern escapes the characters that are special within a LIKE
+// pattern, so that user input only ever matches literally
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		`%`, `\%`,
+		`_`, `\_`,
+	).Replace(s)
+}
+
+// GetMentionSuggestions returns the profiles and microcosms on a site whose
+// names start with the prefix, names that exactly match the prefix first and
+// then ordered by name. Only microcosms that a guest may read are suggested,
+// which allows the suggestions to be cached for everyone on the site.
+func GetMentionSuggestions(
+	siteId int64,
+	prefix string,
+	limit int64,
+) (
+	[]MentionSuggestionType,
+	int,
+	error,
+) {
+
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return []MentionSuggestionType{}, http.StatusOK, nil
+	}
+
+	if limit <= 0 || limit > MaxMentionSuggestions {
+		limit = MaxMentionSuggestions
+	}
+
+	// Prefixes may contain characters that are not valid in memcache keys
+	mcKey := fmt.Sprintf(
+		"mn_%d_%d_%x",
+		siteId,
+		limit,
+		sha1.Sum([]byte(strings.ToLower(prefix))),
+	)
+	if val, ok := c.CacheGet(mcKey, []MentionSuggestionType{}); ok {
+		return val.([]MentionSuggestionType), http.StatusOK, nil
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return []MentionSuggestionType{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetMentionSuggestions
+SELECT item_type
+      ,item_id
+      ,name
+      ,avatar
+  FROM (
+           SELECT 'profile' AS item_type
+                 ,profile_id AS item_id
+                 ,profile_name AS name
+                 ,COALESCE(avatar_url, '') AS avatar
+             FROM profiles
+            WHERE site_id = $1
+              AND profile_name ILIKE $2
+              AND profile_name <> 'deleted'
+            UNION ALL
+           SELECT 'microcosm' AS item_type
+                 ,microcosm_id AS item_id
+                 ,title AS name
+                 ,'' AS avatar
+             FROM microcosms
+            WHERE site_id = $1
+              AND title ILIKE $2
+              AND is_deleted IS NOT TRUE
+              AND is_moderated IS NOT TRUE
+              AND (get_effective_permissions($1,microcosm_id,2,microcosm_id,0)).can_read IS TRUE
+       ) AS s
+ ORDER BY LOWER(name) = LOWER($3) DESC
+         ,name ASC
+ LIMIT $4`,
+		siteId,
+		escapeLikePattern(prefix)+`%`,
+		prefix,
+		limit,
+	)
+	if err != nil {
+		glog.Errorf(
+			"db.Query(%d, `%s`, %d) %+v",
+			siteId,
+			prefix,
+			limit,
+			err,
+		)
+		return []MentionSuggestionType{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	ms := []MentionSuggestionType{}
+	for rows.Next() {
+		m := MentionSuggestionType{}
+		err = rows.Scan(
+			&m.ItemType,
+			&m.Id,
+			&m.Name,
+			&m.Avatar,
+		)
+		if err != nil {
+			glog.Errorf("rows.Scan() %+v", err)
+			return []MentionSuggestionType{}, http.StatusInternalServerError,
+				errors.New("Row parsing error")
+		}
+
+		ms = append(ms, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return []MentionSuggestionType{}, http.StatusInternalServerError,
+			errors.New("Error fetching rows")
+	}
+	rows.Close()
+
+	c.CacheSet(mcKey, ms, mentionSuggestionsTtl)
+
+	return ms, http.StatusOK, nil
+}
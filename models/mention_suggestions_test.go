@@ -0,0 +1,33 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEscapeLikePattern(t *testing.T) {
+	tests := map[string]string{
+		"dave":     "dave",
+		"100%":     `100\%`,
+		"a_b":      `a\_b`,
+		`back\sl`:  `back\\sl`,
+		`%_\`:      `\%\_\\`,
+		"O'Reilly": "O'Reilly",
+	}
+
+	for in, expected := range tests {
+		if out := escapeLikePattern(in); out != expected {
+			t.Errorf("escapeLikePattern(%q) = %q, expected %q", in, out, expected)
+		}
+	}
+}
+
+func TestGetMentionSuggestionsEmptyPrefix(t *testing.T) {
+	ms, status, err := GetMentionSuggestions(1, "  ", 5)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Expected an empty prefix to succeed, got %d %+v", status, err)
+	}
+	if len(ms) != 0 {
+		t.Errorf("Expected no suggestions, got %d", len(ms))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strings"
 
@@ -16,14 +17,14 @@ import (
 
 const (
 	UrlProfile         string = "/profiles/"
-	mentionPunctuation string = `,.:?!-`
+	mentionPunctuation string = `,.:;?!-)]}'"`
 )
 
 var (
 	regPreMentions    = regexp.MustCompile(`(?:^|\W)([+@](\S+))`)
 	regMarkdownChars  = regexp.MustCompile("([\\\\*_{}[\\]()#-.!])")
 	replMarkdownChars = []byte(`\$1`)
-	regMentions       = regexp.MustCompile(`(^|\W)[+@](\S+)`)
+	regMentions       = regexp.MustCompile(`(?:^|\W)([+@]\S+)`)
 )
 
 // PreProcessMentions will escape any characters in a username that markdown
@@ -84,20 +85,10 @@ func ProcessMentions(
 
 		if n.Type == html.TextNode {
 
-			// Convert links to shortUrls
-			if strings.Contains(n.Data, "+") || strings.Contains(n.Data, "@") {
-				matches := regMentions.FindAllString(n.Data, -1)
-				for _, v := range matches {
-					// We track both the mentions and the profiles
-					var mention = strings.TrimRight(
-						strings.TrimLeft(v, " \n\t"),
-						mentionPunctuation,
-					)
-					var profileName = strings.TrimLeft(mention, "+@")
-
-					mentions[mention] = profileName
-					profileNames[profileName] = int64(0)
-				}
+			// We track both the mentions and the profiles
+			for mention, profileName := range parseMentions(n.Data) {
+				mentions[mention] = profileName
+				profileNames[profileName] = int64(0)
 			}
 		}
 
@@ -221,13 +212,41 @@ SELECT c.comment_id
 	return src, nil
 }
 
-// Returns 0 if profile does not exist
+// parseMentions returns the mentions of profiles in the text, as they were
+// written, and the folded name of the profile each refers to. Profiles are
+// mentioned by @handle, or by +handle as they were originally. Punctuation
+// following a mention is not part of the name, and email addresses are not
+// mentions.
+func parseMentions(text string) map[string]string {
+	mentions := map[string]string{}
+
+	if !(strings.Contains(text, "+") || strings.Contains(text, "@")) {
+		return mentions
+	}
+
+	for _, match := range regMentions.FindAllStringSubmatch(text, -1) {
+		mention := strings.TrimRight(match[1], mentionPunctuation)
+
+		profileName := FoldProfileName(strings.TrimLeft(mention, "+@"))
+		if profileName == "" {
+			continue
+		}
+
+		mentions[mention] = profileName
+	}
+
+	return mentions
+}
+
+// FetchProfileId returns the profile on the site of the revision with the
+// name, compared as IsProfileNameTaken does. Returns 0 if profile does not
+// exist
 func FetchProfileId(tx *sql.Tx, profileName string, revisionId int64) int64 {
 	var profileId int64
 	rows, err := tx.Query(`
 SELECT profile_id
   FROM profiles
- WHERE LOWER(profile_name) = $1
+ WHERE TRANSLATE(LOWER(NORMALIZE(profile_name, NFKC)), $3, $4) = $1
    AND site_id = (
            SELECT site_id
              FROM revisions r
@@ -237,9 +256,15 @@ SELECT profile_id
  ORDER BY profile_id ASC
  LIMIT 1
 OFFSET 0`,
-		strings.ToLower(profileName),
+		FoldProfileName(profileName),
 		revisionId,
+		confusableProfileNameRunes,
+		confusableProfileNameReplacements,
 	)
+	if err != nil {
+		glog.Errorf("tx.Query(%s, %d) %+v", profileName, revisionId, err)
+		return 0
+	}
 	defer rows.Close()
 
 	for rows.Next() {
@@ -257,8 +282,9 @@ OFFSET 0`,
 	return profileId
 }
 
-// ProcessMention processes username mentions using the `+username` syntax and generates
-// alerts for the mentioned user if they have are enabled in their preferences.
+// ProcessMention processes username mentions using the `@username` syntax, or
+// the older `+username`, and generates alerts for the mentioned user if they
+// have are enabled in their preferences.
 func ProcessMention(
 	tx *sql.Tx,
 	commentId int64,
@@ -281,6 +307,21 @@ func ProcessMention(
 		return nil
 	}
 
+	// People do not need to be told that they mentioned themselves
+	if profileId == createdBy {
+		return nil
+	}
+
+	ignored, err := isMentionIgnored(tx, profileId, createdBy, itemTypeId, itemId)
+	if err != nil {
+		glog.Errorf("isMentionIgnored(tx, %d, %d, %d, %d) %+v",
+			profileId, createdBy, itemTypeId, itemId, err)
+		return err
+	}
+	if ignored {
+		return nil
+	}
+
 	// Send the update
 	var update = UpdateType{}
 	update.SiteId = siteId
@@ -289,7 +330,7 @@ func ProcessMention(
 	update.ItemTypeId = h.ItemTypes[h.ItemTypeComment]
 	update.ItemId = commentId
 	update.Meta.CreatedById = createdBy
-	_, err := update.insert(tx)
+	_, err = update.insert(tx)
 	if err != nil {
 		glog.Errorf("%s %+v", "update.insert(tx)", err)
 		return err
@@ -303,3 +344,84 @@ func ProcessMention(
 
 	return nil
 }
+
+// isMentionIgnored returns true if the mentioned profile is ignoring the
+// author of the comment, or the item the comment is attached to
+func isMentionIgnored(
+	tx *sql.Tx,
+	profileId int64,
+	createdBy int64,
+	itemTypeId int64,
+	itemId int64,
+) (
+	bool,
+	error,
+) {
+	var ignored bool
+	err := tx.QueryRow(`--isMentionIgnored
+SELECT EXISTS(
+           SELECT 1
+             FROM ignores
+            WHERE profile_id = $1
+              AND (expires IS NULL OR expires > NOW())
+              AND (
+                      (item_type_id = 3 AND item_id = $2)
+                   OR (item_type_id = $3 AND item_id = $4)
+                  )
+       )`,
+		profileId,
+		createdBy,
+		itemTypeId,
+		itemId,
+	).Scan(&ignored)
+
+	return ignored, err
+}
+
+// GetMentionedProfileIds returns the profiles that have been sent an update
+// for being mentioned in the comment
+func GetMentionedProfileIds(commentId int64) (map[int64]bool, int, error) {
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return map[int64]bool{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetMentionedProfileIds
+SELECT for_profile_id
+  FROM updates
+ WHERE item_type_id = $1
+   AND item_id = $2
+   AND update_type_id = $3`,
+		h.ItemTypes[h.ItemTypeComment],
+		commentId,
+		h.UpdateTypes[h.UpdateTypeMentioned],
+	)
+	if err != nil {
+		glog.Errorf("db.Query(%d) %+v", commentId, err)
+		return map[int64]bool{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	ids := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			glog.Errorf("rows.Scan() %+v", err)
+			return map[int64]bool{}, http.StatusInternalServerError,
+				errors.New("Row parsing error")
+		}
+		ids[id] = true
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return map[int64]bool{}, http.StatusInternalServerError,
+			errors.New("Error fetching rows")
+	}
+	rows.Close()
+
+	return ids, http.StatusOK, nil
+}
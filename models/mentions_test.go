@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	mentions := parseMentions(
+		"Hi @Bob, are you coming? (@Dave) +alice! Email carol@example.com " +
+			"or see @Еve.",
+	)
+
+	expected := map[string]string{
+		"@Bob":   "bob",
+		"@Dave":  "dave",
+		"+alice": "alice",
+		// The cyrillic Е is folded to the latin e, as it is for names
+		"@Еve": "eve",
+	}
+	if len(mentions) != len(expected) {
+		t.Errorf("Expected %d mentions, got %+v", len(expected), mentions)
+	}
+	for mention, profileName := range expected {
+		if mentions[mention] != profileName {
+			t.Errorf("Expected %s to mention %s, got %+v", mention, profileName, mentions)
+		}
+	}
+
+	if len(parseMentions("@ alone, a sum of 1+1 and an @")) != 0 {
+		t.Errorf("Expected no mentions without a name")
+	}
+}
@@ -75,7 +75,7 @@ func (v MicrocosmSummaryRequestBySeq) Less(i, j int) bool {
 func (m *MicrocosmType) Validate(exists bool, isImport bool) (int, error) {
 
 	m.Title = SanitiseText(m.Title)
-	m.Description = string(SanitiseHTML([]byte(m.Description)))
+	m.Description = string(SanitiseHTML(m.SiteId, []byte(m.Description)))
 
 	if exists && !isImport {
 		if strings.Trim(m.Meta.EditReason, " ") == "" ||
@@ -505,7 +505,7 @@ SELECT microcosm_id,
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeMicrocosm))
 
 	m.FetchSummaries(siteId, profileId)
 	return m, http.StatusOK, nil
@@ -636,7 +636,7 @@ SELECT microcosm_id
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeMicrocosm))
 
 	m.FetchProfileSummaries(siteId)
 
@@ -673,7 +673,7 @@ SELECT title
 	}
 
 	// Update cache
-	c.CacheSetString(mcKey, title, mcTtl)
+	c.CacheSetString(mcKey, title, cacheTtl(h.ItemTypeMicrocosm))
 
 	return title
 }
@@ -760,6 +760,7 @@ WITH m AS (
      SELECT m.microcosm_id
        FROM microcosms m
        LEFT JOIN ignores i ON i.profile_id = $2
+                          AND (i.expires IS NULL OR i.expires > NOW())
                           AND i.item_type_id = 2
                           AND i.item_id = m.microcosm_id
       WHERE m.site_id = $1
@@ -636,7 +636,7 @@ SELECT choice_id,
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypePoll))
 
 	m.FetchProfileSummaries(siteId)
 	return m, http.StatusOK, nil
@@ -741,7 +741,7 @@ SELECT poll_id
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypePoll))
 
 	m.FetchProfileSummaries(siteId)
 	return m, http.StatusOK, nil
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// exportWriter writes a JSON document piece by piece, the first error stops
+// all further writes
+type exportWriter struct {
+	w   io.Writer
+	enc *json.Encoder
+	err error
+}
+
+func (ew *exportWriter) write(s string) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = io.WriteString(ew.w, s)
+}
+
+func (ew *exportWriter) encode(v interface{}) {
+	if ew.err != nil {
+		return
+	}
+	ew.err = ew.enc.Encode(v)
+}
+
+// exportSection is a list within the export of a profile's data, query
+// returns the IDs of the items that fetch is called for, args being the
+// arguments of the query
+type exportSection struct {
+	name  string
+	query string
+	args  []interface{}
+	fetch func(id int64) (interface{}, int, error)
+}
+
+// ExportProfileData writes the profile and everything it has created on the
+// site to w as a single JSON document, so that members can download their
+// data. Items are fetched and written one at a time so that the document is
+// never held in memory, which means that an error part way through leaves the
+// document incomplete. Items that have been deleted are left out.
+//
+// Huddles are private messages and so are only included when the profile
+// exports its own data, not when a site owner exports it for them.
+func ExportProfileData(
+	w io.Writer,
+	siteId int64,
+	profileId int64,
+	requesterId int64,
+) error {
+	profile, _, err := GetProfile(siteId, profileId)
+	if err != nil {
+		return err
+	}
+
+	sections := exportSections(siteId, profileId, requesterId == profileId)
+
+	ew := &exportWriter{w: w, enc: json.NewEncoder(w)}
+
+	ew.write(`{"profile":`)
+	ew.encode(profile)
+
+	for _, section := range sections {
+		if ew.err != nil {
+			return ew.err
+		}
+
+		ids, err := exportIds(section.query, section.args)
+		if err != nil {
+			return err
+		}
+
+		ew.write(`,"` + section.name + `":[`)
+		first := true
+		for _, id := range ids {
+			m, status, err := section.fetch(id)
+			if status == http.StatusNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if !first {
+				ew.write(`,`)
+			}
+			first = false
+			ew.encode(m)
+		}
+		ew.write(`]`)
+	}
+
+	err = exportAttachments(ew, profileId, requesterId == profileId)
+	if err != nil {
+		return err
+	}
+
+	ew.write(`}`)
+
+	return ew.err
+}
+
+// exportSections returns the lists of items in the export of the profile's
+// data. Huddles, and comments within them, are only included if
+// includeHuddles is true.
+func exportSections(
+	siteId int64,
+	profileId int64,
+	includeHuddles bool,
+) []exportSection {
+
+	sections := []exportSection{
+		{
+			name: "comments",
+			query: `--ExportProfileData comments
+SELECT comment_id
+  FROM comments
+ WHERE profile_id = $1
+   AND is_deleted IS NOT TRUE
+   AND ($2 OR item_type_id <> $3)
+ ORDER BY created ASC`,
+			args: []interface{}{
+				profileId,
+				includeHuddles,
+				h.ItemTypes[h.ItemTypeHuddle],
+			},
+			fetch: func(id int64) (interface{}, int, error) {
+				return GetCommentSummary(siteId, id)
+			},
+		},
+		{
+			name: "events",
+			query: `--ExportProfileData events
+SELECT event_id
+  FROM events
+ WHERE created_by = $1
+   AND is_deleted IS NOT TRUE
+ ORDER BY created ASC`,
+			args: []interface{}{profileId},
+			fetch: func(id int64) (interface{}, int, error) {
+				return getEvent(siteId, id, profileId)
+			},
+		},
+		{
+			name: "attendees",
+			query: `--ExportProfileData attendees
+SELECT attendee_id
+  FROM attendees
+ WHERE profile_id = $1
+ ORDER BY created ASC`,
+			args: []interface{}{profileId},
+			fetch: func(id int64) (interface{}, int, error) {
+				return GetAttendee(siteId, id)
+			},
+		},
+	}
+
+	if includeHuddles {
+		sections = append(sections, exportSection{
+			name: "huddles",
+			query: `--ExportProfileData huddles
+SELECT huddle_id
+  FROM huddle_profiles
+ WHERE profile_id = $1
+ ORDER BY huddle_id ASC`,
+			args: []interface{}{profileId},
+			fetch: func(id int64) (interface{}, int, error) {
+				return GetHuddleSummary(siteId, profileId, id)
+			},
+		})
+	}
+
+	return sections
+}
+
+// exportIds returns the IDs selected by the query
+func exportIds(query string, args []interface{}) ([]int64, error) {
+	db, err := h.GetConnection()
+	if err != nil {
+		return []int64{}, err
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		glog.Errorf("db.Query(%v) %+v", args, err)
+		return []int64{}, errors.New(
+			fmt.Sprintf("Database query failed: %v", err.Error()),
+		)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return []int64{}, errors.New(
+				fmt.Sprintf("Row parsing error: %v", err.Error()),
+			)
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []int64{}, errors.New(
+			fmt.Sprintf("Error fetching rows: %v", err.Error()),
+		)
+	}
+	rows.Close()
+
+	return ids, nil
+}
+
+// exportAttachments writes the attachments uploaded by the profile, these are
+// identified by the item they are attached to and the file rather than an ID.
+// Attachments to comments within huddles are only included if includeHuddles
+// is true.
+func exportAttachments(
+	ew *exportWriter,
+	profileId int64,
+	includeHuddles bool,
+) error {
+	if ew.err != nil {
+		return ew.err
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`--ExportProfileData attachments
+SELECT a.item_type_id
+      ,a.item_id
+      ,a.file_sha1
+  FROM attachments a
+ WHERE a.profile_id = $1
+   AND (
+           $2
+        OR NOT EXISTS (
+               SELECT 1
+                 FROM comments c
+                WHERE a.item_type_id = $3
+                  AND c.comment_id = a.item_id
+                  AND c.item_type_id = $4
+           )
+       )
+ ORDER BY a.created ASC`,
+		profileId,
+		includeHuddles,
+		h.ItemTypes[h.ItemTypeComment],
+		h.ItemTypes[h.ItemTypeHuddle],
+	)
+	if err != nil {
+		glog.Errorf("db.Query(%d) %+v", profileId, err)
+		return errors.New(
+			fmt.Sprintf("Database query failed: %v", err.Error()),
+		)
+	}
+	defer rows.Close()
+
+	type attachmentKey struct {
+		ItemTypeId int64
+		ItemId     int64
+		FileHash   string
+	}
+
+	keys := []attachmentKey{}
+	for rows.Next() {
+		key := attachmentKey{}
+		err = rows.Scan(&key.ItemTypeId, &key.ItemId, &key.FileHash)
+		if err != nil {
+			return errors.New(
+				fmt.Sprintf("Row parsing error: %v", err.Error()),
+			)
+		}
+		keys = append(keys, key)
+	}
+	err = rows.Err()
+	if err != nil {
+		return errors.New(
+			fmt.Sprintf("Error fetching rows: %v", err.Error()),
+		)
+	}
+	rows.Close()
+
+	ew.write(`,"attachments":[`)
+	first := true
+	for _, key := range keys {
+		m, status, err := GetAttachment(key.ItemTypeId, key.ItemId, key.FileHash, false)
+		if status == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			ew.write(`,`)
+		}
+		first = false
+		ew.encode(m)
+	}
+	ew.write(`]`)
+
+	return ew.err
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+func TestExportSections(t *testing.T) {
+	names := func(sections []exportSection) map[string]exportSection {
+		m := map[string]exportSection{}
+		for _, section := range sections {
+			m[section.name] = section
+		}
+		return m
+	}
+
+	own := names(exportSections(1, 2, true))
+	for _, name := range []string{"comments", "events", "attendees", "huddles"} {
+		if _, ok := own[name]; !ok {
+			t.Errorf("Expected %s in the export of a profile's own data", name)
+		}
+	}
+	if own["comments"].args[1] != true {
+		t.Errorf("Expected huddle comments in the export of a profile's own data")
+	}
+
+	// A site owner exporting the data of another profile
+	other := names(exportSections(1, 2, false))
+	if _, ok := other["huddles"]; ok {
+		t.Errorf("Huddles were exported for a site owner")
+	}
+	if other["comments"].args[1] != false ||
+		other["comments"].args[2] != h.ItemTypes[h.ItemTypeHuddle] {
+
+		t.Errorf("Huddle comments were exported for a site owner")
+	}
+
+	for _, section := range other {
+		if len(section.args) == 0 || section.args[0] != int64(2) {
+			t.Errorf("The %s section is not of the profile: %v", section.name, section.args)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// FollowCountsType is how many profiles follow a profile, and how many
+// profiles it follows
+type FollowCountsType struct {
+	Followers int64
+	Following int64
+}
+
+// GetFollowCounts returns the follower and following counts of the profile.
+// The counts are cached until the profile follows or unfollows someone, or is
+// followed or unfollowed.
+func GetFollowCounts(profileId int64) (FollowCountsType, int, error) {
+	mcKey := fmt.Sprintf(mcProfileKeys[c.CacheFollowCounts], profileId)
+	if val, ok := c.CacheGet(mcKey, FollowCountsType{}); ok {
+		return val.(FollowCountsType), http.StatusOK, nil
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return FollowCountsType{}, http.StatusInternalServerError, err
+	}
+
+	m := FollowCountsType{}
+	err = db.QueryRow(`--GetFollowCounts
+SELECT (
+           SELECT COUNT(*)
+             FROM watchers
+            WHERE item_type_id = $2
+              AND item_id = $1
+       ) AS followers
+      ,(
+           SELECT COUNT(*)
+             FROM watchers
+            WHERE item_type_id = $2
+              AND profile_id = $1
+       ) AS following`,
+		profileId,
+		h.ItemTypes[h.ItemTypeProfile],
+	).Scan(
+		&m.Followers,
+		&m.Following,
+	)
+	if err != nil {
+		glog.Errorf("db.QueryRow(%d) %+v", profileId, err)
+		return FollowCountsType{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeProfile))
+
+	return m, http.StatusOK, nil
+}
+
+// purgeFollowCounts removes the cached follow counts of both profiles when one
+// starts or stops following the other, and the listings that may be limited to
+// the profiles that are followed
+func purgeFollowCounts(profileId int64, followedId int64) {
+	PurgeCacheByScope(
+		c.CacheFollowCounts,
+		h.ItemTypes[h.ItemTypeProfile],
+		profileId,
+	)
+	PurgeCacheByScope(
+		c.CacheFollowCounts,
+		h.ItemTypes[h.ItemTypeProfile],
+		followedId,
+	)
+	purgeProfilesCacheOfProfile(profileId)
+}
@@ -0,0 +1,379 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// profileImportBatchSize is how many profiles are imported in each
+// transaction
+const profileImportBatchSize = 100
+
+// MaxProfileImport is the most profiles that may be imported at once
+const MaxProfileImport = 10000
+
+// ProfileImportResultType describes the outcome of importing one profile,
+// Index is the position of the profile in the import
+type ProfileImportResultType struct {
+	Index       int    `json:"index"`
+	UserId      int64  `json:"userId"`
+	ProfileName string `json:"profileName"`
+	ProfileId   int64  `json:"profileId,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ProfileImportType summarises an import of profiles
+type ProfileImportType struct {
+	Succeeded int                       `json:"succeeded"`
+	Failed    int                       `json:"failed"`
+	Results   []ProfileImportResultType `json:"results"`
+}
+
+// ImportProfiles imports many profiles into the site, such as when a forum
+// is migrated. Each profile is repaired and validated as by Import, and a
+// profile that cannot be imported is reported in the summary without stopping
+// the others. Avatars are not fetched, imported profiles use the gravatar URL.
+//
+// The user of each profile is identified by their email address, and is
+// created if there is no user with that address. Existing users may only be
+// given a profile if they already belong to the site or to no site at all,
+// otherwise a site owner could claim users of other sites and learn the hash
+// of their email address from the avatar URL.
+func ImportProfiles(
+	siteId int64,
+	ms []ProfileType,
+) (
+	ProfileImportType,
+	int,
+	error,
+) {
+
+	if len(ms) > MaxProfileImport {
+		return ProfileImportType{}, http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"No more than %d profiles may be imported at once",
+				MaxProfileImport,
+			),
+		)
+	}
+
+	return importInBatches(
+		len(ms),
+		profileImportBatchSize,
+		func(start int, end int) ([]ProfileImportResultType, int, error) {
+			return importProfileBatch(siteId, ms[start:end], start)
+		},
+	)
+}
+
+// importInBatches calls importBatch for each batch of up to batchSize of the
+// n profiles being imported, and summarises the results. An error from a
+// batch stops the import.
+func importInBatches(
+	n int,
+	batchSize int,
+	importBatch func(start int, end int) ([]ProfileImportResultType, int, error),
+) (
+	ProfileImportType,
+	int,
+	error,
+) {
+
+	summary := ProfileImportType{Results: []ProfileImportResultType{}}
+
+	for start := 0; start < n; start += batchSize {
+		end := start + batchSize
+		if end > n {
+			end = n
+		}
+
+		results, status, err := importBatch(start, end)
+		if err != nil {
+			return ProfileImportType{}, status, err
+		}
+
+		for _, result := range results {
+			if result.Error == "" {
+				summary.Succeeded++
+			} else {
+				summary.Failed++
+			}
+			summary.Results = append(summary.Results, result)
+		}
+	}
+
+	return summary, http.StatusOK, nil
+}
+
+// importProfileBatch imports the profiles in a single transaction, each in a
+// savepoint so that one failing does not undo the others. offset is the index
+// of the first profile within the whole import.
+func importProfileBatch(
+	siteId int64,
+	ms []ProfileType,
+	offset int,
+) (
+	[]ProfileImportResultType,
+	int,
+	error,
+) {
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return []ProfileImportResultType{}, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Could not start transaction: %v", err.Error()),
+			)
+	}
+	defer tx.Rollback()
+
+	results := []ProfileImportResultType{}
+	imported := []ProfileType{}
+
+	// Names and users are only checked against committed profiles by
+	// Validate, so those earlier in the batch are tracked here
+	names := map[string]bool{}
+	users := map[int64]bool{}
+	emails := map[string]bool{}
+
+	for i := range ms {
+		m := ms[i]
+		m.SiteId = siteId
+
+		result := ProfileImportResultType{
+			Index:  offset + i,
+			UserId: m.UserId,
+		}
+
+		err := importProfile(tx, &m, names, users, emails)
+		result.UserId = m.UserId
+		if err != nil {
+			result.ProfileName = m.ProfileName
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.ProfileName = m.ProfileName
+		result.ProfileId = m.Id
+		results = append(results, result)
+		imported = append(imported, m)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		glog.Errorf("tx.Commit() %+v", err)
+		for i := range results {
+			if results[i].Error == "" {
+				// Users created for the profiles were rolled back too
+				results[i].UserId = ms[i].UserId
+				results[i].ProfileId = 0
+				results[i].Error = fmt.Sprintf("Transaction failed: %v", err.Error())
+			}
+		}
+		return results, http.StatusOK, nil
+	}
+
+	for _, m := range imported {
+		PurgeProfileIdCache(m.SiteId, m.UserId)
+		go PurgeCache(h.ItemTypes[h.ItemTypeProfile], m.Id)
+		PurgeProfilesCache(m.SiteId)
+
+		// Done one at a time rather than in goroutines as a batch would
+		// otherwise start a hundred transactions at once
+		_, err = MarkAllAsRead(m.Id)
+		if err != nil {
+			glog.Errorf("MarkAllAsRead(%d) %+v", m.Id, err)
+		}
+	}
+
+	return results, http.StatusOK, nil
+}
+
+// importProfile repairs, validates and inserts a single profile of a batch.
+// The profile is validated before a user is created for it, and the user is
+// created within the savepoint of the profile so that nothing is left behind
+// if the profile cannot be imported.
+func importProfile(
+	tx *sql.Tx,
+	m *ProfileType,
+	names map[string]bool,
+	users map[int64]bool,
+	emails map[string]bool,
+) error {
+
+	user, err := getImportUser(m)
+	if err != nil {
+		return err
+	}
+	m.UserId = user.ID
+
+	if users[user.ID] {
+		return errors.New("The user already has a profile in this import")
+	}
+	email := strings.ToLower(strings.Trim(user.Email, " "))
+	if user.ID == 0 && emails[email] {
+		return errors.New(
+			fmt.Sprintf(
+				"The email address %s already has a profile in this import",
+				user.Email,
+			),
+		)
+	}
+
+	// Microcosm usernames cannot contain spaces
+	m.ProfileName = strings.Replace(m.ProfileName, " ", "_", -1)
+
+	_, err = m.validateDetails()
+	if err != nil {
+		return err
+	}
+
+	// Names that are taken are replaced once the user is known, as the
+	// suggested name is made from the user
+	nameTaken, _, err := IsProfileNameTaken(m.SiteId, m.UserId, m.ProfileName)
+	if err != nil {
+		return err
+	}
+	nameTaken = nameTaken || names[FoldProfileName(m.ProfileName)]
+
+	// If the user has never been active, use the date they were created
+	if m.LastActive.Unix() < m.Created.Unix() {
+		m.LastActive = m.Created
+	}
+
+	m.AvatarUrlNullable = sql.NullString{
+		String: MakeGravatarUrl(user.Email),
+		Valid:  true,
+	}
+
+	created := false
+	err = inSavepoint(tx, "import_profile", func() error {
+		if user.ID == 0 {
+			_, err := user.insertTx(tx)
+			if err != nil {
+				return err
+			}
+			m.UserId = user.ID
+			created = true
+		}
+
+		if nameTaken {
+			m.ProfileName = SuggestProfileName(m.SiteId, user)
+			if names[FoldProfileName(m.ProfileName)] {
+				return errors.New(
+					fmt.Sprintf(
+						"The profile name '%s' is already in this import",
+						m.ProfileName,
+					),
+				)
+			}
+		}
+
+		_, err := m.insertTx(tx)
+		return err
+	})
+	if err != nil {
+		// A user created for the profile has been rolled back with it
+		if created {
+			m.UserId = 0
+		}
+		return err
+	}
+
+	names[FoldProfileName(m.ProfileName)] = true
+	users[m.UserId] = true
+	if email != "" {
+		emails[email] = true
+	}
+
+	return nil
+}
+
+// getImportUser returns the user that an imported profile is for. A user may
+// be given by ID only if they already belong to the site. Otherwise the user
+// is found by email address, and an existing user is refused if they belong to
+// another site. A user that does not exist is returned with only the email
+// address, and an ID of 0, to be created when the profile is imported.
+func getImportUser(m *ProfileType) (UserType, error) {
+
+	if m.UserId > 0 {
+		if !UserIsOnSite(m.UserId, m.SiteId) {
+			return UserType{}, errors.New(
+				"Only users of this site may be imported by ID, " +
+					"give the email address of other users instead",
+			)
+		}
+
+		user, _, err := GetUser(m.UserId)
+		return user, err
+	}
+
+	if strings.Trim(m.Email, " ") == "" {
+		return UserType{}, errors.New(
+			"An email address or the ID of a user of this site is required",
+		)
+	}
+
+	user, status, err := GetUserByEmailAddress(m.Email)
+	if status == http.StatusNotFound {
+		return UserType{Email: m.Email}, nil
+	}
+	if err != nil {
+		return UserType{}, err
+	}
+
+	if !UserIsOnSite(user.ID, m.SiteId) && userIsOnAnySite(user.ID) {
+		return UserType{}, errors.New(
+			fmt.Sprintf(
+				"The user with the email address %s belongs to another site",
+				m.Email,
+			),
+		)
+	}
+
+	return user, nil
+}
+
+// txExecer is the part of a transaction needed to run savepoints
+type txExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// inSavepoint runs fn within a savepoint of the transaction. If fn fails the
+// transaction is rolled back to the savepoint, so that the work done before
+// it is kept, and the error from fn is returned.
+func inSavepoint(tx txExecer, name string, fn func() error) error {
+
+	_, err := tx.Exec(`SAVEPOINT ` + name)
+	if err != nil {
+		return errors.New(
+			fmt.Sprintf("Could not create savepoint: %v", err.Error()),
+		)
+	}
+
+	err = fn()
+	if err != nil {
+		_, rbErr := tx.Exec(`ROLLBACK TO SAVEPOINT ` + name)
+		if rbErr != nil {
+			glog.Errorf("tx.Exec(ROLLBACK TO SAVEPOINT) %+v", rbErr)
+		}
+		return err
+	}
+
+	_, err = tx.Exec(`RELEASE SAVEPOINT ` + name)
+	if err != nil {
+		return errors.New(
+			fmt.Sprintf("Could not release savepoint: %v", err.Error()),
+		)
+	}
+
+	return nil
+}
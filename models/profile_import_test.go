@@ -0,0 +1,126 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestImportInBatches(t *testing.T) {
+	type batch struct{ start, end int }
+	var batches []batch
+
+	summary, status, err := importInBatches(
+		250,
+		100,
+		func(start int, end int) ([]ProfileImportResultType, int, error) {
+			batches = append(batches, batch{start, end})
+
+			results := []ProfileImportResultType{}
+			for i := start; i < end; i++ {
+				result := ProfileImportResultType{Index: i, ProfileId: int64(i + 1)}
+				if i%10 == 0 {
+					result.ProfileId = 0
+					result.Error = "Invalid"
+				}
+				results = append(results, result)
+			}
+			return results, http.StatusOK, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("importInBatches() failed: %d %+v", status, err)
+	}
+
+	expected := []batch{{0, 100}, {100, 200}, {200, 250}}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Errorf("Imported in batches %v, expected %v", batches, expected)
+	}
+
+	if summary.Succeeded != 225 || summary.Failed != 25 {
+		t.Errorf(
+			"Expected 225 succeeded and 25 failed, got %d and %d",
+			summary.Succeeded,
+			summary.Failed,
+		)
+	}
+
+	if len(summary.Results) != 250 {
+		t.Fatalf("Expected 250 results, got %d", len(summary.Results))
+	}
+	for i, result := range summary.Results {
+		if result.Index != i {
+			t.Errorf("Result %d has index %d", i, result.Index)
+		}
+	}
+}
+
+func TestImportInBatchesStopsOnError(t *testing.T) {
+	var calls int
+
+	_, status, err := importInBatches(
+		250,
+		100,
+		func(start int, end int) ([]ProfileImportResultType, int, error) {
+			calls++
+			if start == 100 {
+				return nil, http.StatusInternalServerError, errors.New("Failed")
+			}
+			return []ProfileImportResultType{}, http.StatusOK, nil
+		},
+	)
+	if err == nil || status != http.StatusInternalServerError {
+		t.Errorf("Expected the batch error, got %d %+v", status, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the import to stop after 2 batches, made %d", calls)
+	}
+}
+
+// execRecorder records the statements executed on it
+type execRecorder struct {
+	statements []string
+	fail       string
+}
+
+func (r *execRecorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	r.statements = append(r.statements, query)
+	if query == r.fail {
+		return nil, errors.New("Failed")
+	}
+	return nil, nil
+}
+
+func TestInSavepoint(t *testing.T) {
+	tx := &execRecorder{}
+	err := inSavepoint(tx, "sp", func() error { return nil })
+	if err != nil {
+		t.Errorf("inSavepoint() failed: %+v", err)
+	}
+	expected := []string{"SAVEPOINT sp", "RELEASE SAVEPOINT sp"}
+	if !reflect.DeepEqual(tx.statements, expected) {
+		t.Errorf("Executed %v, expected %v", tx.statements, expected)
+	}
+
+	// A failure is rolled back to the savepoint and returned
+	tx = &execRecorder{}
+	failure := errors.New("Profile name taken")
+	err = inSavepoint(tx, "sp", func() error { return failure })
+	if err != failure {
+		t.Errorf("Expected the error from fn, got %+v", err)
+	}
+	expected = []string{"SAVEPOINT sp", "ROLLBACK TO SAVEPOINT sp"}
+	if !reflect.DeepEqual(tx.statements, expected) {
+		t.Errorf("Executed %v, expected %v", tx.statements, expected)
+	}
+
+	// fn is not run if the savepoint cannot be made
+	tx = &execRecorder{fail: "SAVEPOINT sp"}
+	var ran bool
+	err = inSavepoint(tx, "sp", func() error { ran = true; return nil })
+	if err == nil || ran {
+		t.Errorf("Expected an error without running fn, got %+v (ran %t)", err, ran)
+	}
+}
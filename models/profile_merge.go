@@ -0,0 +1,589 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// ProfileMergeType reports what merging one profile into another changed, or
+// would change if it was a dry run. Where both profiles attended the same
+// event or watched the same item the record of the target profile is kept and
+// that of the source is removed.
+type ProfileMergeType struct {
+	SourceProfileId  int64 `json:"sourceProfileId"`
+	TargetProfileId  int64 `json:"targetProfileId"`
+	DryRun           bool  `json:"dryRun"`
+	Comments         int64 `json:"comments"`
+	Revisions        int64 `json:"revisions"`
+	Conversations    int64 `json:"conversations"`
+	Events           int64 `json:"events"`
+	Polls            int64 `json:"polls"`
+	Huddles          int64 `json:"huddles"`
+	HuddlesRemoved   int64 `json:"huddlesRemoved"`
+	Attendees        int64 `json:"attendees"`
+	AttendeesRemoved int64 `json:"attendeesRemoved"`
+	Watchers         int64 `json:"watchers"`
+	WatchersRemoved  int64 `json:"watchersRemoved"`
+	Attachments      int64 `json:"attachments"`
+}
+
+// MergeProfiles moves everything created by the source profile to the target
+// profile and marks the source as deleted, for when one person has ended up
+// with two profiles on a site. The user of the source profile is given the
+// target profile from then on. Merging a profile into the profile it has
+// already been merged into changes nothing. If dryRun is true the merge is
+// rolled back and only the report of what would change is returned.
+func MergeProfiles(
+	siteId int64,
+	sourceProfileId int64,
+	targetProfileId int64,
+	dryRun bool,
+) (
+	ProfileMergeType,
+	int,
+	error,
+) {
+
+	m := ProfileMergeType{
+		SourceProfileId: sourceProfileId,
+		TargetProfileId: targetProfileId,
+		DryRun:          dryRun,
+	}
+
+	if sourceProfileId == targetProfileId {
+		return m, http.StatusBadRequest,
+			errors.New("A profile cannot be merged into itself")
+	}
+
+	// Both must exist on this site, which prevents merges across sites. The
+	// source is loaded as it is, GetProfile would follow an earlier merge.
+	source, status, err := loadProfile(siteId, sourceProfileId)
+	if err != nil {
+		return m, status, err
+	}
+	_, status, err = loadProfile(siteId, targetProfileId)
+	if err != nil {
+		return m, status, err
+	}
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	mergedInto := map[int64]sql.NullInt64{}
+	rows, err := tx.Query(`--MergeProfiles
+SELECT profile_id
+      ,merged_into
+  FROM profiles
+ WHERE profile_id IN ($1, $2)
+ ORDER BY profile_id
+   FOR UPDATE`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Database query failed: %v", err.Error()),
+		)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int64
+			into sql.NullInt64
+		)
+		err = rows.Scan(&id, &into)
+		if err != nil {
+			return m, http.StatusInternalServerError, errors.New(
+				fmt.Sprintf("Row parsing error: %v", err.Error()),
+			)
+		}
+		mergedInto[id] = into
+	}
+	err = rows.Err()
+	if err != nil {
+		return m, http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Error fetching rows: %v", err.Error()),
+		)
+	}
+	rows.Close()
+
+	merged, status, err :=
+		checkMergeable(sourceProfileId, targetProfileId, mergedInto)
+	if err != nil {
+		return m, status, err
+	}
+	if merged {
+		return m, http.StatusOK, nil
+	}
+
+	// Attendance and watching of the same thing by both profiles
+	removedEventIds, err := mergeIds(tx, `--MergeProfiles
+SELECT a.event_id
+  FROM attendees a
+ WHERE a.profile_id = $1
+   AND EXISTS (
+           SELECT 1
+             FROM attendees t
+            WHERE t.event_id = a.event_id
+              AND t.profile_id = $2
+       )`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	removedAttendeeIds, err := mergeIds(tx, `--MergeProfiles
+DELETE FROM attendees a
+ WHERE a.profile_id = $1
+   AND EXISTS (
+           SELECT 1
+             FROM attendees t
+            WHERE t.event_id = a.event_id
+              AND t.profile_id = $2
+       )
+RETURNING a.attendee_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.AttendeesRemoved = int64(len(removedAttendeeIds))
+
+	// The follower counts of the profiles the source follows change whether
+	// their watcher is removed or moved
+	followedIds, err := mergeIds(tx, `--MergeProfiles
+SELECT item_id
+  FROM watchers
+ WHERE profile_id = $1
+   AND item_type_id = $2`,
+		sourceProfileId,
+		h.ItemTypes[h.ItemTypeProfile],
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	removedWatcherIds, err := mergeIds(tx, `--MergeProfiles
+DELETE FROM watchers w
+ WHERE w.profile_id = $1
+   AND EXISTS (
+           SELECT 1
+             FROM watchers t
+            WHERE t.item_type_id = w.item_type_id
+              AND t.item_id = w.item_id
+              AND t.profile_id = $2
+       )
+RETURNING w.watcher_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.WatchersRemoved = int64(len(removedWatcherIds))
+
+	// Everything else moves to the target
+	attendeeIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE attendees
+   SET profile_id = $2
+ WHERE profile_id = $1
+RETURNING attendee_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.Attendees = int64(len(attendeeIds))
+
+	watcherIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE watchers
+   SET profile_id = $2
+ WHERE profile_id = $1
+RETURNING watcher_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.Watchers = int64(len(watcherIds))
+
+	commentIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE comments
+   SET profile_id = $2
+ WHERE profile_id = $1
+RETURNING comment_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.Comments = int64(len(commentIds))
+
+	conversationIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE conversations
+   SET created_by = $2
+ WHERE created_by = $1
+RETURNING conversation_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.Conversations = int64(len(conversationIds))
+
+	pollIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE polls
+   SET created_by = $2
+ WHERE created_by = $1
+RETURNING poll_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.Polls = int64(len(pollIds))
+
+	// Huddles that both profiles are in keep only the target, the source is
+	// replaced by the target in the rest
+	removedHuddleIds, err := mergeIds(tx, `--MergeProfiles
+DELETE FROM huddle_profiles hp
+ WHERE hp.profile_id = $1
+   AND EXISTS (
+           SELECT 1
+             FROM huddle_profiles t
+            WHERE t.huddle_id = hp.huddle_id
+              AND t.profile_id = $2
+       )
+RETURNING hp.huddle_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.HuddlesRemoved = int64(len(removedHuddleIds))
+
+	huddleIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE huddle_profiles
+   SET profile_id = $2
+ WHERE profile_id = $1
+RETURNING huddle_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.Huddles = int64(len(huddleIds))
+
+	ownedHuddleIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE huddles
+   SET created_by = $2
+ WHERE created_by = $1
+RETURNING huddle_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	eventIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE events
+   SET created_by = $2
+ WHERE created_by = $1
+RETURNING event_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+	m.Events = int64(len(eventIds))
+
+	m.Revisions, err = mergeExec(tx, `--MergeProfiles
+UPDATE revisions
+   SET profile_id = $2
+ WHERE profile_id = $1`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	m.Attachments, err = mergeExec(tx, `--MergeProfiles
+UPDATE attachments
+   SET profile_id = $2
+ WHERE profile_id = $1`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	_, err = mergeExec(tx, `--MergeProfiles
+UPDATE flags
+   SET created_by = $2
+ WHERE site_id = $3
+   AND created_by = $1`,
+		sourceProfileId,
+		targetProfileId,
+		siteId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	for _, eventId := range removedEventIds {
+		status, err := (&EventType{Id: eventId}).UpdateAttendees(tx)
+		if err != nil {
+			return m, status, err
+		}
+	}
+
+	// The counts of both profiles are recalculated in the same way as
+	// UpdateCommentCountForAllProfiles, and the source is retired
+	_, err = mergeExec(tx, `--MergeProfiles
+UPDATE profiles AS p
+   SET comment_count = (
+           SELECT COUNT(*)
+             FROM flags
+            WHERE site_id = $1
+              AND created_by = p.profile_id
+              AND item_type_id = 4
+              AND microcosm_is_deleted IS NOT TRUE
+              AND microcosm_is_moderated IS NOT TRUE
+              AND parent_is_deleted IS NOT TRUE
+              AND parent_is_moderated IS NOT TRUE
+              AND item_is_deleted IS NOT TRUE
+              AND item_is_moderated IS NOT TRUE
+       )
+      ,item_count = (
+           SELECT COUNT(*)
+             FROM flags
+            WHERE site_id = $1
+              AND created_by = p.profile_id
+              AND item_type_id IN (6,9)
+              AND microcosm_is_deleted IS NOT TRUE
+              AND microcosm_is_moderated IS NOT TRUE
+              AND parent_is_deleted IS NOT TRUE
+              AND parent_is_moderated IS NOT TRUE
+              AND item_is_deleted IS NOT TRUE
+              AND item_is_moderated IS NOT TRUE
+       )
+ WHERE p.site_id = $1
+   AND p.profile_id IN ($2, $3)`,
+		siteId,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	_, err = mergeExec(tx, `--MergeProfiles
+UPDATE profiles
+   SET is_visible = false
+      ,is_deleted = true
+      ,merged_into = $2
+ WHERE profile_id = $1`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	// Profiles merged into the source earlier now lead to the target, so that
+	// merged_into never points at a merged profile
+	redirectedUserIds, err := mergeIds(tx, `--MergeProfiles
+UPDATE profiles
+   SET merged_into = $2
+ WHERE merged_into = $1
+RETURNING user_id`,
+		sourceProfileId,
+		targetProfileId,
+	)
+	if err != nil {
+		return m, http.StatusInternalServerError, err
+	}
+
+	if dryRun {
+		tx.Rollback()
+		return m, http.StatusOK, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return m, http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Transaction failed: %v", err.Error()),
+		)
+	}
+
+	for _, id := range append(removedAttendeeIds, attendeeIds...) {
+		PurgeCache(h.ItemTypes[h.ItemTypeAttendee], id)
+	}
+	for _, id := range append(removedWatcherIds, watcherIds...) {
+		PurgeCache(h.ItemTypes[h.ItemTypeWatcher], id)
+	}
+	for _, id := range followedIds {
+		PurgeCacheByScope(
+			c.CacheFollowCounts,
+			h.ItemTypes[h.ItemTypeProfile],
+			id,
+		)
+	}
+	for _, id := range commentIds {
+		PurgeCache(h.ItemTypes[h.ItemTypeComment], id)
+	}
+	for _, id := range conversationIds {
+		PurgeCache(h.ItemTypes[h.ItemTypeConversation], id)
+	}
+	for _, id := range append(removedEventIds, eventIds...) {
+		PurgeCache(h.ItemTypes[h.ItemTypeEvent], id)
+	}
+	for _, id := range pollIds {
+		PurgeCache(h.ItemTypes[h.ItemTypePoll], id)
+	}
+	huddleIds = append(huddleIds, ownedHuddleIds...)
+	for _, id := range append(removedHuddleIds, huddleIds...) {
+		PurgeCache(h.ItemTypes[h.ItemTypeHuddle], id)
+	}
+	PurgeCache(h.ItemTypes[h.ItemTypeProfile], sourceProfileId)
+	PurgeCache(h.ItemTypes[h.ItemTypeProfile], targetProfileId)
+	PurgeProfilesCache(siteId)
+	PurgeProfileIdCache(siteId, source.UserId)
+	for _, userId := range redirectedUserIds {
+		PurgeProfileIdCache(siteId, userId)
+	}
+	UpdateUnreadHuddleCount(targetProfileId)
+
+	glog.Infof(
+		"Merged profile %d into profile %d: %+v",
+		sourceProfileId,
+		targetProfileId,
+		m,
+	)
+
+	return m, http.StatusOK, nil
+}
+
+// checkMergeable returns whether the source profile may be merged into the
+// target given the profiles that each has been merged into. The first value
+// is true if the source has already been merged into the target, in which
+// case there is nothing to do.
+func checkMergeable(
+	sourceProfileId int64,
+	targetProfileId int64,
+	mergedInto map[int64]sql.NullInt64,
+) (
+	bool,
+	int,
+	error,
+) {
+
+	if sourceProfileId == targetProfileId {
+		return false, http.StatusBadRequest,
+			errors.New("A profile cannot be merged into itself")
+	}
+
+	if mergedInto[targetProfileId].Valid {
+		return false, http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"Profile %d has been merged into profile %d and cannot be "+
+					"merged into",
+				targetProfileId,
+				mergedInto[targetProfileId].Int64,
+			),
+		)
+	}
+
+	if mergedInto[sourceProfileId].Valid {
+		if mergedInto[sourceProfileId].Int64 == targetProfileId {
+			return true, http.StatusOK, nil
+		}
+
+		return false, http.StatusBadRequest, errors.New(
+			fmt.Sprintf(
+				"Profile %d has already been merged into profile %d",
+				sourceProfileId,
+				mergedInto[sourceProfileId].Int64,
+			),
+		)
+	}
+
+	return false, http.StatusOK, nil
+}
+
+// mergeIds runs a statement of MergeProfiles and returns the IDs it selects
+func mergeIds(tx *sql.Tx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return []int64{}, errors.New(
+			fmt.Sprintf("Database query failed: %v", err.Error()),
+		)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return []int64{}, errors.New(
+				fmt.Sprintf("Row parsing error: %v", err.Error()),
+			)
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []int64{}, errors.New(
+			fmt.Sprintf("Error fetching rows: %v", err.Error()),
+		)
+	}
+	rows.Close()
+
+	return ids, nil
+}
+
+// mergeExec runs a statement of MergeProfiles and returns the number of rows
+// it changed
+func mergeExec(tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, errors.New(
+			fmt.Sprintf("Update failed: %v", err.Error()),
+		)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.New(
+			fmt.Sprintf("Error fetching rows affected: %v", err.Error()),
+		)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+)
+
+func TestCheckMergeable(t *testing.T) {
+	none := map[int64]sql.NullInt64{}
+
+	_, status, err := checkMergeable(1, 1, none)
+	if err == nil || status != http.StatusBadRequest {
+		t.Errorf("Expected a merge into itself to be refused, got %d", status)
+	}
+
+	merged, _, err := checkMergeable(1, 2, none)
+	if err != nil || merged {
+		t.Errorf("Expected an unmerged profile to be mergeable, got %+v", err)
+	}
+
+	// The source was merged into the target already, nothing to do
+	merged, status, err = checkMergeable(
+		1,
+		2,
+		map[int64]sql.NullInt64{1: {Int64: 2, Valid: true}},
+	)
+	if err != nil || !merged || status != http.StatusOK {
+		t.Errorf("Expected a repeated merge to change nothing, got %d %+v", status, err)
+	}
+
+	// The source was merged elsewhere
+	_, status, err = checkMergeable(
+		1,
+		2,
+		map[int64]sql.NullInt64{1: {Int64: 3, Valid: true}},
+	)
+	if err == nil || status != http.StatusBadRequest {
+		t.Errorf("Expected a merged source to be refused, got %d", status)
+	}
+
+	// The target has gone, merging into it would strand the content
+	_, status, err = checkMergeable(
+		1,
+		2,
+		map[int64]sql.NullInt64{2: {Int64: 3, Valid: true}},
+	)
+	if err == nil || status != http.StatusBadRequest {
+		t.Errorf("Expected a merged target to be refused, got %d", status)
+	}
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// ProfileNameHistoryType records a profile name that has since been replaced
+type ProfileNameHistoryType struct {
+	ProfileId   int64       `json:"profileId"`
+	ProfileName string      `json:"profileName"`
+	ChangedById int64       `json:"-"`
+	ChangedBy   interface{} `json:"changedBy"`
+	Changed     time.Time   `json:"changed"`
+}
+
+// recordProfileNameChange stores the current profile name in the history if
+// it differs from the new name. It must be called within the transaction that
+// performs the rename so that the old name read here is the one replaced.
+func recordProfileNameChange(
+	tx *sql.Tx,
+	profileId int64,
+	newName string,
+	changedById int64,
+) error {
+
+	var oldName string
+	err := tx.QueryRow(`--GetProfileNameForUpdate
+SELECT profile_name
+  FROM profiles
+ WHERE profile_id = $1
+   FOR UPDATE`,
+		profileId,
+	).Scan(
+		&oldName,
+	)
+	if err != nil {
+		return errors.New(
+			fmt.Sprintf("Could not fetch current profile name: %v", err.Error()),
+		)
+	}
+
+	if oldName == newName {
+		return nil
+	}
+
+	_, err = tx.Exec(`--RecordProfileNameChange
+INSERT INTO profile_name_history (
+    profile_id
+   ,profile_name
+   ,changed_by
+   ,changed
+) VALUES (
+    $1
+   ,$2
+   ,$3
+   ,NOW()
+)`,
+		profileId,
+		oldName,
+		changedById,
+	)
+	if err != nil {
+		return errors.New(
+			fmt.Sprintf("Could not record profile name change: %v", err.Error()),
+		)
+	}
+
+	return nil
+}
+
+// GetProfileNameHistory returns the previous names of a profile, most recent
+// first
+func GetProfileNameHistory(
+	siteId int64,
+	profileId int64,
+) (
+	[]ProfileNameHistoryType,
+	int,
+	error,
+) {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return []ProfileNameHistoryType{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetProfileNameHistory
+SELECT pnh.profile_id
+      ,pnh.profile_name
+      ,pnh.changed_by
+      ,pnh.changed
+  FROM profile_name_history pnh
+  JOIN profiles p ON p.profile_id = pnh.profile_id
+ WHERE p.site_id = $1
+   AND pnh.profile_id = $2
+ ORDER BY pnh.changed DESC`,
+		siteId,
+		profileId,
+	)
+	if err != nil {
+		glog.Errorf("db.Query(%d, %d) %+v", siteId, profileId, err)
+		return []ProfileNameHistoryType{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	ems := []ProfileNameHistoryType{}
+	for rows.Next() {
+		m := ProfileNameHistoryType{}
+		err = rows.Scan(
+			&m.ProfileId,
+			&m.ProfileName,
+			&m.ChangedById,
+			&m.Changed,
+		)
+		if err != nil {
+			glog.Errorf("rows.Scan() %+v", err)
+			return []ProfileNameHistoryType{}, http.StatusInternalServerError,
+				errors.New("Row parsing error")
+		}
+
+		ems = append(ems, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return []ProfileNameHistoryType{}, http.StatusInternalServerError,
+			errors.New("Error fetching rows")
+	}
+	rows.Close()
+
+	for i, m := range ems {
+		profile, status, err := GetProfileSummary(siteId, m.ChangedById)
+		if err != nil {
+			return []ProfileNameHistoryType{}, status, err
+		}
+		ems[i].ChangedBy = profile
+	}
+
+	return ems, http.StatusOK, nil
+}
@@ -16,6 +16,8 @@ type ProfileOptionType struct {
 	SendEMail     bool  `json:"sendEmail"`
 	SendSMS       bool  `json:"sendSMS"`
 	IsDiscouraged bool  `json:"isDiscouraged"`
+	WatchOnRSVP   bool  `json:"watchOnRSVP"`
+	HideOnline    bool  `json:"hideOnline"`
 }
 
 func (m *ProfileOptionType) Insert(tx *sql.Tx) (int, error) {
@@ -28,6 +30,8 @@ INSERT INTO profile_options (
    ,send_email
    ,send_sms
    ,is_discouraged
+   ,watch_on_rsvp
+   ,hide_online
 ) VALUES (
     $1
    ,$2
@@ -35,6 +39,8 @@ INSERT INTO profile_options (
    ,$4
    ,$5
    ,$6
+   ,$7
+   ,$8
 )`,
 		m.ProfileId,
 		m.ShowDOBYear,
@@ -42,9 +48,10 @@ INSERT INTO profile_options (
 		m.SendEMail,
 		m.SendSMS,
 		m.IsDiscouraged,
+		m.WatchOnRSVP,
+		m.HideOnline,
 	)
 	if err != nil {
-		tx.Rollback()
 		return http.StatusInternalServerError, errors.New(
 			fmt.Sprintf("Error inserting data: %v", err.Error()),
 		)
@@ -64,22 +71,35 @@ func (m *ProfileOptionType) Update() (int, error) {
 
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`
-UPDATE profile_options
+	var siteId int64
+	err = tx.QueryRow(`
+UPDATE profile_options po
     SET show_dob_year = $2
     ,show_dob_date = $3
     ,send_email = $4
     ,send_sms = $5
     ,is_discouraged = $6
-WHERE profile_id = $1`,
+    ,watch_on_rsvp = $7
+    ,hide_online = $8
+   FROM profiles p
+WHERE po.profile_id = $1
+  AND p.profile_id = po.profile_id
+RETURNING p.site_id`,
 		m.ProfileId,
 		m.ShowDOBYear,
 		m.ShowDOB,
 		m.SendEMail,
 		m.SendSMS,
 		m.IsDiscouraged,
+		m.WatchOnRSVP,
+		m.HideOnline,
+	).Scan(
+		&siteId,
 	)
-	if err != nil {
+	if err == sql.ErrNoRows {
+		// There were no options to update
+		return http.StatusOK, nil
+	} else if err != nil {
 		tx.Rollback()
 		return http.StatusInternalServerError, errors.New(
 			fmt.Sprintf("Error inserting data: %v", err.Error()),
@@ -93,6 +113,11 @@ WHERE profile_id = $1`,
 		)
 	}
 
+	// Whether the profile appears online is part of the cached profile, and
+	// decides whether it is in the cached lists of online profiles
+	PurgeCache(h.ItemTypes[h.ItemTypeProfile], m.ProfileId)
+	PurgeProfilesCache(siteId)
+
 	return http.StatusOK, nil
 }
 
@@ -111,6 +136,8 @@ SELECT profile_id
       ,send_email
       ,send_sms
       ,is_discouraged
+      ,COALESCE(watch_on_rsvp, TRUE)
+      ,COALESCE(hide_online, FALSE)
   FROM profile_options
  WHERE profile_id = $1`,
 		profileId,
@@ -121,6 +148,8 @@ SELECT profile_id
 		&m.SendEMail,
 		&m.SendSMS,
 		&m.IsDiscouraged,
+		&m.WatchOnRSVP,
+		&m.HideOnline,
 	)
 	if err == sql.ErrNoRows {
 		return ProfileOptionType{}, http.StatusNotFound,
@@ -190,5 +219,11 @@ SELECT COALESCE(s.send_email, p.send_email) AS send_email
 	m.ShowDOB = false
 	m.ShowDOBYear = false
 
+	// Attending an event has always watched it
+	m.WatchOnRSVP = true
+
+	// Online status has always been public
+	m.HideOnline = false
+
 	return m, http.StatusOK, nil
 }
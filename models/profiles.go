@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"sort"
@@ -15,12 +17,113 @@ import (
 	"unicode/utf8"
 
 	"github.com/golang/glog"
+	"golang.org/x/text/unicode/norm"
 
 	c "github.com/microcosm-cc/microcosm/cache"
+	conf "github.com/microcosm-cc/microcosm/config"
+	e "github.com/microcosm-cc/microcosm/errors"
 	h "github.com/microcosm-cc/microcosm/helpers"
 )
 
-const UrlGravatar string = "https://secure.gravatar.com/avatar/"
+const (
+	UrlGravatar   string = "https://secure.gravatar.com/avatar/"
+	UrlLibravatar string = "https://seccdn.libravatar.org/avatar/"
+)
+
+// avatarClient is used to fetch avatars from remote providers, the default
+// redirect policy (follow up to 10) applies. It only connects to public
+// addresses as avatar servers may be named by the domain of any user's email.
+var avatarClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: &http.Transport{Dial: dialPublicAddress},
+}
+
+// The networks that avatars are never fetched from: loopback, private
+// (RFC 1918 and RFC 4193), link-local (which includes cloud metadata services)
+// and unspecified addresses
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isPublicAddress returns false for any address that is within one of the
+// nonPublicNetworks
+func isPublicAddress(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvesPublic returns true if the host resolves and every address it
+// resolves to is public
+func resolvesPublic(host string) bool {
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !isPublicAddress(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublicAddress dials the first public address of the host. The address is
+// checked as it is connected to so that a host cannot resolve to a public
+// address when checked and an internal one when fetched.
+func dialPublicAddress(network string, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		if isPublicAddress(ip) {
+			return net.DialTimeout(
+				network,
+				net.JoinHostPort(ip.String(), port),
+				10*time.Second,
+			)
+		}
+	}
+
+	return nil, errors.New(
+		fmt.Sprintf("%s does not resolve to a public address", host),
+	)
+}
 
 type ProfilesType struct {
 	Profiles h.ArrayType    `json:"profiles"`
@@ -55,10 +158,12 @@ type ProfileType struct {
 	ProfileComment    interface{}        `json:"profileComment"`
 	Created           time.Time          `json:"created"`
 	LastActive        time.Time          `json:"lastActive"`
+	HideOnline        bool               `json:"-"`
 	AvatarUrlNullable sql.NullString     `json:"-"`
 	AvatarUrl         string             `json:"avatar"`
 	AvatarIdNullable  sql.NullInt64      `json:"-"`
 	AvatarId          int64              `json:"-"`
+	MergedInto        sql.NullInt64      `json:"-"`
 	Meta              h.ExtendedMetaType `json:"meta"`
 }
 
@@ -67,9 +172,29 @@ type ProfileSearchOptions struct {
 	IsFollowing         bool
 	IsOnline            bool
 	StartsWith          string
+	Gender              string
+	CreatedAfter        time.Time
+	CreatedBefore       time.Time
 	ProfileId           int64
 }
 
+// profileSummaryPage is the cached form of a page of GetProfiles results
+type profileSummaryPage struct {
+	Profiles []ProfileSummaryType
+	Total    int64
+	Pages    int64
+}
+
+const (
+	// Pages of GetProfiles are cached against a per-site generation, changing
+	// the generation orphans every cached page for that site
+	mcProfilesGenerationKey string = "pr_g%d"
+	mcProfilesPageKey       string = "pr_p%d_%d_%s"
+
+	mcProfilesPageTtl       int32 = 60 * 5 // 5 minutes
+	mcProfilesOnlinePageTtl int32 = 30     // 30 seconds
+)
+
 type ProfileSummaryRequest struct {
 	Item   ProfileSummaryType
 	Err    error
@@ -91,53 +216,78 @@ func (v ProfileSummaryRequestBySeq) Less(i, j int) bool {
 	return v[i].Seq < v[j].Seq
 }
 
+// invalidProfileName returns an error explaining why a profile name is not
+// valid
+func invalidProfileName(message string) error {
+	return e.New(
+		0,
+		0,
+		"ValidateProfileName",
+		e.InvalidProfileName,
+		message,
+	)
+}
+
 func ValidateProfileName(name string) (string, int, error) {
 	// Note: We are not preventing shouting in usernames as some people will
 	// use their initials for their username
-	name = SanitiseText(strings.Trim(name, " "))
+	//
+	// Names are NFC normalised so that visually identical names composed of
+	// different code points are stored (and compared) identically
+	name = norm.NFC.String(SanitiseText(strings.Trim(name, " ")))
 
 	if name == "" {
 		return name, http.StatusBadRequest,
-			errors.New("You must supply a profile name")
+			invalidProfileName("You must supply a profile name")
 	}
 
 	nameLen := utf8.RuneCountInString(name)
 	if nameLen < 2 {
 		return name, http.StatusBadRequest,
-			errors.New("Profile name is too short, " +
+			invalidProfileName("Profile name is too short, " +
 				"it must be 2 characters or more.")
 	}
 
 	if nameLen > 25 {
 		return name, http.StatusBadRequest,
-			errors.New("Profile name is too long, " +
+			invalidProfileName("Profile name is too long, " +
 				"it must be 25 or fewer characters in length.")
 	}
 
 	if strings.Contains(name, " ") {
 		return name, http.StatusBadRequest,
-			errors.New("Profile name cannot contain a space, " +
+			invalidProfileName("Profile name cannot contain a space, " +
 				"have you considered using an underscore instead?")
 	}
 
 	if strings.Contains(name, "@") {
 		return name, http.StatusBadRequest,
-			errors.New("Profile name cannot contain an @, " +
+			invalidProfileName("Profile name cannot contain an @, " +
 				"have you considered using an underscore instead?")
 	}
 
 	if strings.Contains(name, "+") {
 		return name, http.StatusBadRequest,
-			errors.New("Profile name cannot contain a +, " +
+			invalidProfileName("Profile name cannot contain a +, " +
 				"have you considered using an underscore instead?")
 	}
 
 	return name, http.StatusOK, nil
 }
 
-func (m *ProfileType) Validate(exists bool) (int, error) {
+// ProfileNameTakenError is returned when a profile is updated with a name that
+// belongs to another profile, Suggestion is a name that is available
+type ProfileNameTakenError struct {
+	e.MicrocosmError
+	Suggestion string `json:"suggestion"`
+}
 
-	m.Gender = SanitiseText(m.Gender)
+// Validate checks the profile before it is saved. If the profile name is
+// taken by another profile then it is replaced with a suggested name if
+// renameIfTaken is true, which is used when profiles are created or imported.
+// Otherwise a ProfileNameTakenError is returned so that the client can
+// choose what to do.
+func (m *ProfileType) Validate(exists bool, renameIfTaken bool) (int, error) {
 
 	if m.SiteId < 1 {
 		return http.StatusBadRequest, errors.New("Invalid site ID supplied")
@@ -147,15 +297,10 @@ func (m *ProfileType) Validate(exists bool) (int, error) {
 		return http.StatusBadRequest, errors.New("Invalid user ID supplied")
 	}
 
-	if m.StyleId < 0 {
-		return http.StatusBadRequest, errors.New("Invalid style ID supplied")
-	}
-
-	name, status, err := ValidateProfileName(m.ProfileName)
+	status, err := m.validateDetails()
 	if err != nil {
 		return status, err
 	}
-	m.ProfileName = name
 
 	profileNameTaken, status, err :=
 		IsProfileNameTaken(m.SiteId, m.UserId, m.ProfileName)
@@ -168,8 +313,13 @@ func (m *ProfileType) Validate(exists bool) (int, error) {
 		if err != nil {
 			return status, err
 		}
-
-		m.ProfileName = SuggestProfileName(user)
+		status, err = m.replaceTakenName(
+			SuggestProfileName(m.SiteId, user),
+			renameIfTaken,
+		)
+		if err != nil {
+			return status, err
+		}
 	}
 
 	if !exists {
@@ -202,12 +352,80 @@ func (m *ProfileType) Validate(exists bool) (int, error) {
 	return http.StatusOK, nil
 }
 
+// validateDetails checks and tidies the parts of the profile that do not
+// depend upon its user
+func (m *ProfileType) validateDetails() (int, error) {
+
+	if m.StyleId < 0 {
+		return http.StatusBadRequest, errors.New("Invalid style ID supplied")
+	}
+
+	gender, ok := normaliseGender(SanitiseText(m.Gender))
+	if !ok {
+		return http.StatusBadRequest, e.New(
+			m.SiteId,
+			m.Id,
+			"ProfileType.Validate",
+			e.InvalidContent,
+			fmt.Sprintf(
+				"Gender must be one of: %s",
+				strings.Join(ProfileGenders(), ", "),
+			),
+		)
+	}
+	m.Gender = gender
+	m.GenderNullable = sql.NullString{
+		String: gender,
+		Valid:  gender != ProfileGenderUnspecified,
+	}
+
+	name, status, err := ValidateProfileName(m.ProfileName)
+	if err != nil {
+		return status, err
+	}
+	m.ProfileName = name
+
+	return http.StatusOK, nil
+}
+
+// replaceTakenName renames the profile to the suggested name if renameIfTaken
+// is true, otherwise a ProfileNameTakenError offering the suggestion is
+// returned with a 409 Conflict
+func (m *ProfileType) replaceTakenName(
+	suggestion string,
+	renameIfTaken bool,
+) (
+	int,
+	error,
+) {
+	if !renameIfTaken {
+		return http.StatusConflict, &ProfileNameTakenError{
+			MicrocosmError: e.MicrocosmError{
+				SiteId:    m.SiteId,
+				ProfileId: m.Id,
+				Function:  "ProfileType.Validate",
+				ErrorCode: e.ProfileNameTaken,
+				ErrorMessage: fmt.Sprintf(
+					"The profile name '%s' is taken, how about '%s'?",
+					m.ProfileName,
+					suggestion,
+				),
+			},
+			Suggestion: suggestion,
+		}
+	}
+
+	m.ProfileName = suggestion
+
+	return http.StatusOK, nil
+}
+
 // Insert provides a public interface for creating a profile.
 //
 // Insert performs strict validation and will return an error if the data is
 // not very good (i.e. username contains a space and created date was supplied)
 func (m *ProfileType) Insert() (int, error) {
-	status, err := m.Validate(false)
+	status, err := m.Validate(false, true)
 	if err != nil {
 		return status, err
 	}
@@ -225,13 +443,24 @@ func (m *ProfileType) Insert() (int, error) {
 // data is fundamentally crap. It will repair and fix any data it can, i.e.
 // by replacing spaces in usernames
 func (m *ProfileType) Import() (int, error) {
+	status, err := m.prepareImport()
+	if err != nil {
+		return status, err
+	}
+
+	return m.insert(true)
+}
+
+// prepareImport repairs and validates a profile that is about to be imported
+func (m *ProfileType) prepareImport() (int, error) {
 
 	// Microcosm usernames cannot contain spaces
 	m.ProfileName = strings.Replace(m.ProfileName, " ", "_", -1)
 
 	// Validates as if it already exists to avoid any of that messy "you can't
-	// set the created data" rubbish
-	status, err := m.Validate(true)
+	// set the created data" rubbish. Imported names that are taken are
+	// replaced as there is nobody to ask for another.
+	status, err := m.Validate(true, true)
 	if err != nil {
 		return status, err
 	}
@@ -241,10 +470,25 @@ func (m *ProfileType) Import() (int, error) {
 		m.LastActive = m.Created
 	}
 
-	return m.insert(true)
+	return http.StatusOK, nil
 }
 
 func (m *ProfileType) insert(isImport bool) (int, error) {
+	status, err := m.insertCommitted()
+	if err != nil {
+		return status, err
+	}
+
+	// A profile previously held by the user on this site may still be cached
+	PurgeProfileIdCache(m.SiteId, m.UserId)
+	PurgeProfilesCache(m.SiteId)
+
+	return m.afterInsert(isImport)
+}
+
+// insertCommitted inserts the profile and its options in a transaction that
+// has been committed when it returns successfully
+func (m *ProfileType) insertCommitted() (int, error) {
 
 	tx, err := h.GetTransaction()
 	if err != nil {
@@ -255,8 +499,26 @@ func (m *ProfileType) insert(isImport bool) (int, error) {
 
 	defer tx.Rollback()
 
+	status, err := m.insertTx(tx)
+	if err != nil {
+		return status, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Transaction failed: %+v", err),
+		)
+	}
+
+	return http.StatusOK, nil
+}
+
+// insertTx inserts the profile and its options within the transaction
+func (m *ProfileType) insertTx(tx *sql.Tx) (int, error) {
+
 	var insertId int64
-	err = tx.QueryRow(`--Create Profile
+	err := tx.QueryRow(`--Create Profile
 INSERT INTO profiles (
     site_id
    ,user_id
@@ -327,12 +589,14 @@ INSERT INTO profiles (
 		)
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return http.StatusInternalServerError, errors.New(
-			fmt.Sprintf("Transaction failed: %+v", err),
-		)
-	}
+	return http.StatusOK, nil
+}
+
+// afterInsert completes a profile once the transaction that inserted it has
+// been committed, giving it the identicon of the gravatar as its avatar. The
+// avatar itself is fetched and stored in the background for new profiles, as
+// the profile may be being created during a login that must not wait for it.
+func (m *ProfileType) afterInsert(isImport bool) (int, error) {
 
 	// Fetch gravatar (or default to pattern based on email address)
 	user, _, err := GetUser(m.UserId)
@@ -342,40 +606,13 @@ INSERT INTO profiles (
 		)
 	}
 
-	// Create attachment for avatar and attach it to profile
+	// Construct URL to avatar, update profile with Avatar URL
 	avatarUrl := MakeGravatarUrl(user.Email)
-	if !isImport {
-		fm, _, err := StoreGravatar(avatarUrl)
-		if err != nil {
-			return http.StatusInternalServerError, errors.New(
-				fmt.Sprintf("Could not store gravatar for profile: %+v", err),
-			)
-		}
-
-		// Attach avatar to profile
-		attachment, status, err := AttachAvatar(m.Id, fm)
-		if err != nil {
-			return status, errors.New(
-				fmt.Sprintf("Could not attach avatar to profile: %+v", err),
-			)
-		}
-		m.AvatarIdNullable = sql.NullInt64{
-			Int64: attachment.AttachmentId,
-			Valid: true,
-		}
-		filePath := fm.FileHash
-		if fm.FileExt != "" {
-			filePath += `.` + fm.FileExt
-		}
-		avatarUrl = fmt.Sprintf("%s/%s", h.ApiTypeFile, filePath)
-	}
-
-	// Construct URL to avatar, update profile with Avatar ID and URL
 	m.AvatarUrlNullable = sql.NullString{
 		String: avatarUrl,
 		Valid:  true,
 	}
-	status, err = m.Update()
+	status, err := m.Update()
 	if err != nil {
 		return status, errors.New(
 			fmt.Sprintf("Could not update profile with avatar: %+v", err),
@@ -385,18 +622,139 @@ INSERT INTO profiles (
 	go PurgeCache(h.ItemTypes[h.ItemTypeProfile], m.Id)
 	go MarkAllAsRead(m.Id)
 
+	if !isImport {
+		go storeProfileAvatar(m.Id, user.Email, avatarUrl)
+	}
+
 	return http.StatusOK, nil
 }
 
+// storeProfileAvatar fetches the avatar of a new profile, stores it and
+// attaches it to the profile. The profile is only updated if it still has the
+// default avatar, so that one chosen in the meantime is not replaced. Errors
+// are logged as the profile is usable without the stored avatar.
+func storeProfileAvatar(profileId int64, email string, defaultUrl string) {
+
+	fm, _, err := StoreGravatar(email)
+	if err != nil {
+		glog.Errorf("StoreGravatar(`%s`) %+v", email, err)
+		return
+	}
+
+	attachment, _, err := AttachAvatar(profileId, fm)
+	if err != nil {
+		glog.Errorf("AttachAvatar(%d, %s) %+v", profileId, fm.FileHash, err)
+		return
+	}
+
+	filePath := fm.FileHash
+	if fm.FileExt != "" {
+		filePath += `.` + fm.FileExt
+	}
+	avatarUrl := fmt.Sprintf("%s/%s", h.ApiTypeFile, filePath)
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return
+	}
+
+	var siteId int64
+	err = db.QueryRow(`--storeProfileAvatar
+UPDATE profiles
+   SET avatar_id = $2
+      ,avatar_url = $3
+ WHERE profile_id = $1
+   AND avatar_id IS NULL
+   AND avatar_url = $4
+RETURNING site_id`,
+		profileId,
+		attachment.AttachmentId,
+		avatarUrl,
+		defaultUrl,
+	).Scan(
+		&siteId,
+	)
+	if err == sql.ErrNoRows {
+		// The avatar was changed while the Gravatar was being fetched
+		return
+	} else if err != nil {
+		glog.Errorf("db.QueryRow(%d) %+v", profileId, err)
+		return
+	}
+
+	PurgeCache(h.ItemTypes[h.ItemTypeProfile], profileId)
+	PurgeProfilesCache(siteId)
+}
+
+// Delete removes the profile and its options. Profiles that are referenced by
+// content cannot be deleted as it would break the attribution of that content.
 func (m *ProfileType) Delete() (int, error) {
 
-	return http.StatusNotImplemented,
-		errors.New("Delete Profile is not yet implemented")
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`--Delete Profile Options
+DELETE
+  FROM profile_options
+ WHERE profile_id = $1`,
+		m.Id,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Delete failed: %v", err.Error()),
+		)
+	}
+
+	var siteId, userId int64
+	err = tx.QueryRow(`--Delete Profile
+DELETE
+  FROM profiles
+ WHERE profile_id = $1
+RETURNING site_id, user_id`,
+		m.Id,
+	).Scan(
+		&siteId,
+		&userId,
+	)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, errors.New(
+			fmt.Sprintf("Profile not found: %d", m.Id),
+		)
+	} else if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Delete failed: %v", err.Error()),
+		)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Transaction failed: %v", err.Error()),
+		)
+	}
+
+	PurgeProfileIdCache(siteId, userId)
+	PurgeCache(h.ItemTypes[h.ItemTypeProfile], m.Id)
+	PurgeProfilesCache(siteId)
+
+	return http.StatusOK, nil
 }
 
+// Update saves the profile, any change of name is attributed to the profile
+// itself
 func (m *ProfileType) Update() (int, error) {
+	return m.UpdateBy(m.Id)
+}
+
+// UpdateBy saves the profile, attributing any change of name to the given
+// profile so that moderators can see who renamed whom
+func (m *ProfileType) UpdateBy(changedById int64) (int, error) {
 
-	status, err := m.Validate(true)
+	status, err := m.Validate(true, false)
 	if err != nil {
 		return status, err
 	}
@@ -409,6 +767,11 @@ func (m *ProfileType) Update() (int, error) {
 	}
 	defer tx.Rollback()
 
+	err = recordProfileNameChange(tx, m.Id, m.ProfileName, changedById)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
 	_, err = tx.Exec(`--Update Profile
 UPDATE profiles
    SET profile_name = $2
@@ -447,101 +810,93 @@ UPDATE profiles
 	}
 
 	PurgeCache(h.ItemTypes[h.ItemTypeProfile], m.Id)
+	PurgeProfilesCache(m.SiteId)
 
 	return http.StatusOK, nil
 
 }
 
-func UpdateLastActive(profileId int64, lastActive time.Time) (int, error) {
-
-	db, err := h.GetConnection()
-	if err != nil {
-		glog.Errorf("h.GetConnection() %+v", err)
-		return http.StatusInternalServerError, errors.New(
-			fmt.Sprintf("Could not get a database connection: %v", err.Error()),
-		)
-	}
+func IncrementProfileCommentCount(profileId int64) {
+	updateProfileCommentCount(profileId, 1)
+}
 
-	tx, err := db.Begin()
-	if err != nil {
-		return http.StatusInternalServerError, errors.New(
-			fmt.Sprintf("Could not start transaction: %v", err.Error()),
-		)
-	}
-	defer tx.Rollback()
+func DecrementProfileCommentCount(profileId int64) {
+	updateProfileCommentCount(profileId, -1)
+}
 
-	_, err = tx.Exec(`--UpdateLastActive
-UPDATE profiles
-   SET last_active = $2
- WHERE profile_id = $1;`,
+// profileCountPurgeDelay is how long after a comment count has changed that
+// the profile is purged from the cache a second time. A request that read the
+// profile before the change was committed may otherwise repopulate the cache
+// with the old count after the first purge.
+const profileCountPurgeDelay = 2 * time.Second
+
+// updateProfileCommentCount changes the comment count of a profile by delta.
+// The change is made in the database, which is atomic, and the cached profile
+// is purged once the change has been committed rather than updated as
+// concurrent changes may complete in any order.
+func updateProfileCommentCount(profileId int64, delta int64) {
+	changeProfileCommentCount(
 		profileId,
-		lastActive,
+		delta,
+		execProfileCommentCount,
+		purgeProfileCommentCount,
+		profileCountPurgeDelay,
 	)
-	if err != nil {
-		nerr := tx.Rollback()
-		if nerr != nil {
-			glog.Errorf("Cannot rollback: %+v", nerr)
-		}
-
-		return http.StatusInternalServerError, errors.New(
-			fmt.Sprintf("Update of last active failed: %v", err.Error()),
-		)
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		return http.StatusInternalServerError, errors.New(
-			fmt.Sprintf("Transaction failed: %v", err.Error()),
-		)
-	}
-
-	PurgeCacheByScope(c.CacheDetail, h.ItemTypes[h.ItemTypeProfile], profileId)
-
-	return http.StatusOK, nil
 }
 
-func IncrementProfileCommentCount(profileId int64) {
-
-	db, err := h.GetConnection()
-	if err != nil {
-		glog.Error(err)
+// changeProfileCommentCount makes the change with exec, which returns the site
+// of the profile once the change is committed, and then purges the profile
+// with purge. The purge is repeated after delay to remove any old count that
+// was cached by a request that was already reading the profile.
+func changeProfileCommentCount(
+	profileId int64,
+	delta int64,
+	exec func(profileId int64, delta int64) (int64, error),
+	purge func(siteId int64, profileId int64),
+	delay time.Duration,
+) {
+	siteId, err := exec(profileId, delta)
+	if err == sql.ErrNoRows {
 		return
-	}
-
-	_, err = db.Exec(`--Update Profile Comment Count
-UPDATE profiles
-   SET comment_count = comment_count + 1
- WHERE profile_id = $1`,
-		profileId,
-	)
-	if err != nil {
-		glog.Error(err)
+	} else if err != nil {
+		glog.Errorf("exec(%d, %d) %+v", profileId, delta, err)
 		return
 	}
 
-	PurgeCacheByScope(c.CacheDetail, h.ItemTypes[h.ItemTypeProfile], profileId)
+	purge(siteId, profileId)
+	time.AfterFunc(delay, func() {
+		purge(siteId, profileId)
+	})
 }
 
-func DecrementProfileCommentCount(profileId int64) {
-
+// execProfileCommentCount changes the comment count of the profile in the
+// database and returns the site of the profile
+func execProfileCommentCount(profileId int64, delta int64) (int64, error) {
 	db, err := h.GetConnection()
 	if err != nil {
-		glog.Error(err)
-		return
+		return 0, err
 	}
 
-	_, err = db.Exec(`--Update Profile Comment Count
+	var siteId int64
+	err = db.QueryRow(`--Update Profile Comment Count
 UPDATE profiles
-   SET comment_count = comment_count - 1
- WHERE profile_id = $1`,
+   SET comment_count = comment_count + $2
+ WHERE profile_id = $1
+RETURNING site_id`,
 		profileId,
+		delta,
+	).Scan(
+		&siteId,
 	)
-	if err != nil {
-		glog.Error(err)
-		return
-	}
 
+	return siteId, err
+}
+
+// purgeProfileCommentCount purges the cached profile, and the listings of the
+// site as they may be ordered by comment count
+func purgeProfileCommentCount(siteId int64, profileId int64) {
 	PurgeCacheByScope(c.CacheDetail, h.ItemTypes[h.ItemTypeProfile], profileId)
+	PurgeProfilesCache(siteId)
 }
 
 // UpdateCommentCountForAllProfiles is intended as an import/admin task only.
@@ -625,7 +980,37 @@ UPDATE profiles AS p
 	return http.StatusOK, nil
 }
 
+// GetProfile returns the profile along with how many profiles follow it and
+// how many it follows. A profile that has been merged into another returns
+// the profile it was merged into.
 func GetProfile(siteId int64, id int64) (ProfileType, int, error) {
+	m, status, err := loadProfile(siteId, id)
+	if err != nil {
+		return ProfileType{}, status, err
+	}
+
+	if m.MergedInto.Valid {
+		m, status, err = loadProfile(siteId, m.MergedInto.Int64)
+		if err != nil {
+			return ProfileType{}, status, err
+		}
+	}
+
+	counts, status, err := GetFollowCounts(m.Id)
+	if err != nil {
+		return ProfileType{}, status, err
+	}
+	m.Meta.Stats = append(
+		m.Meta.Stats,
+		h.StatType{Metric: "followers", Value: counts.Followers},
+		h.StatType{Metric: "following", Value: counts.Following},
+	)
+
+	return m, http.StatusOK, nil
+}
+
+// loadProfile returns the profile from the cache or the database
+func loadProfile(siteId int64, id int64) (ProfileType, int, error) {
 
 	if id == 0 {
 		return ProfileType{}, http.StatusNotFound,
@@ -679,8 +1064,15 @@ SELECT p.profile_id
        ) as profile_comment_id
       ,p.created
       ,p.last_active
+      ,COALESCE(
+           (SELECT hide_online
+              FROM profile_options
+             WHERE profile_id = p.profile_id),
+           FALSE
+       ) AS hide_online
       ,p.avatar_url
       ,p.avatar_id
+      ,p.merged_into
   FROM profiles p,
        (
            SELECT COUNT(*) as item_count
@@ -711,8 +1103,10 @@ SELECT p.profile_id
 		&profileCommentId,
 		&m.Created,
 		&m.LastActive,
+		&m.HideOnline,
 		&m.AvatarUrlNullable,
 		&m.AvatarIdNullable,
+		&m.MergedInto,
 	)
 
 	if err == sql.ErrNoRows {
@@ -726,9 +1120,7 @@ SELECT p.profile_id
 		)
 	}
 
-	if m.GenderNullable.Valid {
-		m.Gender = m.GenderNullable.String
-	}
+	m.Gender = readGender(m.GenderNullable.String)
 	if m.AvatarIdNullable.Valid {
 		m.AvatarId = m.AvatarIdNullable.Int64
 	}
@@ -752,7 +1144,7 @@ SELECT p.profile_id
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeProfile))
 
 	return m, http.StatusOK, nil
 }
@@ -857,7 +1249,7 @@ SELECT unread_huddles
 		h.StatType{Metric: "unreadHuddles", Value: unreadHuddles},
 	)
 
-	c.CacheSetInt64(mcKey, unreadHuddles, mcTtl)
+	c.CacheSetInt64(mcKey, unreadHuddles, cacheTtl(h.ItemTypeProfile))
 
 	return http.StatusOK, nil
 }
@@ -905,6 +1297,33 @@ func GetProfileSummary(
 		return m, http.StatusOK, nil
 	}
 
+	// The query is constrained to the site, so concurrent loads for different
+	// sites must not share a result
+	val, status, err := c.CacheLoadOnce(
+		fmt.Sprintf("%s_%d", mcKey, siteId),
+		func() (interface{}, int, error) {
+			m, status, err := loadProfileSummary(siteId, id, mcKey)
+			return m, status, err
+		},
+	)
+	if err != nil {
+		return ProfileSummaryType{}, status, err
+	}
+
+	return val.(ProfileSummaryType), http.StatusOK, nil
+}
+
+// loadProfileSummary fetches a profile summary from the database and caches it
+func loadProfileSummary(
+	siteId int64,
+	id int64,
+	mcKey string,
+) (
+	ProfileSummaryType,
+	int,
+	error,
+) {
+
 	db, err := h.GetConnection()
 	if err != nil {
 		glog.Error(err)
@@ -961,11 +1380,162 @@ SELECT profile_id
 		}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeProfile))
 
 	return m, http.StatusOK, nil
 }
 
+// GetProfilesByIds returns the summaries of the given profiles in the order
+// the IDs were supplied. Duplicate IDs are ignored, cached profiles are served
+// from the cache and the remainder are fetched with a single query. Profiles
+// that do not exist on the site are omitted.
+func GetProfilesByIds(
+	siteId int64,
+	ids []int64,
+) (
+	[]ProfileSummaryType,
+	int,
+	error,
+) {
+
+	found := map[int64]ProfileSummaryType{}
+	uniqueIds := []int64{}
+	missIds := []string{}
+
+	for _, id := range ids {
+		if _, seen := found[id]; seen || id == 0 {
+			continue
+		}
+		found[id] = ProfileSummaryType{}
+		uniqueIds = append(uniqueIds, id)
+
+		mcKey := fmt.Sprintf(mcProfileKeys[c.CacheSummary], id)
+		if val, ok := c.CacheGet(mcKey, ProfileSummaryType{}); ok {
+			m := val.(ProfileSummaryType)
+			if m.SiteId == siteId {
+				found[id] = m
+			}
+			continue
+		}
+
+		missIds = append(missIds, strconv.FormatInt(id, 10))
+	}
+
+	if len(missIds) > 0 {
+		db, err := h.GetConnection()
+		if err != nil {
+			glog.Error(err)
+			return []ProfileSummaryType{}, http.StatusInternalServerError, err
+		}
+
+		rows, err := db.Query(`--GetProfilesByIds
+SELECT profile_id
+      ,site_id
+      ,user_id
+      ,profile_name
+      ,is_visible
+      ,avatar_url
+      ,avatar_id
+  FROM profiles
+ WHERE site_id = $1
+   AND profile_id = ANY($2::bigint[])`,
+			siteId,
+			`{`+strings.Join(missIds, `,`)+`}`,
+		)
+		if err != nil {
+			glog.Error(err)
+			return []ProfileSummaryType{}, http.StatusInternalServerError,
+				errors.New(
+					fmt.Sprintf("Database query failed: %v", err.Error()),
+				)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var m ProfileSummaryType
+			err = rows.Scan(
+				&m.Id,
+				&m.SiteId,
+				&m.UserId,
+				&m.ProfileName,
+				&m.Visible,
+				&m.AvatarUrlNullable,
+				&m.AvatarIdNullable,
+			)
+			if err != nil {
+				glog.Error(err)
+				return []ProfileSummaryType{}, http.StatusInternalServerError,
+					errors.New(
+						fmt.Sprintf("Row parsing error: %v", err.Error()),
+					)
+			}
+
+			if m.AvatarIdNullable.Valid {
+				m.AvatarId = m.AvatarIdNullable.Int64
+			}
+			if m.AvatarUrlNullable.Valid {
+				m.AvatarUrl = m.AvatarUrlNullable.String
+			}
+			m.Meta.Links =
+				[]h.LinkType{
+					h.GetLink("self", "", h.ItemTypeProfile, m.Id),
+					h.GetLink("site", "", h.ItemTypeSite, m.SiteId),
+				}
+
+			// Update cache
+			c.CacheSet(
+				fmt.Sprintf(mcProfileKeys[c.CacheSummary], m.Id),
+				m,
+				cacheTtl(h.ItemTypeProfile),
+			)
+
+			found[m.Id] = m
+		}
+		err = rows.Err()
+		if err != nil {
+			glog.Error(err)
+			return []ProfileSummaryType{}, http.StatusInternalServerError,
+				errors.New(
+					fmt.Sprintf("Error fetching rows: %v", err.Error()),
+				)
+		}
+		rows.Close()
+	}
+
+	ems := []ProfileSummaryType{}
+	for _, id := range uniqueIds {
+		if found[id].Id != 0 {
+			ems = append(ems, found[id])
+		}
+	}
+
+	return ems, http.StatusOK, nil
+}
+
+// mcProfileIdKey maps a site and user to their profile. The profile ID is not
+// known when the mapping is needed, so the key does not conform to the cache
+// flushing mechanism and must be purged with PurgeProfileIdCache whenever a
+// profile is created or deleted.
+const mcProfileIdKey = "s%d_u%d"
+
+// profileIdTtl bounds how long a stale mapping can be served if a purge is
+// missed
+const profileIdTtl int32 = 60 * 60 * 24 // 1 Day
+
+// PurgeProfileIdCache removes the cached profile ID of the user on the site
+func PurgeProfileIdCache(siteId int64, userId int64) {
+	c.CacheDelete(fmt.Sprintf(mcProfileIdKey, siteId, userId))
+}
+
+// profileIdCacheTtl returns the TTL of a cached profile ID given the TTL of
+// cached profiles
+func profileIdCacheTtl(profileTtl int32) int32 {
+	if profileTtl <= 0 || profileTtl > profileIdTtl {
+		return profileIdTtl
+	}
+	return profileTtl
+}
+
 func GetProfileId(siteId int64, userId int64) (int64, int, error) {
 
 	if siteId == 0 || userId == 0 {
@@ -973,15 +1543,23 @@ func GetProfileId(siteId int64, userId int64) (int64, int, error) {
 	}
 
 	// Get from cache if it's available
-	//
-	// This map of siteId+userId = profileId is never expected to change, so
-	// this cache key is unique and does not conform to the cache flushing
-	// mechanism
-	mcKey := fmt.Sprintf("s%d_u%d", siteId, userId)
+	mcKey := fmt.Sprintf(mcProfileIdKey, siteId, userId)
 	if val, ok := c.CacheGetInt64(mcKey); ok {
 		return val, http.StatusOK, nil
 	}
 
+	profileId, status, err := queryProfileId(siteId, userId)
+	if err != nil {
+		return profileId, status, err
+	}
+
+	c.CacheSetInt64(mcKey, profileId, profileIdCacheTtl(cacheTtl(h.ItemTypeProfile)))
+
+	return profileId, http.StatusOK, nil
+}
+
+func queryProfileId(siteId int64, userId int64) (int64, int, error) {
+
 	var profileId int64
 	db, err := h.GetConnection()
 	if err != nil {
@@ -989,8 +1567,9 @@ func GetProfileId(siteId int64, userId int64) (int64, int, error) {
 		return profileId, http.StatusInternalServerError, err
 	}
 
+	// A merged profile resolves to the profile it was merged into
 	err = db.QueryRow(`--GetProfileId
-SELECT profile_id
+SELECT COALESCE(merged_into, profile_id)
   FROM profiles
  WHERE site_id = $1
    AND user_id = $2`,
@@ -1018,11 +1597,12 @@ SELECT profile_id
 			)
 	}
 
-	c.CacheSetInt64(mcKey, profileId, mcTtl)
-
 	return profileId, http.StatusOK, nil
 }
 
+// GetOrCreateProfile returns the profile of the user on the site, creating
+// one if there is none. The status is http.StatusCreated if the profile was
+// created.
 func GetOrCreateProfile(
 	site SiteType,
 	user UserType,
@@ -1050,7 +1630,7 @@ func GetOrCreateProfile(
 	if p.SiteId == 1 {
 		p.ProfileName = strings.Split(user.Email, "@")[0]
 	} else {
-		p.ProfileName = SuggestProfileName(user)
+		p.ProfileName = SuggestProfileName(p.SiteId, user)
 	}
 	p.Visible = true
 
@@ -1059,7 +1639,7 @@ func GetOrCreateProfile(
 		glog.Errorf("Creation of profile failed: %+v\n", err)
 		return ProfileType{}, status, err
 	}
-	return p, http.StatusOK, nil
+	return p, http.StatusCreated, nil
 }
 
 func GetProfiles(
@@ -1075,6 +1655,13 @@ func GetProfiles(
 	error,
 ) {
 
+	// Get from cache if it's available
+	mcKey := getProfilesPageKey(siteId, so, limit, offset)
+	if val, ok := c.CacheGet(mcKey, profileSummaryPage{}); ok {
+		page := val.(profileSummaryPage)
+		return page.Profiles, page.Total, page.Pages, http.StatusOK, nil
+	}
+
 	// Retrieve resources
 	db, err := h.GetConnection()
 	if err != nil {
@@ -1090,12 +1677,6 @@ func GetProfiles(
                       AND p.profile_id = w.item_id`
 	}
 
-	var online string
-	if so.IsOnline {
-		online = `
-   AND p.last_active > NOW() - interval '90 minute'`
-	}
-
 	var selectCountArgs []interface{}
 	var selectArgs []interface{}
 	//                                        $1      $2            $3     $4
@@ -1103,16 +1684,77 @@ func GetProfiles(
 	//                              $1      $2            $3     $4
 	selectArgs = append(selectArgs, siteId, so.ProfileId, limit, offset)
 
+	// Filters are shared by the count and the select, so their parameters
+	// are appended to both and must have the same position in each
+	var online string
+	if so.IsOnline {
+		onlineSince := OnlineSince(time.Now(), GetOnlineWindow())
+		selectCountArgs = append(selectCountArgs, onlineSince)
+		selectArgs = append(selectArgs, onlineSince)
+		online = `
+   AND p.last_active > $` + strconv.Itoa(len(selectArgs)) + `
+   AND (p.profile_id = $2 OR ` + notHidingOnline + `)`
+	}
+
 	var startsWith string
-	var startsWithOrderBy string
 	if so.StartsWith != "" {
-		//                                        $5
 		selectCountArgs = append(selectCountArgs, so.StartsWith+`%`)
-		//                              $5                 $6
-		selectArgs = append(selectArgs, so.StartsWith+`%`, so.StartsWith)
+		selectArgs = append(selectArgs, so.StartsWith+`%`)
 		startsWith = `
-   AND p.profile_name ILIKE $5`
-		startsWithOrderBy = `p.profile_name ILIKE $6 DESC
+   AND p.profile_name ILIKE $` + strconv.Itoa(len(selectArgs))
+	}
+
+	var gender string
+	if so.Gender == ProfileGenderUnspecified {
+		// Free text genders from before the vocabulary are read as
+		// unspecified, so they match too
+		known := []string{}
+		for _, g := range ProfileGenders() {
+			if g == ProfileGenderUnspecified {
+				continue
+			}
+			selectCountArgs = append(selectCountArgs, g)
+			selectArgs = append(selectArgs, g)
+			known = append(known, `$`+strconv.Itoa(len(selectArgs)))
+		}
+		if len(known) > 0 {
+			gender = `
+   AND (p.gender IS NULL OR LOWER(p.gender) NOT IN (` +
+				strings.Join(known, `,`) + `))`
+		} else {
+			gender = `
+   AND p.gender IS NULL`
+		}
+	} else if so.Gender != "" {
+		selectCountArgs = append(selectCountArgs, so.Gender)
+		selectArgs = append(selectArgs, so.Gender)
+		gender = `
+   AND LOWER(p.gender) = LOWER($` + strconv.Itoa(len(selectArgs)) + `)`
+	}
+
+	var createdAfter string
+	if !so.CreatedAfter.IsZero() {
+		selectCountArgs = append(selectCountArgs, so.CreatedAfter)
+		selectArgs = append(selectArgs, so.CreatedAfter)
+		createdAfter = `
+   AND p.created >= $` + strconv.Itoa(len(selectArgs))
+	}
+
+	var createdBefore string
+	if !so.CreatedBefore.IsZero() {
+		selectCountArgs = append(selectCountArgs, so.CreatedBefore)
+		selectArgs = append(selectArgs, so.CreatedBefore)
+		createdBefore = `
+   AND p.created < $` + strconv.Itoa(len(selectArgs))
+	}
+
+	// Ordering parameters only exist in the select, so they follow all of the
+	// filter parameters
+	var startsWithOrderBy string
+	if so.StartsWith != "" {
+		selectArgs = append(selectArgs, so.StartsWith)
+		startsWithOrderBy = `p.profile_name ILIKE $` +
+			strconv.Itoa(len(selectArgs)) + ` DESC
          ,`
 	}
 
@@ -1123,11 +1765,13 @@ SELECT p.profile_id`
 	sqlFromWhere := `
   FROM profiles p
   LEFT JOIN ignores i ON i.profile_id = $2
+                     AND (i.expires IS NULL OR i.expires > NOW())
                      AND i.item_type_id = 3
                      AND i.item_id = p.profile_id` + following + `
  WHERE p.site_id = $1
    AND i.profile_id IS NULL
-   AND p.profile_name <> 'deleted'` + online + startsWith
+   AND p.profile_name <> 'deleted'` + online + startsWith + gender +
+		createdAfter + createdBefore
 
 	var sqlOrderLimit string
 	if so.OrderByCommentCount {
@@ -1238,9 +1882,123 @@ OFFSET $4`
 			)
 	}
 
+	// Online status changes constantly, so those pages are only briefly cached
+	ttl := mcProfilesPageTtl
+	if so.IsOnline {
+		ttl = mcProfilesOnlinePageTtl
+	}
+	c.CacheSet(
+		mcKey,
+		profileSummaryPage{Profiles: ems, Total: total, Pages: pages},
+		ttl,
+	)
+
 	return ems, total, pages, http.StatusOK, nil
 }
 
+// getProfilesPageKey returns the cache key for a page of GetProfiles results.
+// The search options include the requesting profile, which determines both
+// ignores and follows, so the page is only shared by identical requests.
+func getProfilesPageKey(
+	siteId int64,
+	so ProfileSearchOptions,
+	limit int64,
+	offset int64,
+) string {
+
+	return fmt.Sprintf(
+		mcProfilesPageKey,
+		siteId,
+		getProfilesGeneration(siteId),
+		h.Md5sum(fmt.Sprintf("%+v_%d_%d", so, limit, offset)),
+	)
+}
+
+// getProfilesGeneration returns the current cache generation for the profile
+// listings of a site, starting a new generation if none is known
+func getProfilesGeneration(siteId int64) int64 {
+	mcKey := fmt.Sprintf(mcProfilesGenerationKey, siteId)
+	if val, ok := c.CacheGetInt64(mcKey); ok {
+		return val
+	}
+
+	generation := time.Now().UnixNano()
+	c.CacheSetInt64(mcKey, generation, mcTtl)
+
+	return generation
+}
+
+// PurgeProfilesCache invalidates all cached pages of profile listings for a
+// site, it should be called whenever a profile on the site changes
+func PurgeProfilesCache(siteId int64) {
+	c.CacheSetInt64(
+		fmt.Sprintf(mcProfilesGenerationKey, siteId),
+		time.Now().UnixNano(),
+		mcTtl,
+	)
+}
+
+// purgeProfilesCacheOfProfile invalidates the profile listings of the site the
+// profile belongs to. Listings exclude the profiles that the requester ignores
+// and can be limited to those they follow, so they are purged when either
+// changes.
+func purgeProfilesCacheOfProfile(profileId int64) {
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return
+	}
+
+	var siteId int64
+	err = db.QueryRow(`--purgeProfilesCacheOfProfile
+SELECT site_id
+  FROM profiles
+ WHERE profile_id = $1`,
+		profileId,
+	).Scan(
+		&siteId,
+	)
+	if err == sql.ErrNoRows {
+		return
+	} else if err != nil {
+		glog.Errorf("db.QueryRow(%d) %+v", profileId, err)
+		return
+	}
+
+	PurgeProfilesCache(siteId)
+}
+
+// notHidingOnline is a condition on profiles p that excludes the profiles
+// that have chosen not to show when they are online
+const notHidingOnline string = `NOT EXISTS (
+           SELECT 1
+             FROM profile_options po
+            WHERE po.profile_id = p.profile_id
+              AND po.hide_online IS TRUE
+       )`
+
+// HideLastActive removes when the profile was last active if the profile has
+// chosen to hide it
+func (m *ProfileType) HideLastActive() {
+	if m.HideOnline {
+		m.LastActive = time.Time{}
+	}
+}
+
+// GetOnlineWindow returns how recently a profile must have been active to be
+// considered online, as configured for this deployment
+func GetOnlineWindow() time.Duration {
+	return time.Duration(
+		conf.CONFIG_INT64[conf.KEY_ONLINE_WINDOW_MINUTES],
+	) * time.Minute
+}
+
+// OnlineSince returns the time after which a profile must have been active to
+// be considered online at the given time
+func OnlineSince(now time.Time, window time.Duration) time.Time {
+	return now.Add(-window)
+}
+
 func MakeGravatarUrl(email string) string {
 	return fmt.Sprintf(
 		"%s%s?d=identicon",
@@ -1249,23 +2007,60 @@ func MakeGravatarUrl(email string) string {
 	)
 }
 
-func StoreGravatar(gravatarUrl string) (FileMetadataType, int, error) {
+// ResolveAvatarProvider returns the base URL of the Libravatar server that is
+// authoritative for the domain of the given email address. Libravatar is
+// federated, a domain may declare its own avatar server via the
+// _avatars-sec._tcp SRV record, otherwise the central Libravatar service is
+// used. The central service is also used if the declared server is not on
+// port 443 or does not resolve to public addresses, as the domain is chosen by
+// the user and could otherwise point us at internal hosts.
+func ResolveAvatarProvider(email string) string {
+	bits := strings.Split(strings.ToLower(strings.Trim(email, " ")), "@")
+	if len(bits) != 2 || bits[1] == "" {
+		return UrlLibravatar
+	}
 
-	// TODO(matt): reduce duplication with models.FileController
-	resp, err := http.Get(gravatarUrl)
+	_, addrs, err := net.LookupSRV("avatars-sec", "tcp", bits[1])
+	if err != nil || len(addrs) == 0 {
+		return UrlLibravatar
+	}
 
-	if err != nil {
-		glog.Errorf("http.Get(`%s`) %+v", gravatarUrl, err)
-		return FileMetadataType{}, http.StatusInternalServerError,
-			errors.New("Could not retrieve gravatar")
+	host := strings.TrimSuffix(addrs[0].Target, ".")
+	if host == "" || addrs[0].Port != 443 || !resolvesPublic(host) {
+		return UrlLibravatar
 	}
-	defer resp.Body.Close()
 
-	fileContent, err := ioutil.ReadAll(resp.Body)
+	return fmt.Sprintf("https://%s/avatar/", host)
+}
+
+// MakeLibravatarUrl returns the Libravatar URL for an email address. Unlike
+// MakeGravatarUrl this asks for a 404 when no avatar exists so that we can
+// fall back to Gravatar and the identicon default.
+func MakeLibravatarUrl(email string) string {
+	return fmt.Sprintf(
+		"%s%s?d=404",
+		ResolveAvatarProvider(email),
+		h.Md5sum(strings.ToLower(strings.Trim(email, " "))),
+	)
+}
+
+// StoreGravatar fetches the avatar for the given email address and stores it
+// as a file. Libravatar is tried first, and Gravatar is used if Libravatar has
+// no image or cannot be reached.
+func StoreGravatar(email string) (FileMetadataType, int, error) {
+
+	avatarUrl := MakeLibravatarUrl(email)
+	fileContent, mimeType, err := fetchAvatar(avatarUrl)
 	if err != nil {
-		glog.Errorf("ioutil.ReadAll(resp.Body) %+v", err)
-		return FileMetadataType{}, http.StatusInternalServerError,
-			errors.New("Could not read gravatar response")
+		glog.Infof("fetchAvatar(`%s`) %+v", avatarUrl, err)
+
+		avatarUrl = MakeGravatarUrl(email)
+		fileContent, mimeType, err = fetchAvatar(avatarUrl)
+		if err != nil {
+			glog.Errorf("fetchAvatar(`%s`) %+v", avatarUrl, err)
+			return FileMetadataType{}, http.StatusInternalServerError,
+				errors.New("Could not retrieve gravatar")
+		}
 	}
 
 	metadata := FileMetadataType{}
@@ -1277,7 +2072,7 @@ func StoreGravatar(gravatarUrl string) (FileMetadataType, int, error) {
 		return FileMetadataType{}, http.StatusInternalServerError,
 			errors.New("Could not generate file SHA-1")
 	}
-	metadata.MimeType = resp.Header.Get("Content-Type")
+	metadata.MimeType = mimeType
 	metadata.Created = time.Now()
 	metadata.AttachCount += 1
 
@@ -1291,6 +2086,38 @@ func StoreGravatar(gravatarUrl string) (FileMetadataType, int, error) {
 	return metadata, http.StatusOK, nil
 }
 
+// fetchAvatar retrieves an avatar image, returning an error for anything other
+// than a 200 response. The body read is capped at the maximum file size so
+// that a misbehaving provider cannot exhaust our memory.
+func fetchAvatar(avatarUrl string) ([]byte, string, error) {
+
+	// TODO(matt): reduce duplication with models.FileController
+	resp, err := avatarClient.Get(avatarUrl)
+	if err != nil {
+		return []byte{}, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return []byte{}, "", errors.New(
+			fmt.Sprintf("Unexpected response status: %d", resp.StatusCode),
+		)
+	}
+
+	fileContent, err := ioutil.ReadAll(
+		io.LimitReader(resp.Body, GetMaxFileSize()+1),
+	)
+	if err != nil {
+		return []byte{}, "", err
+	}
+
+	if int64(len(fileContent)) > GetMaxFileSize() {
+		return []byte{}, "", errors.New("Avatar exceeds the maximum file size")
+	}
+
+	return fileContent, resp.Header.Get("Content-Type"), nil
+}
+
 func AttachAvatar(
 	profileId int64,
 	fileMetadata FileMetadataType,
@@ -1321,10 +2148,10 @@ func AttachAvatar(
 	return attachment, http.StatusOK, nil
 }
 
-func SuggestProfileName(user UserType) string {
+func SuggestProfileName(siteId int64, user UserType) string {
 	// This is duplication safe for investors
-	if _, inMap := reservedProfileNames[user.Email]; inMap {
-		return reservedProfileNames[user.Email]
+	if name, ok := getReservedProfileNameForEmail(siteId, user.Email); ok {
+		return name
 	}
 
 	// TODO(buro9): This is not duplication safe, and we will need to do a
@@ -1347,7 +2174,7 @@ func IsProfileNameTaken(
 	error,
 ) {
 
-	profileName = strings.ToLower(profileName)
+	profileName = FoldProfileName(profileName)
 
 	db, err := h.GetConnection()
 	if err != nil {
@@ -1362,13 +2189,15 @@ SELECT u.email
         SELECT NOT COUNT(*) = 0 AS exists
           FROM profiles
          WHERE site_id = $1
-           AND LOWER(profile_name) = $3
+           AND TRANSLATE(LOWER(NORMALIZE(profile_name, NFKC)), $4, $5) = $3
            AND user_id != $2
        ) AS p
  WHERE u.user_id = $2`,
 		siteId,
 		userId,
 		profileName,
+		confusableProfileNameRunes,
+		confusableProfileNameReplacements,
 	)
 	if err != nil {
 		return true, http.StatusInternalServerError, errors.New(
@@ -1406,15 +2235,43 @@ SELECT u.email
 	}
 
 	// Is it in the reserved list, but not for the given email?
-	for e, n := range reservedProfileNames {
-		if strings.ToLower(n) == profileName && email != e {
-			return true, http.StatusOK, nil
-		}
+	if isProfileNameReserved(siteId, email, profileName) {
+		return true, http.StatusOK, nil
 	}
 
 	return false, http.StatusOK, nil
 }
 
+// confusableProfileNameRunes are characters that are visually identical to a
+// latin character, confusableProfileNameReplacements holds that latin
+// character at the same position. These are folded when comparing names to
+// prevent impersonation via homoglyphs.
+const (
+	confusableProfileNameRunes        string = "аеорсухіјѕԁοαν"
+	confusableProfileNameReplacements string = "aeopcyxijsdoav"
+)
+
+// FoldProfileName returns the form of a profile name used for uniqueness
+// checks. The name is NFKC normalised so that compatibility forms such as
+// fullwidth letters match, lower cased and has common confusable characters
+// replaced by their latin lookalikes.
+func FoldProfileName(name string) string {
+	from := []rune(confusableProfileNameRunes)
+	to := []rune(confusableProfileNameReplacements)
+
+	return strings.Map(
+		func(r rune) rune {
+			for i, c := range from {
+				if r == c {
+					return to[i]
+				}
+			}
+			return r
+		},
+		strings.ToLower(norm.NFKC.String(name)),
+	)
+}
+
 func GetProfileSearchOptions(query url.Values) ProfileSearchOptions {
 
 	so := ProfileSearchOptions{}
@@ -1447,16 +2304,23 @@ func GetProfileSearchOptions(query url.Values) ProfileSearchOptions {
 		}
 	}
 
+	if query.Get("gender") != "" {
+		so.Gender = strings.ToLower(strings.Trim(query.Get("gender"), " "))
+	}
+
+	if query.Get("joinedAfter") != "" {
+		joinedAfter, err := time.Parse(time.RFC3339, query.Get("joinedAfter"))
+		if err == nil {
+			so.CreatedAfter = joinedAfter
+		}
+	}
+
+	if query.Get("joinedBefore") != "" {
+		joinedBefore, err := time.Parse(time.RFC3339, query.Get("joinedBefore"))
+		if err == nil {
+			so.CreatedBefore = joinedBefore
+		}
+	}
+
 	return so
 }
-
-// Allows you to define a list of profile names that are reserved.
-// i.e. var reservedProfileNames = map[string]string{
-//    "someone@example.com": "someone",
-// }
-// That would result in the username 'someone' only being available to the
-// person whose email address is 'someone@example.com'. This applies across
-// all sites, and can be used to prohibit certain profile names from being
-// used at all, i.e. misleading names like God, Admin, or root, or names that
-// are profane and would harm the community standards.
-var reservedProfileNames = map[string]string{}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// GetOnlineProfiles returns the profiles on the site that have been active
+// within the online window, most recently active first. Profiles that are not
+// visible, that hide being online, or that are ignored by the requesting
+// profile are left out. The requesting profile always sees itself.
+func GetOnlineProfiles(
+	siteId int64,
+	profileId int64,
+	limit int64,
+	offset int64,
+) (
+	[]ProfileSummaryType,
+	int64,
+	int64,
+	int,
+	error,
+) {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return []ProfileSummaryType{}, 0, 0, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetOnlineProfiles
+SELECT COUNT(*) OVER() AS total
+      ,p.profile_id
+  FROM profiles p
+  LEFT JOIN ignores i ON i.profile_id = $2
+                     AND (i.expires IS NULL OR i.expires > NOW())
+                     AND i.item_type_id = 3
+                     AND i.item_id = p.profile_id
+ WHERE p.site_id = $1
+   AND p.last_active > $3
+   AND p.is_visible IS NOT FALSE
+   AND p.profile_name <> 'deleted'
+   AND i.profile_id IS NULL
+   AND (p.profile_id = $2 OR `+notHidingOnline+`)
+ ORDER BY p.last_active DESC
+         ,p.profile_id ASC
+ LIMIT $4
+OFFSET $5`,
+		siteId,
+		profileId,
+		OnlineSince(time.Now(), GetOnlineWindow()),
+		limit,
+		offset,
+	)
+	if err != nil {
+		glog.Errorf(
+			"db.Query(%d, %d, %d, %d) %+v",
+			siteId,
+			profileId,
+			limit,
+			offset,
+			err,
+		)
+		return []ProfileSummaryType{}, 0, 0, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	var total int64
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&total, &id)
+		if err != nil {
+			glog.Errorf("rows.Scan() %+v", err)
+			return []ProfileSummaryType{}, 0, 0, http.StatusInternalServerError,
+				errors.New("Row parsing error")
+		}
+
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return []ProfileSummaryType{}, 0, 0, http.StatusInternalServerError,
+			errors.New("Error fetching rows")
+	}
+	rows.Close()
+
+	pages := h.GetPageCount(total, limit)
+	maxOffset := h.GetMaxOffset(total, limit)
+
+	if offset > maxOffset {
+		return []ProfileSummaryType{}, 0, 0, http.StatusBadRequest,
+			errors.New(
+				fmt.Sprintf("not enough records, "+
+					"offset (%d) would return an empty page.", offset),
+			)
+	}
+
+	ems, status, err := GetProfilesByIds(siteId, ids)
+	if err != nil {
+		return []ProfileSummaryType{}, 0, 0, status, err
+	}
+
+	return ems, total, pages, http.StatusOK, nil
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	conf "github.com/microcosm-cc/microcosm/config"
+	e "github.com/microcosm-cc/microcosm/errors"
+)
+
+func TestOnlineSince(t *testing.T) {
+	now := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	since := OnlineSince(now, 90*time.Minute)
+	expected := time.Date(2014, 10, 1, 10, 30, 0, 0, time.UTC)
+	if !since.Equal(expected) {
+		t.Errorf("Expected %s, got %s", expected, since)
+	}
+
+	// A profile active two hours ago is online only in the wider window
+	lastActive := time.Date(2014, 10, 1, 10, 0, 0, 0, time.UTC)
+	if lastActive.After(since) {
+		t.Error("Profile active 120 minutes ago should not be online at window=90")
+	}
+
+	since = OnlineSince(now, 180*time.Minute)
+	if !lastActive.After(since) {
+		t.Error("Profile active 120 minutes ago should be online at window=180")
+	}
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	for _, addr := range []string{
+		"0.0.0.0",
+		"10.1.2.3",
+		"127.0.0.1",
+		"169.254.169.254",
+		"172.16.0.1",
+		"172.31.255.255",
+		"192.168.1.1",
+		"::",
+		"::1",
+		"::ffff:127.0.0.1",
+		"fd00:ec2::254",
+		"fe80::1",
+	} {
+		if isPublicAddress(net.ParseIP(addr)) {
+			t.Errorf("%s was considered public", addr)
+		}
+	}
+
+	for _, addr := range []string{
+		"8.8.8.8",
+		"172.32.0.1",
+		"192.169.0.1",
+		"2001:4860:4860::8888",
+	} {
+		if !isPublicAddress(net.ParseIP(addr)) {
+			t.Errorf("%s was not considered public", addr)
+		}
+	}
+}
+
+func TestHideLastActive(t *testing.T) {
+	lastActive := time.Now()
+
+	m := ProfileType{LastActive: lastActive}
+	m.HideLastActive()
+	if !m.LastActive.Equal(lastActive) {
+		t.Error("Expected last active to be shown when the profile does not hide it")
+	}
+
+	m.HideOnline = true
+	m.HideLastActive()
+	if !m.LastActive.IsZero() {
+		t.Errorf("Expected last active to be hidden, got %v", m.LastActive)
+	}
+}
+
+func TestProfileIdCacheTtl(t *testing.T) {
+	if ttl := profileIdCacheTtl(60); ttl != 60 {
+		t.Errorf("Expected the profile TTL 60, got %d", ttl)
+	}
+	if ttl := profileIdCacheTtl(profileIdTtl + 1); ttl != profileIdTtl {
+		t.Errorf("Expected the TTL to be bounded by %d, got %d", profileIdTtl, ttl)
+	}
+	if ttl := profileIdCacheTtl(0); ttl != profileIdTtl {
+		t.Errorf("Expected an unbounded TTL to be bounded by %d, got %d", profileIdTtl, ttl)
+	}
+}
+
+func TestFoldProfileName(t *testing.T) {
+	tests := map[string]string{
+		"Bob":     "bob",
+		"ｂｏｂ":     "bob",
+		"ﬁnn":     "finn",
+		"раураl":  "paypal",
+		"Amélie": "amélie",
+	}
+	for name, expected := range tests {
+		if folded := FoldProfileName(name); folded != expected {
+			t.Errorf("Expected %q to fold to %q, got %q", name, expected, folded)
+		}
+	}
+}
+
+func TestChangeProfileCommentCountConcurrently(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		count  int64
+		cached *int64
+	)
+
+	exec := func(profileId int64, delta int64) (int64, error) {
+		mu.Lock()
+		count += delta
+		mu.Unlock()
+		return 1, nil
+	}
+	purge := func(siteId int64, profileId int64) {
+		mu.Lock()
+		cached = nil
+		mu.Unlock()
+	}
+	delay := 50 * time.Millisecond
+
+	// Readers that miss the cache read the count and then cache it, which
+	// races with the purges that follow each change
+	read := func() {
+		mu.Lock()
+		if cached != nil {
+			mu.Unlock()
+			return
+		}
+		n := count
+		mu.Unlock()
+
+		runtime.Gosched()
+
+		mu.Lock()
+		cached = &n
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			changeProfileCommentCount(1, 1, exec, purge, delay)
+		}()
+		go func() {
+			defer wg.Done()
+			read()
+		}()
+	}
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			changeProfileCommentCount(1, -1, exec, purge, delay)
+		}()
+	}
+	wg.Wait()
+
+	// Let the second purges happen
+	time.Sleep(4 * delay)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if count != 70 {
+		t.Errorf("Expected a count of 70, got %d", count)
+	}
+	if cached != nil && *cached != count {
+		t.Errorf("Expected the cache to hold %d or nothing, got %d", count, *cached)
+	}
+}
+
+func TestGenderVocabulary(t *testing.T) {
+	genders := conf.CONFIG_STRING[conf.KEY_PROFILE_GENDERS]
+	defer func() { conf.CONFIG_STRING[conf.KEY_PROFILE_GENDERS] = genders }()
+	conf.CONFIG_STRING[conf.KEY_PROFILE_GENDERS] = "Female, male,nonbinary"
+
+	if g, ok := normaliseGender(" Male "); !ok || g != "male" {
+		t.Errorf("Expected male, got %s (%t)", g, ok)
+	}
+
+	if g, ok := normaliseGender(""); !ok || g != ProfileGenderUnspecified {
+		t.Errorf("Expected an empty gender to be unspecified, got %s", g)
+	}
+
+	// Unspecified is allowed even when it is not configured
+	if _, ok := normaliseGender("unspecified"); !ok {
+		t.Errorf("Expected unspecified to be allowed")
+	}
+
+	if _, ok := normaliseGender("yes please"); ok {
+		t.Errorf("Expected free text to be rejected")
+	}
+
+	// Free text stored before the vocabulary is read as unspecified
+	if g := readGender("yes please"); g != ProfileGenderUnspecified {
+		t.Errorf("Expected free text to be read as unspecified, got %s", g)
+	}
+}
+
+func TestReplaceTakenName(t *testing.T) {
+	// Updates are refused with a suggestion and the name is left alone
+	m := ProfileType{ProfileName: "alice"}
+	status, err := m.replaceTakenName("alice2", false)
+	if status != http.StatusConflict {
+		t.Errorf("Expected %d, got %d", http.StatusConflict, status)
+	}
+	taken, ok := err.(*ProfileNameTakenError)
+	if !ok || taken.Suggestion != "alice2" {
+		t.Fatalf("Expected a ProfileNameTakenError suggesting alice2, got %+v", err)
+	}
+	if code, ok := e.Code(err); !ok || code != e.ProfileNameTaken {
+		t.Errorf("Expected code %d, got %d (%t)", e.ProfileNameTaken, code, ok)
+	}
+	if m.ProfileName != "alice" {
+		t.Errorf("Expected the name to be kept, got %s", m.ProfileName)
+	}
+
+	// Creates and imports take the suggestion
+	status, err = m.replaceTakenName("alice2", true)
+	if err != nil || status != http.StatusOK {
+		t.Errorf("Expected %d, got %d %v", http.StatusOK, status, err)
+	}
+	if m.ProfileName != "alice2" {
+		t.Errorf("Expected alice2, got %s", m.ProfileName)
+	}
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	c "github.com/microcosm-cc/microcosm/cache"
+	conf "github.com/microcosm-cc/microcosm/config"
+	e "github.com/microcosm-cc/microcosm/errors"
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// RateLimit allows Limit events per Window for each subject, i.e. an IP
+// address or an email address. Hit and Exceeded use fixed windows, so counts
+// are reset at the start of each window rather than decaying. A Limit of zero
+// or less disables the rate limit.
+type RateLimit struct {
+	Name   string
+	Limit  int64
+	Window time.Duration
+}
+
+// key returns the counter key of the subject in the window containing t.
+// Subjects are hashed as memcache keys may not contain spaces or control
+// characters.
+func (r RateLimit) key(subject string, t time.Time) string {
+	window := t.Unix() / int64(r.Window/time.Second)
+
+	return fmt.Sprintf(
+		"rl_%s_%x_%d",
+		r.Name,
+		sha1.Sum([]byte(subject)),
+		window,
+	)
+}
+
+func (r RateLimit) disabled() bool {
+	return r.Limit <= 0 || r.Window < time.Second
+}
+
+// Hit counts an event for the subject and returns false if the subject has
+// exceeded the limit in the current window. If the counters are unavailable
+// events are allowed.
+func (r RateLimit) Hit(subject string) bool {
+	if r.disabled() {
+		return true
+	}
+
+	n, ok := c.CacheIncrement(
+		r.key(subject, time.Now()),
+		1,
+		int32(r.Window/time.Second),
+	)
+
+	return r.allows(n, ok)
+}
+
+// allows returns true if a count of n events, including the one just made,
+// is within the limit. ok is false if the count is unavailable.
+func (r RateLimit) allows(n uint64, ok bool) bool {
+	return r.disabled() || !ok || int64(n) <= r.Limit
+}
+
+// Exceeded returns true if the subject has reached the limit in the current
+// window, without counting an event
+func (r RateLimit) Exceeded(subject string) bool {
+	if r.disabled() {
+		return false
+	}
+
+	n, ok := c.CacheGetCounter(r.key(subject, time.Now()))
+
+	return r.reached(n, ok)
+}
+
+// reached returns true if a count of n events has reached the limit. ok is
+// false if the count is unavailable.
+func (r RateLimit) reached(n uint64, ok bool) bool {
+	return !r.disabled() && ok && int64(n) >= r.Limit
+}
+
+func authRateLimit(name string, key string) RateLimit {
+	return RateLimit{
+		Name:  name,
+		Limit: conf.CONFIG_INT64[key],
+		Window: time.Duration(
+			conf.CONFIG_INT64[conf.KEY_AUTH_RATE_LIMIT_WINDOW_SECONDS],
+		) * time.Second,
+	}
+}
+
+// AuthAttemptAllowedForIP counts an authentication attempt from the IP address
+// and returns false if it has made too many attempts, or has failed too many
+// times, in the current window
+func AuthAttemptAllowedForIP(ip string) bool {
+	failures := authRateLimit("auth_failures", conf.KEY_AUTH_RATE_LIMIT_FAILURES)
+	if failures.Exceeded(ip) {
+		return false
+	}
+
+	return authRateLimit("auth_ip", conf.KEY_AUTH_RATE_LIMIT_IP).Hit(ip)
+}
+
+// AuthAttemptAllowedForEmail counts an authentication attempt for the email
+// address and returns false if there have been too many in the current window
+func AuthAttemptAllowedForEmail(email string) bool {
+	return authRateLimit("auth_email", conf.KEY_AUTH_RATE_LIMIT_EMAIL).Hit(
+		strings.ToLower(strings.TrimSpace(email)),
+	)
+}
+
+// RecordFailedAuthAttempt counts a failed authentication attempt from the IP
+// address, such as an assertion that could not be verified or a sign in by a
+// known spammer. Once there have been enough failures the IP address is
+// refused until the window ends.
+func RecordFailedAuthAttempt(ip string) {
+	authRateLimit("auth_failures", conf.KEY_AUTH_RATE_LIMIT_FAILURES).Hit(ip)
+}
+
+func eventRateLimit() RateLimit {
+	return RateLimit{
+		Name:  "events",
+		Limit: conf.CONFIG_INT64[conf.KEY_EVENT_RATE_LIMIT],
+		Window: time.Duration(
+			conf.CONFIG_INT64[conf.KEY_EVENT_RATE_LIMIT_WINDOW_SECONDS],
+		) * time.Second,
+	}
+}
+
+// isEventRateLimitExempt returns true if the profile moderates the microcosm
+// or owns the site
+func isEventRateLimitExempt(siteId int64, profileId int64, microcosmId int64) bool {
+	perms := GetPermission(AuthContext{
+		SiteId:     siteId,
+		ProfileId:  profileId,
+		ItemTypeId: h.ItemTypes[h.ItemTypeMicrocosm],
+		ItemId:     microcosmId,
+	})
+
+	return perms.IsModerator || perms.IsSiteOwner
+}
+
+// checkEventRateLimit returns an error if the profile has created too many
+// events on the site in the window ending now, as configured by
+// event_rate_limit. Moderators and site owners are not limited.
+//
+// The events created are counted within the transaction that inserts the new
+// event, with the row of the profile locked until it commits, so that
+// concurrent requests from the profile are counted one after another.
+func checkEventRateLimit(
+	tx *sql.Tx,
+	siteId int64,
+	profileId int64,
+	microcosmId int64,
+) (
+	int,
+	error,
+) {
+	r := eventRateLimit()
+	if r.disabled() || isEventRateLimitExempt(siteId, profileId, microcosmId) {
+		return http.StatusOK, nil
+	}
+
+	_, err := tx.Exec(`--checkEventRateLimit
+SELECT profile_id
+  FROM profiles
+ WHERE profile_id = $1
+   FOR UPDATE`,
+		profileId,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Could not lock profile: %v", err.Error()),
+		)
+	}
+
+	var created int64
+	err = tx.QueryRow(`--checkEventRateLimit
+SELECT COUNT(*)
+  FROM events e
+  JOIN microcosms m ON m.microcosm_id = e.microcosm_id
+ WHERE e.created_by = $1
+   AND m.site_id = $2
+   AND e.created > NOW() - ($3 * INTERVAL '1 second')`,
+		profileId,
+		siteId,
+		int64(r.Window/time.Second),
+	).Scan(&created)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New(
+			fmt.Sprintf("Could not count events: %v", err.Error()),
+		)
+	}
+
+	return eventRateLimitStatus(r, siteId, profileId, created)
+}
+
+// eventRateLimitStatus returns an error if the number of events created by the
+// profile in the window has reached the limit
+func eventRateLimitStatus(
+	r RateLimit,
+	siteId int64,
+	profileId int64,
+	created int64,
+) (
+	int,
+	error,
+) {
+	if r.disabled() || created < r.Limit {
+		return http.StatusOK, nil
+	}
+
+	return http.StatusTooManyRequests, e.New(
+		siteId,
+		profileId,
+		"checkEventRateLimit",
+		e.ExceededQuota,
+		fmt.Sprintf(
+			"You may only create %d events in %s, please try again later",
+			r.Limit,
+			r.Window,
+		),
+	)
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimitKeyBoundary(t *testing.T) {
+	window := 15 * time.Minute
+	r := RateLimit{Name: "test", Limit: 3, Window: window}
+
+	// Start and last second of a window
+	start := time.Unix(1000*int64(window/time.Second), 0)
+	last := start.Add(window - time.Second)
+	if r.key("192.0.2.1", start) != r.key("192.0.2.1", last) {
+		t.Errorf("Expected the same counter throughout a window")
+	}
+
+	// First second of the next window
+	if r.key("192.0.2.1", last) == r.key("192.0.2.1", last.Add(time.Second)) {
+		t.Errorf("Expected the count to be reset in the next window")
+	}
+
+	if r.key("192.0.2.1", start) == r.key("192.0.2.2", start) {
+		t.Errorf("Expected another subject to be counted separately")
+	}
+
+	other := RateLimit{Name: "other", Limit: 3, Window: window}
+	if r.key("192.0.2.1", start) == other.key("192.0.2.1", start) {
+		t.Errorf("Expected another rate limit to be counted separately")
+	}
+}
+
+func TestRateLimitAllows(t *testing.T) {
+	r := RateLimit{Name: "test", Limit: 3, Window: time.Minute}
+
+	for n := uint64(1); n <= 3; n++ {
+		if !r.allows(n, true) {
+			t.Errorf("Expected hit %d to be allowed", n)
+		}
+	}
+	if r.allows(4, true) {
+		t.Errorf("Expected hit 4 to exceed the limit")
+	}
+	if !r.allows(0, false) {
+		t.Errorf("Expected hits to be allowed when counters are unavailable")
+	}
+}
+
+func TestRateLimitReached(t *testing.T) {
+	r := RateLimit{Name: "test", Limit: 2, Window: time.Minute}
+
+	if r.reached(1, true) {
+		t.Errorf("Expected one hit not to reach a limit of two")
+	}
+	if !r.reached(2, true) {
+		t.Errorf("Expected two hits to reach a limit of two")
+	}
+	if r.reached(0, false) {
+		t.Errorf("Expected the limit not to be reached when counters are unavailable")
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	r := RateLimit{Name: "test", Limit: 0, Window: time.Minute}
+	for n := uint64(1); n < 10; n++ {
+		if !r.allows(n, true) || r.reached(n, true) {
+			t.Fatalf("Expected a limit of zero to allow everything")
+		}
+	}
+}
+
+func TestEventRateLimitStatus(t *testing.T) {
+	r := RateLimit{Name: "events", Limit: 3, Window: 24 * time.Hour}
+
+	for created := int64(0); created < 3; created++ {
+		if _, err := eventRateLimitStatus(r, 1, 1, created); err != nil {
+			t.Errorf("Expected event %d to be allowed: %v", created+1, err)
+		}
+	}
+
+	status, err := eventRateLimitStatus(r, 1, 1, 3)
+	if err == nil || status != http.StatusTooManyRequests {
+		t.Errorf("Expected event 4 to be refused with 429, got %d", status)
+	}
+
+	r.Limit = 0
+	if _, err := eventRateLimitStatus(r, 1, 1, 100); err != nil {
+		t.Errorf("Expected a limit of zero to allow everything: %v", err)
+	}
+}
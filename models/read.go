@@ -9,6 +9,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/lib/pq"
 
+	c "github.com/microcosm-cc/microcosm/cache"
 	h "github.com/microcosm-cc/microcosm/helpers"
 )
 
@@ -225,29 +226,23 @@ SELECT 2 AS item_type_id
 	return nil
 }
 
-func MarkAsRead(
+// validateMarkAsRead checks that MarkAsRead has been told what was read and
+// when. Only updates and huddles may be marked read without an itemId, for
+// huddles this marks all of them as read.
+func validateMarkAsRead(
 	itemTypeId int64,
 	itemId int64,
-	profileId int64,
 	updateTime time.Time,
 ) (
 	int,
 	error,
 ) {
 
-	// Some validation
-	if profileId == 0 {
-		glog.Infof("profileId == 0")
-		return http.StatusOK, nil
-	}
-
-	if (itemTypeId != h.ItemTypes[h.ItemTypeUpdate] && itemId == 0) ||
-		itemTypeId == 0 {
+	if itemTypeId == 0 ||
+		(itemId == 0 &&
+			itemTypeId != h.ItemTypes[h.ItemTypeUpdate] &&
+			itemTypeId != h.ItemTypes[h.ItemTypeHuddle]) {
 
-		glog.Errorln(
-			"(itemTypeId != h.ItemTypes[h.ItemTypeUpdate] && itemId == 0) || " +
-				"itemTypeId == 0",
-		)
 		return http.StatusExpectationFailed,
 			errors.New("itemTypeId and/or itemId was null when MarkAsRead " +
 				"was called. This is illogical, you need to tell us what " +
@@ -255,12 +250,36 @@ func MarkAsRead(
 	}
 
 	if updateTime.IsZero() {
-		glog.Errorln("updateTime.IsZero()")
 		return http.StatusExpectationFailed,
 			errors.New("MarkAsRead has been called but the time supplied " +
 				"is null. You need to tell us when the item was read.")
 	}
 
+	return http.StatusOK, nil
+}
+
+func MarkAsRead(
+	itemTypeId int64,
+	itemId int64,
+	profileId int64,
+	updateTime time.Time,
+) (
+	int,
+	error,
+) {
+
+	// Some validation
+	if profileId == 0 {
+		glog.Infof("profileId == 0")
+		return http.StatusOK, nil
+	}
+
+	status, err := validateMarkAsRead(itemTypeId, itemId, updateTime)
+	if err != nil {
+		glog.Errorln(err)
+		return status, err
+	}
+
 	// Do the deed
 	tx, err := h.GetTransaction()
 	if err != nil {
@@ -276,7 +295,7 @@ func MarkAsRead(
 		ProfileId:  profileId,
 		Read:       updateTime,
 	}
-	status, err := m.upsert(tx)
+	status, err = m.upsert(tx)
 	if err != nil {
 		glog.Errorf("m.upsert(tx) %+v", err)
 		return status, err
@@ -424,6 +443,45 @@ SELECT $1, $2, $3, $4
 	return http.StatusOK, nil
 }
 
+// MarkHuddleAsRead marks the huddle as read by the profile now, and updates
+// their count of unread huddles
+func MarkHuddleAsRead(profileId int64, huddleId int64) (int, error) {
+	if huddleId <= 0 {
+		return http.StatusBadRequest,
+			errors.New("huddleId must be specified to mark a huddle read")
+	}
+
+	status, err := MarkAsRead(
+		h.ItemTypes[h.ItemTypeHuddle],
+		huddleId,
+		profileId,
+		time.Now(),
+	)
+	if err != nil {
+		return status, err
+	}
+
+	UpdateUnreadHuddleCount(profileId)
+
+	return http.StatusOK, nil
+}
+
+// MarkAllHuddlesAsRead marks every huddle as read by the profile now, which
+// sets their count of unread huddles to zero
+func MarkAllHuddlesAsRead(profileId int64) (int, error) {
+	// A huddleId of 0 marks all huddles as read
+	status, err := MarkAsRead(h.ItemTypes[h.ItemTypeHuddle], 0, profileId, time.Now())
+	if err != nil {
+		return status, err
+	}
+
+	// The count was set to zero within the transaction, so the cached count
+	// is only purged now that it has been committed
+	PurgeCacheByScope(c.CacheCounts, h.ItemTypes[h.ItemTypeProfile], profileId)
+
+	return http.StatusOK, nil
+}
+
 func MarkScopeAsRead(profileId int64, rs ReadScopeType) (int, error) {
 	if rs.ItemTypeId == h.ItemTypes[h.ItemTypeSite] {
 		return MarkAllAsRead(profileId)
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+func TestValidateMarkAsRead(t *testing.T) {
+	now := time.Now()
+
+	// A huddleId of 0 marks all huddles as read
+	_, err := validateMarkAsRead(h.ItemTypes[h.ItemTypeHuddle], 0, now)
+	if err != nil {
+		t.Errorf("Expected all huddles to be markable as read, got %+v", err)
+	}
+
+	_, err = validateMarkAsRead(h.ItemTypes[h.ItemTypeUpdate], 0, now)
+	if err != nil {
+		t.Errorf("Expected all updates to be markable as read, got %+v", err)
+	}
+
+	_, err = validateMarkAsRead(h.ItemTypes[h.ItemTypeHuddle], 5, now)
+	if err != nil {
+		t.Errorf("Expected a huddle to be markable as read, got %+v", err)
+	}
+
+	status, err := validateMarkAsRead(h.ItemTypes[h.ItemTypeConversation], 0, now)
+	if err == nil || status != http.StatusExpectationFailed {
+		t.Errorf("Expected a conversation without an ID to be refused, got %d", status)
+	}
+
+	status, err = validateMarkAsRead(0, 5, now)
+	if err == nil || status != http.StatusExpectationFailed {
+		t.Errorf("Expected an item without a type to be refused, got %d", status)
+	}
+
+	status, err = validateMarkAsRead(h.ItemTypes[h.ItemTypeHuddle], 0, time.Time{})
+	if err == nil || status != http.StatusExpectationFailed {
+		t.Errorf("Expected a read without a time to be refused, got %d", status)
+	}
+}
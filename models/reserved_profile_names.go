@@ -0,0 +1,148 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// Profile names may be reserved via the reserved_profile_names table.
+//
+// A reservation with an email address results in the name only being available
+// to the person with that email address, i.e. ('someone@example.com',
+// 'someone') would reserve 'someone' for that person.
+//
+// A reservation without an email address prohibits the name from being used at
+// all, i.e. misleading names like God, Admin, or root, or names that are
+// profane and would harm the community standards.
+//
+// A reservation without a site ID applies across all sites.
+type reservedProfileName struct {
+	Email      string
+	Name       string
+	FoldedName string
+	SiteId     int64
+}
+
+var (
+	reservedProfileNames     = []reservedProfileName{}
+	reservedProfileNamesLock sync.RWMutex
+)
+
+// LoadReservedProfileNames refreshes the in-memory set of reserved profile
+// names from the database. It is called at startup and periodically by cron.
+func LoadReservedProfileNames() {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return
+	}
+
+	rows, err := db.Query(`--LoadReservedProfileNames
+SELECT email
+      ,profile_name
+      ,site_id
+  FROM reserved_profile_names`)
+	if err != nil {
+		glog.Errorf("db.Query() %+v", err)
+		return
+	}
+	defer rows.Close()
+
+	names := []reservedProfileName{}
+	for rows.Next() {
+		var (
+			email  sql.NullString
+			name   string
+			siteId sql.NullInt64
+		)
+		err = rows.Scan(&email, &name, &siteId)
+		if err != nil {
+			glog.Errorf("rows.Scan() %+v", err)
+			return
+		}
+
+		names = append(names, reservedProfileName{
+			Email:      strings.ToLower(strings.Trim(email.String, " ")),
+			Name:       name,
+			FoldedName: FoldProfileName(name),
+			SiteId:     siteId.Int64,
+		})
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return
+	}
+	rows.Close()
+
+	reservedProfileNamesLock.Lock()
+	reservedProfileNames = names
+	reservedProfileNamesLock.Unlock()
+}
+
+// isProfileNameReserved returns true if the profile name is reserved on the
+// site for anyone other than the owner of the given email address. A name may
+// be reserved for several email addresses, in which case it is available to
+// the owner of any of them.
+func isProfileNameReserved(siteId int64, email string, profileName string) bool {
+
+	email = strings.ToLower(strings.Trim(email, " "))
+	profileName = FoldProfileName(profileName)
+
+	reservedProfileNamesLock.RLock()
+	defer reservedProfileNamesLock.RUnlock()
+
+	var reserved, owned bool
+	for _, r := range reservedProfileNames {
+		if r.FoldedName != profileName {
+			continue
+		}
+
+		if r.SiteId != 0 && r.SiteId != siteId {
+			continue
+		}
+
+		if r.Email == "" {
+			return true
+		}
+
+		if email != "" && r.Email == email {
+			owned = true
+		} else {
+			reserved = true
+		}
+	}
+
+	return reserved && !owned
+}
+
+// getReservedProfileNameForEmail returns the profile name reserved on the site
+// for the owner of the given email address, if there is one
+func getReservedProfileNameForEmail(siteId int64, email string) (string, bool) {
+
+	email = strings.ToLower(strings.Trim(email, " "))
+	if email == "" {
+		return "", false
+	}
+
+	reservedProfileNamesLock.RLock()
+	defer reservedProfileNamesLock.RUnlock()
+
+	for _, r := range reservedProfileNames {
+		if r.SiteId != 0 && r.SiteId != siteId {
+			continue
+		}
+
+		if r.Email == email {
+			return r.Name, true
+		}
+	}
+
+	return "", false
+}
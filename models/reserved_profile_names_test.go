@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestIsProfileNameReserved(t *testing.T) {
+	reservedProfileNamesLock.Lock()
+	saved := reservedProfileNames
+	reservedProfileNames = []reservedProfileName{
+		{Name: "admin", FoldedName: FoldProfileName("admin")},
+		{Email: "a@example.com", Name: "alpha", FoldedName: FoldProfileName("alpha")},
+		{Email: "b@example.com", Name: "alpha", FoldedName: FoldProfileName("alpha")},
+		{Email: "c@example.com", Name: "charlie", FoldedName: FoldProfileName("charlie"), SiteId: 2},
+	}
+	reservedProfileNamesLock.Unlock()
+	defer func() {
+		reservedProfileNamesLock.Lock()
+		reservedProfileNames = saved
+		reservedProfileNamesLock.Unlock()
+	}()
+
+	tests := []struct {
+		siteId   int64
+		email    string
+		name     string
+		reserved bool
+	}{
+		{1, "a@example.com", "Admin", true},
+		{1, "", "admin", true},
+		// Either of the two owners may use a name reserved for both
+		{1, "a@example.com", "alpha", false},
+		{1, "B@example.com", "alpha", false},
+		{1, "d@example.com", "alpha", true},
+		{1, "", "alpha", true},
+		// Reservations on another site do not apply
+		{1, "d@example.com", "charlie", false},
+		{2, "d@example.com", "charlie", true},
+		{2, "c@example.com", "charlie", false},
+		{1, "d@example.com", "delta", false},
+	}
+
+	for _, test := range tests {
+		reserved := isProfileNameReserved(test.siteId, test.email, test.name)
+		if reserved != test.reserved {
+			t.Errorf(
+				"isProfileNameReserved(%d, %s, %s) = %t, expected %t",
+				test.siteId,
+				test.email,
+				test.name,
+				reserved,
+				test.reserved,
+			)
+		}
+	}
+
+	if name, ok := getReservedProfileNameForEmail(1, "c@example.com"); ok {
+		t.Errorf("Site 2 reservation %s was suggested on site 1", name)
+	}
+	if name, ok := getReservedProfileNameForEmail(2, "c@example.com"); !ok || name != "charlie" {
+		t.Errorf("Expected charlie to be suggested on site 2, got %s", name)
+	}
+}
@@ -584,7 +584,7 @@ SELECT role_id
 	}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeRole))
 
 	m.FetchProfileSummaries(siteId)
 	return m, http.StatusOK, nil
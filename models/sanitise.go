@@ -1,27 +1,282 @@
 package models
 
 import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
 	"github.com/microcosm-cc/bluemonday"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// Site attributes that adjust the HTML permitted in the content of a site. All
+// are booleans and all default to true, other than video embeds which a site
+// must opt in to.
+const (
+	SiteAttrHTMLAllowTables      string = "html_allow_tables"
+	SiteAttrHTMLAllowImages      string = "html_allow_images"
+	SiteAttrHTMLNoFollowLinks    string = "html_nofollow_links"
+	SiteAttrHTMLTargetBlankLinks string = "html_target_blank_links"
+	SiteAttrHTMLVideoEmbeds      string = "html_video_embeds"
 )
 
+// How long a site's HTML policy is used before the site attributes are read
+// again. Changes made through this process take effect immediately, this
+// bounds how long other processes use the old policy.
+const sitePolicyTTL = 5 * time.Minute
+
 var textPolicy = bluemonday.StripTagsPolicy()
-var htmlPolicy = bluemonday.UGCPolicy()
-var initHtmlPolicy bool
 
-// SanitiseHTML strips any HTML not on the cleanse whitelist, leaving a safe
-// set of HTML intact that is not going to pose an XSS risk
-func SanitiseHTML(src []byte) []byte {
-	if !initHtmlPolicy {
-		htmlPolicy.RequireNoFollowOnLinks(false)
-		htmlPolicy.RequireNoFollowOnFullyQualifiedLinks(true)
-		htmlPolicy.AddTargetBlankToFullyQualifiedLinks(true)
-		initHtmlPolicy = true
-	}
+// htmlPolicySettings describes how a site's policy differs from the default
+type htmlPolicySettings struct {
+	AllowTables      bool
+	AllowImages      bool
+	NoFollowLinks    bool
+	TargetBlankLinks bool
+	VideoEmbeds      bool
+}
+
+var defaultHTMLPolicySettings = htmlPolicySettings{
+	AllowTables:      true,
+	AllowImages:      true,
+	NoFollowLinks:    true,
+	TargetBlankLinks: true,
+}
+
+// sitePolicy holds the policies of a site. The embed policy also permits
+// iframes so that those of recognised video providers can be rewritten.
+type sitePolicy struct {
+	policy      *bluemonday.Policy
+	embedPolicy *bluemonday.Policy
+	videoEmbeds bool
+	expires     time.Time
+}
+
+var (
+	sitePolicies     = map[int64]sitePolicy{}
+	sitePoliciesLock sync.RWMutex
+)
+
+// SanitiseHTML strips any HTML not permitted by the site's policy, leaving a
+// safe set of HTML intact that is not going to pose an XSS risk
+func SanitiseHTML(siteId int64, src []byte) []byte {
+	return getSitePolicy(siteId).policy.SanitizeBytes(src)
+}
 
-	return htmlPolicy.SanitizeBytes(src)
+// AllowVideoEmbeds returns true if the site has opted in to videos being
+// embedded in its content
+func AllowVideoEmbeds(siteId int64) bool {
+	return getSitePolicy(siteId).videoEmbeds
 }
 
 // SanitiseText strips all HTML tags from text
 func SanitiseText(s string) string {
 	return textPolicy.Sanitize(s)
 }
+
+// PurgeSiteHTMLPolicy discards the cached policy of a site so that the next
+// call to SanitiseHTML reads the site attributes again
+func PurgeSiteHTMLPolicy(siteId int64) {
+	sitePoliciesLock.Lock()
+	delete(sitePolicies, siteId)
+	sitePoliciesLock.Unlock()
+}
+
+// purgeSiteHTMLPolicyForItem purges the cached policy if the item is a site,
+// and is called whenever attributes are changed
+func purgeSiteHTMLPolicyForItem(itemTypeId int64, itemId int64) {
+	if itemTypeId == h.ItemTypes[h.ItemTypeSite] {
+		PurgeSiteHTMLPolicy(itemId)
+	}
+}
+
+func getSitePolicy(siteId int64) sitePolicy {
+	sitePoliciesLock.RLock()
+	sp, ok := sitePolicies[siteId]
+	sitePoliciesLock.RUnlock()
+
+	if ok && time.Now().Before(sp.expires) {
+		return sp
+	}
+
+	settings, err := getSiteHTMLPolicySettings(siteId)
+	if err != nil {
+		// Use the default without caching it so that we try again next time
+		glog.Errorf("getSiteHTMLPolicySettings(%d) %+v", siteId, err)
+		return newSitePolicy(defaultHTMLPolicySettings)
+	}
+
+	sp = newSitePolicy(settings)
+
+	sitePoliciesLock.Lock()
+	sitePolicies[siteId] = sp
+	sitePoliciesLock.Unlock()
+
+	return sp
+}
+
+func newSitePolicy(settings htmlPolicySettings) sitePolicy {
+	embedPolicy := newHTMLPolicy(settings)
+	embedPolicy.AllowAttrs("src").OnElements("iframe")
+
+	return sitePolicy{
+		policy:      newHTMLPolicy(settings),
+		embedPolicy: embedPolicy,
+		videoEmbeds: settings.VideoEmbeds,
+		expires:     time.Now().Add(sitePolicyTTL),
+	}
+}
+
+// getSiteHTMLPolicySettings applies the site attributes to the default
+// settings. Attributes that are not booleans are ignored.
+func getSiteHTMLPolicySettings(siteId int64) (htmlPolicySettings, error) {
+	settings := defaultHTMLPolicySettings
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return settings, err
+	}
+
+	rows, err := db.Query(`--getSiteHTMLPolicySettings
+SELECT k.key
+      ,v."boolean"
+  FROM attribute_keys k
+  JOIN attribute_values v ON v.attribute_id = k.attribute_id
+ WHERE k.item_type_id = $1
+   AND k.item_id = $2
+   AND k.key IN ($3, $4, $5, $6, $7)
+   AND v.value_type_id = $8
+   AND v."boolean" IS NOT NULL`,
+		h.ItemTypes[h.ItemTypeSite],
+		siteId,
+		SiteAttrHTMLAllowTables,
+		SiteAttrHTMLAllowImages,
+		SiteAttrHTMLNoFollowLinks,
+		SiteAttrHTMLTargetBlankLinks,
+		SiteAttrHTMLVideoEmbeds,
+		AttributeTypes[BOOLEAN],
+	)
+	if err != nil {
+		return settings, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key   string
+			value bool
+		)
+		err = rows.Scan(&key, &value)
+		if err != nil {
+			return settings, err
+		}
+
+		switch key {
+		case SiteAttrHTMLAllowTables:
+			settings.AllowTables = value
+		case SiteAttrHTMLAllowImages:
+			settings.AllowImages = value
+		case SiteAttrHTMLNoFollowLinks:
+			settings.NoFollowLinks = value
+		case SiteAttrHTMLTargetBlankLinks:
+			settings.TargetBlankLinks = value
+		case SiteAttrHTMLVideoEmbeds:
+			settings.VideoEmbeds = value
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return settings, err
+	}
+	rows.Close()
+
+	return settings, nil
+}
+
+// newHTMLPolicy returns the bluemonday UGC policy, without tables or images if
+// the settings disallow them. Elements cannot be removed from a bluemonday
+// policy once allowed, so this builds the UGC policy from its parts and must
+// be kept in step with bluemonday.UGCPolicy().
+func newHTMLPolicy(settings htmlPolicySettings) *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowStandardAttributes()
+	p.AllowStandardURLs()
+
+	// Sectioning root tags
+	p.AllowElements("article", "aside")
+	p.AllowAttrs(
+		"open",
+	).Matching(regexp.MustCompile(`(?i)^|open$`)).OnElements("details")
+	p.AllowElements("figure")
+	p.AllowElements("section")
+	p.AllowElements("summary")
+
+	// Headings
+	p.AllowElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowElements("hgroup")
+
+	// Content grouping and separating
+	p.AllowAttrs("cite").OnElements("blockquote")
+	p.AllowElements("br", "div", "hr", "p", "span", "wbr")
+
+	// Links
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("alt").Matching(bluemonday.Paragraph).OnElements("area")
+	p.AllowAttrs("coords").Matching(
+		regexp.MustCompile(`^([0-9]+,){2}(,[0-9]+)*$`),
+	).OnElements("area")
+	p.AllowAttrs("href").OnElements("area")
+	p.AllowAttrs("rel").Matching(
+		bluemonday.SpaceSeparatedTokens,
+	).OnElements("area")
+	p.AllowAttrs("shape").Matching(
+		regexp.MustCompile(`(?i)^default|circle|rect|poly$`),
+	).OnElements("area")
+
+	// Phrase elements
+	p.AllowElements("abbr", "acronym", "cite", "code", "dfn", "em",
+		"figcaption", "mark", "s", "samp", "strong", "sub", "sup", "var")
+	p.AllowAttrs("cite").OnElements("q")
+	p.AllowAttrs("datetime").Matching(bluemonday.ISO8601).OnElements("time")
+
+	// Style elements
+	p.AllowElements("b", "i", "pre", "small", "strike", "tt", "u")
+
+	// HTML5 formatting
+	p.AllowAttrs("dir").Matching(bluemonday.Direction).OnElements("bdi", "bdo")
+	p.AllowElements("rp", "rt", "ruby")
+
+	// HTML5 change tracking
+	p.AllowAttrs("cite").Matching(bluemonday.Paragraph).OnElements("del", "ins")
+	p.AllowAttrs("datetime").Matching(bluemonday.ISO8601).OnElements("del", "ins")
+
+	p.AllowLists()
+
+	if settings.AllowTables {
+		p.AllowTables()
+	}
+
+	// Forms that present data
+	p.AllowAttrs(
+		"value",
+		"min",
+		"max",
+		"low",
+		"high",
+		"optimum",
+	).Matching(bluemonday.Number).OnElements("meter")
+	p.AllowAttrs("value", "max").Matching(bluemonday.Number).OnElements("progress")
+
+	if settings.AllowImages {
+		p.AllowImages()
+	}
+
+	p.RequireNoFollowOnLinks(false)
+	p.RequireNoFollowOnFullyQualifiedLinks(settings.NoFollowLinks)
+	p.AddTargetBlankToFullyQualifiedLinks(settings.TargetBlankLinks)
+
+	return p
+}
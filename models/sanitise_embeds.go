@@ -0,0 +1,223 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// The sandbox applied to video embeds. Players need scripts and their own
+// origin, but cannot navigate the page or submit forms.
+const videoEmbedSandbox = "allow-scripts allow-same-origin allow-popups allow-presentation"
+
+// videoProvider describes a site whose videos may be embedded
+type videoProvider struct {
+	// videoId returns the ID of the video the URL refers to, or an empty
+	// string if it does not refer to a video
+	videoId func(u *url.URL) string
+
+	// embedURL is the format of the iframe src, taking the video ID
+	embedURL string
+}
+
+var (
+	youTubeVideoId = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoVideoId   = regexp.MustCompile(`^[0-9]{1,12}$`)
+
+	youTube = videoProvider{
+		videoId: func(u *url.URL) string {
+			var id string
+			switch {
+			case strings.ToLower(u.Host) == "youtu.be":
+				id = strings.TrimPrefix(u.Path, "/")
+			case u.Path == "/watch":
+				id = u.Query().Get("v")
+			case strings.HasPrefix(u.Path, "/embed/"):
+				id = strings.TrimPrefix(u.Path, "/embed/")
+			}
+			if !youTubeVideoId.MatchString(id) {
+				return ""
+			}
+			return id
+		},
+		embedURL: "https://www.youtube-nocookie.com/embed/%s",
+	}
+
+	vimeo = videoProvider{
+		videoId: func(u *url.URL) string {
+			id := strings.TrimPrefix(strings.TrimPrefix(u.Path, "/video"), "/")
+			if !vimeoVideoId.MatchString(id) {
+				return ""
+			}
+			return id
+		},
+		embedURL: "https://player.vimeo.com/video/%s",
+	}
+
+	// videoHosts is the allowlist of video providers, keyed by the exact
+	// host names they serve videos from
+	videoHosts = map[string]videoProvider{
+		"youtube.com":              youTube,
+		"www.youtube.com":          youTube,
+		"m.youtube.com":            youTube,
+		"youtu.be":                 youTube,
+		"youtube-nocookie.com":     youTube,
+		"www.youtube-nocookie.com": youTube,
+		"vimeo.com":                vimeo,
+		"www.vimeo.com":            vimeo,
+		"player.vimeo.com":         vimeo,
+	}
+)
+
+// SanitiseHTMLWithEmbeds sanitises HTML as SanitiseHTML does, but also embeds
+// videos from a fixed set of providers. Iframes and bare links (those whose
+// text is the URL) that refer to a video are replaced by a sandboxed iframe of
+// the provider's player. All other iframes are removed.
+func SanitiseHTMLWithEmbeds(siteId int64, src []byte) []byte {
+	return sanitiseWithEmbeds(getSitePolicy(siteId), src)
+}
+
+func sanitiseWithEmbeds(sp sitePolicy, src []byte) []byte {
+	clean := sp.embedPolicy.SanitizeBytes(src)
+
+	if !bytes.Contains(clean, []byte("<iframe")) &&
+		!bytes.Contains(clean, []byte("<a ")) {
+
+		return clean
+	}
+
+	nodes, err := html.ParseFragment(
+		bytes.NewReader(clean),
+		&html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body},
+	)
+	if err != nil {
+		// Fall back to the policy without iframes
+		glog.Errorf("html.ParseFragment() %+v", err)
+		return sp.policy.SanitizeBytes(src)
+	}
+
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+
+	embedVideos(root)
+
+	b := new(bytes.Buffer)
+	for n := root.FirstChild; n != nil; n = n.NextSibling {
+		err = html.Render(b, n)
+		if err != nil {
+			glog.Errorf("html.Render() %+v", err)
+			return sp.policy.SanitizeBytes(src)
+		}
+	}
+
+	return b.Bytes()
+}
+
+// embedVideos walks the tree replacing iframes and bare links that refer to
+// videos with a video embed, and removing all other iframes
+func embedVideos(element *html.Node) {
+	for child := element.FirstChild; child != nil; {
+		next := child.NextSibling
+
+		if child.Type == html.ElementNode {
+			switch child.Data {
+			case "iframe":
+				if src, ok := getVideoEmbedURL(getHTMLAttr(child, "src")); ok {
+					element.InsertBefore(newVideoEmbed(src), child)
+				}
+				element.RemoveChild(child)
+
+			case "a":
+				href := getHTMLAttr(child, "href")
+				if src, ok := getVideoEmbedURL(href); ok && isBareLink(child, href) {
+					element.InsertBefore(newVideoEmbed(src), child)
+					element.RemoveChild(child)
+				} else {
+					embedVideos(child)
+				}
+
+			default:
+				embedVideos(child)
+			}
+		}
+
+		child = next
+	}
+}
+
+// getVideoEmbedURL returns the URL of the player for the video that rawurl
+// refers to. The host must exactly match an allowed provider.
+func getVideoEmbedURL(rawurl string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(rawurl))
+	if err != nil {
+		return "", false
+	}
+
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+
+	if u.User != nil {
+		return "", false
+	}
+
+	provider, ok := videoHosts[strings.ToLower(u.Host)]
+	if !ok {
+		return "", false
+	}
+
+	id := provider.videoId(u)
+	if id == "" {
+		return "", false
+	}
+
+	return fmt.Sprintf(provider.embedURL, id), true
+}
+
+// isBareLink returns true if the only content of the anchor is its URL, as is
+// the case for links that were pasted rather than written as markdown
+func isBareLink(a *html.Node, href string) bool {
+	if a.FirstChild == nil ||
+		a.FirstChild != a.LastChild ||
+		a.FirstChild.Type != html.TextNode {
+
+		return false
+	}
+
+	text := strings.Replace(a.FirstChild.Data, "\u00AD", "", -1)
+
+	return strings.TrimSpace(text) == strings.TrimSpace(href)
+}
+
+func newVideoEmbed(src string) *html.Node {
+	return &html.Node{
+		Type:     html.ElementNode,
+		Data:     "iframe",
+		DataAtom: atom.Iframe,
+		Attr: []html.Attribute{
+			{Key: "src", Val: src},
+			{Key: "width", Val: "560"},
+			{Key: "height", Val: "315"},
+			{Key: "frameborder", Val: "0"},
+			{Key: "sandbox", Val: videoEmbedSandbox},
+			{Key: "allowfullscreen", Val: ""},
+		},
+	}
+}
+
+func getHTMLAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetVideoEmbedURL(t *testing.T) {
+	tests := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ":      "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ",
+		"http://youtu.be/dQw4w9WgXcQ":                      "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ",
+		"https://www.youtube.com/embed/dQw4w9WgXcQ":        "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ",
+		"//www.youtube-nocookie.com/embed/dQw4w9WgXcQ":     "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ",
+		"https://vimeo.com/76979871":                       "https://player.vimeo.com/video/76979871",
+		"https://player.vimeo.com/video/76979871":          "https://player.vimeo.com/video/76979871",
+		"https://youtube.com.evil.com/watch?v=dQw4w9WgXcQ": "",
+		"https://evil.com/www.youtube.com/embed/dQw4w9WgX": "",
+		"https://evilyoutube.com/watch?v=dQw4w9WgXcQ":      "",
+		"https://www.youtube.com@evil.com/embed/dQw4w9WgX": "",
+		"https://www.youtube.com:8080/embed/dQw4w9WgXcQ":   "",
+		"javascript://www.youtube.com/embed/dQw4w9WgXcQ":   "",
+		"https://www.youtube.com/embed/dQw4w9WgXcQ\"><x":   "",
+		"https://vimeo.com/channels/staffpicks":            "",
+	}
+
+	for input, expected := range tests {
+		actual, ok := getVideoEmbedURL(input)
+		if actual != expected || ok != (expected != "") {
+			t.Errorf("Input `%s`: expected `%s`, got `%s`", input, expected, actual)
+		}
+	}
+}
+
+func TestSanitiseWithEmbeds(t *testing.T) {
+	sp := newSitePolicy(defaultHTMLPolicySettings)
+
+	output := string(sanitiseWithEmbeds(sp, []byte(
+		`<p><a href="https://youtu.be/dQw4w9WgXcQ">https://youtu.be/dQw4w9WgXcQ</a></p>`+
+			`<p><a href="https://vimeo.com/76979871">a video</a></p>`+
+			`<iframe src="https://player.vimeo.com/video/76979871" onload="x()"></iframe>`+
+			`<iframe src="https://youtube.com.evil.com/embed/dQw4w9WgXcQ"></iframe>`+
+			`<script>alert(1)</script>`,
+	)))
+
+	expected := []string{
+		`<iframe src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"`,
+		`<a href="https://vimeo.com/76979871"`,
+		`<iframe src="https://player.vimeo.com/video/76979871"`,
+		`sandbox="` + videoEmbedSandbox + `"`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected `%s` in `%s`", e, output)
+		}
+	}
+
+	unexpected := []string{"evil.com", "onload", "<script", "youtu.be"}
+	for _, u := range unexpected {
+		if strings.Contains(output, u) {
+			t.Errorf("Did not expect `%s` in `%s`", u, output)
+		}
+	}
+
+	if strings.Count(output, "<iframe") != 2 {
+		t.Errorf("Expected two iframes in `%s`", output)
+	}
+}
+
+func TestSanitiseWithEmbedsTwice(t *testing.T) {
+	if newSitePolicy(defaultHTMLPolicySettings).videoEmbeds {
+		t.Errorf("Expected video embeds to be off unless a site opts in")
+	}
+
+	sp := newSitePolicy(htmlPolicySettings{VideoEmbeds: true})
+	if !sp.videoEmbeds {
+		t.Errorf("Expected the site policy to allow video embeds")
+	}
+
+	// Comments are scrubbed before their links are shortened and again at the
+	// end, the embeds of the first pass must survive the second
+	once := sanitiseWithEmbeds(sp, []byte(
+		`<p><a href="https://www.youtube.com/watch?v=dQw4w9WgXcQ">https://www.youtube.com/watch?v=dQw4w9WgXcQ</a></p>`+
+			`<p><a href="https://vimeo.com/76979871">https://vimeo.com/76979871</a></p>`,
+	))
+	twice := sanitiseWithEmbeds(sp, once)
+
+	if string(once) != string(twice) {
+		t.Errorf("Expected `%s` to be unchanged, got `%s`", once, twice)
+	}
+	if strings.Count(string(twice), "<iframe") != 2 {
+		t.Errorf("Expected two iframes in `%s`", twice)
+	}
+}
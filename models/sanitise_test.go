@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+func TestNewHTMLPolicyDefaultMatchesUGC(t *testing.T) {
+	ugc := bluemonday.UGCPolicy()
+	ugc.RequireNoFollowOnLinks(false)
+	ugc.RequireNoFollowOnFullyQualifiedLinks(true)
+	ugc.AddTargetBlankToFullyQualifiedLinks(true)
+
+	policy := newHTMLPolicy(defaultHTMLPolicySettings)
+
+	inputs := []string{
+		`<p><a href="http://example.com/">link</a> <a href="/local">local</a></p>`,
+		`<table summary="s"><tr><td align="left">cell</td></tr></table>`,
+		`<img src="http://example.com/a.png" alt="a" width="10">`,
+		`<ol type="a"><li value="2">item</li></ol><del datetime="2014-01-01">x</del>`,
+		`<details open>d</details><meter value="1" max="2">m</meter>`,
+		`<script>alert(1)</script><iframe src="http://example.com/"></iframe>`,
+	}
+
+	for _, input := range inputs {
+		expected := ugc.Sanitize(input)
+		actual := policy.Sanitize(input)
+		if actual != expected {
+			t.Errorf("Input `%s`: expected `%s`, got `%s`", input, expected, actual)
+		}
+	}
+}
+
+func TestNewHTMLPolicySettings(t *testing.T) {
+	settings := htmlPolicySettings{}
+	policy := newHTMLPolicy(settings)
+
+	output := policy.Sanitize(
+		`<table><tr><td>cell</td></tr></table>` +
+			`<img src="http://example.com/a.png">` +
+			`<a href="http://example.com/">link</a>`,
+	)
+
+	for _, unwanted := range []string{"<table", "<td", "<img", "nofollow", "_blank"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("Expected `%s` to be removed, got `%s`", unwanted, output)
+		}
+	}
+	if !strings.Contains(output, "cell") {
+		t.Errorf("Expected table content to be kept, got `%s`", output)
+	}
+}
@@ -6,12 +6,32 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
 )
 
+// sqlSearchMicrocosms is the microcosms on the site ($1) that the profile ($2)
+// can read and has not ignored. Items in microcosms are only searched if they
+// are in one of these, so the items of an ignored microcosm are not found. A
+// guest has no ignores.
+const sqlSearchMicrocosms = `
+WITH m AS (
+    SELECT m.microcosm_id
+      FROM microcosms m
+      LEFT JOIN ignores i ON i.profile_id = $2
+                         AND (i.expires IS NULL OR i.expires > NOW())
+                         AND i.item_type_id = 2
+                         AND i.item_id = m.microcosm_id
+     WHERE m.site_id = $1
+       AND i.profile_id IS NULL
+       AND (get_effective_permissions($1,m.microcosm_id,2,m.microcosm_id,$2)).can_read IS TRUE
+)`
+
 type SearchResults struct {
-	Query     SearchQuery `json:"query"`
-	TimeTaken int64       `json:"timeTakenInMs,omitempty"`
-	Results   interface{} `json:"results,omitempty"`
+	Query     SearchQuery    `json:"query"`
+	TimeTaken int64          `json:"timeTakenInMs,omitempty"`
+	Results   interface{}    `json:"results,omitempty"`
+	Meta      h.CoreMetaType `json:"meta"`
 }
 
 type SearchResult struct {
@@ -35,8 +55,12 @@ func Search(
 	siteId int64,
 	searchUrl url.URL,
 	profileId int64,
+	limit int64,
+	offset int64,
 ) (
 	SearchResults,
+	int64,
+	int64,
 	int,
 	error,
 ) {
@@ -48,13 +72,13 @@ func Search(
 	}
 
 	if !m.Query.Valid {
-		return m, http.StatusOK, nil
+		return m, 0, 0, http.StatusOK, nil
 	}
 
 	if strings.Trim(m.Query.Query, " ") != "" {
-		return searchFullText(siteId, searchUrl, profileId, m)
+		return searchFullText(siteId, searchUrl, profileId, limit, offset, m)
 	} else {
-		return searchMetaData(siteId, searchUrl, profileId, m)
+		return searchMetaData(siteId, searchUrl, profileId, limit, offset, m)
 	}
 
 }
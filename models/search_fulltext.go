@@ -28,19 +28,17 @@ func searchFullText(
 	siteId int64,
 	searchUrl url.URL,
 	profileId int64,
+	limit int64,
+	offset int64,
 	m SearchResults,
 ) (
 	SearchResults,
+	int64,
+	int64,
 	int,
 	error,
 ) {
 
-	limit, offset, status, err := h.GetLimitAndOffset(searchUrl.Query())
-	if err != nil {
-		glog.Errorf("h.GetLimitAndOffset(searchUrl.Query()) %+v", err)
-		return m, status, err
-	}
-
 	start := time.Now()
 
 	// Search options
@@ -191,6 +189,11 @@ func searchFullText(
 			filterMicrocosmIds = `
    AND f.microcosm_id IN (` + inList + `)`
 		}
+
+		// Only microcosms the profile may read can be searched, whichever ids
+		// were asked for
+		filterMicrocosmIds += `
+   AND f.microcosm_id IN (SELECT microcosm_id FROM m)`
 	}
 
 	var filterModified string
@@ -255,17 +258,7 @@ func searchFullText(
 		}
 	}
 
-	sqlQuery := `
-WITH m AS (
-    SELECT m.microcosm_id
-      FROM microcosms m
-      LEFT JOIN ignores i ON i.profile_id = $2
-                         AND i.item_type_id = 2
-                         AND i.item_id = m.microcosm_id
-     WHERE m.site_id = $1
-       AND i.profile_id IS NULL
-       AND (get_effective_permissions($1,m.microcosm_id,2,m.microcosm_id,$2)).can_read IS TRUE
-)
+	sqlQuery := sqlSearchMicrocosms + `
 SELECT total
       ,item_type_id
       ,item_id
@@ -273,7 +266,7 @@ SELECT total
       ,parent_item_id
       ,last_modified
       ,rank
-      ,ts_headline(` + fullTextScope + `_text, query) AS highlight
+      ,ts_headline(` + fullTextScope + `_text, query, $6) AS highlight
       ,has_unread(item_type_id, item_id, $2)
   FROM (
            SELECT COUNT(*) OVER() AS total
@@ -289,8 +282,13 @@ SELECT total
                   JOIN flags f ON f.item_type_id = si.item_type_id
                               AND f.item_id = si.item_id
              LEFT JOIN ignores i ON i.profile_id = $2
+                                AND (i.expires IS NULL OR i.expires > NOW())
                                 AND i.item_type_id = f.item_type_id
-                                AND i.item_id = f.item_id` +
+                                AND i.item_id = f.item_id
+             LEFT JOIN ignores ia ON ia.profile_id = $2
+                                 AND (ia.expires IS NULL OR ia.expires > NOW())
+                                 AND ia.item_type_id = 3
+                                 AND ia.item_id = f.created_by` +
 		filterEventsJoin +
 		filterFollowing + `
              LEFT JOIN huddle_profiles h ON (f.parent_item_type_id = 5 OR f.item_type_id = 5)
@@ -298,7 +296,10 @@ SELECT total
                                         AND h.profile_id = $2
                  ,plainto_tsquery($3) AS query
             WHERE f.site_id = $1
-              AND i.profile_id IS NULL` +
+              AND i.profile_id IS NULL
+              AND ia.profile_id IS NULL
+              AND ` + sqlCanSeeEvent(`f.item_type_id`, `f.item_id`, `$2`) + `
+              AND ` + sqlCanSeeEvent(`f.parent_item_type_id`, `f.parent_item_id`, `$2`) +
 		filterModified +
 		filterMicrocosmIds +
 		filterTitle +
@@ -331,7 +332,7 @@ SELECT total
 	db, err := h.GetConnection()
 	if err != nil {
 		glog.Errorf("h.GetConnection() %+v", err)
-		return m, http.StatusInternalServerError, err
+		return m, 0, 0, http.StatusInternalServerError, err
 	}
 
 	queryId := `Search` + randomString()
@@ -354,6 +355,7 @@ SELECT total
 		m.Query.Query,
 		limit,
 		offset,
+		searchSnippetOptions(),
 	)
 	queryTimer.Stop()
 	if err != nil {
@@ -369,7 +371,7 @@ SELECT total
 				offset,
 				err,
 			)
-			return m, http.StatusInternalServerError,
+			return m, 0, 0, http.StatusInternalServerError,
 				errors.New("Database query failed")
 		}
 
@@ -379,7 +381,7 @@ SELECT total
 				"Query for '%s' took too long",
 				m.Query.Query,
 			)
-			return m, http.StatusInternalServerError,
+			return m, 0, 0, http.StatusInternalServerError,
 				merrors.MicrocosmError{
 					ErrorCode:    24,
 					ErrorMessage: "The search query took too long and has been cancelled",
@@ -394,7 +396,7 @@ SELECT total
 				offset,
 				err,
 			)
-			return m, http.StatusInternalServerError,
+			return m, 0, 0, http.StatusInternalServerError,
 				errors.New("Database query failed")
 		}
 	}
@@ -417,9 +419,10 @@ SELECT total
 		)
 		if err != nil {
 			glog.Errorf("rows.Scan() %+v", err)
-			return m, http.StatusInternalServerError,
+			return m, 0, 0, http.StatusInternalServerError,
 				errors.New("Row parsing error")
 		}
+		r.Highlight = formatSearchSnippet(r.Highlight)
 
 		itemType, err := h.GetMapStringFromInt(h.ItemTypes, r.ItemTypeId)
 		if err != nil {
@@ -428,7 +431,7 @@ SELECT total
 				r.ItemTypeId,
 				err,
 			)
-			return m, http.StatusInternalServerError, err
+			return m, 0, 0, http.StatusInternalServerError, err
 		}
 		r.ItemType = itemType
 
@@ -441,7 +444,7 @@ SELECT total
 					r.ParentItemTypeId.Int64,
 					err,
 				)
-				return m, http.StatusInternalServerError, err
+				return m, 0, 0, http.StatusInternalServerError, err
 			}
 			r.ParentItemType = parentItemType
 		}
@@ -451,7 +454,7 @@ SELECT total
 	err = rows.Err()
 	if err != nil {
 		glog.Errorf("rows.Err() %+v", err)
-		return m, http.StatusInternalServerError,
+		return m, 0, 0, http.StatusInternalServerError,
 			errors.New("Error fetching rows")
 	}
 	rows.Close()
@@ -461,7 +464,7 @@ SELECT total
 
 	if offset > maxOffset {
 		glog.Infoln("offset > maxOffset")
-		return m, http.StatusBadRequest, errors.New(
+		return m, 0, 0, http.StatusBadRequest, errors.New(
 			fmt.Sprintf("not enough records, "+
 				"offset (%d) would return an empty page.", offset),
 		)
@@ -509,7 +512,7 @@ SELECT total
 
 	for _, resp := range resps {
 		if resp.Err != nil {
-			return m, resp.Status, resp.Err
+			return m, 0, 0, resp.Status, resp.Err
 		}
 	}
 
@@ -527,20 +530,12 @@ SELECT total
 		}
 	}
 
-	m.Results = h.ConstructArray(
-		rs,
-		"result",
-		total,
-		limit,
-		offset,
-		pages,
-		&searchUrl,
-	)
+	m.Results = rs
 
 	// return milliseconds
 	m.TimeTaken = time.Now().Sub(start).Nanoseconds() / 1000000
 
-	return m, http.StatusOK, nil
+	return m, total, pages, http.StatusOK, nil
 
 }
 
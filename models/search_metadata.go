@@ -19,19 +19,17 @@ func searchMetaData(
 	siteId int64,
 	searchUrl url.URL,
 	profileId int64,
+	limit int64,
+	offset int64,
 	m SearchResults,
 ) (
 	SearchResults,
+	int64,
+	int64,
 	int,
 	error,
 ) {
 
-	limit, offset, status, err := h.GetLimitAndOffset(searchUrl.Query())
-	if err != nil {
-		glog.Errorf("h.GetLimitAndOffset(searchUrl.Query()) %+v", err)
-		return m, status, err
-	}
-
 	start := time.Now()
 
 	// The goal is to produce a piece of SQL that looks at just the flags table
@@ -220,6 +218,11 @@ func searchMetaData(
 			filterMicrocosmIds = `
    AND f.microcosm_id IN (` + inList + `)`
 		}
+
+		// Only microcosms the profile may read can be searched, whichever ids
+		// were asked for
+		filterMicrocosmIds += `
+   AND f.microcosm_id IN (SELECT microcosm_id FROM m)`
 	}
 
 	var filterModified string
@@ -290,17 +293,7 @@ SELECT 0,0,0,NULL,NULL,NOW(),0,''`
   FROM flags WHERE 1=2`
 
 	// Query with only meta data
-	sqlWith := `
-WITH m AS (
-    SELECT m.microcosm_id
-      FROM microcosms m
-      LEFT JOIN ignores i ON i.profile_id = $2
-                         AND i.item_type_id = 2
-                         AND i.item_id = m.microcosm_id
-     WHERE m.site_id = $1
-       AND i.profile_id IS NULL
-       AND (get_effective_permissions($1,m.microcosm_id,2,m.microcosm_id,$2)).can_read IS TRUE
-)`
+	sqlWith := sqlSearchMicrocosms
 	if includeHuddles || includeComments {
 		if filterModified != "" {
 			sqlWith += `, h AS (
@@ -332,12 +325,20 @@ SELECT f.item_type_id
 	sqlFromWhere = `
   FROM flags f
   LEFT JOIN ignores i ON i.profile_id = $2
+                     AND (i.expires IS NULL OR i.expires > NOW())
                      AND i.item_type_id = f.item_type_id
-                     AND i.item_id = f.item_id` +
+                     AND i.item_id = f.item_id
+  LEFT JOIN ignores ia ON ia.profile_id = $2
+                      AND (ia.expires IS NULL OR ia.expires > NOW())
+                      AND ia.item_type_id = 3
+                      AND ia.item_id = f.created_by` +
 		filterFollowing +
 		filterEventsJoin + `
  WHERE f.site_id = $1
-   AND i.profile_id IS NULL` +
+   AND i.profile_id IS NULL
+   AND ia.profile_id IS NULL
+   AND ` + sqlCanSeeEvent(`f.item_type_id`, `f.item_id`, `$2`) + `
+   AND ` + sqlCanSeeEvent(`f.parent_item_type_id`, `f.parent_item_id`, `$2`) +
 		filterModified +
 		filterMicrocosmIds +
 		filterItemTypes +
@@ -393,7 +394,7 @@ OFFSET $4`
 	db, err := h.GetConnection()
 	if err != nil {
 		glog.Errorf("h.GetConnection() %+v", err)
-		return m, http.StatusInternalServerError, err
+		return m, 0, 0, http.StatusInternalServerError, err
 	}
 
 	var total int64
@@ -404,7 +405,7 @@ OFFSET $4`
 	).Scan(&total)
 	if err != nil {
 		glog.Error(err)
-		return m, http.StatusInternalServerError, err
+		return m, 0, 0, http.StatusInternalServerError, err
 	}
 
 	// This nested query is used to run the `has_unread` query on only the rows
@@ -440,7 +441,7 @@ SELECT item_type_id
 			offset,
 			err,
 		)
-		return m, http.StatusInternalServerError,
+		return m, 0, 0, http.StatusInternalServerError,
 			errors.New("Database query failed")
 	}
 	defer rows.Close()
@@ -460,7 +461,7 @@ SELECT item_type_id
 		)
 		if err != nil {
 			glog.Errorf("rows.Scan() %+v", err)
-			return m, http.StatusInternalServerError,
+			return m, 0, 0, http.StatusInternalServerError,
 				errors.New("Row parsing error")
 		}
 
@@ -471,7 +472,7 @@ SELECT item_type_id
 				r.ItemTypeId,
 				err,
 			)
-			return m, http.StatusInternalServerError, err
+			return m, 0, 0, http.StatusInternalServerError, err
 		}
 		r.ItemType = itemType
 
@@ -484,7 +485,7 @@ SELECT item_type_id
 					r.ParentItemTypeId.Int64,
 					err,
 				)
-				return m, http.StatusInternalServerError, err
+				return m, 0, 0, http.StatusInternalServerError, err
 			}
 			r.ParentItemType = parentItemType
 		}
@@ -494,7 +495,7 @@ SELECT item_type_id
 	err = rows.Err()
 	if err != nil {
 		glog.Errorf("rows.Err() %+v", err)
-		return m, http.StatusInternalServerError,
+		return m, 0, 0, http.StatusInternalServerError,
 			errors.New("Error fetching rows")
 	}
 	rows.Close()
@@ -504,7 +505,7 @@ SELECT item_type_id
 
 	if offset > maxOffset {
 		glog.Infoln("offset > maxOffset")
-		return m, http.StatusBadRequest, errors.New(
+		return m, 0, 0, http.StatusBadRequest, errors.New(
 			fmt.Sprintf("not enough records, "+
 				"offset (%d) would return an empty page.", offset),
 		)
@@ -552,7 +553,7 @@ SELECT item_type_id
 
 	for _, resp := range resps {
 		if resp.Err != nil {
-			return m, resp.Status, resp.Err
+			return m, 0, 0, resp.Status, resp.Err
 		}
 	}
 
@@ -570,19 +571,11 @@ SELECT item_type_id
 		}
 	}
 
-	m.Results = h.ConstructArray(
-		rs,
-		"result",
-		total,
-		limit,
-		offset,
-		pages,
-		&searchUrl,
-	)
+	m.Results = rs
 
 	// return milliseconds
 	m.TimeTaken = time.Now().Sub(start).Nanoseconds() / 1000000
 
-	return m, http.StatusOK, nil
+	return m, total, pages, http.StatusOK, nil
 
 }
@@ -56,6 +56,31 @@ type SearchQuery struct {
 	Valid bool `json:"-"`
 }
 
+// searchItemTypes are the item types that are indexed and may be given as a
+// type to restrict a search to
+var searchItemTypes = []string{
+	h.ItemTypeComment,
+	h.ItemTypeConversation,
+	h.ItemTypeEvent,
+	h.ItemTypeHuddle,
+	h.ItemTypeMicrocosm,
+	h.ItemTypePoll,
+	h.ItemTypeProfile,
+}
+
+// searchItemTypeId returns the item type ID of a type given in a search, or
+// zero if the type is unknown or cannot be searched for
+func searchItemTypeId(itemType string) int64 {
+	itemType = strings.ToLower(strings.TrimSpace(itemType))
+	for _, t := range searchItemTypes {
+		if t == itemType {
+			return h.ItemTypes[t]
+		}
+	}
+
+	return 0
+}
+
 func GetSearchQueryFromUrl(requestUrl url.URL) SearchQuery {
 
 	sq := SearchQuery{
@@ -104,13 +129,14 @@ func (sq *SearchQuery) ParseFullQueryString() {
 			}
 		}
 
-		if k == "forumId" {
+		// microcosmId is accepted as a synonym of forumId
+		if k == "forumId" || k == "microcosmId" {
 			for _, t := range v {
 				i, err := strconv.ParseInt(t, 10, 64)
 				if err != nil {
 					sq.IgnoredArr = append(
 						sq.IgnoredArr,
-						fmt.Sprintf("forumId=%s", t),
+						fmt.Sprintf("%s=%s", k, t),
 					)
 				} else {
 					var found bool
@@ -129,7 +155,7 @@ func (sq *SearchQuery) ParseFullQueryString() {
 
 		if k == "type" {
 			for _, t := range v {
-				itemTypeId := h.ItemTypes[t]
+				itemTypeId := searchItemTypeId(t)
 
 				if itemTypeId == 0 {
 					sq.IgnoredArr = append(
@@ -222,7 +248,7 @@ func (sq *SearchQuery) ParseSingleQueryValue() {
 						sq.ItemIds = append(sq.ItemIds, i)
 					}
 				}
-			case "forumid":
+			case "forumid", "microcosmid":
 				i, err := strconv.ParseInt(value, 10, 64)
 				if err != nil {
 					sq.IgnoredArr = append(sq.IgnoredArr, frag)
@@ -240,8 +266,7 @@ func (sq *SearchQuery) ParseSingleQueryValue() {
 				}
 			case "type":
 				// itemTypes
-				itemType := value
-				itemTypeId := h.ItemTypes[itemType]
+				itemTypeId := searchItemTypeId(value)
 
 				if itemTypeId == 0 {
 					sq.IgnoredArr = append(sq.IgnoredArr, frag)
@@ -414,7 +439,7 @@ func (sq *SearchQuery) ParseInt(key string, value string, frag string) {
 	switch strings.ToLower(key) {
 	case "radius":
 		sq.Radius = i
-	case "forumid":
+	case "forumid", "microcosmid":
 		sq.MicrocosmIds = append(sq.MicrocosmIds, i)
 	case "authorid":
 		sq.ProfileId = i
@@ -638,6 +663,22 @@ func (sq *SearchQuery) Validate() {
 		}
 	}
 
+	// Results are ordered by relevance unless a valid sort is given
+	switch sq.Sort {
+	case "", "relevance", "date":
+	case "oldest", "newest":
+		// Only events have an event date to sort by
+		if !(len(sq.ItemTypeIds) == 1 &&
+			sq.ItemTypeIds[0] == h.ItemTypes[h.ItemTypeEvent]) {
+
+			sq.IgnoredArr = append(sq.IgnoredArr, fmt.Sprintf("sort:%s", sq.Sort))
+			sq.Sort = ""
+		}
+	default:
+		sq.IgnoredArr = append(sq.IgnoredArr, fmt.Sprintf("sort:%s", sq.Sort))
+		sq.Sort = ""
+	}
+
 	if len(sq.MicrocosmIds) > 0 {
 		// Implement Microcosm search, which means havign a really cheap way of looking
 		// up a Microcosm Id even when given a comment ID
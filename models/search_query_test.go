@@ -4,6 +4,8 @@ import (
 	"net/url"
 	"testing"
 	"time"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
 )
 
 func TestSearchQueryParsing(t *testing.T) {
@@ -126,3 +128,86 @@ func TestSearchQueryParsing(t *testing.T) {
 		t.Errorf("Query does not match: %s", sq.Query)
 	}
 }
+
+func TestSearchQueryMicrocosmId(t *testing.T) {
+	u, _ := url.Parse("https://test.microco.sm/api/v1/search?q=searchTerm&microcosmId=5&forumId=5")
+
+	sq := GetSearchQueryFromUrl(*u)
+
+	if len(sq.MicrocosmIds) != 1 || sq.MicrocosmIds[0] != 5 {
+		t.Errorf("Expected microcosm 5 once, got %v", sq.MicrocosmIds)
+	}
+
+	// No microcosm means a site wide search
+	u, _ = url.Parse("https://test.microco.sm/api/v1/search?q=searchTerm")
+
+	sq = GetSearchQueryFromUrl(*u)
+
+	if len(sq.MicrocosmIds) != 0 {
+		t.Errorf("Expected no microcosms, got %v", sq.MicrocosmIds)
+	}
+
+	u, _ = url.Parse("https://test.microco.sm/api/v1/search?q=searchTerm+microcosmId:7")
+
+	sq = GetSearchQueryFromUrl(*u)
+
+	if len(sq.MicrocosmIds) != 1 || sq.MicrocosmIds[0] != 7 {
+		t.Errorf("Expected microcosm 7 from the query, got %v", sq.MicrocosmIds)
+	}
+}
+
+func TestSearchQuerySort(t *testing.T) {
+	tests := []struct {
+		query string
+		sort  string
+	}{
+		{"q=searchTerm", ""},
+		{"q=searchTerm&sort=relevance", "relevance"},
+		{"q=searchTerm&sort=DATE", "date"},
+		{"q=searchTerm+sort:date", "date"},
+		{"q=searchTerm&sort=bogus", ""},
+		{"q=searchTerm&sort=newest", ""},
+		{"q=searchTerm&type=event&sort=newest", "newest"},
+	}
+
+	for _, test := range tests {
+		u, _ := url.Parse("https://test.microco.sm/api/v1/search?" + test.query)
+
+		sq := GetSearchQueryFromUrl(*u)
+
+		if sq.Sort != test.sort {
+			t.Errorf("%s: expected sort %q, got %q", test.query, test.sort, sq.Sort)
+		}
+	}
+}
+
+func TestSearchQueryTypes(t *testing.T) {
+	tests := []struct {
+		query string
+		types []int64
+	}{
+		{"q=searchTerm", nil},
+		{"q=searchTerm&type=event", []int64{h.ItemTypes[h.ItemTypeEvent]}},
+		{"q=searchTerm&type=Conversation", []int64{h.ItemTypes[h.ItemTypeConversation]}},
+		{"q=searchTerm&type=event&type=event", []int64{h.ItemTypes[h.ItemTypeEvent]}},
+		{"q=searchTerm&type=bogus&type=site", nil},
+		{"q=searchTerm&type=comment&type=watcher", []int64{h.ItemTypes[h.ItemTypeComment]}},
+		{"q=searchTerm+type:profile+type:auth", []int64{h.ItemTypes[h.ItemTypeProfile]}},
+	}
+
+	for _, test := range tests {
+		u, _ := url.Parse("https://test.microco.sm/api/v1/search?" + test.query)
+
+		sq := GetSearchQueryFromUrl(*u)
+
+		if len(sq.ItemTypeIds) != len(test.types) {
+			t.Errorf("%s: expected types %v, got %v", test.query, test.types, sq.ItemTypeIds)
+			continue
+		}
+		for i, itemTypeId := range test.types {
+			if sq.ItemTypeIds[i] != itemTypeId {
+				t.Errorf("%s: expected types %v, got %v", test.query, test.types, sq.ItemTypeIds)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+
+	conf "github.com/microcosm-cc/microcosm/config"
+)
+
+// The matched terms in a search snippet are wrapped in private use characters
+// by ts_headline, so that they survive the sanitising of the snippet and can
+// then be replaced by searchSnippetStartTag and searchSnippetStopTag
+const (
+	searchSnippetStartSel string = "\ue000"
+	searchSnippetStopSel  string = "\ue001"
+	searchSnippetStartTag string = "<mark>"
+	searchSnippetStopTag  string = "</mark>"
+)
+
+// searchSnippetOptions returns the ts_headline options for the excerpt of
+// each search result, the length is configured by search_snippet_max_words
+func searchSnippetOptions() string {
+	maxWords := conf.CONFIG_INT64[conf.KEY_SEARCH_SNIPPET_MAX_WORDS]
+	if maxWords < 2 {
+		maxWords = 2
+	}
+
+	return fmt.Sprintf(
+		"StartSel=%s, StopSel=%s, MaxWords=%d, MinWords=%d",
+		searchSnippetStartSel,
+		searchSnippetStopSel,
+		maxWords,
+		maxWords/2,
+	)
+}
+
+// formatSearchSnippet makes the excerpt returned by ts_headline safe to
+// display. The indexed text may contain HTML, all of which is stripped, and
+// the matched terms are then wrapped in <mark> tags for the client to style.
+func formatSearchSnippet(snippet string) string {
+	snippet = SanitiseText(snippet)
+
+	var (
+		b    bytes.Buffer
+		open bool
+	)
+	for _, r := range snippet {
+		switch string(r) {
+		case searchSnippetStartSel:
+			if !open {
+				b.WriteString(searchSnippetStartTag)
+				open = true
+			}
+		case searchSnippetStopSel:
+			if open {
+				b.WriteString(searchSnippetStopTag)
+				open = false
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if open {
+		b.WriteString(searchSnippetStopTag)
+	}
+
+	return b.String()
+}
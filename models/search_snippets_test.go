@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestFormatSearchSnippet(t *testing.T) {
+	tests := []struct {
+		snippet  string
+		expected string
+	}{
+		{
+			snippet:  "the " + searchSnippetStartSel + "cake" + searchSnippetStopSel + " is a lie",
+			expected: "the <mark>cake</mark> is a lie",
+		},
+		{
+			snippet:  "<script>alert(1)</script>" + searchSnippetStartSel + "cake" + searchSnippetStopSel,
+			expected: "<mark>cake</mark>",
+		},
+		{
+			snippet:  "a <b>bold</b> " + searchSnippetStartSel + "cake" + searchSnippetStopSel,
+			expected: "a bold <mark>cake</mark>",
+		},
+		{
+			snippet:  "fish &amp; " + searchSnippetStartSel + "chips",
+			expected: "fish &amp; <mark>chips</mark>",
+		},
+		{
+			snippet:  "no " + searchSnippetStopSel + "match",
+			expected: "no match",
+		},
+	}
+
+	for _, test := range tests {
+		actual := formatSearchSnippet(test.snippet)
+		if actual != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, actual)
+		}
+	}
+}
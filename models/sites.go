@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/golang/glog"
 
@@ -297,7 +298,7 @@ func (m *SiteType) FetchProfileSummaries() (int, error) {
 			glog.Error(err)
 		} else {
 			m.Meta.Stats = stats
-			c.CacheSet(mcKey, m.Meta.Stats, mcTtl)
+			c.CacheSet(mcKey, m.Meta.Stats, cacheTtl(h.ItemTypeSite))
 		}
 	}
 
@@ -347,7 +348,7 @@ func CreateOwnedSite(
 
 	// Create stub profile to serve as site owner
 	profile := ProfileType{}
-	profile.ProfileName = SuggestProfileName(user)
+	profile.ProfileName = SuggestProfileName(site.Id, user)
 	profile.UserId = user.ID
 	profile.Visible = true
 
@@ -455,8 +456,7 @@ SELECT new_ids.new_site_id,
 	}
 
 	// Create attachment for avatar and attach it to profile
-	gravatarUrl := MakeGravatarUrl(profile.ProfileName)
-	fm, _, err := StoreGravatar(gravatarUrl)
+	fm, _, err := StoreGravatar(profile.ProfileName)
 	if err != nil {
 		return SiteType{}, ProfileType{}, http.StatusInternalServerError,
 			errors.New(
@@ -614,6 +614,15 @@ DELETE FROM sites
 	return http.StatusOK, nil
 }
 
+// GetHost returns the host the site is served on, which is the custom domain
+// when the site has one
+func (m *SiteType) GetHost() string {
+	if m.Domain == "" {
+		return m.SubdomainKey + ".microco.sm"
+	}
+	return m.Domain
+}
+
 func (m *SiteType) GetUrl() string {
 	if m.Domain == "" {
 		return "https://" + m.SubdomainKey + ".microco.sm"
@@ -650,7 +659,7 @@ SELECT title
 	}
 
 	// Update cache
-	c.CacheSetString(mcKey, title, mcTtl)
+	c.CacheSetString(mcKey, title, cacheTtl(h.ItemTypeSite))
 
 	return title
 }
@@ -760,7 +769,7 @@ SELECT s.site_id
 		}
 	m.FetchProfileSummaries()
 
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeSite))
 
 	return m, http.StatusOK, nil
 }
@@ -789,13 +798,15 @@ SELECT COUNT(*)
 		return stats, err
 	}
 
-	// Online profiles
+	// Online profiles, those hiding that they are online are not counted
 	err = db.QueryRow(`
 SELECT COUNT(*)
-  FROM profiles
- WHERE site_id = $1 
-   AND last_active > NOW() - interval '90 minute'`,
+  FROM profiles p
+ WHERE p.site_id = $1
+   AND p.last_active > $2
+   AND `+notHidingOnline,
 		siteId,
+		OnlineSince(time.Now(), GetOnlineWindow()),
 	).Scan(
 		&stats.OnlineProfiles,
 	)
@@ -1052,7 +1063,7 @@ SELECT site_id
 	}
 
 	// Update cache
-	c.CacheSetInt64(mcKey, siteId, mcTtl)
+	c.CacheSetInt64(mcKey, siteId, cacheTtl(h.ItemTypeSite))
 
 	return GetSite(siteId)
 }
@@ -1101,11 +1112,49 @@ SELECT site_id
 	}
 
 	// Update cache
-	c.CacheSetInt64(mcKey, siteId, mcTtl)
+	c.CacheSetInt64(mcKey, siteId, cacheTtl(h.ItemTypeSite))
 
 	return GetSite(siteId)
 }
 
+// Hosts are cached by name and so cannot be purged when a site changes its
+// domain, hence the short time to live
+const siteHostTtl int32 = 60 * 5
+
+// GetSiteHostForHost returns the host of the site that serves the given host,
+// which may be a custom domain or a subdomain of microco.sm
+func GetSiteHostForHost(host string) (string, int, error) {
+
+	host = strings.ToLower(strings.Trim(host, " "))
+
+	mcKey := fmt.Sprintf("s_h%s", host)
+	if val, ok := c.CacheGetString(mcKey); ok {
+		return val, http.StatusOK, nil
+	}
+
+	var (
+		site   SiteType
+		status int
+		err    error
+	)
+	if strings.HasSuffix(host, ".microco.sm") {
+		site, status, err = GetSiteBySubdomain(
+			strings.TrimSuffix(host, ".microco.sm"),
+		)
+	} else {
+		site, status, err = GetSiteByDomain(host)
+	}
+	if err != nil {
+		return "", status, err
+	}
+
+	siteHost := site.GetHost()
+
+	c.CacheSetString(mcKey, siteHost, siteHostTtl)
+
+	return siteHost, http.StatusOK, nil
+}
+
 func GetSites(
 	userId int64,
 	limit int64,
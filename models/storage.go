@@ -0,0 +1,406 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/mitchellh/goamz/aws"
+	"github.com/mitchellh/goamz/s3"
+
+	conf "github.com/microcosm-cc/microcosm/config"
+)
+
+const (
+	StorageBackendS3         string = "s3"
+	StorageBackendFilesystem string = "filesystem"
+)
+
+// Storage is where uploaded files are kept. Keys are file hashes (or are
+// derived from them) and the content stored under a key never changes.
+type Storage interface {
+	// Put stores length bytes read from content under the given key
+	Put(key string, content io.Reader, length int64, mimeType string) error
+
+	// Get returns the HTTP headers that describe the content (Content-Type,
+	// Content-Length, etc) and the content itself, which the caller must close
+	Get(key string) (map[string]string, io.ReadCloser, error)
+
+	// Size returns the size in bytes of the content stored under the key, or
+	// zero if nothing is stored there
+	Size(key string) (int64, error)
+
+	// Delete removes the content stored under the key. Deleting a key that
+	// holds nothing is not an error.
+	Delete(key string) error
+
+	// List returns the keys that begin with prefix
+	List(prefix string) ([]string, error)
+}
+
+// GetStorage returns the storage backend selected by the config file, which
+// defaults to S3
+func GetStorage() (Storage, error) {
+	switch conf.CONFIG_STRING[conf.KEY_STORAGE_BACKEND] {
+	case StorageBackendS3, "":
+		return s3Storage{}, nil
+	case StorageBackendFilesystem:
+		return NewFilesystemStorage(conf.CONFIG_STRING[conf.KEY_STORAGE_PATH])
+	default:
+		return nil, errors.New(
+			fmt.Sprintf(
+				"Unknown storage backend: %s",
+				conf.CONFIG_STRING[conf.KEY_STORAGE_BACKEND],
+			),
+		)
+	}
+}
+
+// putUnlessStored puts the content unless the key already holds content of
+// the same length, returning whether it was put. Keys are content hashes so a
+// stored object of the right length is the same content, but one of a
+// different length is the remains of a failed upload and is replaced.
+func putUnlessStored(
+	storage Storage,
+	key string,
+	content io.Reader,
+	length int64,
+	mimeType string,
+) (
+	bool,
+	error,
+) {
+	size, err := storage.Size(key)
+	if err != nil {
+		// Not fatal, the put will tell us whether storage is really broken
+		glog.Warningf("storage.Size(`%s`) %+v", key, err)
+	}
+
+	if err == nil && size == length {
+		return false, nil
+	}
+
+	if size > 0 {
+		glog.Warningf(
+			"Stored object `%s` is %d bytes, expected %d. Replacing it",
+			key,
+			size,
+			length,
+		)
+	}
+
+	err = storage.Put(key, content, length, mimeType)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// s3Storage keeps files in the S3 bucket named in the config file
+type s3Storage struct{}
+
+// getBucket returns the S3 bucket named in the config file, in the region
+// named in the config file (which defaults to eu-west-1)
+func getBucket() (*s3.Bucket, error) {
+	region, ok := aws.Regions[conf.CONFIG_STRING[conf.KEY_S3_REGION]]
+	if !ok {
+		return nil, errors.New(
+			fmt.Sprintf(
+				"Unknown S3 region: %s",
+				conf.CONFIG_STRING[conf.KEY_S3_REGION],
+			),
+		)
+	}
+
+	auth := aws.Auth{
+		AccessKey: conf.CONFIG_STRING[conf.KEY_AWS_ACCESS_KEY_ID],
+		SecretKey: conf.CONFIG_STRING[conf.KEY_AWS_SECRET_ACCESS_KEY],
+	}
+
+	s3Instance := s3.New(auth, region)
+	return s3Instance.Bucket(conf.CONFIG_STRING[conf.KEY_S3_BUCKET]), nil
+}
+
+func (s s3Storage) Put(
+	key string,
+	content io.Reader,
+	length int64,
+	mimeType string,
+) error {
+	bucket, err := getBucket()
+	if err != nil {
+		return err
+	}
+
+	return bucket.PutReader(key, content, length, mimeType, s3.Private)
+}
+
+func (s s3Storage) Get(key string) (map[string]string, io.ReadCloser, error) {
+
+	headersOut := map[string]string{}
+
+	bucket, err := getBucket()
+	if err != nil {
+		return headersOut, nil, err
+	}
+
+	resp, err := bucket.GetResponse(key)
+	if err != nil {
+		return headersOut, nil, err
+	}
+
+	headers := []string{
+		"Content-Disposition",
+		"Content-Encoding",
+		"Content-Length",
+		"Content-Type",
+		"ETag",
+		"Last-Modified",
+	}
+
+	for _, h := range headers {
+		v := resp.Header.Get(h)
+		if v != "" {
+			headersOut[h] = v
+		}
+	}
+
+	return headersOut, resp.Body, nil
+}
+
+func (s s3Storage) Size(key string) (int64, error) {
+	bucket, err := getBucket()
+	if err != nil {
+		return 0, err
+	}
+
+	k, err := bucket.GetKey(key)
+	if err != nil {
+		if isStorageNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return k.Size, nil
+}
+
+func (s s3Storage) Delete(key string) error {
+	bucket, err := getBucket()
+	if err != nil {
+		return err
+	}
+
+	return bucket.Del(key)
+}
+
+func (s s3Storage) List(prefix string) ([]string, error) {
+	bucket, err := getBucket()
+	if err != nil {
+		return []string{}, err
+	}
+
+	keys := []string{}
+	marker := ""
+	for {
+		resp, err := bucket.List(prefix, "", marker, 1000)
+		if err != nil {
+			return keys, err
+		}
+
+		for _, k := range resp.Contents {
+			keys = append(keys, k.Key)
+		}
+
+		if !resp.IsTruncated || len(resp.Contents) == 0 {
+			break
+		}
+		marker = resp.Contents[len(resp.Contents)-1].Key
+	}
+
+	return keys, nil
+}
+
+// filesystemStorage keeps files in a local directory, which is useful for
+// development and for deployments without S3. The MIME type of each file is
+// kept alongside it in a file with a .type suffix.
+type filesystemStorage struct {
+	root string
+}
+
+// NewFilesystemStorage returns a Storage that keeps files beneath root,
+// creating the directory if it does not exist
+func NewFilesystemStorage(root string) (Storage, error) {
+	if strings.Trim(root, " ") == "" {
+		return nil, errors.New(
+			fmt.Sprintf(
+				"%s must be set to use the %s storage backend",
+				conf.KEY_STORAGE_PATH,
+				StorageBackendFilesystem,
+			),
+		)
+	}
+
+	err := os.MkdirAll(root, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return filesystemStorage{root: root}, nil
+}
+
+func (s filesystemStorage) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", errors.New(fmt.Sprintf("Invalid storage key: %s", key))
+	}
+
+	return filepath.Join(s.root, key), nil
+}
+
+func (s filesystemStorage) Put(
+	key string,
+	content io.Reader,
+	length int64,
+	mimeType string,
+) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename so that readers never see a
+	// partially written file
+	tmp, err := ioutil.TempFile(s.root, ".upload-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, content)
+	if err == nil && n != length {
+		err = errors.New(
+			fmt.Sprintf("Expected %d bytes for %s but read %d", length, key, n),
+		)
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(p+".type", []byte(mimeType), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s filesystemStorage) Get(key string) (map[string]string, io.ReadCloser, error) {
+
+	headersOut := map[string]string{}
+
+	p, err := s.path(key)
+	if err != nil {
+		return headersOut, nil, err
+	}
+
+	file, err := os.Open(p)
+	if err != nil {
+		return headersOut, nil, err
+	}
+
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return headersOut, nil, err
+	}
+
+	mimeType, err := ioutil.ReadFile(p + ".type")
+	if err == nil && len(mimeType) > 0 {
+		headersOut["Content-Type"] = string(mimeType)
+	}
+	headersOut["Content-Length"] = strconv.FormatInt(fi.Size(), 10)
+	headersOut["ETag"] = `"` + key + `"`
+	headersOut["Last-Modified"] = fi.ModTime().UTC().Format(http.TimeFormat)
+
+	return headersOut, file, nil
+}
+
+// isStorageNotFound returns true if the error from a storage is because the
+// key does not exist
+func isStorageNotFound(err error) bool {
+	if s3err, ok := err.(*s3.Error); ok {
+		return s3err.StatusCode == http.StatusNotFound
+	}
+	return os.IsNotExist(err)
+}
+
+func (s filesystemStorage) Size(key string) (int64, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	fi, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return fi.Size(), nil
+}
+
+func (s filesystemStorage) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Remove(p + ".type")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (s filesystemStorage) List(prefix string) ([]string, error) {
+	infos, err := ioutil.ReadDir(s.root)
+	if err != nil {
+		return []string{}, err
+	}
+
+	keys := []string{}
+	for _, fi := range infos {
+		name := fi.Name()
+		if fi.IsDir() ||
+			strings.HasPrefix(name, ".") ||
+			strings.HasSuffix(name, ".type") ||
+			!strings.HasPrefix(name, prefix) {
+
+			continue
+		}
+		keys = append(keys, name)
+	}
+
+	return keys, nil
+}
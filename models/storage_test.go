@@ -0,0 +1,133 @@
+package models
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFilesystemStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microcosm-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage, err := NewFilesystemStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFilesystemStorage() failed: %+v", err)
+	}
+
+	key := "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+	content := []byte("GIF89a")
+
+	size, err := storage.Size(key)
+	if err != nil || size != 0 {
+		t.Errorf("Expected size 0 for a missing key, got %d (%v)", size, err)
+	}
+
+	_, _, err = storage.Get(key)
+	if !isStorageNotFound(err) {
+		t.Errorf("Expected Get() of a missing key to be not found, got %v", err)
+	}
+
+	err = storage.Put(key, bytes.NewReader(content), int64(len(content)), ImageGifMimeType)
+	if err != nil {
+		t.Fatalf("Put() failed: %+v", err)
+	}
+
+	size, err = storage.Size(key)
+	if err != nil || size != int64(len(content)) {
+		t.Errorf("Expected size %d, got %d (%v)", len(content), size, err)
+	}
+
+	headers, body, err := storage.Get(key)
+	if err != nil {
+		t.Fatalf("Get() failed: %+v", err)
+	}
+	data, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatalf("Reading the body failed: %+v", err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("Get() returned %q, expected %q", data, content)
+	}
+	if headers["Content-Type"] != ImageGifMimeType {
+		t.Errorf("Expected Content-Type %s, got %s", ImageGifMimeType, headers["Content-Type"])
+	}
+
+	err = storage.Put(VariantKey(key, 100, 0), bytes.NewReader(content), int64(len(content)), ImageGifMimeType)
+	if err != nil {
+		t.Fatalf("Put() failed: %+v", err)
+	}
+
+	keys, err := storage.List(key + "_")
+	if err != nil || len(keys) != 1 || keys[0] != VariantKey(key, 100, 0) {
+		t.Errorf("Expected List() to return the variant only, got %v (%v)", keys, err)
+	}
+
+	err = storage.Delete(key)
+	if err != nil {
+		t.Fatalf("Delete() failed: %+v", err)
+	}
+	size, err = storage.Size(key)
+	if err != nil || size != 0 {
+		t.Errorf("Expected size 0 after Delete(), got %d (%v)", size, err)
+	}
+
+	err = storage.Put("../escape", bytes.NewReader(content), int64(len(content)), ImageGifMimeType)
+	if err == nil {
+		t.Errorf("Put() accepted a key outside of the storage directory")
+	}
+}
+
+func TestPutUnlessStoredReplacesShortObject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microcosm-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage, err := NewFilesystemStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFilesystemStorage() failed: %+v", err)
+	}
+
+	key := "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+	content := []byte("the complete content of the file")
+
+	// A previous upload that failed part way through
+	short := content[:10]
+	err = storage.Put(key, bytes.NewReader(short), int64(len(short)), ImagePngMimeType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put, err := putUnlessStored(storage, key, bytes.NewReader(content), int64(len(content)), ImagePngMimeType)
+	if err != nil {
+		t.Fatalf("putUnlessStored() failed: %+v", err)
+	}
+	if !put {
+		t.Errorf("A short stored object was not replaced")
+	}
+
+	_, body, err := storage.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(data, content) {
+		t.Errorf("Stored content is %q, expected %q", data, content)
+	}
+
+	put, err = putUnlessStored(storage, key, bytes.NewReader(content), int64(len(content)), ImagePngMimeType)
+	if err != nil {
+		t.Fatalf("putUnlessStored() failed: %+v", err)
+	}
+	if put {
+		t.Errorf("A complete stored object was uploaded again")
+	}
+}
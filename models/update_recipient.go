@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -109,6 +111,7 @@ SELECT CASE WHEN BIT_AND(a.item_watcher) > 0 THEN
                  ,w.send_sms
              FROM watchers w
              LEFT JOIN ignores i ON i.profile_id = w.profile_id
+                                AND (i.expires IS NULL OR i.expires > NOW())
                                 AND (
                                         (i.item_type_id = w.item_type_id AND i.item_id = w.item_id)
                                      OR (i.item_type_id = $2 AND i.item_id = $3)
@@ -176,6 +179,7 @@ SELECT CASE WHEN BIT_AND(a.item_watcher) > 0 THEN
  ORDER BY a.profile_id
        ) AS w
   LEFT JOIN ignores i ON i.profile_id = w.profile_id
+                     AND (i.expires IS NULL OR i.expires > NOW())
                      AND i.item_type_id = 3 -- profile
                      AND i.item_id = $4 -- created by
       ,flags f
@@ -245,3 +249,124 @@ SELECT CASE WHEN BIT_AND(a.item_watcher) > 0 THEN
 
 	return ems, http.StatusOK, nil
 }
+
+// excludeIgnoringRecipients removes the recipients who have ignored the item,
+// the microcosm it is in, or the profile that caused the update. Recipients
+// who have turned off emails for the update type are kept but not emailed.
+func excludeIgnoringRecipients(
+	recipients []UpdateRecipient,
+	itemTypeId int64,
+	itemId int64,
+	updateTypeId int64,
+	createdById int64,
+) (
+	[]UpdateRecipient,
+	int,
+	error,
+) {
+
+	if len(recipients) == 0 {
+		return recipients, http.StatusOK, nil
+	}
+
+	profileIds := []string{}
+	for _, recipient := range recipients {
+		profileIds = append(
+			profileIds,
+			strconv.FormatInt(recipient.ForProfile.Id, 10),
+		)
+	}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return []UpdateRecipient{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--excludeIgnoringRecipients
+SELECT p.profile_id
+      ,EXISTS(
+           SELECT 1
+             FROM ignores i
+            WHERE i.profile_id = p.profile_id
+              AND (i.expires IS NULL OR i.expires > NOW())
+              AND (
+                      (i.item_type_id = $2 AND i.item_id = $3)
+                   OR (i.item_type_id = 2 AND i.item_id IN (
+                          SELECT microcosm_id
+                            FROM flags
+                           WHERE item_type_id = $2
+                             AND item_id = $3
+                      ))
+                   OR (i.item_type_id = 3 AND i.item_id = $5)
+                  )
+       ) AS is_ignoring
+      ,COALESCE(uo.send_email, TRUE) AS send_email
+  FROM UNNEST($1::bigint[]) AS p(profile_id)
+  LEFT JOIN update_options uo ON uo.profile_id = p.profile_id
+                             AND uo.update_type_id = $4`,
+		`{`+strings.Join(profileIds, `,`)+`}`,
+		itemTypeId,
+		itemId,
+		updateTypeId,
+		createdById,
+	)
+	if err != nil {
+		return []UpdateRecipient{}, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Database query failed: %v", err.Error()),
+			)
+	}
+	defer rows.Close()
+
+	ignoring := map[int64]bool{}
+	optedOut := map[int64]bool{}
+	for rows.Next() {
+		var (
+			profileId  int64
+			isIgnoring bool
+			sendEmail  bool
+		)
+		err = rows.Scan(&profileId, &isIgnoring, &sendEmail)
+		if err != nil {
+			return []UpdateRecipient{}, http.StatusInternalServerError,
+				errors.New(
+					fmt.Sprintf("Row parsing error: %v", err.Error()),
+				)
+		}
+
+		ignoring[profileId] = isIgnoring
+		optedOut[profileId] = !sendEmail
+	}
+	err = rows.Err()
+	if err != nil {
+		return []UpdateRecipient{}, http.StatusInternalServerError,
+			errors.New(
+				fmt.Sprintf("Error fetching rows: %v", err.Error()),
+			)
+	}
+	rows.Close()
+
+	return excludeRecipients(recipients, ignoring, optedOut), http.StatusOK, nil
+}
+
+// excludeRecipients removes the ignoring recipients and stops emails to those
+// who have opted out
+func excludeRecipients(
+	recipients []UpdateRecipient,
+	ignoring map[int64]bool,
+	optedOut map[int64]bool,
+) []UpdateRecipient {
+
+	ems := []UpdateRecipient{}
+	for _, recipient := range recipients {
+		if ignoring[recipient.ForProfile.Id] {
+			continue
+		}
+		if optedOut[recipient.ForProfile.Id] {
+			recipient.SendEmail = false
+		}
+		ems = append(ems, recipient)
+	}
+
+	return ems
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestExcludeRecipients(t *testing.T) {
+	recipients := []UpdateRecipient{}
+	for _, id := range []int64{1, 2, 3, 4} {
+		recipients = append(recipients, UpdateRecipient{
+			ForProfile: ProfileSummaryType{Id: id},
+			SendEmail:  true,
+		})
+	}
+
+	ems := excludeRecipients(
+		recipients,
+		map[int64]bool{1: false, 2: true, 4: true},
+		map[int64]bool{1: true, 2: true, 3: false},
+	)
+
+	if len(ems) != 2 {
+		t.Fatalf("Expected 2 recipients, got %d", len(ems))
+	}
+	if ems[0].ForProfile.Id != 1 || ems[0].SendEmail {
+		t.Errorf("Expected profile 1 to be kept without email, got %+v", ems[0])
+	}
+	if ems[1].ForProfile.Id != 3 || !ems[1].SendEmail {
+		t.Errorf("Expected profile 3 to be kept with email, got %+v", ems[1])
+	}
+}
@@ -72,7 +72,7 @@ SELECT update_type_id
 			)
 	}
 
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeUpdate))
 
 	return m, http.StatusOK, nil
 }
@@ -321,7 +321,7 @@ SELECT update_id
 	m.ItemType = itemType
 	m.FetchSummaries(siteId)
 
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeUpdate))
 	return m, http.StatusOK, nil
 }
 
@@ -349,6 +349,7 @@ WITH m AS (
     SELECT m.microcosm_id
       FROM microcosms m
       LEFT JOIN ignores i ON i.profile_id = $2
+                         AND (i.expires IS NULL OR i.expires > NOW())
                          AND i.item_type_id = 2
                          AND i.item_id = m.microcosm_id
      WHERE m.site_id = $1
@@ -398,6 +399,7 @@ SELECT total
                                                  JOIN flags f ON f.item_type_id = u.item_type_id
                                                              AND f.item_id = u.item_id
                                             LEFT JOIN ignores i ON i.profile_id = $2
+                                                               AND (i.expires IS NULL OR i.expires > NOW())
                                                                AND (
                                                                        (i.item_type_id = 3 AND i.item_id = u.created_by)
                                                                     OR (i.item_type_id = f.parent_item_type_id AND i.item_id = f.parent_item_id)
@@ -446,6 +448,7 @@ SELECT total
                                                                         AND hp.profile_id = u.for_profile_id
                                                                         AND f.parent_item_type_id = 5
                                             LEFT JOIN ignores i ON i.profile_id = $2
+                                                               AND (i.expires IS NULL OR i.expires > NOW())
                                                                AND (
                                                                        (i.item_type_id = 3 AND i.item_id = u.created_by)
                                                                     OR (i.item_type_id = f.parent_item_type_id AND i.item_id = f.parent_item_id)
@@ -489,6 +492,7 @@ SELECT total
                                                            AND w.item_type_id = 2
                                                            AND w.item_id = f.microcosm_id
                                             LEFT JOIN ignores i ON i.profile_id = $2
+                                                               AND (i.expires IS NULL OR i.expires > NOW())
                                                                AND i.item_type_id = 3
                                                                AND i.item_id = u.created_by
                                       WHERE u.for_profile_id = $2
@@ -62,6 +62,14 @@ func SendUpdatesForNewCommentInItem(
 		return status, err
 	}
 
+	// People mentioned in the comment have already been sent an update for
+	// the mention, and do not need another for the comment
+	recipients, status, err = withoutMentionedRecipients(comment.Id, recipients)
+	if err != nil {
+		glog.Errorf("%s %+v", "withoutMentionedRecipients()", err)
+		return status, err
+	}
+
 	// SEND UPDATES
 	//
 	// Freely acknowledging that we're going to loop the same thing many
@@ -279,12 +287,33 @@ func SendUpdatesForNewReplyToYourComment(
 	}
 	profileId := parentComment.Meta.CreatedById
 
+	// Replying to yourself is not news to you
+	if profileId == comment.Meta.CreatedById {
+		return http.StatusOK, nil
+	}
+
 	forProfile, status, err := GetProfileSummary(siteId, profileId)
 	if err != nil {
 		glog.Errorf("%s %+v", "GetProfileSummary()", err)
 		return http.StatusInternalServerError, err
 	}
 
+	// Nor is a reply from a profile, or on an item, that you have ignored
+	recipients, status, err := excludeIgnoringRecipients(
+		[]UpdateRecipient{{ForProfile: forProfile}},
+		comment.ItemTypeId,
+		comment.ItemId,
+		updateType.Id,
+		comment.Meta.CreatedById,
+	)
+	if err != nil {
+		glog.Errorf("%s %+v", "excludeIgnoringRecipients()", err)
+		return status, err
+	}
+	if len(recipients) == 0 {
+		return http.StatusOK, nil
+	}
+
 	///////////////////
 	// LOCAL UPDATES //
 	///////////////////
@@ -600,6 +629,14 @@ func SendUpdatesForNewCommentInHuddle(
 		return status, err
 	}
 
+	// People mentioned in the comment have already been sent an update for
+	// the mention, and do not need another for the comment
+	recipients, status, err = withoutMentionedRecipients(comment.Id, recipients)
+	if err != nil {
+		glog.Errorf("%s %+v", "withoutMentionedRecipients()", err)
+		return status, err
+	}
+
 	// SEND UPDATES
 	//
 	// Freely acknowledging that we're going to loop the same thing many
@@ -826,6 +863,20 @@ func SendUpdatesForNewAttendeeInAnEvent(
 		return status, err
 	}
 
+	// Those who have ignored the event, its microcosm or the attendee are not
+	// told about the RSVP at all
+	recipients, status, err = excludeIgnoringRecipients(
+		recipients,
+		h.ItemTypes[h.ItemTypeEvent],
+		attendee.EventId,
+		updateType.Id,
+		attendee.ProfileId,
+	)
+	if err != nil {
+		glog.Errorf("%s %+v", "excludeIgnoringRecipients()", err)
+		return status, err
+	}
+
 	// SEND UPDATES
 	//
 	// Freely acknowledging that we're going to loop the same thing many
@@ -1245,6 +1296,34 @@ func SendUpdatesForNewProfileOnSite(
 	return http.StatusOK, nil
 }
 
+// withoutMentionedRecipients removes the profiles that were mentioned in the
+// comment from the recipients
+func withoutMentionedRecipients(
+	commentId int64,
+	recipients []UpdateRecipient,
+) (
+	[]UpdateRecipient,
+	int,
+	error,
+) {
+	mentioned, status, err := GetMentionedProfileIds(commentId)
+	if err != nil {
+		return []UpdateRecipient{}, status, err
+	}
+	if len(mentioned) == 0 {
+		return recipients, http.StatusOK, nil
+	}
+
+	unmentioned := []UpdateRecipient{}
+	for _, recipient := range recipients {
+		if !mentioned[recipient.ForProfile.Id] {
+			unmentioned = append(unmentioned, recipient)
+		}
+	}
+
+	return unmentioned, http.StatusOK, nil
+}
+
 // returns a user's update options if present, otherwise it returns
 // the default preference for the given update type.
 func GetCommunicationOptions(
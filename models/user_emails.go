@@ -0,0 +1,140 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+)
+
+// UserEmailType is an email address that a user may sign in with. The primary
+// address is the one held on the user, other addresses are kept in the
+// user_emails table and are only used once they have been verified.
+type UserEmailType struct {
+	Email            string      `json:"email"`
+	Primary          bool        `json:"primary"`
+	VerifiedNullable pq.NullTime `json:"-"`
+	Verified         string      `json:"verified,omitempty"`
+}
+
+// UserEmailRequestType is a Persona assertion of an email address that the
+// authenticated user wishes to add
+type UserEmailRequestType struct {
+	Assertion string `json:"assertion"`
+}
+
+// GetUserEmails returns the primary email address of the user followed by any
+// other addresses they have added
+func GetUserEmails(userId int64) ([]UserEmailType, int, error) {
+
+	user, status, err := GetUser(userId)
+	if err != nil {
+		return []UserEmailType{}, status, err
+	}
+
+	ems := []UserEmailType{{Email: user.Email, Primary: true}}
+
+	db, err := h.GetConnection()
+	if err != nil {
+		return []UserEmailType{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetUserEmails
+SELECT email
+      ,verified
+  FROM user_emails
+ WHERE user_id = $1
+ ORDER BY created ASC`,
+		userId,
+	)
+	if err != nil {
+		return []UserEmailType{}, http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Database query failed: %v", err.Error()))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := UserEmailType{}
+		err = rows.Scan(
+			&m.Email,
+			&m.VerifiedNullable,
+		)
+		if err != nil {
+			return []UserEmailType{}, http.StatusInternalServerError,
+				errors.New(fmt.Sprintf("Row parsing error: %v", err.Error()))
+		}
+
+		if m.VerifiedNullable.Valid {
+			m.Verified = m.VerifiedNullable.Time.Format(time.RFC3339Nano)
+		}
+
+		ems = append(ems, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []UserEmailType{}, http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Error fetching rows: %v", err.Error()))
+	}
+	rows.Close()
+
+	return ems, http.StatusOK, nil
+}
+
+// AddVerifiedUserEmail adds an email address that the user has proven they
+// own, so that they may sign in with it. An address that belongs to another
+// user cannot be added, and adding an address the user already has does
+// nothing.
+func AddVerifiedUserEmail(userId int64, email string) (int, error) {
+
+	email = strings.Trim(email, " ")
+	if email == "" {
+		return http.StatusBadRequest,
+			errors.New("You must specify an email address")
+	}
+
+	existing, status, err := GetUserByEmailAddress(email)
+	if err == nil {
+		if existing.ID == userId {
+			return http.StatusOK, nil
+		}
+
+		return http.StatusConflict,
+			errors.New("That email address belongs to another user")
+
+	} else if status != http.StatusNotFound {
+		return status, err
+	}
+
+	tx, err := h.GetTransaction()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`--AddVerifiedUserEmail
+INSERT INTO user_emails (
+    user_id, email, created, verified
+) VALUES (
+    $1, $2, NOW(), NOW()
+)`,
+		userId,
+		email,
+	)
+	if err != nil {
+		return http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Error inserting data: %v", err.Error()))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError,
+			errors.New(fmt.Sprintf("Transaction failed: %v", err.Error()))
+	}
+
+	return http.StatusOK, nil
+}
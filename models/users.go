@@ -18,7 +18,10 @@ type UsersType struct {
 	Meta  h.CoreMetaType `json:"meta"`
 }
 
-// UserType encapsulates a user in the system
+// UserType encapsulates a user in the system. Email is the primary email
+// address of the user, which is the one their avatar is derived from (see
+// MakeGravatarUrl) and that notifications are sent to. A user may also sign
+// in with any other address they have verified, see UserEmailType.
 type UserType struct {
 	ID           int64          `json:"userId"`
 	Email        string         `json:"email"`
@@ -76,9 +79,27 @@ func (m *UserType) Insert() (int, error) {
 
 	defer tx.Rollback()
 
+	status, err = m.insertTx(tx)
+	if err != nil {
+		return status, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return http.StatusInternalServerError,
+			fmt.Errorf("Transaction failed: %v", err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+// insertTx creates the user within the transaction, so that it is undone if
+// the transaction is rolled back
+func (m *UserType) insertTx(tx *sql.Tx) (int, error) {
+
 	var insertID int64
 	// TODO(buro9): language constraints, password flow
-	err = tx.QueryRow(`
+	err := tx.QueryRow(`
 INSERT INTO users (
     email, created, language, is_banned, password,
     password_date
@@ -98,12 +119,6 @@ INSERT INTO users (
 	}
 	m.ID = insertID
 
-	err = tx.Commit()
-	if err != nil {
-		return http.StatusInternalServerError,
-			fmt.Errorf("Transaction failed: %v", err.Error())
-	}
-
 	return http.StatusOK, nil
 }
 
@@ -129,6 +144,28 @@ SELECT COUNT(*) > 0
 	return val
 }
 
+// userIsOnAnySite returns true if the user has a profile on any site. Errors
+// are treated as true as this is used to refuse access to the user.
+func userIsOnAnySite(userId int64) bool {
+	db, err := h.GetConnection()
+	if err != nil {
+		return true
+	}
+
+	var val bool
+	err = db.QueryRow(`--userIsOnAnySite
+SELECT COUNT(*) > 0
+  FROM profiles
+ WHERE user_id = $1`,
+		userId,
+	).Scan(&val)
+	if err != nil {
+		return true
+	}
+
+	return val
+}
+
 // GetUser will fetch a user for a given ID
 func GetUser(id int64) (UserType, int, error) {
 
@@ -190,7 +227,7 @@ SELECT user_id
 			h.GetLink("self", "", h.ItemTypeUser, m.ID),
 		}
 
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeUser))
 
 	return m, http.StatusOK, nil
 }
@@ -347,8 +384,9 @@ func CreateUserByEmailAddress(email string) (UserType, int, error) {
 
 }
 
-// GetUserByEmailAddress performs a case-insensitive search for any matching
-// user and returns it.
+// GetUserByEmailAddress performs a case-insensitive search for the user whose
+// primary email address, or any verified secondary address, matches and
+// returns it.
 func GetUserByEmailAddress(email string) (UserType, int, error) {
 
 	if strings.Trim(email, " ") == "" {
@@ -375,8 +413,20 @@ func GetUserByEmailAddress(email string) (UserType, int, error) {
 	var m UserType
 	err = db.QueryRow(`
 SELECT user_id
-  FROM users
- WHERE LOWER(email) = LOWER($1)`,
+  FROM (
+           SELECT user_id
+                 ,1 AS seq
+             FROM users
+            WHERE LOWER(email) = LOWER($1)
+            UNION ALL
+           SELECT user_id
+                 ,2 AS seq
+             FROM user_emails
+            WHERE LOWER(email) = LOWER($1)
+              AND verified IS NOT NULL
+       ) AS e
+ ORDER BY seq
+ LIMIT 1`,
 		email,
 	).Scan(
 		&m.ID,
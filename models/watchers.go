@@ -117,6 +117,9 @@ INSERT INTO watchers (
 	}
 
 	PurgeCache(h.ItemTypes[h.ItemTypeWatcher], m.ID)
+	if m.ItemTypeID == h.ItemTypes[h.ItemTypeProfile] {
+		purgeFollowCounts(m.ProfileID, m.ItemID)
+	}
 
 	return http.StatusOK, nil
 }
@@ -175,13 +178,24 @@ func (m *WatcherType) Delete() (int, error) {
 	defer tx.Rollback()
 
 	if m.ID > 0 {
-		_, err = tx.Exec(`
+		err = tx.QueryRow(`
 DELETE
   FROM watchers
- WHERE watcher_id = $1`,
+ WHERE watcher_id = $1
+RETURNING profile_id
+         ,item_type_id
+         ,item_id`,
 			m.ID,
+		).Scan(
+			&m.ProfileID,
+			&m.ItemTypeID,
+			&m.ItemID,
 		)
 		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusOK, nil
+			}
+
 			glog.Error(err)
 			return http.StatusInternalServerError,
 				fmt.Errorf("Delete failed: %v", err.Error())
@@ -227,6 +241,9 @@ SELECT watcher_id
 	}
 
 	PurgeCache(h.ItemTypes[h.ItemTypeWatcher], m.ID)
+	if m.ItemTypeID == h.ItemTypes[h.ItemTypeProfile] {
+		purgeFollowCounts(m.ProfileID, m.ItemID)
+	}
 
 	return http.StatusOK, nil
 }
@@ -263,6 +280,7 @@ SELECT MAX(watcher_id) AS watcher_id,
             WHERE item_type_id = $1
               AND item_id = $2
               AND profile_id = $3
+              AND (expires IS NULL OR expires > NOW())
        )) AS ignored
   FROM (
            SELECT watcher_id,
@@ -435,7 +453,7 @@ SELECT watcher_id,
 	}
 
 	// Update cache
-	c.CacheSet(mcKey, m, mcTtl)
+	c.CacheSet(mcKey, m, cacheTtl(h.ItemTypeWatcher))
 
 	return m, http.StatusOK, nil
 }
@@ -584,3 +602,84 @@ func RegisterWatcher(
 
 	return updateOptions.SendEmail, http.StatusOK, nil
 }
+
+// SetWatcherFlags sets whether the profile watches an item and how they are
+// notified of changes to it. Flags that are not valid are left as they are.
+// Asking for email or SMS notifications of an item that is not being watched
+// will watch it, and unwatching an item removes all notifications.
+func SetWatcherFlags(
+	siteID int64,
+	profileID int64,
+	updateTypeID int64,
+	itemTypeID int64,
+	itemID int64,
+	watched sql.NullBool,
+	sendEmail sql.NullBool,
+	sendSMS sql.NullBool,
+) (
+	int,
+	error,
+) {
+
+	if watched.Valid && !watched.Bool {
+		if (sendEmail.Valid && sendEmail.Bool) || (sendSMS.Valid && sendSMS.Bool) {
+			return http.StatusBadRequest, errors.New(
+				"Notifications cannot be sent for an item that is not watched",
+			)
+		}
+
+		m := WatcherType{
+			ProfileID:  profileID,
+			ItemTypeID: itemTypeID,
+			ItemID:     itemID,
+		}
+		return m.Delete()
+	}
+
+	if (watched.Valid && watched.Bool) ||
+		(sendEmail.Valid && sendEmail.Bool) ||
+		(sendSMS.Valid && sendSMS.Bool) {
+
+		_, status, err := RegisterWatcher(
+			profileID,
+			updateTypeID,
+			itemID,
+			itemTypeID,
+			siteID,
+		)
+		if err != nil {
+			return status, err
+		}
+	}
+
+	if !sendEmail.Valid && !sendSMS.Valid {
+		return http.StatusOK, nil
+	}
+
+	watcherID, _, _, _, status, err := GetWatcherAndIgnoreStatus(
+		itemTypeID,
+		itemID,
+		profileID,
+	)
+	if err != nil {
+		return status, err
+	}
+	if watcherID == 0 {
+		// Turning notifications off for an item that isn't watched
+		return http.StatusOK, nil
+	}
+
+	m, status, err := GetWatcher(watcherID, siteID)
+	if err != nil {
+		return status, err
+	}
+
+	if sendEmail.Valid {
+		m.SendEmail = sendEmail.Bool
+	}
+	if sendSMS.Valid {
+		m.SendSMS = sendSMS.Bool
+	}
+
+	return m.Update()
+}
@@ -0,0 +1,140 @@
+package redirector
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+
+	h "github.com/microcosm-cc/microcosm/helpers"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+// AffiliateClick records a link being sent through an affiliate network, so
+// that what the networks report can be reconciled against it
+type AffiliateClick struct {
+	Id        int64         `json:"id"`
+	LinkId    int64         `json:"linkId"`
+	SiteId    sql.NullInt64 `json:"-"`
+	Domain    string        `json:"domain"`
+	Network   string        `json:"network"`
+	ProgramId sql.NullInt64 `json:"-"`
+	Clicked   time.Time     `json:"clicked"`
+}
+
+// logAffiliateClick records the click. Redirects are not authenticated so the
+// profile that clicked is not known, the site is that of the comment that the
+// link was posted in. Errors are logged and otherwise ignored, the redirect
+// must not fail because of this.
+func logAffiliateClick(link models.Link, network string, programID int) {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return
+	}
+
+	_, err = db.Exec(`--logAffiliateClick
+INSERT INTO affiliate_clicks (
+    link_id, site_id, domain, network, program_id, clicked
+) VALUES (
+    $1,
+    (
+        SELECT f.site_id
+          FROM revision_links rl
+          JOIN revisions r ON r.revision_id = rl.revision_id
+          JOIN flags f ON f.item_type_id = 4
+                      AND f.item_id = r.comment_id
+         WHERE rl.link_id = $1
+         LIMIT 1
+    ),
+    $2, $3, $4, NOW()
+)`,
+		link.Id,
+		link.Domain,
+		network,
+		sql.NullInt64{Int64: int64(programID), Valid: programID > 0},
+	)
+	if err != nil {
+		glog.Errorf(
+			"db.Exec(%d, `%s`, `%s`, %d) %+v",
+			link.Id,
+			link.Domain,
+			network,
+			programID,
+			err,
+		)
+	}
+}
+
+// GetAffiliateClicks returns the clicks from (inclusive) until (exclusive) the
+// given times, oldest first
+func GetAffiliateClicks(
+	from time.Time,
+	until time.Time,
+) (
+	[]AffiliateClick,
+	int,
+	error,
+) {
+
+	db, err := h.GetConnection()
+	if err != nil {
+		glog.Errorf("h.GetConnection() %+v", err)
+		return []AffiliateClick{}, http.StatusInternalServerError, err
+	}
+
+	rows, err := db.Query(`--GetAffiliateClicks
+SELECT affiliate_click_id
+      ,link_id
+      ,site_id
+      ,domain
+      ,network
+      ,program_id
+      ,clicked
+  FROM affiliate_clicks
+ WHERE clicked >= $1
+   AND clicked < $2
+ ORDER BY clicked ASC`,
+		from,
+		until,
+	)
+	if err != nil {
+		glog.Errorf("db.Query(%v, %v) %+v", from, until, err)
+		return []AffiliateClick{}, http.StatusInternalServerError,
+			errors.New("Database query failed")
+	}
+	defer rows.Close()
+
+	ems := []AffiliateClick{}
+	for rows.Next() {
+		m := AffiliateClick{}
+		err = rows.Scan(
+			&m.Id,
+			&m.LinkId,
+			&m.SiteId,
+			&m.Domain,
+			&m.Network,
+			&m.ProgramId,
+			&m.Clicked,
+		)
+		if err != nil {
+			glog.Errorf("rows.Scan() %+v", err)
+			return []AffiliateClick{}, http.StatusInternalServerError,
+				errors.New("Row parsing error")
+		}
+
+		ems = append(ems, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		glog.Errorf("rows.Err() %+v", err)
+		return []AffiliateClick{}, http.StatusInternalServerError,
+			errors.New("Error fetching rows")
+	}
+	rows.Close()
+
+	return ems, http.StatusOK, nil
+}
@@ -8,19 +8,69 @@ import (
 	"github.com/microcosm-cc/microcosm/models"
 )
 
-var affDomainParts = append(
-	append(
-		append(
-			append(
-				[]string{},
-				affwinDomainParts...,
-			),
-			ebayDomainParts...,
-		),
-		amazonDomainParts...,
-	),
-	webgainsDomainParts...,
-)
+// Redirector is implemented by the links of each affiliate network.
+// getDestination returns true and the affiliate URL if the network can earn
+// from the link, and false and the original URL if it cannot.
+type Redirector interface {
+	getDestination() (bool, string)
+}
+
+// programmeRedirector is implemented by the links of networks that have a
+// program per merchant, and returns the program of the last destination
+type programmeRedirector interface {
+	getProgramID() int
+}
+
+// affiliateNetwork is an affiliate network that links may be sent through
+type affiliateNetwork struct {
+	Name string
+
+	// Returns parts of the domains the network may handle, links to other
+	// domains are not offered to it
+	DomainParts func() []string
+
+	// Returns the Redirector for a link
+	NewLink func(link models.Link) Redirector
+}
+
+// affiliateNetworks are tried in order, the first that handles a link wins
+var affiliateNetworks = []affiliateNetwork{
+	affiliateNetwork{
+		Name:        "Affiliate Window",
+		DomainParts: func() []string { return affwinDomainParts },
+		NewLink:     func(link models.Link) Redirector { return &affWinLink{Link: link} },
+	},
+	affiliateNetwork{
+		Name:        "Ebay Partner Network",
+		DomainParts: func() []string { return ebayDomainParts },
+		NewLink:     func(link models.Link) Redirector { return &ebayLink{Link: link} },
+	},
+	affiliateNetwork{
+		Name:        "Webgains",
+		DomainParts: func() []string { return webgainsDomainParts },
+		NewLink:     func(link models.Link) Redirector { return &webgainsLink{Link: link} },
+	},
+	affiliateNetwork{
+		Name:        "Amazon",
+		DomainParts: func() []string { return amazonDomainParts },
+		NewLink:     func(link models.Link) Redirector { return &amazonLink{Link: link} },
+	},
+	affiliateNetwork{
+		Name:        "Skimlinks",
+		DomainParts: func() []string { return skimlinksDomainParts },
+		NewLink:     func(link models.Link) Redirector { return &skimlinksLink{Link: link} },
+	},
+}
+
+var affDomainParts = allAffiliateDomainParts()
+
+func allAffiliateDomainParts() []string {
+	parts := []string{}
+	for _, network := range affiliateNetworks {
+		parts = append(parts, network.DomainParts()...)
+	}
+	return parts
+}
 
 func affiliateMayExist(domain string) bool {
 	domains := ahocorasick.NewStringMatcher(affDomainParts)
@@ -30,38 +80,32 @@ func affiliateMayExist(domain string) bool {
 }
 
 func getAffiliateLink(link models.Link) string {
+	_, u, _, _ := getAffiliateDestination(link)
+	return u
+}
 
-	// Affiliate Window
-	if !(len(ahocorasick.NewStringMatcher(affwinDomainParts).Match([]byte(strings.ToLower(link.Domain)))) == 0) {
-		m := affWinLink{Link: link}
-		if ok, u := m.getDestination(); ok {
-			return u
-		}
-	}
+// getAffiliateDestination returns whether a network handled the link, the
+// destination URL, and the network and program that handled it
+func getAffiliateDestination(link models.Link) (bool, string, string, int) {
 
-	// Ebay Partner Network
-	if !(len(ahocorasick.NewStringMatcher(ebayDomainParts).Match([]byte(strings.ToLower(link.Domain)))) == 0) {
-		m := ebayLink{Link: link}
-		if ok, u := m.getDestination(); ok {
-			return u
+	for _, network := range affiliateNetworks {
+		parts := network.DomainParts()
+		if len(parts) == 0 {
+			continue
 		}
-	}
 
-	// Webgains
-	if !(len(ahocorasick.NewStringMatcher(webgainsDomainParts).Match([]byte(strings.ToLower(link.Domain)))) == 0) {
-		m := webgainsLink{Link: link}
-		if ok, u := m.getDestination(); ok {
-			return u
-		}
-	}
+		if !(len(ahocorasick.NewStringMatcher(parts).Match([]byte(strings.ToLower(link.Domain)))) == 0) {
+			r := network.NewLink(link)
+			if ok, u := r.getDestination(); ok {
+				var programID int
+				if p, ok := r.(programmeRedirector); ok {
+					programID = p.getProgramID()
+				}
 
-	// Amazon
-	if !(len(ahocorasick.NewStringMatcher(amazonDomainParts).Match([]byte(strings.ToLower(link.Domain)))) == 0) {
-		m := amazonLink{Link: link}
-		if ok, u := m.getDestination(); ok {
-			return u
+				return true, u, network.Name, programID
+			}
 		}
 	}
 
-	return link.Url
+	return false, link.Url, "", 0
 }
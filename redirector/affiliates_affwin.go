@@ -1,18 +1,51 @@
 package redirector
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/golang/glog"
 
+	conf "github.com/microcosm-cc/microcosm/config"
 	"github.com/microcosm-cc/microcosm/models"
 )
 
-// This must never be changed, this is how we make money
-const affWinAffiliateID string = "101164"
+// This must never be changed, this is how we make money. It is only used if
+// the AffiliateWin config file does not supply an affiliate ID.
+const defaultAffWinAffiliateID string = "101164"
 
-var affwinDomainParts = []string{
+// The programs we are members of, by merchant domain. Used unless replaced by
+// the AffiliateWin config file.
+var defaultAffWinPrograms = map[string]int{
+	"www.chainreactioncycles.com": 2698,
+	"www.cyclestore.co.uk":        3462,
+	"www.evanscycles.com":         1302,
+	"www.hargrovescycles.co.uk":   2828,
+	"www.howies.co.uk":            3167,
+	"www.merlincycles.co.uk":      3361,
+	"www.probikekit.co.uk":        3977,
+	"www.probikekit.com":          3977,
+	"www.ribblecycles.co.uk":      5923,
+	"www.rutlandcycling.com":      3395,
+	"www.wiggle.co.uk":            1857,
+	"www.wiggle.es":               1857,
+	"www.wiggle.cn":               1857,
+	"www.wiggle.com":              1857,
+	"www.wiggle.com.au":           1857,
+	"www.wiggle.fr":               1857,
+	"www.wigglesport.it":          1857,
+	"www.wigglesport.de":          1857,
+	"www.wiggle.jp":               1857,
+	"www.wiggle.ru":               1857,
+	"www.wiggle.pt":               1857,
+}
+
+var defaultAffwinDomainParts = []string{
 	".awin1.",
 	".chainreactioncycles.",
 	".cyclestore.",
@@ -26,8 +59,91 @@ var affwinDomainParts = []string{
 	".wiggle",
 }
 
+var (
+	affWinAffiliateID = defaultAffWinAffiliateID
+	affWinPrograms    = defaultAffWinPrograms
+	affwinDomainParts = defaultAffwinDomainParts
+)
+
+// affWinConfig is the content of the AffiliateWin config file, e.g.
+//
+//	{
+//	    "affiliateId": "101164",
+//	    "programs": {
+//	        "www.wiggle.co.uk": 1857
+//	    }
+//	}
+type affWinConfig struct {
+	AffiliateID string         `json:"affiliateId"`
+	Programs    map[string]int `json:"programs"`
+}
+
+func init() {
+	path := conf.CONFIG_STRING[conf.KEY_AFFWIN_CONFIG_FILE]
+	if path == "" {
+		return
+	}
+
+	err := loadAffWinConfig(path)
+	if err != nil {
+		glog.Errorf("loadAffWinConfig(`%s`) %+v. Using the defaults", path, err)
+	}
+}
+
+// loadAffWinConfig replaces the compiled in affiliate ID and programs with
+// those in the file. Nothing is replaced unless the file is valid and names
+// at least one program.
+func loadAffWinConfig(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var c affWinConfig
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return err
+	}
+
+	if strings.Trim(c.AffiliateID, " ") == "" {
+		return errors.New("affiliateId must be given")
+	}
+
+	if len(c.Programs) == 0 {
+		return errors.New("At least one program must be given")
+	}
+
+	programs := map[string]int{}
+	domainParts := []string{".awin1."}
+	for domain, programID := range c.Programs {
+		domain = strings.ToLower(strings.Trim(domain, " "))
+		if domain == "" || programID <= 0 {
+			return errors.New(
+				fmt.Sprintf("Invalid program %d for domain `%s`", programID, domain),
+			)
+		}
+
+		programs[domain] = programID
+		domainParts = append(domainParts, domain)
+	}
+
+	affWinAffiliateID = c.AffiliateID
+	affWinPrograms = programs
+	affwinDomainParts = domainParts
+	affDomainParts = allAffiliateDomainParts()
+
+	glog.Infof("Loaded %d AffiliateWin programs from %s", len(programs), path)
+
+	return nil
+}
+
 type affWinLink struct {
-	Link models.Link
+	Link      models.Link
+	programID int
+}
+
+func (m *affWinLink) getProgramID() int {
+	return m.programID
 }
 
 func (m *affWinLink) getDestination() (bool, string) {
@@ -49,54 +165,11 @@ func (m *affWinLink) getDestination() (bool, string) {
 	}
 
 	// Fetch a program ID based on domain
-	var programID int
-	switch m.Link.Domain {
-	case "www.chainreactioncycles.com":
-		programID = 2698
-	case "www.cyclestore.co.uk":
-		programID = 3462
-	case "www.evanscycles.com":
-		programID = 1302
-	case "www.hargrovescycles.co.uk":
-		programID = 2828
-	case "www.howies.co.uk":
-		programID = 3167
-	case "www.merlincycles.co.uk":
-		programID = 3361
-	case "www.probikekit.co.uk":
-		programID = 3977
-	case "www.probikekit.com":
-		programID = 3977
-	case "www.ribblecycles.co.uk":
-		programID = 5923
-	case "www.rutlandcycling.com":
-		programID = 3395
-	case "www.wiggle.co.uk":
-		programID = 1857
-	case "www.wiggle.es":
-		programID = 1857
-	case "www.wiggle.cn":
-		programID = 1857
-	case "www.wiggle.com":
-		programID = 1857
-	case "www.wiggle.com.au":
-		programID = 1857
-	case "www.wiggle.fr":
-		programID = 1857
-	case "www.wigglesport.it":
-		programID = 1857
-	case "www.wigglesport.de":
-		programID = 1857
-	case "www.wiggle.jp":
-		programID = 1857
-	case "www.wiggle.ru":
-		programID = 1857
-	case "www.wiggle.pt":
-		programID = 1857
-
-	default:
+	programID, ok := affWinPrograms[m.Link.Domain]
+	if !ok {
 		return false, m.Link.Url
 	}
+	m.programID = programID
 
 	if programID == 3977 {
 		u, _ := url.Parse(m.Link.Url)
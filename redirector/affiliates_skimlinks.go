@@ -0,0 +1,69 @@
+package redirector
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/golang/glog"
+
+	conf "github.com/microcosm-cc/microcosm/config"
+	"github.com/microcosm-cc/microcosm/models"
+)
+
+// Skimlinks wraps links to any of its merchants, so the domains we send
+// through it are configured rather than compiled in. Nothing is sent through
+// it unless a publisher ID is configured.
+var skimlinksDomainParts = getSkimlinksDomainParts()
+
+func getSkimlinksDomainParts() []string {
+	if conf.CONFIG_STRING[conf.KEY_SKIMLINKS_ID] == "" {
+		return []string{}
+	}
+
+	parts := []string{"go.redirectingat.com"}
+	for _, domain := range strings.Split(conf.CONFIG_STRING[conf.KEY_SKIMLINKS_DOMAINS], ",") {
+		domain = strings.ToLower(strings.Trim(domain, " "))
+		if domain != "" {
+			parts = append(parts, domain)
+		}
+	}
+
+	return parts
+}
+
+type skimlinksLink struct {
+	Link models.Link
+}
+
+func (m *skimlinksLink) getDestination() (bool, string) {
+
+	publisherID := conf.CONFIG_STRING[conf.KEY_SKIMLINKS_ID]
+	if publisherID == "" {
+		return false, m.Link.Url
+	}
+
+	// Hijack an existing affiliate link
+	if m.Link.Domain == "go.redirectingat.com" {
+		u, err := url.Parse(m.Link.Url)
+		if err != nil {
+			glog.Errorf("url.Parse(`%s`) %+v", m.Link.Url, err)
+			return false, m.Link.Url
+		}
+
+		q := u.Query()
+		q.Del("id")
+		q.Add("id", publisherID)
+		u.RawQuery = q.Encode()
+
+		return true, u.String()
+	}
+
+	u, _ := url.Parse("http://go.redirectingat.com/")
+	q := u.Query()
+	q.Add("id", publisherID)
+	q.Add("xs", "1")
+	q.Add("url", m.Link.Url)
+	u.RawQuery = q.Encode()
+
+	return true, u.String()
+}
@@ -1,8 +1,11 @@
 package redirector
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
+	conf "github.com/microcosm-cc/microcosm/config"
 	"github.com/microcosm-cc/microcosm/models"
 )
 
@@ -22,3 +25,87 @@ func TestAffiliatesMatching(t *testing.T) {
 		t.Error("Chain Reaction URL (Affiliate Window) did not match expected value")
 	}
 }
+
+func TestLoadAffWinConfig(t *testing.T) {
+	defer func() {
+		affWinAffiliateID = defaultAffWinAffiliateID
+		affWinPrograms = defaultAffWinPrograms
+		affwinDomainParts = defaultAffwinDomainParts
+		affDomainParts = allAffiliateDomainParts()
+	}()
+
+	f, err := ioutil.TempFile("", "affwin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	// An empty config must not replace the defaults
+	ioutil.WriteFile(f.Name(), []byte(`{"affiliateId": "1", "programs": {}}`), 0644)
+	if err := loadAffWinConfig(f.Name()); err == nil {
+		t.Error("A config without programs was accepted")
+	}
+	if affWinAffiliateID != defaultAffWinAffiliateID {
+		t.Error("A rejected config replaced the affiliate ID")
+	}
+
+	ioutil.WriteFile(f.Name(), []byte(`{"affiliateId": "42", "programs": {"www.example.com": 7}}`), 0644)
+	if err := loadAffWinConfig(f.Name()); err != nil {
+		t.Fatalf("loadAffWinConfig() failed: %+v", err)
+	}
+
+	m := models.Link{Domain: "www.example.com", Url: "http://www.example.com/"}
+	if !affiliateMayExist(m.Domain) {
+		t.Error(`affiliateMayExist("www.example.com") should be true`)
+	}
+
+	s := getAffiliateLink(m)
+	if s != `http://www.awin1.com/cread.php?awinaffid=42&awinmid=7&clickref=&p=http%3A%2F%2Fwww.example.com%2F` {
+		t.Errorf("Unexpected link for a configured program: %s", s)
+	}
+}
+
+func TestAffiliatesNoMatch(t *testing.T) {
+	m := models.Link{
+		Domain: "www.example.org",
+		Url:    "http://www.example.org/some/page?q=1",
+	}
+
+	if s := getAffiliateLink(m); s != m.Url {
+		t.Errorf("Expected an unmatched link to be unchanged, got %s", s)
+	}
+}
+
+func TestSkimlinks(t *testing.T) {
+	defer func() {
+		conf.CONFIG_STRING[conf.KEY_SKIMLINKS_ID] = ""
+		conf.CONFIG_STRING[conf.KEY_SKIMLINKS_DOMAINS] = ""
+		skimlinksDomainParts = getSkimlinksDomainParts()
+	}()
+
+	conf.CONFIG_STRING[conf.KEY_SKIMLINKS_ID] = "12345X678"
+	conf.CONFIG_STRING[conf.KEY_SKIMLINKS_DOMAINS] = "www.example.org, shop.example.net"
+	skimlinksDomainParts = getSkimlinksDomainParts()
+
+	m := models.Link{
+		Domain: "www.example.org",
+		Url:    "http://www.example.org/bike",
+	}
+
+	s := getAffiliateLink(m)
+	if s != `http://go.redirectingat.com/?id=12345X678&url=http%3A%2F%2Fwww.example.org%2Fbike&xs=1` {
+		t.Errorf("Unexpected Skimlinks link: %s", s)
+	}
+}
+
+func TestAffiliateDestinationProgram(t *testing.T) {
+	m := models.Link{
+		Domain: "www.chainreactioncycles.com",
+		Url:    "http://www.chainreactioncycles.com/",
+	}
+
+	ok, _, network, programID := getAffiliateDestination(m)
+	if !ok || network != "Affiliate Window" || programID != 2698 {
+		t.Errorf("Got (%t, %s, %d), expected Affiliate Window program 2698", ok, network, programID)
+	}
+}
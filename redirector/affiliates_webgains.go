@@ -21,7 +21,12 @@ var webgainsDomainParts = []string{
 }
 
 type webgainsLink struct {
-	Link models.Link
+	Link      models.Link
+	programID int
+}
+
+func (m *webgainsLink) getProgramID() int {
+	return m.programID
 }
 
 func (m *webgainsLink) getDestination() (bool, string) {
@@ -60,6 +65,7 @@ func (m *webgainsLink) getDestination() (bool, string) {
 	default:
 		return false, m.Link.Url
 	}
+	m.programID = programID
 
 	// Create our affiliate link
 	u, _ := url.Parse("http://track.webgains.com/click.html")
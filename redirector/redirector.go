@@ -85,7 +85,11 @@ RETURNING
 	}
 
 	if affiliateMayExist(m.Domain) {
-		m.Url = getAffiliateLink(m)
+		ok, u, network, programID := getAffiliateDestination(m)
+		if ok {
+			go logAffiliateClick(m, network, programID)
+		}
+		m.Url = u
 	}
 
 	//glog.Infof("Found models.link %s redirecting to %s", shortURL, m.Url)
@@ -1,6 +1,13 @@
 package server
 
 import (
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/robfig/cron"
+
+	conf "github.com/microcosm-cc/microcosm/config"
 	"github.com/microcosm-cc/microcosm/models"
 )
 
@@ -13,15 +20,83 @@ import (
 // Month        | Yes        | 1-12 or JAN-DEC | * / , -
 // Day of week  | Yes        | 0-6 or SUN-SAT  | * / , - ?
 
+// cronJob is a function run on a schedule. The schedule may be overridden in
+// the [cron] section of the config file using the name of the job as the key,
+// and an empty schedule disables the job.
+type cronJob struct {
+	Schedule string
+	Func     func() error
+}
+
+// logsErrors adapts a job that logs its own errors rather than returning them
+func logsErrors(f func()) func() error {
+	return func() error {
+		f()
+		return nil
+	}
+}
+
 var (
-	jobs = map[string]func(){
-		//SS MI HH  DOM MON DOW
-		"  0  *  *    *   *   *": models.UpdateViewCounts,          // Every minute
-		" 30  *  *    *   *   *": models.UpdateWhosOnline,          // Every minute at 30s
-		"  0 30  *    *   *   *": models.UpdateAllSiteStats,        // Every hour at half past
-		"  0  0  0/4  *   *   *": models.UpdateMetricsCron,         // Every day at midnight and every 4 hours thereafter
-		"  0  0  2    *   *   *": models.UpdateMicrocosmItemCounts, // Every day at 2am
-		"  0  0  4    *   *   *": models.DeleteOrphanedHuddles,     // Every day at 4am
-		"  0  0  3    *   *   0": models.UpdateProfileCounts,       // Every Sunday at 3am
+	jobs = map[string]cronJob{
+		//                                    SS MI HH  DOM MON DOW
+		"flush_last_active":            {"0/15 *  *    *   *   *", models.FlushLastActive},                         // Every 15 seconds
+		"update_view_counts":           {"  0  *  *    *   *   *", models.UpdateViewCounts},                        // Every minute
+		"load_reserved_profile_names":  {" 15 0/10 *   *   *   *", logsErrors(models.LoadReservedProfileNames)},    // Every 10 minutes at 15s
+		"update_whos_online":           {" 30  *  *    *   *   *", logsErrors(models.UpdateWhosOnline)},            // Every minute at 30s
+		"update_event_statuses":        {" 45 0/15 *   *   *   *", logsErrors(models.UpdateEventStatuses)},         // Every 15 minutes at 45s
+		"update_unread_huddle_counts":  {" 50 5/15 *   *   *   *", logsErrors(models.UpdateAllUnreadHuddleCounts)}, // Every 15 minutes from 5 past, at 50s
+		"update_all_site_stats":        {"  0 30  *    *   *   *", logsErrors(models.UpdateAllSiteStats)},          // Every hour at half past
+		"update_metrics":               {"  0  0  0/4  *   *   *", logsErrors(models.UpdateMetricsCron)},           // Every day at midnight and every 4 hours thereafter
+		"update_microcosm_item_counts": {"  0  0  2    *   *   *", logsErrors(models.UpdateMicrocosmItemCounts)},   // Every day at 2am
+		"delete_orphaned_huddles":      {"  0  0  4    *   *   *", logsErrors(models.DeleteOrphanedHuddles)},       // Every day at 4am
+		"delete_expired_ignores":       {"  0 15  4    *   *   *", logsErrors(models.DeleteExpiredIgnores)},        // Every day at 4:15am
+		"delete_orphaned_attachments":  {"  0 30  4    *   *   *", logsErrors(models.DeleteOrphanedAttachments)},   // Every day at 4:30am
+		"delete_expired_access_tokens": {"  0 45  4    *   *   *", logsErrors(models.DeleteExpiredAccessTokens)},   // Every day at 4:45am
+		"update_profile_counts":        {"  0  0  3    *   *   0", logsErrors(models.UpdateProfileCounts)},         // Every Sunday at 3am
 	}
 )
+
+// scheduleJobs registers the jobs so that they can be run on demand, and adds
+// them to the scheduler using the schedules in the config file where given and
+// the defaults above otherwise. An invalid schedule is fatal, so that mistakes
+// are noticed at startup.
+func scheduleJobs(c *cron.Cron) {
+
+	for name := range conf.CONFIG_CRON {
+		if _, ok := jobs[name]; !ok {
+			glog.Warningf("Unknown cron job in config: %s", name)
+		}
+	}
+
+	names := []string{}
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		job := jobs[name]
+
+		spec := job.Schedule
+		if override, ok := conf.CONFIG_CRON[name]; ok {
+			spec = override
+		}
+		spec = strings.TrimSpace(spec)
+
+		// Disabled jobs may still be run on demand
+		registered := models.RegisterCronJob(name, spec, job.Func)
+
+		if spec == "" {
+			glog.Infof("Cron job %s is disabled", name)
+			continue
+		}
+
+		schedule, err := cron.Parse(spec)
+		if err != nil {
+			glog.Fatalf("Invalid schedule for cron job %s `%s`: %+v", name, spec, err)
+		}
+
+		c.Schedule(schedule, registered)
+		glog.Infof("Cron job %s scheduled at `%s`", name, spec)
+	}
+}
@@ -8,7 +8,12 @@ import (
 
 var (
 	rootHandlers = map[string]func(http.ResponseWriter, *http.Request){
-		"/api/v1/auth": controller.AuthHandler,
+		"/api/v1/auth":         controller.AuthHandler,
+		"/api/v1/auth/emails":  controller.AuthEmailsHandler,
+		"/api/v1/auth/refresh": controller.AuthRefreshHandler,
+
+		"/api/v1/cron":               controller.CronJobsHandler,
+		"/api/v1/cron/{job:[a-z_]+}": controller.CronJobHandler,
 
 		"/api/v1/hosts/{host:[0-9a-zA-Z-.]+}": controller.SiteHostHandler,
 
@@ -37,10 +42,12 @@ var (
 		"/api/v1/whoami": controller.WhoAmIHandler,
 	}
 	siteHandlers = map[string]func(http.ResponseWriter, *http.Request){
-		"/":            controller.RootHandler,
-		"/api":         controller.ApiHandler,
-		"/api/v1":      controller.V1Handler,
-		"/api/v1/auth": controller.AuthHandler,
+		"/":                    controller.RootHandler,
+		"/api":                 controller.ApiHandler,
+		"/api/v1":              controller.V1Handler,
+		"/api/v1/auth":         controller.AuthHandler,
+		"/api/v1/auth/emails":  controller.AuthEmailsHandler,
+		"/api/v1/auth/refresh": controller.AuthRefreshHandler,
 
 		"/api/v1/{type:comments}":                                                                controller.CommentsHandler,
 		"/api/v1/{type:comments}/{comment_id:[0-9]+}":                                            controller.CommentHandler,
@@ -48,28 +55,43 @@ var (
 		"/api/v1/{type:comments}/{comment_id:[0-9]+}/attachments/{fileHash:[0-9A-Za-z]+}.{null}": controller.AttachmentHandler,
 		"/api/v1/{type:comments}/{comment_id:[0-9]+}/attachments/{fileHash:[0-9A-Za-z]+}":        controller.AttachmentHandler,
 		"/api/v1/{type:comments}/{comment_id:[0-9]+}/incontext":                                  controller.CommentContextHandler,
+		"/api/v1/{type:comments}/{comment_id:[0-9]+}/revisions":                                  controller.CommentRevisionsHandler,
+		"/api/v1/{type:comments}/{comment_id:[0-9]+}/reactions":                                  controller.CommentReactionsHandler,
+		"/api/v1/{type:comments}/{comment_id:[0-9]+}/reactions/{reaction:[a-zA-Z]+}":             controller.CommentReactionHandler,
 		"/api/v1/{type:comments}/{comment_id:[0-9]+}/attributes":                                 controller.AttributesHandler,
+		"/api/v1/{type:comments}/{comment_id:[0-9]+}/audit":                                      controller.AuditTrailHandler,
 		"/api/v1/{type:comments}/{comment_id:[0-9]+}/attributes/{key:[0-9a-zA-Z_-]+}":            controller.AttributeHandler,
 
 		"/api/v1/{type:conversations}":                                                          controller.ConversationsHandler,
 		"/api/v1/{type:conversations}/{conversation_id:[0-9]+}":                                 controller.ConversationHandler,
 		"/api/v1/{type:conversations}/{conversation_id:[0-9]+}/attributes":                      controller.AttributesHandler,
+		"/api/v1/{type:conversations}/{conversation_id:[0-9]+}/audit":                           controller.AuditTrailHandler,
 		"/api/v1/{type:conversations}/{conversation_id:[0-9]+}/attributes/{key:[0-9a-zA-Z_-]+}": controller.AttributeHandler,
 		"/api/v1/{type:conversations}/{conversation_id:[0-9]+}/lastcomment":                     controller.LastCommentHandler,
 		"/api/v1/{type:conversations}/{conversation_id:[0-9]+}/newcomment":                      controller.NewCommentHandler,
-
-		"/api/v1/{type:events}":                                                   controller.EventsHandler,
-		"/api/v1/{type:events}/{event_id:[0-9]+}":                                 controller.EventHandler,
-		"/api/v1/{type:events}/{event_id:[0-9]+}/attendees":                       controller.AttendeesHandler,
-		"/api/v1/{type:events}/{event_id:[0-9]+}/attendees/{profile_id:[0-9]+}":   controller.AttendeeHandler,
-		"/api/v1/{type:events}/{event_id:[0-9]+}/attributes":                      controller.AttributesHandler,
-		"/api/v1/{type:events}/{event_id:[0-9]+}/attributes/{key:[0-9a-zA-Z_-]+}": controller.AttributeHandler,
-		"/api/v1/{type:events}/{event_id:[0-9]+}/lastcomment":                     controller.LastCommentHandler,
-		"/api/v1/{type:events}/{event_id:[0-9]+}/newcomment":                      controller.NewCommentHandler,
-
-		"/api/v1/files":                                controller.FilesHandler,
-		"/api/v1/files/{fileHash:[0-9A-Za-z]+}.{null}": controller.FileHandler,
-		"/api/v1/files/{fileHash:[0-9A-Za-z]+}":        controller.FileHandler,
+		"/api/v1/{type:conversations}/{conversation_id:[0-9]+}/tags":                            controller.ConversationTagsHandler,
+		"/api/v1/{type:conversations}/{conversation_id:[0-9]+}/tags/{tag:[^/]+}":                controller.ConversationTagHandler,
+
+		"/api/v1/{type:events}":                                                              controller.EventsHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}":                                            controller.EventHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/attendees":                                  controller.AttendeesHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/attendees/{profile_id:[0-9]+}":              controller.AttendeeHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/attachments":                                controller.AttachmentsHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/attachments/{fileHash:[0-9A-Za-z]+}.{null}": controller.AttachmentHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/attachments/{fileHash:[0-9A-Za-z]+}":        controller.AttachmentHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/attributes":                                 controller.AttributesHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/audit":                                      controller.AuditTrailHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/attributes/{key:[0-9a-zA-Z_-]+}":            controller.AttributeHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/ical":                                       controller.EventICalHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/invites":                                    controller.EventInvitesHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/invites/{profile_id:[0-9]+}":                controller.EventInviteHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/lastcomment":                                controller.LastCommentHandler,
+		"/api/v1/{type:events}/{event_id:[0-9]+}/newcomment":                                 controller.NewCommentHandler,
+
+		"/api/v1/files": controller.FilesHandler,
+		"/api/v1/files/{fileHash:[0-9A-Za-z]+}.{null}":    controller.FileHandler,
+		"/api/v1/files/{fileHash:[0-9A-Za-z]+}":           controller.FileHandler,
+		"/api/v1/files/{fileHash:[0-9A-Za-z]+}/thumbnail": controller.FileThumbnailHandler,
 
 		"/api/v1/geocode": controller.GeoCodeHandler,
 
@@ -90,6 +112,7 @@ var (
 		"/api/v1/{type:microcosms}":                                                                             controller.MicrocosmsHandler,
 		"/api/v1/{type:microcosms}/{microcosm_id:[0-9]+}":                                                       controller.MicrocosmHandler,
 		"/api/v1/{type:microcosms}/{microcosm_id:[0-9]+}/attributes":                                            controller.AttributesHandler,
+		"/api/v1/{type:microcosms}/{microcosm_id:[0-9]+}/audit":                                                 controller.AuditTrailHandler,
 		"/api/v1/{type:microcosms}/{microcosm_id:[0-9]+}/attributes/{key:[0-9a-zA-Z_-]+}":                       controller.AttributeHandler,
 		"/api/v1/{type:microcosms}/{microcosm_id:[0-9]+}/effectivepermissions":                                  controller.EffectivePermissionsHandler,
 		"/api/v1/{type:microcosms}/{microcosm_id:[0-9]+}/effectivepermissions/{profile_id:[0-9]+}":              controller.EffectivePermissionsHandler,
@@ -108,17 +131,25 @@ var (
 		"/api/v1/{type:polls}/{poll_id:[0-9]+}/lastcomment":                     controller.LastCommentHandler,
 		"/api/v1/{type:polls}/{poll_id:[0-9]+}/newcomment":                      controller.NewCommentHandler,
 		"/api/v1/{type:polls}/{poll_id:[0-9]+}/attributes":                      controller.AttributesHandler,
+		"/api/v1/{type:polls}/{poll_id:[0-9]+}/audit":                           controller.AuditTrailHandler,
 		"/api/v1/{type:polls}/{poll_id:[0-9]+}/attributes/{key:[0-9a-zA-Z_-]+}": controller.AttributeHandler,
 
-		"/api/v1/{type:profiles}":                                                                controller.ProfilesHandler,
-		"/api/v1/{type:profiles}/options":                                                        controller.ProfileOptionsHandler,
-		"/api/v1/{type:profiles}/read":                                                           controller.ProfileReadHandler,
-		"/api/v1/{type:profiles}/{profile_id:[0-9]+}":                                            controller.ProfileHandler,
-		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/attachments":                                controller.AttachmentsHandler,
+		"/api/v1/{type:profiles}":                                 controller.ProfilesHandler,
+		"/api/v1/{type:profiles}/import":                          controller.ProfileImportHandler,
+		"/api/v1/{type:profiles}/online":                          controller.ProfilesOnlineHandler,
+		"/api/v1/{type:profiles}/options":                         controller.ProfileOptionsHandler,
+		"/api/v1/{type:profiles}/read":                            controller.ProfileReadHandler,
+		"/api/v1/{type:profiles}/{profile_id:[0-9]+}":             controller.ProfileHandler,
+		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/attachments": controller.AttachmentsHandler,
 		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/attachments/{fileHash:[0-9A-Za-z]+}.{null}": controller.AttachmentHandler,
 		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/attachments/{fileHash:[0-9A-Za-z]+}":        controller.AttachmentHandler,
 		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/attributes":                                 controller.AttributesHandler,
 		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/attributes/{key:[0-9a-zA-Z_-]+}":            controller.AttributeHandler,
+		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/events":                                     controller.ProfileEventsHandler,
+		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/export":                                     controller.ProfileExportHandler,
+		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/namehistory":                                controller.ProfileNameHistoryHandler,
+		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/merge":                                      controller.ProfileMergeHandler,
+		"/api/v1/{type:profiles}/{profile_id:[0-9]+}/audit":                                      controller.AuditTrailHandler,
 
 		"/api/v1/resolve": controller.Redirect404Handler,
 
@@ -130,6 +161,8 @@ var (
 		"/api/v1/roles/{role_id:[0-9]+}/criteria/{criterion_id:[0-9]+}": controller.RoleCriterionHandler,
 		"/api/v1/roles/{role_id:[0-9]+}/members":                        controller.RoleMembersHandler,
 
+		"/api/v1/mentions": controller.MentionsHandler,
+
 		"/api/v1/search": controller.SearchHandler,
 
 		"/api/v1/{type:site}":                                                  controller.SiteHandler,
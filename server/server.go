@@ -16,9 +16,7 @@ func StartServer(port int64) {
 
 	// Set up the cron jobs
 	c := cron.New()
-	for schedule, job := range jobs {
-		c.AddFunc(schedule, job)
-	}
+	scheduleJobs(c)
 	c.Start()
 
 	r := mux.NewRouter()